@@ -0,0 +1,336 @@
+package controller
+
+import (
+	"com668-backend/database"
+	"com668-backend/utility"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type GetManyAPIKeysResponseSchema utility.GetManyResponseSchema[*utility.APIKeyGetResponseBodySchema]
+
+// GetAPIKeys godoc
+//
+//	@Summary		Get a list of API keys
+//	@Description	Get a list of the API keys of the logged in user, or of any user with the user:read permission. Keys that have expired or been revoked are included
+//	@Tags			API Keys
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			user_id			path		string	true	"User UUID"
+//	@Param			page			query		int		false	"Page number"
+//	@Param			pageSize		query		int		false	"Number of items per page"
+//	@Param			cursor			query		string	false	"Cursor of the page to get, from the meta of another page"
+//	@Param			sort			query		string	false	"Comma separated list of fields to sort by, each prefixed with '-' for descending order. One of name, createdAt, expiresAt"
+//	@Param			If-None-Match	header		string	false	"ETag of a copy already held, to get a 304 if it is still current"
+//	@Success		200				{object}	GetManyAPIKeysResponseSchema
+//	@Header			200				{string}	ETag	"Version of the response"
+//	@Success		304
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/me/api-keys [get]
+//	@Router			/users/{user_id}/api-keys [get]
+func GetAPIKeys() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		params, err := getCommonParams(ctx)
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		page := params["page"].(int)
+		pageSize := params["pageSize"].(int)
+
+		sort, err := getSortParam(ctx, database.APIKeySortFields)
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		user, ok := getAPIKeyOwner(ctx)
+		if !ok {
+			return
+		}
+
+		cursors := &database.PageCursors{}
+		keys, count, err := database.GetAPIKeys(ctx, database.GetAPIKeysFilters{
+			UserID:   &user.ID,
+			Page:     &page,
+			PageSize: &pageSize,
+			Sort:     sort,
+			Cursor:   params["cursor"].(*string),
+			Cursors:  cursors,
+		})
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		resp := &utility.GetManyResponseSchema[*utility.APIKeyGetResponseBodySchema]{
+			Data: make([]*utility.APIKeyGetResponseBodySchema, 0),
+			Meta: utility.MetaSchema{
+				TotalItems: count,
+				Pages:      int(math.Ceil(float64(count) / float64(pageSize))),
+				Page:       page,
+				PageSize:   pageSize,
+				Next:       cursors.Next,
+				Prev:       cursors.Prev,
+			},
+		}
+		for _, key := range keys {
+			resp.Data = append(resp.Data, newAPIKeyResponse(key))
+		}
+		ctx.Set("Status", http.StatusOK)
+		ctx.Set("Body", resp)
+	}
+}
+
+// GetAPIKey godoc
+//
+//	@Summary		Get an API key
+//	@Description	Get an API key of the logged in user, or of any user with the user:read permission. The key itself is only shown when it is created
+//	@Tags			API Keys
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			user_id			path		string	true	"User UUID"
+//	@Param			key_id			path		string	true	"API key UUID"
+//	@Param			If-None-Match	header		string	false	"ETag of a copy already held, to get a 304 if it is still current"
+//	@Success		200				{object}	utility.APIKeyGetResponseBodySchema
+//	@Header			200				{string}	ETag	"Version of the response"
+//	@Success		304
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/me/api-keys/{key_id} [get]
+//	@Router			/users/{user_id}/api-keys/{key_id} [get]
+func GetAPIKey() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key, ok := getAPIKeyParam(ctx)
+		if !ok {
+			return
+		}
+
+		ctx.Set("Status", http.StatusOK)
+		ctx.Set("Body", newAPIKeyResponse(key))
+	}
+}
+
+// CreateAPIKey godoc
+//
+//	@Summary		Create an API key
+//	@Description	Create a personal API key for the logged in user, or a key for a service account with the user:write permission. The key is sent in the X-API-Key header instead of a JWT, and can only be used for the permissions in its scopes that its user holds. It is only shown in this response, and keys cannot be created when authenticated with a key
+//	@Tags			API Keys
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			user_id	path		string								true	"User UUID"
+//	@Param			body	body		utility.APIKeyPostRequestBodySchema	true	"API key creation request"
+//	@Header			201		header		string								"GET URL"
+//	@Success		201		{object}	utility.APIKeyPostResponseBodySchema
+//	@Failure		400		{object}	utility.ErrorResponseSchema
+//	@Failure		401		{object}	utility.ErrorResponseSchema
+//	@Failure		403		{object}	utility.ErrorResponseSchema
+//	@Failure		404		{object}	utility.ErrorResponseSchema
+//	@Failure		500		{object}	utility.ErrorResponseSchema
+//	@Router			/me/api-keys [post]
+//	@Router			/users/{user_id}/api-keys [post]
+func CreateAPIKey() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		// a key could otherwise be used to make a key which outlives it
+		if _, ok := ctx.Get("apiKey"); ok {
+			ctx.Set("Status", http.StatusForbidden)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "api keys cannot be created when authenticated with an api key",
+			})
+			ctx.Next()
+			return
+		}
+
+		var body *utility.APIKeyPostRequestBodySchema
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		user, ok := getAPIKeyOwner(ctx)
+		if !ok {
+			return
+		}
+		self := user.ID == ctx.MustGet("user").(*database.User).ID
+		if !self && !user.ServiceAccount {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "api keys can only be created for service accounts, other users create their own",
+			})
+			ctx.Next()
+			return
+		}
+
+		scopes := make([]database.APIKeyScope, 0)
+		for _, permission := range body.Scopes {
+			if !slices.Contains(database.Permissions, permission) {
+				ctx.Set("Status", http.StatusBadRequest)
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: fmt.Sprintf("'%s' is not a permission", permission),
+				})
+				ctx.Next()
+				return
+			}
+			if self && !database.HasPermission(ctx, permission) {
+				ctx.Set("Status", http.StatusForbidden)
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: fmt.Sprintf("logged in user does not have the '%s' permission", permission),
+				})
+				ctx.Next()
+				return
+			}
+			if !slices.ContainsFunc(scopes, func(scope database.APIKeyScope) bool { return scope.Permission == permission }) {
+				scopes = append(scopes, database.APIKeyScope{Permission: permission})
+			}
+		}
+
+		key := &database.APIKey{
+			Name:      body.Name,
+			UserID:    user.ID,
+			Scopes:    scopes,
+			ExpiresAt: body.ExpiresAt,
+		}
+		secret, err := database.CreateAPIKey(ctx, key)
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Header("Location", fmt.Sprintf("%s://%s%s/%s", ctx.Request.URL.Scheme, ctx.Request.URL.Host, ctx.Request.URL.Path, key.UUID))
+		ctx.Set("Status", http.StatusCreated)
+		ctx.Set("Body", &utility.APIKeyPostResponseBodySchema{
+			APIKeyGetResponseBodySchema: *newAPIKeyResponse(key),
+			Key:                         secret,
+		})
+	}
+}
+
+// RevokeAPIKey godoc
+//
+//	@Summary		Revoke an API key
+//	@Description	Revoke an API key of the logged in user, or of any user with the user:write permission, so it can no longer be used. It is still listed, with when it was revoked
+//	@Tags			API Keys
+//	@Security		JWT
+//	@Produce		json
+//	@Param			user_id	path	string	true	"User UUID"
+//	@Param			key_id	path	string	true	"API key UUID"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/me/api-keys/{key_id} [delete]
+//	@Router			/users/{user_id}/api-keys/{key_id} [delete]
+func RevokeAPIKey() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key, ok := getAPIKeyParam(ctx)
+		if !ok {
+			return
+		}
+
+		if err := database.RevokeAPIKey(ctx, key); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// Get the user whose API keys a request is for, which is the logged in user on the /me routes
+func getAPIKeyOwner(ctx *gin.Context) (*database.User, bool) {
+	if ctx.Param("user_id") == "" {
+		return ctx.MustGet("user").(*database.User), true
+	}
+	return getUserParam(ctx)
+}
+
+func getAPIKeyParam(ctx *gin.Context) (*database.APIKey, bool) {
+	keyUUID := ctx.Param("key_id")
+	if _, err := uuid.Parse(keyUUID); err != nil {
+		ctx.Set("Status", http.StatusBadRequest)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: "invalid api key UUID",
+		})
+		ctx.Next()
+		return nil, false
+	}
+
+	user, ok := getAPIKeyOwner(ctx)
+	if !ok {
+		return nil, false
+	}
+	key, err := database.GetAPIKey(ctx, database.GetAPIKeysFilters{
+		UserID: &user.ID,
+		UUIDs:  []string{keyUUID},
+	})
+	if err != nil {
+		ctx.Set("Status", ctx.GetInt("errorCode"))
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return nil, false
+	}
+	return key, true
+}
+
+func newAPIKeyResponse(key *database.APIKey) *utility.APIKeyGetResponseBodySchema {
+	return &utility.APIKeyGetResponseBodySchema{
+		UUID:       key.UUID,
+		Name:       key.Name,
+		Scopes:     key.ScopeNames(),
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
@@ -2,9 +2,15 @@ package controller
 
 import (
 	"com668-backend/database"
+	"com668-backend/middleware"
 	"com668-backend/utility"
+	"crypto/ecdsa"
 	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
 	"encoding/hex"
+	"fmt"
+	"math/big"
 	"net/http"
 	"os"
 	"time"
@@ -175,3 +181,79 @@ func AuthoriseSlack() gin.HandlerFunc {
 		ctx.Redirect(302, "http://localhost:3000/dashboard")
 	}
 }
+
+// GetJWKS godoc
+//
+//	@Summary		Get the keys tokens are signed with
+//	@Description	Get the public keys of every signing key, as a JSON Web Key Set (RFC 7517), so other services can verify access tokens offline. A token names the key it is signed with in its kid header. A key is listed for as long as the response may be cached before it starts signing, and until every token it signed has expired
+//	@Tags			Third-Party Auth
+//	@Produce		json
+//	@Param			If-None-Match	header		string	false	"ETag of a copy already held, to get a 304 if it is still current"
+//	@Success		200				{object}	utility.JWKSetResponseSchema
+//	@Header			200				{string}	ETag			"Version of the response"
+//	@Header			200				{string}	Cache-Control	"How long the response may be cached for"
+//	@Success		304
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/.well-known/jwks.json [get]
+func GetJWKS() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		keys, err := database.GetSigningKeys(ctx)
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		resp := &utility.JWKSetResponseSchema{
+			Keys: make([]utility.JWKResponseSchema, 0),
+		}
+		for _, key := range keys {
+			jwk, err := newJWKResponse(key)
+			if err != nil {
+				ctx.Set("Status", http.StatusInternalServerError)
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: err.Error(),
+				})
+				ctx.Next()
+				return
+			}
+			resp.Keys = append(resp.Keys, *jwk)
+		}
+		// a new key is published for this long before it signs, so a cached copy always has the key a token names
+		ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(middleware.JWKSCacheLifetime.Seconds())))
+		ctx.Set("Status", http.StatusOK)
+		ctx.Set("Body", resp)
+	}
+}
+
+func newJWKResponse(key *database.SigningKey) (*utility.JWKResponseSchema, error) {
+	signer, err := key.Signer()
+	if err != nil {
+		return nil, err
+	}
+	jwk := &utility.JWKResponseSchema{
+		Use: "sig",
+		Alg: key.Algorithm,
+		Kid: key.KID,
+	}
+	encode := base64.RawURLEncoding.EncodeToString
+	switch public := signer.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encode(public.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		// the coordinates are padded to the size of the curve
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = public.Curve.Params().Name
+		jwk.X = encode(public.X.FillBytes(make([]byte, size)))
+		jwk.Y = encode(public.Y.FillBytes(make([]byte, size)))
+	default:
+		return nil, fmt.Errorf("signing key %s is neither an RSA nor an EC key", key.KID)
+	}
+	return jwk, nil
+}
@@ -0,0 +1,250 @@
+package controller
+
+import (
+	"com668-backend/database"
+	"com668-backend/utility"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type GetManyGroupMappingsResponseSchema utility.GetManyResponseSchema[*utility.GroupMappingGetResponseBodySchema]
+
+// GetGroupMappings godoc
+//
+//	@Summary		Get a list of group mappings
+//	@Description	Get the identity provider groups whose members are given a team membership, a role or the admin flag when they log in with single sign-on
+//	@Tags			Roles
+//	@Security		JWT
+//	@Produce		json
+//	@Success		200	{object}	GetManyGroupMappingsResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/group-mappings [get]
+func GetGroupMappings() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		mappings, err := database.GetGroupMappings(ctx)
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		// there are only ever a few mappings, so they are all given on one page
+		resp := &utility.GetManyResponseSchema[*utility.GroupMappingGetResponseBodySchema]{
+			Data: make([]*utility.GroupMappingGetResponseBodySchema, 0),
+			Meta: utility.MetaSchema{
+				TotalItems: int64(len(mappings)),
+				Pages:      1,
+				Page:       1,
+				PageSize:   len(mappings),
+			},
+		}
+		for _, mapping := range mappings {
+			resp.Data = append(resp.Data, newGroupMappingResponse(mapping))
+		}
+		ctx.Set("Status", http.StatusOK)
+		ctx.Set("Body", resp)
+	}
+}
+
+// GetGroupMapping godoc
+//
+//	@Summary		Get a group mapping
+//	@Description	Get what the members of an identity provider group are given when they log in with single sign-on
+//	@Tags			Roles
+//	@Security		JWT
+//	@Produce		json
+//	@Param			mapping_id	path		string	true	"Group mapping UUID"
+//	@Success		200			{object}	utility.GroupMappingGetResponseBodySchema
+//	@Failure		400			{object}	utility.ErrorResponseSchema
+//	@Failure		401			{object}	utility.ErrorResponseSchema
+//	@Failure		403			{object}	utility.ErrorResponseSchema
+//	@Failure		404			{object}	utility.ErrorResponseSchema
+//	@Failure		500			{object}	utility.ErrorResponseSchema
+//	@Router			/group-mappings/{mapping_id} [get]
+func GetGroupMapping() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		mapping, ok := getGroupMappingParam(ctx)
+		if !ok {
+			return
+		}
+
+		ctx.Set("Status", http.StatusOK)
+		ctx.Set("Body", newGroupMappingResponse(mapping))
+	}
+}
+
+// CreateGroupMapping godoc
+//
+//	@Summary		Create a group mapping
+//	@Description	Give the members of an identity provider group a team membership, a role or the admin flag. They are given it the next time they log in with single sign-on, and lose it when they log in after leaving the group. Only an admin can map a group to the admin flag or the admin role
+//	@Tags			Roles
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body	utility.GroupMappingPostRequestBodySchema	true	"Group mapping creation request"
+//	@Header			201		header	string										"GET URL"
+//	@Success		201
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/group-mappings [post]
+func CreateGroupMapping() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var body *utility.GroupMappingPostRequestBodySchema
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		mapping := &database.GroupMapping{
+			Group:    body.Group,
+			TeamRole: database.TeamRoleMember,
+			Admin:    body.Admin,
+		}
+		if body.Team != nil {
+			team, err := database.GetTeam(ctx, database.GetTeamsFilters{
+				UUIDs: []string{*body.Team},
+			})
+			if err != nil {
+				if ctx.GetInt("errorCode") == http.StatusNotFound {
+					ctx.Set("errorCode", http.StatusBadRequest)
+				}
+				ctx.Set("Status", ctx.GetInt("errorCode"))
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: err.Error(),
+				})
+				ctx.Next()
+				return
+			}
+			mapping.TeamID = &team.ID
+			if body.TeamRole != "" {
+				mapping.TeamRole = body.TeamRole
+			}
+		}
+		if body.Role != nil {
+			role, err := database.GetRole(ctx, database.GetRolesFilters{
+				UUIDs: []string{*body.Role},
+			})
+			if err != nil {
+				if ctx.GetInt("errorCode") == http.StatusNotFound {
+					ctx.Set("errorCode", http.StatusBadRequest)
+				}
+				ctx.Set("Status", ctx.GetInt("errorCode"))
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: err.Error(),
+				})
+				ctx.Next()
+				return
+			}
+			mapping.RoleID = &role.ID
+			mapping.Role = role
+		}
+
+		if err := database.CreateGroupMapping(ctx, mapping); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Header("Location", fmt.Sprintf("%s://%s/group-mappings/%s", ctx.Request.URL.Scheme, ctx.Request.URL.Host, mapping.UUID))
+		ctx.Set("Status", http.StatusCreated)
+	}
+}
+
+// DeleteGroupMapping godoc
+//
+//	@Summary		Delete a group mapping
+//	@Description	Delete a group mapping. Users keep what it gave them until they next log in with single sign-on
+//	@Tags			Roles
+//	@Security		JWT
+//	@Produce		json
+//	@Param			mapping_id	path	string	true	"Group mapping UUID"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/group-mappings/{mapping_id} [delete]
+func DeleteGroupMapping() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		mapping, ok := getGroupMappingParam(ctx)
+		if !ok {
+			return
+		}
+
+		if err := database.DeleteGroupMapping(ctx, mapping); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// Get the group mapping given by the mapping_id path parameter, setting the response if it is invalid or not found
+func getGroupMappingParam(ctx *gin.Context) (*database.GroupMapping, bool) {
+	mappingUUID := ctx.Param("mapping_id")
+	if _, err := uuid.Parse(mappingUUID); err != nil {
+		ctx.Set("Status", http.StatusBadRequest)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: "invalid group mapping UUID",
+		})
+		ctx.Next()
+		return nil, false
+	}
+
+	mapping, err := database.GetGroupMapping(ctx, mappingUUID)
+	if err != nil {
+		ctx.Set("Status", ctx.GetInt("errorCode"))
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return nil, false
+	}
+	return mapping, true
+}
+
+func newGroupMappingResponse(mapping *database.GroupMapping) *utility.GroupMappingGetResponseBodySchema {
+	resp := &utility.GroupMappingGetResponseBodySchema{
+		UUID:  mapping.UUID,
+		Group: mapping.Group,
+		Admin: mapping.Admin,
+	}
+	if mapping.Team != nil {
+		resp.Team = &mapping.Team.UUID
+		resp.TeamRole = &mapping.TeamRole
+	}
+	if mapping.Role != nil {
+		resp.Role = &mapping.Role.UUID
+	}
+	return resp
+}
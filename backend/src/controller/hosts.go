@@ -3,6 +3,7 @@ package controller
 import (
 	"com668-backend/database"
 	"com668-backend/utility"
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
@@ -22,12 +23,18 @@ type GetManyHostsResponseSchema utility.GetManyResponseSchema[*utility.HostMachi
 //	@Security		JWT
 //	@Accept			json
 //	@Produce		json
-//	@Param			page		query		int	false		"Page number"
-//	@Param			pageSize	query		int	false		"Number of items per page"
-//	@Param			hostnames	query		string	false	"Server hostname"
-//	@Success		200			{object}	GetManyHostsResponseSchema
-//	@Failure		401			{object}	utility.ErrorResponseSchema
-//	@Failure		500			{object}	utility.ErrorResponseSchema
+//	@Param			page			query		int		false	"Page number"
+//	@Param			pageSize		query		int		false	"Number of items per page"
+//	@Param			cursor			query		string	false	"Cursor of the page to get, from the meta of another page"
+//	@Param			sort			query		string	false	"Comma separated list of fields to sort by, each prefixed with '-' for descending order. One of hostname, os"
+//	@Param			hostnames		query		string	false	"Server hostname"
+//	@Param			If-None-Match	header		string	false	"ETag of a copy already held, to get a 304 if it is still current"
+//	@Success		200				{object}	GetManyHostsResponseSchema
+//	@Header			200				{string}	ETag	"Version of the response"
+//	@Success		304
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
 //	@Router			/hosts [get]
 func GetHosts() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -43,9 +50,22 @@ func GetHosts() gin.HandlerFunc {
 		page := params["page"].(int)
 		pageSize := params["pageSize"].(int)
 
+		sort, err := getSortParam(ctx, database.HostSortFields)
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
 		filters := database.GetHostsFilters{
 			Page:     &page,
 			PageSize: &pageSize,
+			Sort:     sort,
+			Cursor:   params["cursor"].(*string),
+			Cursors:  &database.PageCursors{},
 		}
 
 		if hostname, ok := ctx.GetQuery("hostnames"); ok && len(hostname) > 0 {
@@ -71,6 +91,8 @@ func GetHosts() gin.HandlerFunc {
 				Pages:      int(math.Ceil(float64(count) / float64(pageSize))),
 				Page:       page,
 				PageSize:   pageSize,
+				Next:       filters.Cursors.Next,
+				Prev:       filters.Cursors.Prev,
 			},
 		}
 		for _, host := range hosts {
@@ -110,12 +132,16 @@ func GetHosts() gin.HandlerFunc {
 //	@Security		JWT
 //	@Accept			json
 //	@Produce		json
-//	@Param			host_id	path		string	true	"Host UUID"
-//	@Success		200		{object}	utility.HostMachineGetResponseBodySchema
-//	@Failure		400		{object}	utility.ErrorResponseSchema
-//	@Failure		401		{object}	utility.ErrorResponseSchema
-//	@Failure		404		{object}	utility.ErrorResponseSchema
-//	@Failure		500		{object}	utility.ErrorResponseSchema
+//	@Param			host_id			path		string	true	"Host UUID"
+//	@Param			If-None-Match	header		string	false	"ETag of a copy already held, to get a 304 if it is still current"
+//	@Success		200				{object}	utility.HostMachineGetResponseBodySchema
+//	@Header			200				{string}	ETag	"Version of the response"
+//	@Success		304
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
 //	@Router			/hosts/{host_id} [get]
 func GetHost() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -141,19 +167,24 @@ func GetHost() gin.HandlerFunc {
 			return
 		}
 
-		h := &utility.HostMachineGetResponseBodySchema{
-			UUID:     host.UUID,
-			Hostname: host.Hostname,
-			IP4:      host.IP4,
-			IP6:      host.IP6,
-			OS:       host.OS,
-			Team: utility.TeamGetResponseBodySchema{
-				UUID: host.Team.UUID,
-				Name: host.Team.Name,
-			},
-		}
+		response := newHostResponse(host)
+		ctx.Header("ETag", utility.ResourceETag(host.Version, response))
 		ctx.Set("Status", http.StatusOK)
-		ctx.Set("Body", h)
+		ctx.Set("Body", response)
+	}
+}
+
+func newHostResponse(host *database.HostMachine) *utility.HostMachineGetResponseBodySchema {
+	return &utility.HostMachineGetResponseBodySchema{
+		UUID:     host.UUID,
+		Hostname: host.Hostname,
+		IP4:      host.IP4,
+		IP6:      host.IP6,
+		OS:       host.OS,
+		Team: utility.TeamGetResponseBodySchema{
+			UUID: host.Team.UUID,
+			Name: host.Team.Name,
+		},
 	}
 }
 
@@ -213,6 +244,14 @@ func CreateHost() gin.HandlerFunc {
 			IP6:      body.IP6,
 			TeamID:   team.ID,
 		}
+		if err := checkHostOwner(ctx, host); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
 		if err := database.CreateHost(ctx, host); err != nil {
 			ctx.Set("Status", ctx.GetInt("errorCode"))
 			ctx.Set("Body", &utility.ErrorResponseSchema{
@@ -234,13 +273,15 @@ func CreateHost() gin.HandlerFunc {
 //	@Security		JWT
 //	@Accept			json
 //	@Produce		json
-//	@Param			host_id	path	string										true	"Host UUID"
-//	@Param			body	body	utility.HostMachinePostPutRequestBodySchema	true	"Host update request"
+//	@Param			host_id		path	string										true	"Host UUID"
+//	@Param			body		body	utility.HostMachinePostPutRequestBodySchema	true	"Host update request"
+//	@Param			If-Match	header	string										false	"ETag of the version being changed, to get a 412 if another request has changed it since"
 //	@Success		204
 //	@Failure		400	{object}	utility.ErrorResponseSchema
 //	@Failure		401	{object}	utility.ErrorResponseSchema
 //	@Failure		403	{object}	utility.ErrorResponseSchema
 //	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		412	{object}	utility.ErrorResponseSchema
 //	@Failure		500	{object}	utility.ErrorResponseSchema
 //	@Router			/hosts/{host_id} [put]
 func UpdateHost() gin.HandlerFunc {
@@ -286,8 +327,55 @@ func UpdateHost() gin.HandlerFunc {
 			return
 		}
 
-		team, err := database.GetTeam(ctx, database.GetTeamsFilters{
-			UUIDs: []string{body.TeamID},
+		updateHost(ctx, host, body)
+	}
+}
+
+// PatchHost godoc
+//
+//	@Summary		Partially update a Host
+//	@Description	Update some fields of a Host with a JSON Merge Patch (RFC 7386) or a JSON Patch (RFC 6902) of the body of PUT /hosts/{host_id}
+//	@Tags			Hosts
+//	@Security		JWT
+//	@Accept			application/merge-patch+json,application/json-patch+json
+//	@Produce		json
+//	@Param			host_id		path	string	true	"Host UUID"
+//	@Param			body		body	object	true	"The patch"
+//	@Param			If-Match	header	string	false	"ETag of the version being changed, to get a 412 if another request has changed it since"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		412	{object}	utility.ErrorResponseSchema
+//	@Failure		415	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/hosts/{host_id} [patch]
+func PatchHost() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		hostUUID := ctx.Param("host_id")
+		if _, err := uuid.Parse(hostUUID); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "Invalid Host UUID",
+			})
+			ctx.Next()
+			return
+		}
+
+		patch, err := ctx.GetRawData()
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		host, err := database.GetHost(ctx, database.GetHostsFilters{
+			UUIDs: []string{hostUUID},
 		})
 		if err != nil {
 			ctx.Set("Status", ctx.GetInt("errorCode"))
@@ -297,14 +385,16 @@ func UpdateHost() gin.HandlerFunc {
 			ctx.Next()
 			return
 		}
-		host.TeamID = team.ID
-		host.OS = body.OS
-		host.Hostname = body.Hostname
-		host.IP4 = body.IP4
-		host.IP6 = body.IP6
 
-		if err := database.UpdateHost(ctx, host); err != nil {
-			ctx.Set("Status", ctx.GetInt("errorCode"))
+		body, status, err := utility.ApplyPatch(ctx.GetHeader("Content-Type"), utility.HostMachinePostPutRequestBodySchema{
+			OS:       host.OS,
+			Hostname: host.Hostname,
+			IP4:      host.IP4,
+			IP6:      host.IP6,
+			TeamID:   host.Team.UUID,
+		}, patch)
+		if err != nil {
+			ctx.Set("Status", status)
 			ctx.Set("Body", &utility.ErrorResponseSchema{
 				Error: err.Error(),
 			})
@@ -312,10 +402,62 @@ func UpdateHost() gin.HandlerFunc {
 			return
 		}
 
-		ctx.Set("Status", http.StatusNoContent)
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		updateHost(ctx, host, body)
 	}
 }
 
+// Replace the fields of a host with those of a validated PUT or patched body
+func updateHost(ctx *gin.Context, host *database.HostMachine, body *utility.HostMachinePostPutRequestBodySchema) {
+	if !checkIfMatch(ctx, host.Version, newHostResponse(host)) {
+		return
+	}
+
+	err := checkHostOwner(ctx, host)
+	var team *database.Team
+	if err == nil {
+		team, err = database.GetTeam(ctx, database.GetTeamsFilters{
+			UUIDs: []string{body.TeamID},
+		})
+	}
+	if err == nil {
+		// the host can only be given to a team the user could have created it for
+		err = checkHostOwner(ctx, &database.HostMachine{TeamID: team.ID})
+	}
+	if err != nil {
+		ctx.Set("Status", ctx.GetInt("errorCode"))
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return
+	}
+	host.TeamID = team.ID
+	host.OS = body.OS
+	host.Hostname = body.Hostname
+	host.IP4 = body.IP4
+	host.IP6 = body.IP6
+
+	if err := database.UpdateHost(ctx, host); err != nil {
+		ctx.Set("Status", ctx.GetInt("errorCode"))
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return
+	}
+
+	ctx.Set("Status", http.StatusNoContent)
+}
+
 // DeleteHost godoc
 //
 //	@Summary		Delete a Host
@@ -324,12 +466,14 @@ func UpdateHost() gin.HandlerFunc {
 //	@Security		JWT
 //	@Accept			json
 //	@Produce		json
-//	@Param			host_id	path	string	true	"Host UUID"
+//	@Param			host_id		path	string	true	"Host UUID"
+//	@Param			If-Match	header	string	false	"ETag of the version being changed, to get a 412 if another request has changed it since"
 //	@Success		204
 //	@Failure		400	{object}	utility.ErrorResponseSchema
 //	@Failure		401	{object}	utility.ErrorResponseSchema
 //	@Failure		403	{object}	utility.ErrorResponseSchema
 //	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		412	{object}	utility.ErrorResponseSchema
 //	@Failure		500	{object}	utility.ErrorResponseSchema
 //	@Router			/hosts/{host_id} [delete]
 func DeleteHost() gin.HandlerFunc {
@@ -344,9 +488,12 @@ func DeleteHost() gin.HandlerFunc {
 			return
 		}
 
-		_, err := database.GetHost(ctx, database.GetHostsFilters{
+		host, err := database.GetHost(ctx, database.GetHostsFilters{
 			UUIDs: []string{hostUUID},
 		})
+		if err == nil {
+			err = checkHostOwner(ctx, host)
+		}
 		if err != nil {
 			ctx.Set("Status", ctx.GetInt("errorCode"))
 			ctx.Set("Body", &utility.ErrorResponseSchema{
@@ -355,6 +502,9 @@ func DeleteHost() gin.HandlerFunc {
 			ctx.Next()
 			return
 		}
+		if !checkIfMatch(ctx, host.Version, newHostResponse(host)) {
+			return
+		}
 
 		if err := database.DeleteHost(ctx, hostUUID); err != nil {
 			ctx.Set("Status", ctx.GetInt("errorCode"))
@@ -367,3 +517,13 @@ func DeleteHost() gin.HandlerFunc {
 		ctx.Set("Status", http.StatusNoContent)
 	}
 }
+
+// Check the logged in user can change a host, which members of the team owning it and users with the host:manage
+// permission can, setting the error code if they cannot
+func checkHostOwner(ctx *gin.Context, host *database.HostMachine) error {
+	if database.HasPermission(ctx, database.PermissionHostManage) || host.OwnedBy(ctx.MustGet("user").(*database.User)) {
+		return nil
+	}
+	ctx.Set("errorCode", http.StatusForbidden)
+	return errors.New("logged in user must be a member of the team owning the host")
+}
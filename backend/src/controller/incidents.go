@@ -3,17 +3,20 @@ package controller
 import (
 	"com668-backend/database"
 	"com668-backend/utility"
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
+	"slices"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type GetManyIncidentsResponseSchema utility.GetManyResponseSchema[*utility.IncidentGetResponseBodySchema]
+type GetManyIncidentEventsResponseSchema utility.GetManyResponseSchema[*utility.IncidentEventGetResponseBodySchema]
 
 // GetIncidents godoc
 //
@@ -22,14 +25,30 @@ type GetManyIncidentsResponseSchema utility.GetManyResponseSchema[*utility.Incid
 //	@Tags			Incidents
 //	@Security		JWT
 //	@Produce		json
-//	@Param			page		query		int		false	"Page number"
-//	@Param			pageSize	query		int		false	"Number of items per page"
-//	@Param			resolved	query		bool	false	"Filter by resolved status"
-//	@Param			myTeams		query		bool	false	"Filter by my teams only"
-//	@Param			hash		query		string	false	"Filter by hash"
-//	@Success		200			{object}	GetManyIncidentsResponseSchema
-//	@Failure		401			{object}	utility.ErrorResponseSchema
-//	@Failure		500			{object}	utility.ErrorResponseSchema
+//	@Param			page			query		int		false	"Page number"
+//	@Param			pageSize		query		int		false	"Number of items per page"
+//	@Param			resolved		query		bool	false	"Filter by resolved status"
+//	@Param			regressed		query		bool	false	"Filter by regressed status"
+//	@Param			status			query		string	false	"Filter by status"	Enums(open, acknowledged, investigating, mitigated, resolved)
+//	@Param			severity		query		string	false	"Filter by a comma separated list of severities (1-5)"
+//	@Param			priority		query		string	false	"Filter by a comma separated list of priorities (1-5)"
+//	@Param			cursor			query		string	false	"Cursor of the page to get, from the meta of another page"
+//	@Param			sort			query		string	false	"Comma separated list of fields to sort by, each prefixed with '-' for descending order. One of severity, priority, impact, urgency, status, occurrences, createdAt, firstSeen, lastSeen"
+//	@Param			myTeams			query		bool	false	"Filter by my teams only"
+//	@Param			myAssigned		query		bool	false	"Filter by incidents I am the assignee of or a responder to"
+//	@Param			hash			query		string	false	"Filter by hash"
+//	@Param			q				query		string	false	"Full-text search over the summary, description, comments and stack frames. Results are ranked by relevance unless sorted"
+//	@Param			query			query		string	false	"Filter by a query such as 'status:open severity:<=2 (team:DevOps OR host:7e83c1b6c515) created:>-7d'. Terms can be negated with '-' or NOT, and words without a field are searched for as free text. Queries are limited to 2048 characters"
+//	@Param			expand			query		string	false	"Comma separated list of relations to include, such as 'comments,hostsAffected.team'. Every relation is included if not given"
+//	@Param			fields			query		string	false	"Comma separated list of fields to include, such as 'uuid,summary,status'. Every field but stackTrace is included if not given"
+//	@Param			If-None-Match	header		string	false	"ETag of a copy already held, to get a 304 if it is still current"
+//	@Success		200				{object}	GetManyIncidentsResponseSchema
+//	@Header			200				{string}	ETag	"Version of the response"
+//	@Success		304
+//	@Failure		400	{object}	utility.QueryErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
 //	@Router			/incidents [get]
 func GetIncidents() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -48,6 +67,8 @@ func GetIncidents() gin.HandlerFunc {
 		filters := database.GetIncidentsFilters{
 			Page:     &page,
 			PageSize: &pageSize,
+			Cursor:   params["cursor"].(*string),
+			Cursors:  &database.PageCursors{},
 		}
 		if resolved := ctx.Query("resolved"); resolved != "" {
 			resolvedBool, err := strconv.ParseBool(resolved)
@@ -62,6 +83,66 @@ func GetIncidents() gin.HandlerFunc {
 			filters.Resolved = &resolvedBool
 		}
 
+		if regressed := ctx.Query("regressed"); regressed != "" {
+			regressedBool, err := strconv.ParseBool(regressed)
+			if err != nil {
+				ctx.Set("Status", http.StatusBadRequest)
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: err.Error(),
+				})
+				ctx.Next()
+				return
+			}
+			filters.Regressed = &regressedBool
+		}
+
+		if status := ctx.Query("status"); status != "" {
+			if !slices.Contains(database.IncidentStatuses, status) {
+				ctx.Set("Status", http.StatusBadRequest)
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: fmt.Sprintf("status query parameter must be one of '%s'", strings.Join(database.IncidentStatuses, "', '")),
+				})
+				ctx.Next()
+				return
+			}
+			filters.Status = &status
+		}
+
+		if severity := ctx.Query("severity"); severity != "" {
+			severities, err := parseLevels(severity, 5)
+			if err != nil {
+				ctx.Set("Status", http.StatusBadRequest)
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: fmt.Sprintf("severity query parameter %s", err.Error()),
+				})
+				ctx.Next()
+				return
+			}
+			filters.Severities = severities
+		}
+		if priority := ctx.Query("priority"); priority != "" {
+			priorities, err := parseLevels(priority, 5)
+			if err != nil {
+				ctx.Set("Status", http.StatusBadRequest)
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: fmt.Sprintf("priority query parameter %s", err.Error()),
+				})
+				ctx.Next()
+				return
+			}
+			filters.Priorities = priorities
+		}
+		sort, err := getSortParam(ctx, database.IncidentSortFields)
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		filters.Sort = sort
+
 		myTeams := ctx.Query("myTeams")
 		if myTeams == "" {
 			myTeams = "false"
@@ -77,12 +158,88 @@ func GetIncidents() gin.HandlerFunc {
 		}
 		filters.MyTeams = myTeamsBool
 
+		if myAssigned := ctx.Query("myAssigned"); myAssigned != "" {
+			myAssignedBool, err := strconv.ParseBool(myAssigned)
+			if err != nil {
+				ctx.Set("Status", http.StatusBadRequest)
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: err.Error(),
+				})
+				ctx.Next()
+				return
+			}
+			filters.MyAssigned = myAssignedBool
+		}
+
 		hash := ctx.Query("hash")
 		if hash != "" {
 			filters.Hash = &hash
 		}
 
+		if search := strings.TrimSpace(ctx.Query("q")); search != "" {
+			filters.Search = &search
+		}
+
+		query, err := utility.ParseQuery(ctx.Query("query"))
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", newQueryErrorResponse(err))
+			ctx.Next()
+			return
+		}
+		filters.Query = query
+
+		expand, err := getExpandParam(ctx, database.IncidentExpansions)
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		fields, err := getFieldsParam(ctx, utility.IncidentGetResponseBodySchema{})
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		// only relations which are both expanded and among the fields are loaded, and the rest are left out of the response
+		omit := make([]string, 0)
+		if fields != nil {
+			for field := range (utility.IncidentGetResponseBodySchema{}).JSON() {
+				if !slices.Contains(fields, field) {
+					omit = append(omit, field)
+				}
+			}
+		}
+		// the raw stack trace is only listed if it is asked for, as the parsed frames are usually enough
+		if !slices.Contains(fields, "stackTrace") {
+			filters.OmitStackTrace = true
+			if !slices.Contains(omit, "stackTrace") {
+				omit = append(omit, "stackTrace")
+			}
+		}
+		filters.Expand = make([]string, 0)
+		for _, relation := range database.IncidentExpansions {
+			root, _, _ := strings.Cut(relation, ".")
+			if (expand == nil || slices.Contains(expand, relation)) && !slices.Contains(omit, root) {
+				filters.Expand = append(filters.Expand, relation)
+			} else {
+				omit = append(omit, relation)
+			}
+		}
+
 		incidents, count, err := database.GetIncidents(ctx, filters)
+		if queryErr := (*utility.QueryError)(nil); errors.As(err, &queryErr) {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", newQueryErrorResponse(queryErr))
+			ctx.Next()
+			return
+		}
 		if err != nil {
 			ctx.Set("Status", ctx.GetInt("errorCode"))
 			ctx.Set("Body", &utility.ErrorResponseSchema{
@@ -99,87 +256,20 @@ func GetIncidents() gin.HandlerFunc {
 				Pages:      int(math.Ceil(float64(count) / float64(pageSize))),
 				Page:       page,
 				PageSize:   pageSize,
+				Next:       filters.Cursors.Next,
+				Prev:       filters.Cursors.Prev,
 			},
 		}
 		for _, incident := range incidents {
-			inc := &utility.IncidentGetResponseBodySchema{
-				UUID:            incident.UUID,
-				Comments:        make([]utility.IncidentCommentGetResponseBodySchema, 0),
-				HostsAffected:   make([]utility.HostMachineGetResponseBodySchema, 0),
-				Description:     incident.Description,
-				Summary:         incident.Summary,
-				ResolvedAt:      incident.ResolvedAt,
-				CreatedAt:       incident.CreatedAt,
-				ResolutionTeams: make([]utility.TeamGetResponseBodySchema, 0),
-				Hash:            incident.Hash,
-			}
-			for _, team := range incident.ResolutionTeams {
-				users := make([]utility.UserGetResponseBodySchema, 0)
-				for _, user := range team.Users {
-					users = append(users, utility.UserGetResponseBodySchema{
-						UUID:    user.UUID,
-						Name:    user.Name,
-						Email:   user.Email,
-						SlackID: user.SlackID,
-						Admin:   &user.Admin,
-					})
-				}
-				inc.ResolutionTeams = append(inc.ResolutionTeams, utility.TeamGetResponseBodySchema{
-					UUID:  team.UUID,
-					Name:  team.Name,
-					Users: users,
-				})
-			}
-			for _, comment := range incident.Comments {
-				c := utility.IncidentCommentGetResponseBodySchema{
-					UUID:        comment.UUID,
-					Comment:     comment.Comment,
-					CommentedAt: comment.CommentedAt,
-					CommentedBy: utility.UserGetResponseBodySchema{
-						UUID:    comment.CommentedBy.UUID,
-						Name:    comment.CommentedBy.Name,
-						Email:   comment.CommentedBy.Email,
-						Teams:   make([]utility.TeamGetResponseBodySchema, 0),
-						SlackID: comment.CommentedBy.SlackID,
-						Admin:   &comment.CommentedBy.Admin,
-					},
-				}
-				for _, team := range comment.CommentedBy.Teams {
-					c.CommentedBy.Teams = append(c.CommentedBy.Teams, utility.TeamGetResponseBodySchema{
-						UUID: team.UUID,
-						Name: team.Name,
-					})
-				}
-				inc.Comments = append(inc.Comments, c)
-			}
-			for _, host := range incident.HostsAffected {
-				inc.HostsAffected = append(inc.HostsAffected, utility.HostMachineGetResponseBodySchema{
-					UUID:     host.UUID,
-					Hostname: host.Hostname,
-					OS:       host.OS,
-					IP4:      host.IP4,
-					IP6:      host.IP6,
-					Team: utility.TeamGetResponseBodySchema{
-						UUID: host.Team.UUID,
-						Name: host.Team.Name,
-					},
-				})
-			}
-			// check for nullptr
-			if incident.ResolvedBy != nil {
-				inc.ResolvedBy = &utility.UserGetResponseBodySchema{
-					UUID:    incident.ResolvedBy.UUID,
-					Name:    incident.ResolvedBy.Name,
-					Email:   incident.ResolvedBy.Email,
-					Teams:   make([]utility.TeamGetResponseBodySchema, 0),
-					SlackID: incident.ResolvedBy.SlackID,
-					Admin:   &incident.ResolvedBy.Admin,
+			inc := newIncidentResponse(incident)
+			inc.Omit = omit
+			if incident.Search != nil {
+				inc.Search = &utility.IncidentSearchGetResponseBodySchema{
+					Score:      incident.Search.Score,
+					Highlights: make(map[string][]string),
 				}
-				for _, team := range incident.ResolvedBy.Teams {
-					inc.ResolvedBy.Teams = append(inc.ResolvedBy.Teams, utility.TeamGetResponseBodySchema{
-						UUID: team.UUID,
-						Name: team.Name,
-					})
+				for field, fragments := range incident.Search.Highlights {
+					inc.Search.Highlights[field] = fragments
 				}
 			}
 			response.Data = append(response.Data, inc)
@@ -197,25 +287,1029 @@ func GetIncidents() gin.HandlerFunc {
 //	@Tags			Incidents
 //	@Security		JWT
 //	@Produce		json
-//	@Param			incident_id	path		string	true	"Incident UUID"
-//	@Success		200			{object}	utility.IncidentGetResponseBodySchema
-//	@Failure		401			{object}	utility.ErrorResponseSchema
-//	@Failure		404			{object}	utility.ErrorResponseSchema
-//	@Failure		500			{object}	utility.ErrorResponseSchema
+//	@Param			incident_id		path		string	true	"Incident UUID"
+//	@Param			If-None-Match	header		string	false	"ETag of a copy already held, to get a 304 if it is still current"
+//	@Success		200				{object}	utility.IncidentGetResponseBodySchema
+//	@Header			200				{string}	ETag	"Version of the response"
+//	@Success		304
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
 //	@Router			/incidents/{incident_id} [get]
 func GetIncident() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		incidentUUID := ctx.Param("incident_id")
-		if _, err := uuid.Parse(incidentUUID); err != nil {
+		incidentUUID := ctx.Param("incident_id")
+		if _, err := uuid.Parse(incidentUUID); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "invalid incident UUID",
+			})
+			ctx.Next()
+			return
+		}
+
+		incident, err := database.GetIncident(ctx, incidentUUID)
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		response := newIncidentResponse(incident)
+		ctx.Header("ETag", utility.ResourceETag(incident.Version, response))
+		ctx.Set("Status", http.StatusOK)
+		ctx.Set("Body", response)
+	}
+}
+
+func newIncidentResponse(incident *database.Incident) *utility.IncidentGetResponseBodySchema {
+	var resolvedBy *utility.UserGetResponseBodySchema = nil
+	if incident.ResolvedBy != nil {
+		resolvedBy = &utility.UserGetResponseBodySchema{
+			UUID:    incident.ResolvedBy.UUID,
+			Name:    incident.ResolvedBy.Name,
+			Email:   incident.ResolvedBy.Email,
+			Teams:   make([]utility.TeamGetResponseBodySchema, 0),
+			SlackID: incident.ResolvedBy.SlackID,
+			Admin:   &incident.ResolvedBy.Admin,
+		}
+		for _, team := range incident.ResolvedBy.Teams {
+			resolvedBy.Teams = append(resolvedBy.Teams, utility.TeamGetResponseBodySchema{
+				UUID: team.UUID,
+				Name: team.Name,
+			})
+		}
+	}
+	inc := &utility.IncidentGetResponseBodySchema{
+		UUID:               incident.UUID,
+		Comments:           make([]utility.IncidentCommentGetResponseBodySchema, 0),
+		HostsAffected:      make([]utility.HostMachineGetResponseBodySchema, 0),
+		Description:        incident.Description,
+		Summary:            incident.Summary,
+		ResolvedAt:         incident.ResolvedAt,
+		ResolvedBy:         resolvedBy,
+		CreatedAt:          incident.CreatedAt,
+		ResolutionTeams:    make([]utility.TeamGetResponseBodySchema, 0),
+		Hash:               incident.Hash,
+		FirstSeenAt:        incident.FirstSeenAt,
+		LastSeenAt:         incident.LastSeenAt,
+		OccurrenceCount:    incident.OccurrenceCount,
+		Regressed:          incident.Regressed,
+		RegressionCount:    incident.RegressionCount,
+		Status:             incident.Status,
+		StatusHistory:      make([]utility.IncidentStatusChangeGetResponseBodySchema, 0),
+		Severity:           incident.Severity,
+		Impact:             incident.Impact,
+		Urgency:            incident.Urgency,
+		Priority:           incident.Priority,
+		Responders:         make([]utility.UserGetResponseBodySchema, 0),
+		AssignmentHistory:  make([]utility.IncidentAssignmentGetResponseBodySchema, 0),
+		HashAliases:        make([]string, 0),
+		Links:              make([]utility.IncidentLinkGetResponseBodySchema, 0),
+		LinkedBy:           make([]utility.IncidentLinkGetResponseBodySchema, 0),
+		StackTrace:         incident.StackTrace,
+		StackLanguage:      incident.StackLanguage,
+		StackFrames:        make([]utility.IncidentStackFrameGetResponseBodySchema, 0),
+		FingerprintVersion: incident.FingerprintVersion,
+	}
+	if incident.MergedInto != nil {
+		inc.MergedInto = &incident.MergedInto.UUID
+	}
+	for _, change := range incident.StatusChanges {
+		inc.StatusHistory = append(inc.StatusHistory, utility.IncidentStatusChangeGetResponseBodySchema{
+			UUID:       change.UUID,
+			FromStatus: change.FromStatus,
+			ToStatus:   change.ToStatus,
+			ChangedBy:  newIncidentHistoryUserResponse(change.ChangedBy, change.ChangedByName),
+			ChangedAt:  change.ChangedAt,
+		})
+	}
+	if incident.Assignee != nil {
+		inc.Assignee = &utility.UserGetResponseBodySchema{
+			UUID:    incident.Assignee.UUID,
+			Name:    incident.Assignee.Name,
+			Email:   incident.Assignee.Email,
+			SlackID: incident.Assignee.SlackID,
+			Admin:   &incident.Assignee.Admin,
+		}
+	}
+	for _, responder := range incident.Responders {
+		inc.Responders = append(inc.Responders, utility.UserGetResponseBodySchema{
+			UUID:    responder.UUID,
+			Name:    responder.Name,
+			Email:   responder.Email,
+			SlackID: responder.SlackID,
+			Admin:   &responder.Admin,
+		})
+	}
+	for _, assignment := range incident.Assignments {
+		a := utility.IncidentAssignmentGetResponseBodySchema{
+			UUID:      assignment.UUID,
+			User:      newIncidentHistoryUserResponse(assignment.User, assignment.UserName),
+			Role:      assignment.Role,
+			Assigned:  assignment.Assigned,
+			ChangedAt: assignment.ChangedAt,
+		}
+		// automated changes, such as a user being deactivated by a directory sync, weren't made by anyone
+		if assignment.ChangedBy != nil || assignment.ChangedByName != nil {
+			a.ChangedBy = utility.Pointer(newIncidentHistoryUserResponse(assignment.ChangedBy, assignment.ChangedByName))
+		}
+		inc.AssignmentHistory = append(inc.AssignmentHistory, a)
+	}
+	for _, alias := range incident.HashAliases {
+		inc.HashAliases = append(inc.HashAliases, alias.Hash)
+	}
+	for _, link := range incident.Links {
+		inc.Links = append(inc.Links, utility.IncidentLinkGetResponseBodySchema{
+			UUID:      link.UUID,
+			Type:      link.Type,
+			Incident:  link.LinkedIncident.UUID,
+			Summary:   link.LinkedIncident.Summary,
+			CreatedBy: newIncidentHistoryUserResponse(link.CreatedBy, link.CreatedByName),
+			CreatedAt: link.CreatedAt,
+		})
+	}
+	for _, link := range incident.LinkedBy {
+		inc.LinkedBy = append(inc.LinkedBy, utility.IncidentLinkGetResponseBodySchema{
+			UUID:      link.UUID,
+			Type:      link.Type,
+			Incident:  link.Incident.UUID,
+			Summary:   link.Incident.Summary,
+			CreatedBy: newIncidentHistoryUserResponse(link.CreatedBy, link.CreatedByName),
+			CreatedAt: link.CreatedAt,
+		})
+	}
+	for _, frame := range incident.StackFrames {
+		inc.StackFrames = append(inc.StackFrames, utility.IncidentStackFrameGetResponseBodySchema{
+			File:     frame.File,
+			Function: frame.Function,
+			Line:     frame.Line,
+			Column:   frame.Column,
+			InApp:    frame.InApp,
+			Culprit:  frame.Culprit,
+		})
+	}
+	for _, team := range incident.ResolutionTeams {
+		users := make([]utility.UserGetResponseBodySchema, 0)
+		for _, user := range team.Users {
+			users = append(users, utility.UserGetResponseBodySchema{
+				UUID:    user.UUID,
+				Name:    user.Name,
+				Email:   user.Email,
+				SlackID: user.SlackID,
+				Admin:   &user.Admin,
+			})
+		}
+		inc.ResolutionTeams = append(inc.ResolutionTeams, utility.TeamGetResponseBodySchema{
+			UUID:  team.UUID,
+			Name:  team.Name,
+			Users: users,
+		})
+	}
+	for _, comment := range incident.Comments {
+		inc.Comments = append(inc.Comments, newIncidentCommentResponse(comment))
+	}
+	for _, host := range incident.HostsAffected {
+		inc.HostsAffected = append(inc.HostsAffected, utility.HostMachineGetResponseBodySchema{
+			UUID:     host.UUID,
+			Hostname: host.Hostname,
+			OS:       host.OS,
+			IP4:      host.IP4,
+			IP6:      host.IP6,
+			Team: utility.TeamGetResponseBodySchema{
+				UUID: host.Team.UUID,
+				Name: host.Team.Name,
+			},
+		})
+	}
+	return inc
+}
+
+// CreateIncident godoc
+//
+//	@Summary		Create an incident
+//	@Description	Create an incident
+//	@Tags			Incidents
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			incident	body	utility.IncidentPostRequestBodySchema	true	"The request body"
+//	@Header			201			header	string									"GET URL"
+//	@Success		201
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/incidents [post]
+func CreateIncident() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var body *utility.IncidentPostRequestBodySchema
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		incident, err := database.CreateIncident(ctx, body)
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("Location", fmt.Sprintf("%s://%s/incidents/%s", ctx.Request.URL.Scheme, ctx.Request.URL.Host, incident.UUID))
+		ctx.Set("Status", http.StatusCreated)
+	}
+}
+
+// ReportIncidentOccurrence godoc
+//
+//	@Summary		Report an occurrence of an incident
+//	@Description	Create an incident for the hash if one does not exist, otherwise bump its occurrence count and attach any newly affected hosts. A resolved incident is reopened as a regression. A stack trace is fingerprinted by the backend, and a hash sent with it still finds incidents already reported under that hash
+//	@Tags			Incidents
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			incident	body	utility.IncidentPostRequestBodySchema	true	"The request body"
+//	@Header			201			header	string									"GET URL"
+//	@Header			204			header	string									"GET URL"
+//	@Success		201
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/incidents/occurrences [put]
+func ReportIncidentOccurrence() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var body *utility.IncidentPostRequestBodySchema
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		incident, created, err := database.ReportIncidentOccurrence(ctx, body)
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("Location", fmt.Sprintf("%s://%s/incidents/%s", ctx.Request.URL.Scheme, ctx.Request.URL.Host, incident.UUID))
+		if created {
+			ctx.Set("Status", http.StatusCreated)
+		} else {
+			ctx.Set("Status", http.StatusNoContent)
+		}
+	}
+}
+
+// UpdateIncident godoc
+//
+//	@Summary		Update an incident
+//	@Description	Update an incident. Its status is changed with the transition endpoints such as POST /incidents/{incident_id}/resolve, and the deprecated 'resolved' leaves the status alone if it is not given, and needs the incident:resolve permission to change it
+//	@Tags			Incidents
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			incident	body	utility.IncidentPutRequestBodySchema	true	"The request body"
+//	@Param			incident_id	path	string									true	"Incident UUID"
+//	@Param			If-Match	header	string									false	"ETag of the version being changed, to get a 412 if another request has changed it since"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		412	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/incidents/{incident_id} [put]
+func UpdateIncident() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		incidentUUID := ctx.Param("incident_id")
+		if _, err := uuid.Parse(incidentUUID); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "invalid incident UUID",
+			})
+			ctx.Next()
+			return
+		}
+
+		var body *utility.IncidentPutRequestBodySchema
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		incident, err := database.GetIncident(ctx, incidentUUID)
+		if err == nil {
+			err = checkIncidentOwner(ctx, incident)
+		}
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		updateIncident(ctx, incident, body)
+	}
+}
+
+// PatchIncident godoc
+//
+//	@Summary		Partially update an incident
+//	@Description	Update some fields of an incident with a JSON Merge Patch (RFC 7386) or a JSON Patch (RFC 6902) of the body of PUT /incidents/{incident_id}
+//	@Tags			Incidents
+//	@Security		JWT
+//	@Accept			application/merge-patch+json,application/json-patch+json
+//	@Produce		json
+//	@Param			incident	body	object	true	"The patch"
+//	@Param			incident_id	path	string	true	"Incident UUID"
+//	@Param			If-Match	header	string	false	"ETag of the version being changed, to get a 412 if another request has changed it since"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		412	{object}	utility.ErrorResponseSchema
+//	@Failure		415	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/incidents/{incident_id} [patch]
+func PatchIncident() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		incidentUUID := ctx.Param("incident_id")
+		if _, err := uuid.Parse(incidentUUID); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "invalid incident UUID",
+			})
+			ctx.Next()
+			return
+		}
+
+		patch, err := ctx.GetRawData()
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		incident, err := database.GetIncident(ctx, incidentUUID)
+		if err == nil {
+			err = checkIncidentOwner(ctx, incident)
+		}
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		current := utility.IncidentPutRequestBodySchema{
+			Summary:         incident.Summary,
+			Description:     incident.Description,
+			HostsAffected:   make([]string, 0),
+			ResolutionTeams: make([]string, 0),
+			Severity:        &incident.Severity,
+			Impact:          &incident.Impact,
+			Urgency:         &incident.Urgency,
+		}
+		for _, host := range incident.HostsAffected {
+			current.HostsAffected = append(current.HostsAffected, host.UUID)
+		}
+		for _, team := range incident.ResolutionTeams {
+			current.ResolutionTeams = append(current.ResolutionTeams, team.UUID)
+		}
+		body, status, err := utility.ApplyPatch(ctx.GetHeader("Content-Type"), current, patch)
+		if err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		updateIncident(ctx, incident, body)
+	}
+}
+
+// Replace the fields of an incident with those of a validated PUT or patched body
+func updateIncident(ctx *gin.Context, incident *database.Incident, body *utility.IncidentPutRequestBodySchema) {
+	if !checkIfMatch(ctx, incident.Version, newIncidentResponse(incident)) {
+		return
+	}
+	// resolving or reopening needs the permission its own routes need, and is refused before anything is changed
+	resolving := body.Resolved != nil && *body.Resolved != (incident.Status == database.IncidentStatusResolved)
+	if resolving && !database.HasPermission(ctx, database.PermissionIncidentResolve) {
+		ctx.Set("Status", http.StatusForbidden)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: fmt.Sprintf("logged in user does not have the '%s' permission", database.PermissionIncidentResolve),
+		})
+		ctx.Next()
+		return
+	}
+
+	hosts := make([]database.HostMachine, 0)
+	if len(body.HostsAffected) > 0 {
+		hs, count, err := database.GetHosts(ctx, database.GetHostsFilters{
+			UUIDs:    body.HostsAffected,
+			PageSize: utility.Pointer(len(body.HostsAffected)),
+		})
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		if int(count) != len(body.HostsAffected) {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "one or more hosts not found",
+			})
+			ctx.Next()
+			return
+		}
+		for _, host := range hs {
+			hosts = append(hosts, *host)
+		}
+	}
+
+	teams := make([]database.Team, 0)
+	if len(body.ResolutionTeams) > 0 {
+		ts, count, err := database.GetTeams(ctx, database.GetTeamsFilters{
+			UUIDs:    body.ResolutionTeams,
+			PageSize: utility.Pointer(len(body.ResolutionTeams)),
+		})
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		if int(count) != len(body.ResolutionTeams) {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "one or more teams not found",
+			})
+			ctx.Next()
+			return
+		}
+		for _, team := range ts {
+			teams = append(teams, *team)
+		}
+	}
+
+	severity := incident.Severity
+	if body.Severity != nil {
+		severity = *body.Severity
+	}
+	impact := incident.Impact
+	if body.Impact != nil {
+		impact = *body.Impact
+	}
+	urgency := incident.Urgency
+	if body.Urgency != nil {
+		urgency = *body.Urgency
+	}
+	priority, err := database.GetPriority(ctx, impact, urgency)
+	if err != nil {
+		ctx.Set("Status", ctx.GetInt("errorCode"))
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return
+	}
+
+	newIncident := &database.Incident{
+		ID:              incident.ID,
+		UUID:            incident.UUID,
+		Summary:         body.Summary,
+		Description:     body.Description,
+		HostsAffected:   hosts,
+		Comments:        incident.Comments,
+		CreatedAt:       incident.CreatedAt,
+		ResolutionTeams: teams,
+		Severity:        severity,
+		Impact:          impact,
+		Urgency:         urgency,
+		Priority:        priority,
+		Version:         incident.Version,
+	}
+	err = database.UpdateIncident(ctx, database.GetIncidentsFilters{
+		UUID: &incident.UUID,
+	}, newIncident)
+	if err != nil {
+		ctx.Set("Status", ctx.GetInt("errorCode"))
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return
+	}
+
+	// the deprecated 'resolved' only moves the incident in or out of the resolved status, so that progress through the
+	// other statuses is kept, and the status is left alone without it
+	if resolving {
+		status := database.IncidentStatusOpen
+		if *body.Resolved {
+			status = database.IncidentStatusResolved
+		}
+		if err := database.TransitionIncidentStatus(ctx, incident, status); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+	}
+
+	ctx.Set("Status", http.StatusNoContent)
+}
+
+// AcknowledgeIncident godoc
+//
+//	@Summary		Acknowledge an incident
+//	@Description	Move an open incident to the acknowledged status
+//	@Tags			Incidents
+//	@Security		JWT
+//	@Produce		json
+//	@Param			incident_id	path	string	true	"Incident UUID"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/incidents/{incident_id}/acknowledge [post]
+func AcknowledgeIncident() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		transitionIncidentStatus(ctx, database.IncidentStatusAcknowledged)
+	}
+}
+
+// InvestigateIncident godoc
+//
+//	@Summary		Start investigating an incident
+//	@Description	Move an acknowledged incident to the investigating status
+//	@Tags			Incidents
+//	@Security		JWT
+//	@Produce		json
+//	@Param			incident_id	path	string	true	"Incident UUID"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/incidents/{incident_id}/investigate [post]
+func InvestigateIncident() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		transitionIncidentStatus(ctx, database.IncidentStatusInvestigating)
+	}
+}
+
+// MitigateIncident godoc
+//
+//	@Summary		Mitigate an incident
+//	@Description	Move an incident under investigation to the mitigated status
+//	@Tags			Incidents
+//	@Security		JWT
+//	@Produce		json
+//	@Param			incident_id	path	string	true	"Incident UUID"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/incidents/{incident_id}/mitigate [post]
+func MitigateIncident() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		transitionIncidentStatus(ctx, database.IncidentStatusMitigated)
+	}
+}
+
+// ResolveIncident godoc
+//
+//	@Summary		Resolve an incident
+//	@Description	Move an unresolved incident to the resolved status
+//	@Tags			Incidents
+//	@Security		JWT
+//	@Produce		json
+//	@Param			incident_id	path	string	true	"Incident UUID"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/incidents/{incident_id}/resolve [post]
+func ResolveIncident() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		transitionIncidentStatus(ctx, database.IncidentStatusResolved)
+	}
+}
+
+// ReopenIncident godoc
+//
+//	@Summary		Reopen an incident
+//	@Description	Move a resolved incident back to the open status
+//	@Tags			Incidents
+//	@Security		JWT
+//	@Produce		json
+//	@Param			incident_id	path	string	true	"Incident UUID"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/incidents/{incident_id}/reopen [post]
+func ReopenIncident() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		transitionIncidentStatus(ctx, database.IncidentStatusOpen)
+	}
+}
+
+func transitionIncidentStatus(ctx *gin.Context, status string) {
+	incidentUUID := ctx.Param("incident_id")
+	if _, err := uuid.Parse(incidentUUID); err != nil {
+		ctx.Set("Status", http.StatusBadRequest)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: "invalid incident UUID",
+		})
+		ctx.Next()
+		return
+	}
+
+	incident, err := database.GetIncident(ctx, incidentUUID)
+	if err == nil {
+		err = checkIncidentOwner(ctx, incident)
+	}
+	if err != nil {
+		ctx.Set("Status", ctx.GetInt("errorCode"))
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return
+	}
+
+	if err := database.TransitionIncidentStatus(ctx, incident, status); err != nil {
+		ctx.Set("Status", ctx.GetInt("errorCode"))
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return
+	}
+
+	ctx.Set("Status", http.StatusNoContent)
+}
+
+// AssignIncident godoc
+//
+//	@Summary		Assign a user to an incident
+//	@Description	Make a user the incident's assignee or an additional responder. The assignee must be a member of one of the incident's resolution teams
+//	@Tags			Incidents
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			assignment	body	utility.IncidentAssignmentPostRequestBodySchema	true	"The request body"
+//	@Param			incident_id	path	string											true	"Incident UUID"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/incidents/{incident_id}/assign [post]
+func AssignIncident() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		changeIncidentAssignment(ctx, database.AssignIncident)
+	}
+}
+
+// UnassignIncident godoc
+//
+//	@Summary		Unassign a user from an incident
+//	@Description	Remove a user as the incident's assignee or as an additional responder
+//	@Tags			Incidents
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			assignment	body	utility.IncidentAssignmentPostRequestBodySchema	true	"The request body"
+//	@Param			incident_id	path	string											true	"Incident UUID"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/incidents/{incident_id}/unassign [post]
+func UnassignIncident() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		changeIncidentAssignment(ctx, database.UnassignIncident)
+	}
+}
+
+func changeIncidentAssignment(ctx *gin.Context, change func(*gin.Context, *database.Incident, *database.User, string) error) {
+	incidentUUID := ctx.Param("incident_id")
+	if _, err := uuid.Parse(incidentUUID); err != nil {
+		ctx.Set("Status", http.StatusBadRequest)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: "invalid incident UUID",
+		})
+		ctx.Next()
+		return
+	}
+
+	var body *utility.IncidentAssignmentPostRequestBodySchema
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.Set("Status", http.StatusBadRequest)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return
+	}
+
+	if status, err := body.Validate(); err != nil {
+		ctx.Set("Status", status)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return
+	}
+	role := body.Role
+	if role == "" {
+		role = database.IncidentAssignmentRoleAssignee
+	}
+
+	incident, err := database.GetIncident(ctx, incidentUUID)
+	if err == nil {
+		err = checkIncidentOwner(ctx, incident)
+	}
+	if err != nil {
+		ctx.Set("Status", ctx.GetInt("errorCode"))
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return
+	}
+
+	user, err := database.GetUser(ctx, database.GetUserFilters{UUID: &body.User})
+	if err != nil {
+		ctx.Set("Status", ctx.GetInt("errorCode"))
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return
+	}
+	if user == nil {
+		ctx.Set("Status", http.StatusNotFound)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: "user not found",
+		})
+		ctx.Next()
+		return
+	}
+
+	if err := change(ctx, incident, user, role); err != nil {
+		ctx.Set("Status", ctx.GetInt("errorCode"))
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return
+	}
+
+	ctx.Set("Status", http.StatusNoContent)
+}
+
+// MergeIncident godoc
+//
+//	@Summary		Merge an incident into another
+//	@Description	Fold a duplicate incident into this one. The duplicate's comments, hosts and resolution teams are moved across and its hash becomes an alias of this incident. The duplicate is kept with its timeline and history, pointing at this incident through mergedInto, and is left out of incident lists
+//	@Tags			Incidents
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			merge		body	utility.IncidentMergePostRequestBodySchema	true	"The request body"
+//	@Param			incident_id	path	string										true	"Incident UUID"
+//	@Success		201
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/incidents/{incident_id}/merge [post]
+func MergeIncident() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		incidentUUID := ctx.Param("incident_id")
+		if _, err := uuid.Parse(incidentUUID); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "invalid incident UUID",
+			})
+			ctx.Next()
+			return
+		}
+
+		var body *utility.IncidentMergePostRequestBodySchema
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		incident, err := database.GetIncident(ctx, incidentUUID)
+		if err == nil {
+			err = checkIncidentOwner(ctx, incident)
+		}
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		duplicate, err := database.GetIncident(ctx, body.Incident)
+		if err == nil {
+			err = checkIncidentOwner(ctx, duplicate)
+		}
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if err := database.MergeIncidents(ctx, incident, duplicate); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("Location", fmt.Sprintf("%s://%s/incidents/%s", ctx.Request.URL.Scheme, ctx.Request.URL.Host, incident.UUID))
+		ctx.Set("Status", http.StatusCreated)
+	}
+}
+
+// BulkIncidents godoc
+//
+//	@Summary		Act on many incidents at once
+//	@Description	Resolve, reopen, assign a team to, comment on, set the severity of or merge many incidents into another in one transaction, reporting the result for each, including a 404 for each incident which is not found. If the action fails on any incident then no incident is changed. Resolving and reopening need the incident:resolve permission, and selecting the incidents by a query needs incident:manage
+//	@Tags			Incidents
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			bulk	body		utility.IncidentBulkPostRequestBodySchema	true	"The request body"
+//	@Success		200		{object}	utility.IncidentBulkResponseSchema
+//	@Failure		400		{object}	utility.QueryErrorResponseSchema
+//	@Failure		401		{object}	utility.ErrorResponseSchema
+//	@Failure		403		{object}	utility.ErrorResponseSchema
+//	@Failure		404		{object}	utility.ErrorResponseSchema
+//	@Failure		409		{object}	utility.IncidentBulkResponseSchema
+//	@Failure		500		{object}	utility.ErrorResponseSchema
+//	@Router			/incidents/bulk [post]
+func BulkIncidents() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var body *utility.IncidentBulkPostRequestBodySchema
+		if err := ctx.ShouldBindJSON(&body); err != nil {
 			ctx.Set("Status", http.StatusBadRequest)
 			ctx.Set("Body", &utility.ErrorResponseSchema{
-				Error: "invalid incident UUID",
+				Error: err.Error(),
 			})
 			ctx.Next()
 			return
 		}
 
-		incident, err := database.GetIncident(ctx, incidentUUID)
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		if (body.Action == "resolve" || body.Action == "reopen") && !database.HasPermission(ctx, database.PermissionIncidentResolve) {
+			ctx.Set("Status", http.StatusForbidden)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: fmt.Sprintf("logged in user does not have the '%s' permission", database.PermissionIncidentResolve),
+			})
+			ctx.Next()
+			return
+		}
+
+		incidents, missing, err := getBulkIncidents(ctx, body)
+		if queryErr := (*utility.QueryError)(nil); errors.As(err, &queryErr) {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", newQueryErrorResponse(queryErr))
+			ctx.Next()
+			return
+		}
 		if err != nil {
 			ctx.Set("Status", ctx.GetInt("errorCode"))
 			ctx.Set("Body", &utility.ErrorResponseSchema{
@@ -225,111 +1319,198 @@ func GetIncident() gin.HandlerFunc {
 			return
 		}
 
-		var resolvedBy *utility.UserGetResponseBodySchema = nil
-		if incident.ResolvedBy != nil {
-			resolvedBy = &utility.UserGetResponseBodySchema{
-				UUID:    incident.ResolvedBy.UUID,
-				Name:    incident.ResolvedBy.Name,
-				Email:   incident.ResolvedBy.Email,
-				Teams:   make([]utility.TeamGetResponseBodySchema, 0),
-				SlackID: incident.ResolvedBy.SlackID,
-				Admin:   &incident.ResolvedBy.Admin,
-			}
-			for _, team := range incident.ResolvedBy.Teams {
-				resolvedBy.Teams = append(resolvedBy.Teams, utility.TeamGetResponseBodySchema{
-					UUID: team.UUID,
-					Name: team.Name,
+		var team *database.Team
+		if body.Action == "assignTeam" {
+			team, err = database.GetTeam(ctx, database.GetTeamsFilters{
+				UUIDs: []string{body.Team},
+			})
+			if err != nil {
+				ctx.Set("Status", ctx.GetInt("errorCode"))
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: err.Error(),
 				})
+				ctx.Next()
+				return
 			}
 		}
-		inc := &utility.IncidentGetResponseBodySchema{
-			UUID:            incident.UUID,
-			Comments:        make([]utility.IncidentCommentGetResponseBodySchema, 0),
-			HostsAffected:   make([]utility.HostMachineGetResponseBodySchema, 0),
-			Description:     incident.Description,
-			Summary:         incident.Summary,
-			ResolvedAt:      incident.ResolvedAt,
-			ResolvedBy:      resolvedBy,
-			CreatedAt:       incident.CreatedAt,
-			ResolutionTeams: make([]utility.TeamGetResponseBodySchema, 0),
-			Hash:            incident.Hash,
-		}
-		for _, team := range incident.ResolutionTeams {
-			users := make([]utility.UserGetResponseBodySchema, 0)
-			for _, user := range team.Users {
-				users = append(users, utility.UserGetResponseBodySchema{
-					UUID:    user.UUID,
-					Name:    user.Name,
-					SlackID: user.SlackID,
-					Admin:   &user.Admin,
+		if body.Action == "mergeInto" {
+			if _, err := database.GetIncident(ctx, body.Target); err != nil {
+				ctx.Set("Status", ctx.GetInt("errorCode"))
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: err.Error(),
 				})
+				ctx.Next()
+				return
 			}
-			inc.ResolutionTeams = append(inc.ResolutionTeams, utility.TeamGetResponseBodySchema{
-				UUID:  team.UUID,
-				Name:  team.Name,
-				Users: users,
-			})
-		}
-		for _, comment := range incident.Comments {
-			c := utility.IncidentCommentGetResponseBodySchema{
-				UUID:        comment.UUID,
-				Comment:     comment.Comment,
-				CommentedAt: comment.CommentedAt,
-				CommentedBy: utility.UserGetResponseBodySchema{
-					UUID:    comment.CommentedBy.UUID,
-					Name:    comment.CommentedBy.Name,
-					Email:   comment.CommentedBy.Email,
-					Teams:   make([]utility.TeamGetResponseBodySchema, 0),
-					SlackID: comment.CommentedBy.SlackID,
-					Admin:   &comment.CommentedBy.Admin,
-				},
+		}
+
+		// a dry run acts on every incident the same as a real one, and is then rolled back along with any action that failed
+		response := &utility.IncidentBulkResponseSchema{
+			Action:  body.Action,
+			DryRun:  body.DryRun,
+			Applied: !body.DryRun,
+			Results: make([]utility.IncidentBulkResultSchema, 0),
+		}
+		for _, incident := range incidents {
+			result := utility.IncidentBulkResultSchema{Incident: incident.UUID, Status: http.StatusOK}
+			ctx.Set("errorCode", http.StatusInternalServerError)
+			if err := applyBulkIncidentAction(ctx, body, incident, team); err != nil {
+				result.Status = ctx.GetInt("errorCode")
+				result.Error = err.Error()
+				response.Applied = false
 			}
-			for _, team := range comment.CommentedBy.Teams {
-				c.CommentedBy.Teams = append(c.CommentedBy.Teams, utility.TeamGetResponseBodySchema{
-					UUID: team.UUID,
-					Name: team.Name,
+			response.Results = append(response.Results, result)
+		}
+		for _, incidentUUID := range missing {
+			response.Results = append(response.Results, utility.IncidentBulkResultSchema{
+				Incident: incidentUUID,
+				Status:   http.StatusNotFound,
+				Error:    "incident not found",
+			})
+			response.Applied = false
+		}
+		if !response.Applied {
+			if err := database.RollbackDBTransaction(ctx); err != nil {
+				ctx.Set("Status", ctx.GetInt("errorCode"))
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: err.Error(),
 				})
+				ctx.Next()
+				return
 			}
-			inc.Comments = append(inc.Comments, c)
 		}
-		for _, host := range incident.HostsAffected {
-			inc.HostsAffected = append(inc.HostsAffected, utility.HostMachineGetResponseBodySchema{
-				UUID:     host.UUID,
-				Hostname: host.Hostname,
-				OS:       host.OS,
-				IP4:      host.IP4,
-				IP6:      host.IP6,
-				Team: utility.TeamGetResponseBodySchema{
-					UUID: host.Team.UUID,
-					Name: host.Team.Name,
-				},
-			})
+
+		if !body.DryRun && !response.Applied {
+			ctx.Set("Status", http.StatusConflict)
+		} else {
+			ctx.Set("Status", http.StatusOK)
 		}
+		ctx.Set("Body", response)
+	}
+}
 
-		ctx.Set("Status", http.StatusOK)
-		ctx.Set("Body", inc)
+// Get the incidents a bulk action is for, in the order they were given or otherwise by creation, along with the UUIDs
+// given which are not of any incident the logged in user can see
+func getBulkIncidents(ctx *gin.Context, body *utility.IncidentBulkPostRequestBodySchema) ([]*database.Incident, []string, error) {
+	if body.Query != nil {
+		if !database.HasPermission(ctx, database.PermissionIncidentManage) {
+			ctx.Set("errorCode", http.StatusForbidden)
+			return nil, nil, fmt.Errorf("selecting incidents by a query needs the '%s' permission", database.PermissionIncidentManage)
+		}
+		query, err := utility.ParseQuery(*body.Query)
+		if err != nil {
+			return nil, nil, err
+		}
+		incidents, count, err := database.GetIncidents(ctx, database.GetIncidentsFilters{
+			Query:    query,
+			Sort:     []database.SortKey{{Field: "createdAt"}},
+			PageSize: utility.Pointer(utility.IncidentBulkLimit),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if count > int64(utility.IncidentBulkLimit) {
+			ctx.Set("errorCode", http.StatusBadRequest)
+			return nil, nil, fmt.Errorf("'query' matches %d incidents, more than the %d a bulk action can be applied to", count, utility.IncidentBulkLimit)
+		}
+		return incidents, nil, nil
+	}
+
+	incidents := make([]*database.Incident, 0)
+	missing := make([]string, 0)
+	for _, incidentUUID := range body.Incidents {
+		if slices.ContainsFunc(incidents, func(i *database.Incident) bool { return i.UUID == incidentUUID }) || slices.Contains(missing, incidentUUID) {
+			continue
+		}
+		incident, err := database.GetIncident(ctx, incidentUUID)
+		if err != nil && ctx.GetInt("errorCode") == http.StatusNotFound {
+			missing = append(missing, incidentUUID)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		incidents = append(incidents, incident)
 	}
+	return incidents, missing, nil
 }
 
-// CreateIncident godoc
+// Check the logged in user can change an incident, which members of the teams owning it and users with the
+// incident:manage permission can, setting the error code if they cannot
+func checkIncidentOwner(ctx *gin.Context, incident *database.Incident) error {
+	if database.HasPermission(ctx, database.PermissionIncidentManage) || incident.OwnedBy(ctx.MustGet("user").(*database.User)) {
+		return nil
+	}
+	ctx.Set("errorCode", http.StatusForbidden)
+	return errors.New("logged in user must be a member of a team owning the incident")
+}
+
+// Apply the action of a bulk request to one incident, setting the error code if it fails
+func applyBulkIncidentAction(ctx *gin.Context, body *utility.IncidentBulkPostRequestBodySchema, incident *database.Incident, team *database.Team) error {
+	if err := checkIncidentOwner(ctx, incident); err != nil {
+		return err
+	}
+	switch body.Action {
+	case "resolve":
+		return database.TransitionIncidentStatus(ctx, incident, database.IncidentStatusResolved)
+	case "reopen":
+		return database.TransitionIncidentStatus(ctx, incident, database.IncidentStatusOpen)
+	case "assignTeam":
+		return database.AddIncidentResolutionTeam(ctx, incident, team)
+	case "comment":
+		_, err := database.CreateIncidentComment(ctx, &database.IncidentComment{
+			Comment:       body.Comment,
+			IncidentID:    incident.ID,
+			CommentedByID: &ctx.MustGet("user").(*database.User).ID,
+		})
+		return err
+	case "setSeverity":
+		return database.SetIncidentSeverity(ctx, incident, *body.Severity)
+	case "mergeInto":
+		// the target gains the hosts and teams of every incident merged into it, so it is read again for each
+		target, err := database.GetIncident(ctx, body.Target)
+		if err == nil {
+			err = checkIncidentOwner(ctx, target)
+		}
+		if err != nil {
+			return err
+		}
+		return database.MergeIncidents(ctx, target, incident)
+	}
+	return nil
+}
+
+// CreateIncidentLink godoc
 //
-//	@Summary		Create an incident
-//	@Description	Create an incident
+//	@Summary		Link an incident to another
+//	@Description	Create a typed link from this incident to another, e.g. this incident is caused by another
 //	@Tags			Incidents
 //	@Security		JWT
 //	@Accept			json
 //	@Produce		json
-//	@Param			incident	body	utility.IncidentPostRequestBodySchema	true	"The request body"
-//	@Header			201			header	string									"GET URL"
+//	@Param			link		body	utility.IncidentLinkPostRequestBodySchema	true	"The request body"
+//	@Param			incident_id	path	string										true	"Incident UUID"
 //	@Success		201
 //	@Failure		400	{object}	utility.ErrorResponseSchema
 //	@Failure		401	{object}	utility.ErrorResponseSchema
 //	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
 //	@Failure		500	{object}	utility.ErrorResponseSchema
-//	@Router			/incidents [post]
-func CreateIncident() gin.HandlerFunc {
+//	@Router			/incidents/{incident_id}/links [post]
+func CreateIncidentLink() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		var body *utility.IncidentPostRequestBodySchema
+		incidentUUID := ctx.Param("incident_id")
+		if _, err := uuid.Parse(incidentUUID); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "invalid incident UUID",
+			})
+			ctx.Next()
+			return
+		}
+
+		var body *utility.IncidentLinkPostRequestBodySchema
 		if err := ctx.ShouldBindJSON(&body); err != nil {
 			ctx.Set("Status", http.StatusBadRequest)
 			ctx.Set("Body", &utility.ErrorResponseSchema{
@@ -348,7 +1529,20 @@ func CreateIncident() gin.HandlerFunc {
 			return
 		}
 
-		incident, err := database.CreateIncident(ctx, body)
+		incident, err := database.GetIncident(ctx, incidentUUID)
+		if err == nil {
+			err = checkIncidentOwner(ctx, incident)
+		}
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		linked, err := database.GetIncident(ctx, body.Incident)
 		if err != nil {
 			ctx.Set("Status", ctx.GetInt("errorCode"))
 			ctx.Set("Body", &utility.ErrorResponseSchema{
@@ -358,28 +1552,37 @@ func CreateIncident() gin.HandlerFunc {
 			return
 		}
 
+		if _, err := database.CreateIncidentLink(ctx, incident, linked, body.Type); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
 		ctx.Header("Location", fmt.Sprintf("%s://%s/incidents/%s", ctx.Request.URL.Scheme, ctx.Request.URL.Host, incident.UUID))
 		ctx.Set("Status", http.StatusCreated)
 	}
 }
 
-// UpdateIncident godoc
+// DeleteIncidentLink godoc
 //
-//	@Summary		Update an incident
-//	@Description	Update an incident
+//	@Summary		Delete an incident link
+//	@Description	Delete a link from this incident to another
 //	@Tags			Incidents
 //	@Security		JWT
-//	@Accept			json
 //	@Produce		json
-//	@Param			incident	body	utility.IncidentPutRequestBodySchema	true	"The request body"
-//	@Param			incident_id	path	string									true	"Incident UUID"
+//	@Param			incident_id	path	string	true	"Incident UUID"
+//	@Param			link_id		path	string	true	"Link UUID"
 //	@Success		204
 //	@Failure		400	{object}	utility.ErrorResponseSchema
 //	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
 //	@Failure		404	{object}	utility.ErrorResponseSchema
 //	@Failure		500	{object}	utility.ErrorResponseSchema
-//	@Router			/incidents/{incident_id} [put]
-func UpdateIncident() gin.HandlerFunc {
+//	@Router			/incidents/{incident_id}/links/{link_id} [delete]
+func DeleteIncidentLink() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		incidentUUID := ctx.Param("incident_id")
 		if _, err := uuid.Parse(incidentUUID); err != nil {
@@ -390,19 +1593,22 @@ func UpdateIncident() gin.HandlerFunc {
 			ctx.Next()
 			return
 		}
-
-		var body *utility.IncidentPutRequestBodySchema
-		if err := ctx.ShouldBindJSON(&body); err != nil {
+		linkUUID := ctx.Param("link_id")
+		if _, err := uuid.Parse(linkUUID); err != nil {
 			ctx.Set("Status", http.StatusBadRequest)
 			ctx.Set("Body", &utility.ErrorResponseSchema{
-				Error: err.Error(),
+				Error: "invalid link UUID",
 			})
 			ctx.Next()
 			return
 		}
 
-		if status, err := body.Validate(); err != nil {
-			ctx.Set("Status", status)
+		incident, err := database.GetIncident(ctx, incidentUUID)
+		if err == nil {
+			err = checkIncidentOwner(ctx, incident)
+		}
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
 			ctx.Set("Body", &utility.ErrorResponseSchema{
 				Error: err.Error(),
 			})
@@ -410,8 +1616,7 @@ func UpdateIncident() gin.HandlerFunc {
 			return
 		}
 
-		incident, err := database.GetIncident(ctx, incidentUUID)
-		if err != nil {
+		if err := database.DeleteIncidentLink(ctx, incident, linkUUID); err != nil {
 			ctx.Set("Status", ctx.GetInt("errorCode"))
 			ctx.Set("Body", &utility.ErrorResponseSchema{
 				Error: err.Error(),
@@ -420,83 +1625,65 @@ func UpdateIncident() gin.HandlerFunc {
 			return
 		}
 
-		hosts := make([]database.HostMachine, 0)
-		if len(body.HostsAffected) > 0 {
-			hs, count, err := database.GetHosts(ctx, database.GetHostsFilters{
-				UUIDs:    body.HostsAffected,
-				PageSize: utility.Pointer(len(body.HostsAffected)),
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// GetIncidentTimeline godoc
+//
+//	@Summary		Get an incident's timeline
+//	@Description	Get every recorded change to an incident, oldest first
+//	@Tags			Incidents
+//	@Security		JWT
+//	@Produce		json
+//	@Param			incident_id	path		string	true	"Incident UUID"
+//	@Param			page		query		int		false	"Page number"
+//	@Param			pageSize	query		int		false	"Number of items per page"
+//	@Success		200			{object}	GetManyIncidentEventsResponseSchema
+//	@Failure		400			{object}	utility.ErrorResponseSchema
+//	@Failure		401			{object}	utility.ErrorResponseSchema
+//	@Failure		404			{object}	utility.ErrorResponseSchema
+//	@Failure		500			{object}	utility.ErrorResponseSchema
+//	@Router			/incidents/{incident_id}/timeline [get]
+func GetIncidentTimeline() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		incidentUUID := ctx.Param("incident_id")
+		if _, err := uuid.Parse(incidentUUID); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "invalid incident UUID",
 			})
-			if err != nil {
-				ctx.Set("Status", ctx.GetInt("errorCode"))
-				ctx.Set("Body", &utility.ErrorResponseSchema{
-					Error: err.Error(),
-				})
-				ctx.Next()
-				return
-			}
-			if int(count) != len(body.HostsAffected) {
-				ctx.Set("Status", http.StatusBadRequest)
-				ctx.Set("Body", &utility.ErrorResponseSchema{
-					Error: "one or more hosts not found",
-				})
-				ctx.Next()
-				return
-			}
-			for _, host := range hs {
-				hosts = append(hosts, *host)
-			}
+			ctx.Next()
+			return
 		}
 
-		teams := make([]database.Team, 0)
-		if len(body.ResolutionTeams) > 0 {
-			ts, count, err := database.GetTeams(ctx, database.GetTeamsFilters{
-				UUIDs:    body.ResolutionTeams,
-				PageSize: utility.Pointer(len(body.ResolutionTeams)),
+		params, err := getCommonParams(ctx)
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
 			})
-			if err != nil {
-				ctx.Set("Status", ctx.GetInt("errorCode"))
-				ctx.Set("Body", &utility.ErrorResponseSchema{
-					Error: err.Error(),
-				})
-				ctx.Next()
-				return
-			}
-			if int(count) != len(body.ResolutionTeams) {
-				ctx.Set("Status", http.StatusBadRequest)
-				ctx.Set("Body", &utility.ErrorResponseSchema{
-					Error: "one or more teams not found",
-				})
-				ctx.Next()
-				return
-			}
-			for _, team := range ts {
-				teams = append(teams, *team)
-			}
+			ctx.Next()
+			return
 		}
+		page := params["page"].(int)
+		pageSize := params["pageSize"].(int)
 
-		var resolvedByID *uint = nil
-		var resolvedAt *time.Time = nil
-		if body.Resolved != nil && *body.Resolved {
-			user := ctx.MustGet("user").(*database.User)
-			resolvedByID = &user.ID
-			resolvedAt = utility.Pointer(time.Now())
+		incident, err := database.GetIncident(ctx, incidentUUID)
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
 		}
 
-		newIncident := &database.Incident{
-			ID:              incident.ID,
-			UUID:            incident.UUID,
-			Summary:         body.Summary,
-			Description:     body.Description,
-			HostsAffected:   hosts,
-			Comments:        incident.Comments,
-			ResolvedByID:    resolvedByID,
-			ResolvedAt:      resolvedAt,
-			CreatedAt:       incident.CreatedAt,
-			ResolutionTeams: teams,
-		}
-		err = database.UpdateIncident(ctx, database.GetIncidentsFilters{
-			UUID: &incidentUUID,
-		}, newIncident)
+		events, count, err := database.GetIncidentEvents(ctx, database.GetIncidentEventsFilters{
+			IncidentID: &incident.ID,
+			Page:       &page,
+			PageSize:   &pageSize,
+		})
 		if err != nil {
 			ctx.Set("Status", ctx.GetInt("errorCode"))
 			ctx.Set("Body", &utility.ErrorResponseSchema{
@@ -506,7 +1693,33 @@ func UpdateIncident() gin.HandlerFunc {
 			return
 		}
 
-		ctx.Set("Status", http.StatusNoContent)
+		response := &utility.GetManyResponseSchema[*utility.IncidentEventGetResponseBodySchema]{
+			Data: make([]*utility.IncidentEventGetResponseBodySchema, 0),
+			Meta: utility.MetaSchema{
+				TotalItems: count,
+				Pages:      int(math.Ceil(float64(count) / float64(pageSize))),
+				Page:       page,
+				PageSize:   pageSize,
+			},
+		}
+		for _, event := range events {
+			e := &utility.IncidentEventGetResponseBodySchema{
+				UUID:      event.UUID,
+				Type:      event.Type,
+				Field:     event.Field,
+				OldValue:  event.OldValue,
+				NewValue:  event.NewValue,
+				Automated: event.Automated,
+				CreatedAt: event.CreatedAt,
+			}
+			if event.Actor != nil || event.ActorName != nil {
+				e.Actor = utility.Pointer(newIncidentHistoryUserResponse(event.Actor, event.ActorName))
+			}
+			response.Data = append(response.Data, e)
+		}
+
+		ctx.Set("Status", http.StatusOK)
+		ctx.Set("Body", response)
 	}
 }
 
@@ -523,6 +1736,7 @@ func UpdateIncident() gin.HandlerFunc {
 //	@Success		201
 //	@Failure		400	{object}	utility.ErrorResponseSchema
 //	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
 //	@Failure		500	{object}	utility.ErrorResponseSchema
 //	@Router			/incidents/{incident_id}/comments [post]
 func CreateIncidentComment() gin.HandlerFunc {
@@ -557,6 +1771,9 @@ func CreateIncidentComment() gin.HandlerFunc {
 		}
 
 		incident, err := database.GetIncident(ctx, incidentUUID)
+		if err == nil {
+			err = checkIncidentOwner(ctx, incident)
+		}
 		if err != nil {
 			ctx.Set("Status", ctx.GetInt("errorCode"))
 			ctx.Set("Body", &utility.ErrorResponseSchema{
@@ -570,7 +1787,7 @@ func CreateIncidentComment() gin.HandlerFunc {
 		comment := &database.IncidentComment{
 			Comment:       body.Comment,
 			IncidentID:    incident.ID,
-			CommentedByID: user.ID,
+			CommentedByID: &user.ID,
 		}
 		comment, err = database.CreateIncidentComment(ctx, comment)
 		if err != nil {
@@ -625,7 +1842,20 @@ func DeleteIncidentComment() gin.HandlerFunc {
 			return
 		}
 
-		comment, err := database.GetIncidentComment(ctx, incidentUUID, commentUUID)
+		incident, err := database.GetIncident(ctx, incidentUUID)
+		if err == nil {
+			err = checkIncidentOwner(ctx, incident)
+		}
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		comment, err := database.GetIncidentComment(ctx, incident.UUID, commentUUID)
 		if err != nil {
 			ctx.Set("Status", ctx.GetInt("errorCode"))
 			ctx.Set("Body", &utility.ErrorResponseSchema{
@@ -635,7 +1865,7 @@ func DeleteIncidentComment() gin.HandlerFunc {
 			return
 		}
 
-		if !user.Admin && comment.CommentedByID != user.ID {
+		if !database.HasPermission(ctx, database.PermissionIncidentManage) && (comment.CommentedByID == nil || *comment.CommentedByID != user.ID) {
 			ctx.Set("Status", http.StatusForbidden)
 			ctx.Set("Body", &utility.ErrorResponseSchema{
 				Error: "you are not allowed to delete this comment",
@@ -657,3 +1887,48 @@ func DeleteIncidentComment() gin.HandlerFunc {
 		ctx.Set("Status", http.StatusNoContent)
 	}
 }
+
+// Get the response for a comment, which has no commenter if it was left automatically
+func newIncidentCommentResponse(comment database.IncidentComment) utility.IncidentCommentGetResponseBodySchema {
+	response := utility.IncidentCommentGetResponseBodySchema{
+		UUID:        comment.UUID,
+		Comment:     comment.Comment,
+		Automated:   comment.Automated,
+		CommentedAt: comment.CommentedAt,
+	}
+	if comment.CommentedBy != nil {
+		response.CommentedBy = &utility.UserGetResponseBodySchema{
+			UUID:    comment.CommentedBy.UUID,
+			Name:    comment.CommentedBy.Name,
+			Email:   comment.CommentedBy.Email,
+			Teams:   make([]utility.TeamGetResponseBodySchema, 0),
+			SlackID: comment.CommentedBy.SlackID,
+			Admin:   &comment.CommentedBy.Admin,
+		}
+		for _, team := range comment.CommentedBy.Teams {
+			response.CommentedBy.Teams = append(response.CommentedBy.Teams, utility.TeamGetResponseBodySchema{
+				UUID: team.UUID,
+				Name: team.Name,
+			})
+		}
+	}
+	return response
+}
+
+// Get the response for a user in an incident's history, which only has their name if they have since been deleted
+func newIncidentHistoryUserResponse(user *database.User, name *string) utility.UserGetResponseBodySchema {
+	if user == nil {
+		response := utility.UserGetResponseBodySchema{}
+		if name != nil {
+			response.Name = *name
+		}
+		return response
+	}
+	return utility.UserGetResponseBodySchema{
+		UUID:    user.UUID,
+		Name:    user.Name,
+		Email:   user.Email,
+		SlackID: user.SlackID,
+		Admin:   &user.Admin,
+	}
+}
@@ -0,0 +1,305 @@
+package controller
+
+import (
+	"com668-backend/database"
+	"com668-backend/utility"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	// the identity provider users who log in with their directory password are linked to
+	ldapIdentityProvider string = "ldap"
+	ldapPageSize         uint32 = 500
+	ldapTimeout                 = time.Second * 10
+)
+
+// The directory users can log in with, as given by the LDAP_ environment variables
+type ldapConfig struct {
+	url string
+	// the account the directory is searched with, or empty to search it anonymously
+	bindDN       string
+	bindPassword string
+	baseDN       string
+	startTLS     bool
+	// the filter every user in the directory who can log in matches
+	userFilter string
+	// the attribute users log in with, which is matched against the email they give
+	loginAttribute string
+	emailAttribute string
+	nameAttribute  string
+	// the attribute which identifies a user even if they are renamed or moved, or empty to use their DN
+	idAttribute string
+	// the attribute listing the DNs of the groups of a user, which are mapped to teams and roles
+	groupAttribute string
+	syncInterval   time.Duration
+}
+
+// Get the configuration of the directory, which is read every time so it can be changed without a restart
+func getLDAPConfig() (*ldapConfig, bool) {
+	config := &ldapConfig{
+		url:            os.Getenv("LDAP_URL"),
+		bindDN:         os.Getenv("LDAP_BIND_DN"),
+		bindPassword:   os.Getenv("LDAP_BIND_PASSWORD"),
+		baseDN:         os.Getenv("LDAP_BASE_DN"),
+		startTLS:       os.Getenv("LDAP_START_TLS") == "true",
+		userFilter:     os.Getenv("LDAP_USER_FILTER"),
+		loginAttribute: os.Getenv("LDAP_LOGIN_ATTRIBUTE"),
+		emailAttribute: os.Getenv("LDAP_EMAIL_ATTRIBUTE"),
+		nameAttribute:  os.Getenv("LDAP_NAME_ATTRIBUTE"),
+		idAttribute:    os.Getenv("LDAP_ID_ATTRIBUTE"),
+		groupAttribute: os.Getenv("LDAP_GROUP_ATTRIBUTE"),
+		syncInterval:   time.Hour,
+	}
+	if config.url == "" || config.baseDN == "" {
+		return nil, false
+	}
+	defaults := map[*string]string{
+		&config.userFilter:     "(objectClass=person)",
+		&config.loginAttribute: "mail",
+		&config.emailAttribute: "mail",
+		&config.nameAttribute:  "uid",
+		&config.groupAttribute: "memberOf",
+	}
+	for value, fallback := range defaults {
+		if *value == "" {
+			*value = fallback
+		}
+	}
+	if interval, err := time.ParseDuration(os.Getenv("LDAP_SYNC_INTERVAL")); err == nil && interval > 0 {
+		config.syncInterval = interval
+	}
+	return config, true
+}
+
+// Connect to the directory and bind as the account it is searched with
+func (config *ldapConfig) connect() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(config.url, ldap.DialWithDialer(&net.Dialer{Timeout: ldapTimeout}))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(ldapTimeout)
+	if config.startTLS {
+		u, err := url.Parse(config.url)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if err := conn.StartTLS(&tls.Config{ServerName: u.Hostname()}); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if config.bindDN != "" {
+		if err := conn.Bind(config.bindDN, config.bindPassword); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (config *ldapConfig) searchRequest(filter string, sizeLimit int) *ldap.SearchRequest {
+	attributes := []string{config.emailAttribute, config.nameAttribute, config.groupAttribute}
+	if config.idAttribute != "" {
+		attributes = append(attributes, config.idAttribute)
+	}
+	return ldap.NewSearchRequest(config.baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, sizeLimit, int(ldapTimeout.Seconds()), false, filter, attributes, nil)
+}
+
+// Who a directory entry is, and the groups they are in. Groups are only given by their whole DN, as the first part of
+// it alone could name a group of the same name anywhere in the directory, or a group from the OpenID Connect provider
+func (config *ldapConfig) identity(entry *ldap.Entry) (database.ExternalIdentity, []string) {
+	identity := database.ExternalIdentity{
+		Provider: ldapIdentityProvider,
+		Subject:  entry.DN,
+		Name:     entry.GetAttributeValue(config.nameAttribute),
+		Email:    entry.GetAttributeValue(config.emailAttribute),
+	}
+	if config.idAttribute != "" {
+		// ids such as the objectGUID of Active Directory are binary, so are kept as hex
+		id := entry.GetRawAttributeValue(config.idAttribute)
+		if utf8.Valid(id) && !containsControl(string(id)) {
+			identity.Subject = string(id)
+		} else {
+			identity.Subject = hex.EncodeToString(id)
+		}
+	}
+
+	return identity, entry.GetAttributeValues(config.groupAttribute)
+}
+
+func containsControl(value string) bool {
+	for _, r := range value {
+		if unicode.IsControl(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Log a user in with their directory password, by finding their entry as the account the directory is searched with and
+// binding as it. A user logging in for the first time is created, unless a user already has their email and has not
+// been linked to them by an admin, and the teams, roles and admin flag mapped to their groups are kept in step with them
+func ldapLogin(ctx *gin.Context, config *ldapConfig, login string, password string) (*database.User, error) {
+	invalid := errors.New("invalid email or password")
+	if password == "" {
+		// binding with no password is an anonymous bind, which succeeds for anyone
+		ctx.Set("errorCode", http.StatusBadRequest)
+		return nil, invalid
+	}
+	conn, err := config.connect()
+	if err != nil {
+		ctx.Set("errorCode", http.StatusBadGateway)
+		return nil, fmt.Errorf("failed to connect to the directory: %s", err)
+	}
+	defer conn.Close()
+
+	filter := fmt.Sprintf("(&%s(%s=%s))", config.userFilter, config.loginAttribute, ldap.EscapeFilter(login))
+	result, err := conn.Search(config.searchRequest(filter, 2))
+	if err != nil {
+		ctx.Set("errorCode", http.StatusBadGateway)
+		return nil, fmt.Errorf("failed to search the directory: %s", err)
+	}
+	// more than one entry means the login attribute does not identify users, so neither can be trusted to be them
+	if len(result.Entries) != 1 {
+		ctx.Set("errorCode", http.StatusBadRequest)
+		return nil, invalid
+	}
+	entry := result.Entries[0]
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			ctx.Set("errorCode", http.StatusBadRequest)
+			return nil, invalid
+		}
+		ctx.Set("errorCode", http.StatusBadGateway)
+		return nil, fmt.Errorf("failed to bind to the directory: %s", err)
+	}
+
+	identity, groups := config.identity(entry)
+	user, err := database.ProvisionExternalUser(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeactivatedAt == nil {
+		if err := database.SyncGroupMappings(ctx, user, groups); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// Bring the users from the directory in step with it, deactivating those who are no longer in it and giving the rest
+// the teams, roles and admin flag mapped to their groups. Deactivated users are left alone, as they may have been
+// deactivated on purpose
+func syncLDAPUsers(ctx *gin.Context, config *ldapConfig) error {
+	conn, err := config.connect()
+	if err != nil {
+		ctx.Set("errorCode", http.StatusBadGateway)
+		return fmt.Errorf("failed to connect to the directory: %s", err)
+	}
+	defer conn.Close()
+	result, err := conn.SearchWithPaging(config.searchRequest(config.userFilter, 0), ldapPageSize)
+	if err != nil {
+		ctx.Set("errorCode", http.StatusBadGateway)
+		return fmt.Errorf("failed to search the directory: %s", err)
+	}
+	entries := make(map[string][]string)
+	for _, entry := range result.Entries {
+		identity, groups := config.identity(entry)
+		entries[identity.Subject] = groups
+	}
+
+	users, err := database.GetExternalUsers(ctx, ldapIdentityProvider)
+	if err != nil {
+		return err
+	}
+	// a directory which is misconfigured or part way through a migration can look empty, which should not lock everyone out
+	if len(entries) == 0 && len(users) > 0 {
+		ctx.Set("errorCode", http.StatusBadGateway)
+		return errors.New("the directory has no users, so none have been deactivated")
+	}
+	for _, user := range users {
+		if user.DeactivatedAt != nil {
+			continue
+		}
+		groups, ok := entries[*user.ExternalID]
+		if !ok {
+			err = database.DeactivateUser(ctx, user)
+		} else {
+			err = database.SyncGroupMappings(ctx, user, groups)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sync the users from the directory every LDAP_SYNC_INTERVAL, which is an hour unless it is set, until the server stops
+func RunLDAPSync() {
+	for {
+		interval := time.Hour
+		if config, ok := getLDAPConfig(); ok {
+			interval = config.syncInterval
+			err := database.RunJob(func(ctx *gin.Context) error {
+				return syncLDAPUsers(ctx, config)
+			})
+			if err != nil {
+				log.Default().Printf("Failed to sync the users from the directory: %s\n", err)
+			}
+		}
+		time.Sleep(interval)
+	}
+}
+
+// SyncLDAPUsers godoc
+//
+//	@Summary		Sync the users from the directory
+//	@Description	Sync the users from the LDAP directory now, rather than waiting for LDAP_SYNC_INTERVAL. Users no longer in the directory are deactivated, and the rest are given the teams, roles and admin flag mapped to their groups
+//	@Tags			Users
+//	@Security		JWT
+//	@Produce		json
+//	@Success		204
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Failure		502	{object}	utility.ErrorResponseSchema
+//	@Router			/users/ldap-sync [post]
+func SyncLDAPUsers() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		config, ok := getLDAPConfig()
+		if !ok {
+			ctx.Set("Status", http.StatusNotFound)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "ldap is not configured",
+			})
+			ctx.Next()
+			return
+		}
+
+		if err := syncLDAPUsers(ctx, config); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
@@ -1,143 +1,457 @@
 package controller
 
 import (
+	"com668-backend/database"
 	"com668-backend/middleware"
 	"com668-backend/utility"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 type registerControllerOptions struct {
-	useAdminAuth bool
-	useAuth      bool
-	useDB        bool
+	useAuth bool
+	useDB   bool
+	// the permission the logged in user must hold, or empty if any logged in user can use the endpoint
+	permission string
 }
 
 func RegisterControllers(engine *gin.Engine) {
 	// Register authentication endpoints
 	register(engine, http.MethodGet, "/authorise/slack", SlackRedirect(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: false,
+		useAuth: true,
+		useDB:   true,
 	})
 	register(engine, http.MethodGet, "/authorise/slack/callback", AuthoriseSlack(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: false,
+		useAuth: true,
+		useDB:   true,
+	})
+	register(engine, http.MethodGet, "/.well-known/jwks.json", GetJWKS(), registerControllerOptions{
+		useAuth: false,
+		useDB:   true,
+	})
+	register(engine, http.MethodGet, "/authorise/oidc", OIDCRedirect(), registerControllerOptions{
+		useAuth: false,
+		useDB:   true,
+	})
+	register(engine, http.MethodGet, "/authorise/oidc/callback", AuthoriseOIDC(), registerControllerOptions{
+		useAuth: false,
+		useDB:   true,
 	})
 
 	// Register teams endpoints
 	register(engine, http.MethodGet, "/teams", GetTeams(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: false,
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionTeamRead,
+	})
+	register(engine, http.MethodGet, "/teams/:team_id", GetTeam(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionTeamRead,
+	})
+	register(engine, http.MethodPost, "/teams", CreateTeam(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionTeamWrite,
+	})
+	register(engine, http.MethodPut, "/teams/:team_id", UpdateTeam(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionTeamWrite,
+	})
+	register(engine, http.MethodDelete, "/teams/:team_id", DeleteTeam(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionTeamWrite,
+	})
+	register(engine, http.MethodPut, "/teams/:team_id/members/:user_id", SetTeamMember(), registerControllerOptions{
+		useAuth: true,
+		useDB:   true,
+	})
+	register(engine, http.MethodDelete, "/teams/:team_id/members/:user_id", RemoveTeamMember(), registerControllerOptions{
+		useAuth: true,
+		useDB:   true,
 	})
 
 	// Register users endpoints
 	register(engine, http.MethodPost, "/users", CreateUser(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: true,
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionUserWrite,
 	})
 	register(engine, http.MethodPost, "/users/login", LoginUser(), registerControllerOptions{
-		useAuth:      false,
-		useDB:        true,
-		useAdminAuth: false,
+		useAuth: false,
+		useDB:   true,
+	})
+	register(engine, http.MethodPost, "/users/refresh", RefreshUserToken(), registerControllerOptions{
+		useAuth: false,
+		useDB:   true,
+	})
+	register(engine, http.MethodPost, "/users/logout", LogoutUser(), registerControllerOptions{
+		useAuth: true,
+		useDB:   true,
+	})
+	register(engine, http.MethodDelete, "/users/:user_id/sessions", RevokeUserSessions(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionUserWrite,
+	})
+	register(engine, http.MethodPost, "/users/ldap-sync", SyncLDAPUsers(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionUserWrite,
 	})
 	register(engine, http.MethodGet, "/me", GetUser(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: false,
+		useAuth: true,
+		useDB:   true,
+	})
+	register(engine, http.MethodGet, "/users", GetUsers(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionUserRead,
+	})
+	register(engine, http.MethodGet, "/users/:user_id", GetUserByID(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionUserRead,
+	})
+	register(engine, http.MethodPut, "/users/:user_id", UpdateUser(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionUserWrite,
+	})
+	register(engine, http.MethodPatch, "/users/:user_id", PatchUser(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionUserWrite,
+	})
+	register(engine, http.MethodDelete, "/users/:user_id", DeleteUser(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionUserWrite,
+	})
+	register(engine, http.MethodPut, "/users/:user_id/identity", LinkUserIdentity(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionUserWrite,
+	})
+	register(engine, http.MethodPost, "/users/:user_id/deactivate", DeactivateUser(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionUserWrite,
+	})
+	register(engine, http.MethodPost, "/users/:user_id/reactivate", ReactivateUser(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionUserWrite,
+	})
+	register(engine, http.MethodPut, "/me/password", ChangePassword(), registerControllerOptions{
+		useAuth: true,
+		useDB:   true,
+	})
+
+	// Register API key endpoints
+	register(engine, http.MethodGet, "/me/api-keys", GetAPIKeys(), registerControllerOptions{
+		useAuth: true,
+		useDB:   true,
+	})
+	register(engine, http.MethodGet, "/me/api-keys/:key_id", GetAPIKey(), registerControllerOptions{
+		useAuth: true,
+		useDB:   true,
+	})
+	register(engine, http.MethodPost, "/me/api-keys", CreateAPIKey(), registerControllerOptions{
+		useAuth: true,
+		useDB:   true,
+	})
+	register(engine, http.MethodDelete, "/me/api-keys/:key_id", RevokeAPIKey(), registerControllerOptions{
+		useAuth: true,
+		useDB:   true,
+	})
+	register(engine, http.MethodGet, "/users/:user_id/api-keys", GetAPIKeys(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionUserRead,
+	})
+	register(engine, http.MethodGet, "/users/:user_id/api-keys/:key_id", GetAPIKey(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionUserRead,
+	})
+	register(engine, http.MethodPost, "/users/:user_id/api-keys", CreateAPIKey(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionUserWrite,
+	})
+	register(engine, http.MethodDelete, "/users/:user_id/api-keys/:key_id", RevokeAPIKey(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionUserWrite,
+	})
+
+	// Register role endpoints
+	register(engine, http.MethodGet, "/roles", GetRoles(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionRoleRead,
+	})
+	register(engine, http.MethodGet, "/roles/:role_id", GetRole(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionRoleRead,
+	})
+	register(engine, http.MethodPost, "/roles", CreateRole(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionRoleWrite,
+	})
+	register(engine, http.MethodPut, "/roles/:role_id", UpdateRole(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionRoleWrite,
+	})
+	register(engine, http.MethodDelete, "/roles/:role_id", DeleteRole(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionRoleWrite,
+	})
+	register(engine, http.MethodPut, "/users/:user_id/roles/:role_id", AssignUserRole(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionRoleWrite,
+	})
+	register(engine, http.MethodDelete, "/users/:user_id/roles/:role_id", UnassignUserRole(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionRoleWrite,
+	})
+	register(engine, http.MethodPut, "/teams/:team_id/roles/:role_id", AssignTeamRole(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionRoleWrite,
+	})
+	register(engine, http.MethodDelete, "/teams/:team_id/roles/:role_id", UnassignTeamRole(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionRoleWrite,
+	})
+	register(engine, http.MethodGet, "/group-mappings", GetGroupMappings(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionRoleRead,
+	})
+	register(engine, http.MethodGet, "/group-mappings/:mapping_id", GetGroupMapping(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionRoleRead,
+	})
+	register(engine, http.MethodPost, "/group-mappings", CreateGroupMapping(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionRoleWrite,
+	})
+	register(engine, http.MethodDelete, "/group-mappings/:mapping_id", DeleteGroupMapping(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionRoleWrite,
 	})
 
 	// Register incident endpoints
 	register(engine, http.MethodGet, "/incidents", GetIncidents(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: false,
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentRead,
 	})
 	register(engine, http.MethodGet, "/incidents/:incident_id", GetIncident(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: false,
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentRead,
 	})
 	register(engine, http.MethodPost, "/incidents", CreateIncident(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: true,
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentCreate,
+	})
+	register(engine, http.MethodPost, "/incidents/bulk", BulkIncidents(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentWrite,
+	})
+	register(engine, http.MethodPut, "/incidents/occurrences", ReportIncidentOccurrence(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentCreate,
 	})
 	register(engine, http.MethodPut, "/incidents/:incident_id", UpdateIncident(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: false,
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentWrite,
+	})
+	register(engine, http.MethodPatch, "/incidents/:incident_id", PatchIncident(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentWrite,
+	})
+	register(engine, http.MethodPost, "/incidents/:incident_id/acknowledge", AcknowledgeIncident(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentWrite,
+	})
+	register(engine, http.MethodPost, "/incidents/:incident_id/investigate", InvestigateIncident(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentWrite,
+	})
+	register(engine, http.MethodPost, "/incidents/:incident_id/mitigate", MitigateIncident(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentWrite,
+	})
+	register(engine, http.MethodPost, "/incidents/:incident_id/resolve", ResolveIncident(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentResolve,
+	})
+	register(engine, http.MethodPost, "/incidents/:incident_id/reopen", ReopenIncident(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentResolve,
+	})
+	register(engine, http.MethodPost, "/incidents/:incident_id/assign", AssignIncident(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentWrite,
+	})
+	register(engine, http.MethodPost, "/incidents/:incident_id/unassign", UnassignIncident(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentWrite,
+	})
+	register(engine, http.MethodPost, "/incidents/:incident_id/merge", MergeIncident(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentWrite,
+	})
+	register(engine, http.MethodPost, "/incidents/:incident_id/links", CreateIncidentLink(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentWrite,
+	})
+	register(engine, http.MethodDelete, "/incidents/:incident_id/links/:link_id", DeleteIncidentLink(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentWrite,
+	})
+	register(engine, http.MethodGet, "/incidents/:incident_id/timeline", GetIncidentTimeline(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentRead,
 	})
 	register(engine, http.MethodPost, "/incidents/:incident_id/comments", CreateIncidentComment(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: false,
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentWrite,
 	})
 	register(engine, http.MethodDelete, "/incidents/:incident_id/comments/:comment_id", DeleteIncidentComment(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: false,
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionIncidentWrite,
 	})
 
 	// Register settings endpoints
 	register(engine, http.MethodGet, "/providers", GetProviders(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: true,
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionProviderRead,
 	})
 	register(engine, http.MethodGet, "/providers/:provider_id", GetProvider(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: true,
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionProviderRead,
 	})
 	register(engine, http.MethodPost, "/providers", CreateProvider(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: true,
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionProviderWrite,
 	})
 	register(engine, http.MethodPut, "/providers/:provider_id", UpdateProvider(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: true,
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionProviderWrite,
+	})
+	register(engine, http.MethodPatch, "/providers/:provider_id", PatchProvider(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionProviderWrite,
 	})
 	register(engine, http.MethodDelete, "/providers/:provider_id", DeleteProvider(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: true,
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionProviderWrite,
+	})
+
+	register(engine, http.MethodGet, "/priority-matrix", GetPriorityMatrix(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionSettingsRead,
+	})
+	register(engine, http.MethodPut, "/priority-matrix", UpdatePriorityMatrix(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionSettingsWrite,
+	})
+	register(engine, http.MethodGet, "/fingerprint-rules", GetFingerprintRules(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionSettingsRead,
+	})
+	register(engine, http.MethodPost, "/fingerprint-rules", CreateFingerprintRule(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionSettingsWrite,
+	})
+	register(engine, http.MethodDelete, "/fingerprint-rules/:rule_id", DeleteFingerprintRule(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionSettingsWrite,
 	})
 
 	// Register hosts endpoints
 	register(engine, http.MethodGet, "/hosts", GetHosts(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: false,
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionHostRead,
 	})
 	register(engine, http.MethodGet, "/hosts/:host_id", GetHost(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: false,
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionHostRead,
 	})
 	register(engine, http.MethodPost, "/hosts", CreateHost(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: true,
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionHostWrite,
 	})
 	register(engine, http.MethodPut, "/hosts/:host_id", UpdateHost(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: true,
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionHostWrite,
+	})
+	register(engine, http.MethodPatch, "/hosts/:host_id", PatchHost(), registerControllerOptions{
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionHostWrite,
 	})
 	register(engine, http.MethodDelete, "/hosts/:host_id", DeleteHost(), registerControllerOptions{
-		useAuth:      true,
-		useDB:        true,
-		useAdminAuth: true,
+		useAuth:    true,
+		useDB:      true,
+		permission: database.PermissionHostWrite,
 	})
 }
 
@@ -165,7 +479,10 @@ func register(engine *gin.Engine, method string, endpoint string, handler gin.Ha
 	if options.useDB {
 		handlers = append(handlers, middleware.TransactionRequestMW())
 		if options.useAuth {
-			handlers = append(handlers, middleware.UserAuthRequestMW(options.useAdminAuth))
+			handlers = append(handlers, middleware.UserAuthRequestMW())
+			if options.permission != "" {
+				handlers = append(handlers, middleware.PermissionRequestMW(options.permission))
+			}
 		}
 	}
 
@@ -223,5 +540,122 @@ func getCommonParams(ctx *gin.Context) (map[string]any, error) {
 	}
 	params["pageSize"] = pageSizeInt
 
+	// a cursor from a previous page's meta takes the place of the page number
+	var cursor *string = nil
+	if cursorStr := ctx.Query("cursor"); cursorStr != "" {
+		cursor = &cursorStr
+	}
+	params["cursor"] = cursor
+
 	return params, nil
 }
+
+// Parse the sort query parameter, a comma separated list of fields each optionally prefixed with '-' for descending order
+func getSortParam(ctx *gin.Context, fields []string) ([]database.SortKey, error) {
+	keys := make([]database.SortKey, 0)
+	sort := ctx.Query("sort")
+	if sort == "" {
+		return keys, nil
+	}
+	for _, part := range strings.Split(sort, ",") {
+		part = strings.TrimSpace(part)
+		field := strings.TrimPrefix(part, "-")
+		if !slices.Contains(fields, field) {
+			return nil, fmt.Errorf("sort query parameter must be a comma separated list of '%s', each optionally prefixed with '-'", strings.Join(fields, "', '"))
+		}
+		if slices.ContainsFunc(keys, func(k database.SortKey) bool { return k.Field == field }) {
+			return nil, fmt.Errorf("sort query parameter contains '%s' more than once", field)
+		}
+		keys = append(keys, database.SortKey{Field: field, Desc: strings.HasPrefix(part, "-")})
+	}
+	return keys, nil
+}
+
+// Parse the expand query parameter, a comma separated list of relations to include in the response. Expanding a nested
+// relation such as 'hostsAffected.team' expands its parent too. Returns nil if the parameter is not given
+func getExpandParam(ctx *gin.Context, relations []string) ([]string, error) {
+	expand, ok := ctx.GetQuery("expand")
+	if !ok {
+		return nil, nil
+	}
+	expanded := make([]string, 0)
+	for _, part := range strings.Split(expand, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !slices.Contains(relations, part) {
+			return nil, fmt.Errorf("expand query parameter must be a comma separated list of '%s'", strings.Join(relations, "', '"))
+		}
+		for _, relation := range relations {
+			if (relation == part || strings.HasPrefix(part, relation+".")) && !slices.Contains(expanded, relation) {
+				expanded = append(expanded, relation)
+			}
+		}
+	}
+	return expanded, nil
+}
+
+// Parse the fields query parameter, a comma separated list of the top level fields of schema to include in the response.
+// Returns nil if the parameter is not given
+func getFieldsParam(ctx *gin.Context, schema utility.ResponseSchema) ([]string, error) {
+	value := ctx.Query("fields")
+	if value == "" {
+		return nil, nil
+	}
+	names := make([]string, 0)
+	for name := range schema.JSON() {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	fields := make([]string, 0)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if !slices.Contains(names, part) {
+			return nil, fmt.Errorf("fields query parameter must be a comma separated list of '%s'", strings.Join(names, "', '"))
+		}
+		if !slices.Contains(fields, part) {
+			fields = append(fields, part)
+		}
+	}
+	return fields, nil
+}
+
+// Check the If-Match header of a request against the ETag of the current version and response of the resource it
+// changes, setting a 412 response if it does not match. A request without the header is let through, so clients which
+// do not send it are not broken
+func checkIfMatch(ctx *gin.Context, version uint, current utility.ResponseSchema) bool {
+	header := ctx.GetHeader("If-Match")
+	if header == "" || utility.ETagMatches(header, utility.ResourceETag(version, current), false) {
+		return true
+	}
+	ctx.Set("Status", http.StatusPreconditionFailed)
+	ctx.Set("Body", &utility.ErrorResponseSchema{
+		Error: "If-Match does not match the current version of the resource, get it again and retry",
+	})
+	ctx.Next()
+	return false
+}
+
+// Build the response for a query parameter which failed to parse, pointing at the character at fault
+func newQueryErrorResponse(err error) *utility.QueryErrorResponseSchema {
+	response := &utility.QueryErrorResponseSchema{Error: fmt.Sprintf("invalid query parameter: %s", err.Error())}
+	if queryErr := (*utility.QueryError)(nil); errors.As(err, &queryErr) {
+		response.Position = queryErr.Pos
+	}
+	return response
+}
+
+// Parse a comma separated list of levels between 1 and highest (e.g. severities or priorities)
+func parseLevels(value string, highest uint) ([]uint, error) {
+	levels := make([]uint, 0)
+	for _, part := range strings.Split(value, ",") {
+		level, err := strconv.ParseUint(strings.TrimSpace(part), 10, 0)
+		if err != nil || level < 1 || uint(level) > highest {
+			return nil, fmt.Errorf("must be a comma separated list of integers between 1 and %d", highest)
+		}
+		levels = append(levels, uint(level))
+	}
+	return levels, nil
+}
@@ -0,0 +1,503 @@
+package controller
+
+import (
+	"com668-backend/database"
+	"com668-backend/middleware"
+	"com668-backend/utility"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// the identity provider users who log in with OpenID Connect are linked to
+	oidcIdentityProvider string = "oidc"
+	// how long a user has to log in with the identity provider before they have to start again
+	oidcLoginLifetime time.Duration = time.Minute * 5
+	// how many logins can be waiting to come back from the identity provider, past which the oldest are dropped
+	oidcLoginLimit int = 10000
+	// the cookie holding the state of a login, so that it can only be finished in the browser it was started in
+	oidcStateCookie string = "OIDC-State"
+	// how long the discovery document of the identity provider is kept before it is fetched again
+	oidcDiscoveryLifetime time.Duration = time.Hour
+)
+
+// The identity provider users can log in with, as given by the OIDC_ environment variables
+type oidcConfig struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       []string
+	// the claim of the id token listing the groups of the user, which are mapped to teams and roles
+	groupsClaim string
+	// where the user is sent once they have logged in, or empty to respond with the tokens
+	postLoginRedirect string
+}
+
+// The endpoints of an identity provider, from its discovery document, and the keys it signs id tokens with
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	fetchedAt             time.Time
+	keys                  map[string]any
+}
+
+var (
+	oidcMutex     sync.Mutex
+	oidcProviders = make(map[string]*oidcProvider)
+	oidcClient    = &http.Client{Timeout: time.Second * 10}
+)
+
+// Get the configuration of the identity provider, which is read every time so it can be changed without a restart
+func getOIDCConfig() (*oidcConfig, bool) {
+	config := &oidcConfig{
+		issuer:            strings.TrimSuffix(os.Getenv("OIDC_ISSUER"), "/"),
+		clientID:          os.Getenv("OIDC_CLIENT_ID"),
+		clientSecret:      os.Getenv("OIDC_CLIENT_SECRET"),
+		redirectURL:       os.Getenv("OIDC_REDIRECT_URL"),
+		scopes:            strings.Fields(os.Getenv("OIDC_SCOPES")),
+		groupsClaim:       os.Getenv("OIDC_GROUPS_CLAIM"),
+		postLoginRedirect: os.Getenv("OIDC_POST_LOGIN_REDIRECT"),
+	}
+	if config.issuer == "" || config.clientID == "" || config.redirectURL == "" {
+		return nil, false
+	}
+	if len(config.scopes) == 0 {
+		config.scopes = []string{"openid", "email", "profile"}
+	}
+	if config.groupsClaim == "" {
+		config.groupsClaim = "groups"
+	}
+	return config, true
+}
+
+// Whether users can only log in with single sign-on, and not with a password
+func localPasswordsDisabled() bool {
+	return os.Getenv("DISABLE_LOCAL_PASSWORDS") == "true"
+}
+
+func (config *oidcConfig) oauth2Config(provider *oidcProvider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     config.clientID,
+		ClientSecret: config.clientSecret,
+		Scopes:       config.scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  provider.AuthorizationEndpoint,
+			TokenURL: provider.TokenEndpoint,
+		},
+		RedirectURL: config.redirectURL,
+	}
+}
+
+// Get the endpoints of an identity provider from its discovery document, fetching it if it is not already known
+func discoverOIDCProvider(issuer string) (*oidcProvider, error) {
+	oidcMutex.Lock()
+	provider, ok := oidcProviders[issuer]
+	oidcMutex.Unlock()
+	if ok && time.Since(provider.fetchedAt) < oidcDiscoveryLifetime {
+		return provider, nil
+	}
+
+	provider = &oidcProvider{}
+	if err := fetchOIDCJSON(issuer+"/.well-known/openid-configuration", provider); err != nil {
+		return nil, err
+	}
+	// the document must be for the issuer it was fetched from, or anyone able to change it could issue tokens
+	if strings.TrimSuffix(provider.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("identity provider discovery document is for issuer '%s', not '%s'", provider.Issuer, issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, errors.New("identity provider discovery document is missing an endpoint")
+	}
+	provider.fetchedAt = time.Now()
+	provider.keys = make(map[string]any)
+
+	oidcMutex.Lock()
+	oidcProviders[issuer] = provider
+	oidcMutex.Unlock()
+	return provider, nil
+}
+
+func fetchOIDCJSON(url string, value any) error {
+	resp, err := oidcClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("identity provider responded to %s with status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(value)
+}
+
+// Get the key an identity provider signed an id token with, fetching its keys again if the key is not known, as it may
+// have rotated them
+func (provider *oidcProvider) verificationKey(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	oidcMutex.Lock()
+	key, ok := provider.keys[kid]
+	oidcMutex.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	var set utility.JWKSetResponseSchema
+	if err := fetchOIDCJSON(provider.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]any)
+	for _, jwk := range set.Keys {
+		// keys for encryption, or of a type which cannot be read, cannot have signed the token anyway
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if public, err := parseJWK(jwk); err == nil {
+			keys[jwk.Kid] = public
+		}
+	}
+	oidcMutex.Lock()
+	provider.keys = keys
+	oidcMutex.Unlock()
+
+	key, ok = keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("identity provider has no signing key '%s'", kid)
+	}
+	return key, nil
+}
+
+// The public key in a JSON Web Key, which is an *rsa.PublicKey or an *ecdsa.PublicKey
+func parseJWK(jwk utility.JWKResponseSchema) (any, error) {
+	decode := func(value string) (*big.Int, error) {
+		bytes, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(bytes), nil
+	}
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[jwk.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve '%s'", jwk.Crv)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type '%s'", jwk.Kty)
+	}
+}
+
+// Check an id token was issued to this server by the identity provider for the login it was sent on, returning its
+// claims
+func verifyIDToken(config *oidcConfig, provider *oidcProvider, idToken string, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, provider.verificationKey,
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(provider.Issuer),
+		jwt.WithAudience(config.clientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	// the nonce ties the token to the login, so a token from another login cannot be replayed
+	if claimed, _ := claims["nonce"].(string); claimed != nonce {
+		return nil, errors.New("id token nonce does not match")
+	}
+	return claims, nil
+}
+
+// The groups of the user from a claim of their id token, which identity providers give as a list or a single string
+func oidcGroups(claims jwt.MapClaims, claim string) []string {
+	groups := make([]string, 0)
+	switch value := claims[claim].(type) {
+	case string:
+		groups = append(groups, value)
+	case []any:
+		for _, group := range value {
+			if name, ok := group.(string); ok {
+				groups = append(groups, name)
+			}
+		}
+	}
+	return groups
+}
+
+func newOIDCIdentity(claims jwt.MapClaims) database.ExternalIdentity {
+	identity := database.ExternalIdentity{
+		Provider: oidcIdentityProvider,
+	}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["preferred_username"].(string)
+	if identity.Name == "" {
+		identity.Name, _ = claims["name"].(string)
+	}
+	return identity
+}
+
+// OIDCRedirect godoc
+//
+//	@Summary		Log in with single sign-on
+//	@Description	Redirect to the OpenID Connect identity provider to log in, using the authorisation code flow with PKCE. The identity provider sends the user back to GET /authorise/oidc/callback, which must be in the same browser as it is given the OIDC-State cookie
+//	@Tags			Third-Party Auth
+//	@Produce		json
+//	@Header			302	header	string	"Login URL of the identity provider"
+//	@Success		302
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Failure		502	{object}	utility.ErrorResponseSchema
+//	@Router			/authorise/oidc [get]
+func OIDCRedirect() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		config, ok := getOIDCConfig()
+		if !ok {
+			ctx.Set("Status", http.StatusNotFound)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "single sign-on is not configured",
+			})
+			ctx.Next()
+			return
+		}
+		provider, err := discoverOIDCProvider(config.issuer)
+		if err != nil {
+			ctx.Set("Status", http.StatusBadGateway)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		b := make([]byte, 20)
+		if _, err := rand.Read(b); err != nil {
+			ctx.Set("Status", http.StatusInternalServerError)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		state, nonce := hex.EncodeToString(b[:10]), hex.EncodeToString(b[10:])
+		login := &database.OIDCLogin{Verifier: oauth2.GenerateVerifier(), Nonce: nonce}
+		if err := database.CreateOIDCLogin(ctx, login, state, oidcLoginLifetime, oidcLoginLimit); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		url := config.oauth2Config(provider).AuthCodeURL(state, oauth2.S256ChallengeOption(login.Verifier), oauth2.SetAuthURLParam("nonce", nonce))
+		// lax, as the identity provider sends the user back with a top level navigation from another site
+		ctx.SetSameSite(http.SameSiteLaxMode)
+		ctx.SetCookie(oidcStateCookie, state, int(oidcLoginLifetime.Seconds()), "/authorise/oidc", ctx.Request.URL.Host, true, true)
+		ctx.Header("Location", url)
+		ctx.Set("Status", http.StatusFound)
+	}
+}
+
+// AuthoriseOIDC godoc
+//
+//	@Summary		Finish logging in with single sign-on
+//	@Description	Swap the code the identity provider sent the user back with for their id token, then log them in. The state must match the OIDC-State cookie set by GET /authorise/oidc. A user logging in for the first time is created, unless a user already has their email, who has to be linked to their sub with PUT /users/{user_id}/identity by an admin first. The teams, roles and admin flag mapped to the groups in their id token are kept in step with them. Responds like POST /users/login, or redirects to OIDC_POST_LOGIN_REDIRECT if it is set
+//	@Tags			Third-Party Auth
+//	@Produce		json
+//	@Param			state	query	string	true	"State sent to the identity provider"
+//	@Param			code	query	string	true	"Authorisation code"
+//	@Header			204		header	string	"JWT Token"
+//	@Header			204		header	string	"Refresh-Token"
+//	@Success		204
+//	@Success		302
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Failure		502	{object}	utility.ErrorResponseSchema
+//	@Router			/authorise/oidc/callback [get]
+func AuthoriseOIDC() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		config, ok := getOIDCConfig()
+		if !ok {
+			ctx.Set("Status", http.StatusNotFound)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "single sign-on is not configured",
+			})
+			ctx.Next()
+			return
+		}
+		oidcState := ctx.Request.URL.Query().Get("state")
+		code := ctx.Request.URL.Query().Get("code")
+		errStr := ctx.Request.URL.Query().Get("error")
+
+		if errStr != "" {
+			ctx.Set("Status", http.StatusUnauthorized)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: errStr,
+			})
+			ctx.Next()
+			return
+		}
+		if oidcState == "" || code == "" {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "missing state or code",
+			})
+			ctx.Next()
+			return
+		}
+		// a login can only be finished in the browser it was started in, so a user cannot be sent back with a code for
+		// someone else's account and logged in as them
+		cookie, _ := ctx.Cookie(oidcStateCookie)
+		if subtle.ConstantTimeCompare([]byte(cookie), []byte(oidcState)) != 1 {
+			ctx.Set("Status", http.StatusForbidden)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "login was not started in this browser",
+			})
+			ctx.Next()
+			return
+		}
+		ctx.SetCookie(oidcStateCookie, "", -1, "/authorise/oidc", ctx.Request.URL.Host, true, true)
+		// a state can only be used once, so a login cannot be replayed
+		login, err := database.TakeOIDCLogin(ctx, oidcState, oidcLoginLifetime)
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		provider, err := discoverOIDCProvider(config.issuer)
+		if err != nil {
+			ctx.Set("Status", http.StatusBadGateway)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		exchangeCtx := context.WithValue(ctx.Request.Context(), oauth2.HTTPClient, oidcClient)
+		token, err := config.oauth2Config(provider).Exchange(exchangeCtx, code, oauth2.VerifierOption(login.Verifier))
+		if err != nil {
+			ctx.Set("Status", http.StatusUnauthorized)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		idToken, _ := token.Extra("id_token").(string)
+		if idToken == "" {
+			ctx.Set("Status", http.StatusUnauthorized)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "identity provider did not give an id token",
+			})
+			ctx.Next()
+			return
+		}
+		claims, err := verifyIDToken(config, provider, idToken, login.Nonce)
+		if err != nil {
+			ctx.Set("Status", http.StatusUnauthorized)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		identity := newOIDCIdentity(claims)
+		if identity.Subject == "" {
+			ctx.Set("Status", http.StatusUnauthorized)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "id token has no subject",
+			})
+			ctx.Next()
+			return
+		}
+
+		user, err := database.ProvisionExternalUser(ctx, identity)
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		if user.DeactivatedAt != nil {
+			ctx.Set("Status", http.StatusForbidden)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "user account has been deactivated",
+			})
+			ctx.Next()
+			return
+		}
+		if err := database.SyncGroupMappings(ctx, user, oidcGroups(claims, config.groupsClaim)); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		session, refreshToken, err := database.CreateSession(ctx, user, middleware.RefreshTokenLifetime)
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		issueTokens(ctx, user, session, refreshToken)
+		if ctx.GetInt("Status") == http.StatusNoContent && config.postLoginRedirect != "" {
+			ctx.Header("Location", config.postLoginRedirect)
+			ctx.Set("Status", http.StatusFound)
+		}
+	}
+}
@@ -0,0 +1,487 @@
+package controller
+
+import (
+	"com668-backend/database"
+	"com668-backend/utility"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type GetManyRolesResponseSchema utility.GetManyResponseSchema[*utility.RoleGetResponseBodySchema]
+
+// GetRoles godoc
+//
+//	@Summary		Get a list of Roles
+//	@Description	Get a list of Roles and the permissions each of them grants
+//	@Tags			Roles
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			page			query		int		false	"Page number"
+//	@Param			pageSize		query		int		false	"Number of items per page"
+//	@Param			cursor			query		string	false	"Cursor of the page to get, from the meta of another page"
+//	@Param			sort			query		string	false	"Comma separated list of fields to sort by, each prefixed with '-' for descending order. One of name"
+//	@Param			If-None-Match	header		string	false	"ETag of a copy already held, to get a 304 if it is still current"
+//	@Success		200				{object}	GetManyRolesResponseSchema
+//	@Header			200				{string}	ETag	"Version of the response"
+//	@Success		304
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/roles [get]
+func GetRoles() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		params, err := getCommonParams(ctx)
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		page := params["page"].(int)
+		pageSize := params["pageSize"].(int)
+
+		sort, err := getSortParam(ctx, database.RoleSortFields)
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		cursors := &database.PageCursors{}
+		roles, count, err := database.GetRoles(ctx, database.GetRolesFilters{
+			Page:     &page,
+			PageSize: &pageSize,
+			Sort:     sort,
+			Cursor:   params["cursor"].(*string),
+			Cursors:  cursors,
+		})
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		resp := &utility.GetManyResponseSchema[*utility.RoleGetResponseBodySchema]{
+			Data: make([]*utility.RoleGetResponseBodySchema, 0),
+			Meta: utility.MetaSchema{
+				TotalItems: count,
+				Pages:      int(math.Ceil(float64(count) / float64(pageSize))),
+				Page:       page,
+				PageSize:   pageSize,
+				Next:       cursors.Next,
+				Prev:       cursors.Prev,
+			},
+		}
+		for _, role := range roles {
+			resp.Data = append(resp.Data, newRoleResponse(role))
+		}
+		ctx.Set("Status", http.StatusOK)
+		ctx.Set("Body", resp)
+	}
+}
+
+// GetRole godoc
+//
+//	@Summary		Get a Role
+//	@Description	Get a Role and the permissions it grants
+//	@Tags			Roles
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			role_id			path		string	true	"Role UUID"
+//	@Param			If-None-Match	header		string	false	"ETag of a copy already held, to get a 304 if it is still current"
+//	@Success		200				{object}	utility.RoleGetResponseBodySchema
+//	@Header			200				{string}	ETag	"Version of the response"
+//	@Success		304
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/roles/{role_id} [get]
+func GetRole() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		role, ok := getRoleParam(ctx)
+		if !ok {
+			return
+		}
+
+		ctx.Set("Status", http.StatusOK)
+		ctx.Set("Body", newRoleResponse(role))
+	}
+}
+
+// CreateRole godoc
+//
+//	@Summary		Create a Role
+//	@Description	Create a Role granting a set of permissions
+//	@Tags			Roles
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body	utility.RolePostPutRequestBodySchema	true	"Role creation request"
+//	@Header			201		header	string									"GET URL"
+//	@Success		201
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/roles [post]
+func CreateRole() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		body, ok := getRoleBody(ctx)
+		if !ok {
+			return
+		}
+
+		role := &database.Role{
+			Name:        body.Name,
+			Description: body.Description,
+			Default:     body.Default,
+			Permissions: newRolePermissions(body.Permissions),
+		}
+		if err := database.CreateRole(ctx, role); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Header("Location", fmt.Sprintf("%s://%s/roles/%s", ctx.Request.URL.Scheme, ctx.Request.URL.Host, role.UUID))
+		ctx.Set("Status", http.StatusCreated)
+	}
+}
+
+// UpdateRole godoc
+//
+//	@Summary		Update a Role
+//	@Description	Replace the details and permissions of a Role. The admin role cannot be changed
+//	@Tags			Roles
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			role_id	path	string									true	"Role UUID"
+//	@Param			body	body	utility.RolePostPutRequestBodySchema	true	"Role update request"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/roles/{role_id} [put]
+func UpdateRole() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		body, ok := getRoleBody(ctx)
+		if !ok {
+			return
+		}
+
+		role, ok := getRoleParam(ctx)
+		if !ok {
+			return
+		}
+
+		role.Name = body.Name
+		role.Description = body.Description
+		role.Default = body.Default
+		role.Permissions = newRolePermissions(body.Permissions)
+		if err := database.UpdateRole(ctx, role); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// DeleteRole godoc
+//
+//	@Summary		Delete a Role
+//	@Description	Delete a Role, taking it away from the users and teams it is assigned to. The admin role cannot be deleted
+//	@Tags			Roles
+//	@Security		JWT
+//	@Produce		json
+//	@Param			role_id	path	string	true	"Role UUID"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/roles/{role_id} [delete]
+func DeleteRole() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		role, ok := getRoleParam(ctx)
+		if !ok {
+			return
+		}
+
+		if err := database.DeleteRole(ctx, role); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// AssignUserRole godoc
+//
+//	@Summary		Assign a Role to a user
+//	@Description	Assign a Role to a user, who then holds its permissions. Only an admin can assign the admin role
+//	@Tags			Roles
+//	@Security		JWT
+//	@Produce		json
+//	@Param			user_id	path	string	true	"User UUID"
+//	@Param			role_id	path	string	true	"Role UUID"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/users/{user_id}/roles/{role_id} [put]
+func AssignUserRole() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := getUserParam(ctx)
+		if !ok {
+			return
+		}
+		role, ok := getRoleParam(ctx)
+		if !ok {
+			return
+		}
+
+		if err := database.AssignUserRole(ctx, user, role); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// UnassignUserRole godoc
+//
+//	@Summary		Take a Role away from a user
+//	@Description	Take a Role away from a user. The user still holds it if it is a default role or is assigned to one of their teams. Only an admin can take the admin role away
+//	@Tags			Roles
+//	@Security		JWT
+//	@Produce		json
+//	@Param			user_id	path	string	true	"User UUID"
+//	@Param			role_id	path	string	true	"Role UUID"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/users/{user_id}/roles/{role_id} [delete]
+func UnassignUserRole() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := getUserParam(ctx)
+		if !ok {
+			return
+		}
+		role, ok := getRoleParam(ctx)
+		if !ok {
+			return
+		}
+
+		if err := database.UnassignUserRole(ctx, user, role); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// AssignTeamRole godoc
+//
+//	@Summary		Assign a Role to a Team
+//	@Description	Assign a Role to a Team, whose members then hold its permissions. Only an admin can assign the admin role
+//	@Tags			Roles
+//	@Security		JWT
+//	@Produce		json
+//	@Param			team_id	path	string	true	"Team UUID"
+//	@Param			role_id	path	string	true	"Role UUID"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/teams/{team_id}/roles/{role_id} [put]
+func AssignTeamRole() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		team, ok := getTeamParam(ctx)
+		if !ok {
+			return
+		}
+		role, ok := getRoleParam(ctx)
+		if !ok {
+			return
+		}
+
+		if err := database.AssignTeamRole(ctx, team, role); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// UnassignTeamRole godoc
+//
+//	@Summary		Take a Role away from a Team
+//	@Description	Take a Role away from a Team and so from its members, unless they hold it some other way. Only an admin can take the admin role away
+//	@Tags			Roles
+//	@Security		JWT
+//	@Produce		json
+//	@Param			team_id	path	string	true	"Team UUID"
+//	@Param			role_id	path	string	true	"Role UUID"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/teams/{team_id}/roles/{role_id} [delete]
+func UnassignTeamRole() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		team, ok := getTeamParam(ctx)
+		if !ok {
+			return
+		}
+		role, ok := getRoleParam(ctx)
+		if !ok {
+			return
+		}
+
+		if err := database.UnassignTeamRole(ctx, team, role); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// Bind and validate the body of a role creation or update, setting the response if it is invalid
+func getRoleBody(ctx *gin.Context) (*utility.RolePostPutRequestBodySchema, bool) {
+	var body *utility.RolePostPutRequestBodySchema
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.Set("Status", http.StatusBadRequest)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return nil, false
+	}
+
+	if status, err := body.Validate(); err != nil {
+		ctx.Set("Status", status)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return nil, false
+	}
+	for _, permission := range body.Permissions {
+		if !slices.Contains(database.Permissions, permission) {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: fmt.Sprintf("'permissions' must only contain '%s'", strings.Join(database.Permissions, "', '")),
+			})
+			ctx.Next()
+			return nil, false
+		}
+	}
+	return body, true
+}
+
+// Get the role given by the role_id path parameter, setting the response if it is invalid or not found
+func getRoleParam(ctx *gin.Context) (*database.Role, bool) {
+	roleUUID := ctx.Param("role_id")
+	if _, err := uuid.Parse(roleUUID); err != nil {
+		ctx.Set("Status", http.StatusBadRequest)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: "invalid role UUID",
+		})
+		ctx.Next()
+		return nil, false
+	}
+
+	role, err := database.GetRole(ctx, database.GetRolesFilters{
+		UUIDs: []string{roleUUID},
+	})
+	if err != nil {
+		ctx.Set("Status", ctx.GetInt("errorCode"))
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return nil, false
+	}
+	return role, true
+}
+
+// The permissions of a role from a list of validated permission names, ignoring any repeats
+func newRolePermissions(names []string) []database.RolePermission {
+	permissions := make([]database.RolePermission, 0)
+	for _, name := range names {
+		if !slices.ContainsFunc(permissions, func(permission database.RolePermission) bool { return permission.Permission == name }) {
+			permissions = append(permissions, database.RolePermission{Permission: name})
+		}
+	}
+	return permissions
+}
+
+func newRoleResponse(role *database.Role) *utility.RoleGetResponseBodySchema {
+	return &utility.RoleGetResponseBodySchema{
+		UUID:        role.UUID,
+		Name:        role.Name,
+		Description: role.Description,
+		Default:     role.Default,
+		Permissions: role.PermissionNames(),
+	}
+}
@@ -14,6 +14,7 @@ import (
 )
 
 type GetManyProvidersResponseSchema utility.GetManyResponseSchema[*utility.ProviderGetResponseSchema]
+type GetManyFingerprintRulesResponseSchema utility.GetManyResponseSchema[*utility.FingerprintRuleGetResponseBodySchema]
 
 // GetProviders godoc
 //
@@ -26,10 +27,15 @@ type GetManyProvidersResponseSchema utility.GetManyResponseSchema[*utility.Provi
 //	@Param			provider_type	query		string	true	"The type of provider"	Enums(log, alert)
 //	@Param			page			query		int		false	"Page number"
 //	@Param			pageSize		query		int		false	"Number of items per page"
+//	@Param			cursor			query		string	false	"Cursor of the page to get, from the meta of another page"
+//	@Param			sort			query		string	false	"Comma separated list of fields to sort by, each prefixed with '-' for descending order. One of name, type"
+//	@Param			If-None-Match	header		string	false	"ETag of a copy already held, to get a 304 if it is still current"
 //	@Success		200				{object}	GetManyProvidersResponseSchema
-//	@Failure		401				{object}	utility.ErrorResponseSchema
-//	@Failure		403				{object}	utility.ErrorResponseSchema
-//	@Failure		500				{object}	utility.ErrorResponseSchema
+//	@Header			200				{string}	ETag	"Version of the response"
+//	@Success		304
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
 //	@Router			/providers [get]
 func GetProviders() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -55,8 +61,24 @@ func GetProviders() gin.HandlerFunc {
 			return
 		}
 
+		sort, err := getSortParam(ctx, database.ProviderSortFields)
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		cursors := &database.PageCursors{}
 		providers, count, err := database.GetProviders(ctx, database.GetProvidersFilters{
 			ProviderType: &providerType,
+			Page:         &page,
+			PageSize:     &pageSize,
+			Sort:         sort,
+			Cursor:       params["cursor"].(*string),
+			Cursors:      cursors,
 		})
 		if err != nil {
 			ctx.Set("Status", ctx.GetInt("errorCode"))
@@ -74,6 +96,8 @@ func GetProviders() gin.HandlerFunc {
 				PageSize:   pageSize,
 				TotalItems: count,
 				Pages:      int(math.Ceil(float64(count) / float64(pageSize))),
+				Next:       cursors.Next,
+				Prev:       cursors.Prev,
 			},
 		}
 		for _, provider := range providers {
@@ -107,11 +131,15 @@ func GetProviders() gin.HandlerFunc {
 //	@Security		JWT
 //	@Accept			json
 //	@Produce		json
-//	@Param			provider_id	path		string	true	"Provider ID"	format(uuid)
-//	@Success		200			{object}	utility.ProviderGetResponseSchema
-//	@Failure		401			{object}	utility.ErrorResponseSchema
-//	@Failure		404			{object}	utility.ErrorResponseSchema
-//	@Failure		500			{object}	utility.ErrorResponseSchema
+//	@Param			provider_id		path		string	true	"Provider ID"	format(uuid)
+//	@Param			If-None-Match	header		string	false	"ETag of a copy already held, to get a 304 if it is still current"
+//	@Success		200				{object}	utility.ProviderGetResponseSchema
+//	@Header			200				{string}	ETag	"Version of the response"
+//	@Success		304
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
 //	@Router			/providers/{provider_id} [get]
 func GetProvider() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -135,24 +163,29 @@ func GetProvider() gin.HandlerFunc {
 			return
 		}
 
-		fields := make([]utility.KeyValueSchema, 0)
-		for _, field := range provider.Fields {
-			fields = append(fields, utility.KeyValueSchema{
-				Key:      field.Key,
-				Value:    field.Value,
-				Type:     field.Type,
-				Required: &field.Required,
-			})
-		}
-
+		response := newProviderResponse(provider)
+		ctx.Header("ETag", utility.ResourceETag(provider.Version, response))
 		ctx.Set("Status", http.StatusOK)
-		ctx.Set("Body", &utility.ProviderGetResponseSchema{
-			UUID:   provider.UUID,
-			Name:   provider.Name,
-			Fields: fields,
-			Type:   provider.Type,
+		ctx.Set("Body", response)
+	}
+}
+
+func newProviderResponse(provider *database.Provider) *utility.ProviderGetResponseSchema {
+	fields := make([]utility.KeyValueSchema, 0)
+	for _, field := range provider.Fields {
+		fields = append(fields, utility.KeyValueSchema{
+			Key:      field.Key,
+			Value:    field.Value,
+			Type:     field.Type,
+			Required: &field.Required,
 		})
 	}
+	return &utility.ProviderGetResponseSchema{
+		UUID:   provider.UUID,
+		Name:   provider.Name,
+		Fields: fields,
+		Type:   provider.Type,
+	}
 }
 
 // CreateProvider godoc
@@ -229,10 +262,12 @@ func CreateProvider() gin.HandlerFunc {
 //	@Produce		json
 //	@Param			provider_id	path	string									true	"Provider ID"	format(uuid)
 //	@Param			body		body	utility.ProviderPutRequestBodySchema	true	"Provider data"
+//	@Param			If-Match	header	string									false	"ETag of the version being changed, to get a 412 if another request has changed it since"
 //	@Success		204
 //	@Failure		401	{object}	utility.ErrorResponseSchema
 //	@Failure		403	{object}	utility.ErrorResponseSchema
 //	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		412	{object}	utility.ErrorResponseSchema
 //	@Failure		500	{object}	utility.ErrorResponseSchema
 //	@Router			/providers/{provider_id} [put]
 func UpdateProvider() gin.HandlerFunc {
@@ -275,34 +310,131 @@ func UpdateProvider() gin.HandlerFunc {
 			ctx.Next()
 			return
 		}
-		provider.Name = body.Name
-		provider.Fields = []database.ProviderField{}
-		for _, field := range body.Fields {
-			var required bool = false
-			if field.Required != nil {
-				required = *field.Required
-			}
-			providerField := database.ProviderField{
+		updateProvider(ctx, provider, body)
+	}
+}
+
+// PatchProvider godoc
+//
+//	@Summary		Partially update a provider
+//	@Description	Update some fields of a provider with a JSON Merge Patch (RFC 7386) or a JSON Patch (RFC 6902) of the body of PUT /providers/{provider_id}
+//	@Tags			Settings
+//	@Security		JWT
+//	@Accept			application/merge-patch+json,application/json-patch+json
+//	@Produce		json
+//	@Param			provider_id	path	string	true	"Provider ID"	format(uuid)
+//	@Param			body		body	object	true	"The patch"
+//	@Param			If-Match	header	string	false	"ETag of the version being changed, to get a 412 if another request has changed it since"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		412	{object}	utility.ErrorResponseSchema
+//	@Failure		415	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/providers/{provider_id} [patch]
+func PatchProvider() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		providerID := ctx.Param("provider_id")
+		if _, err := uuid.Parse(providerID); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "Invalid provider ID",
+			})
+			ctx.Next()
+			return
+		}
+
+		patch, err := ctx.GetRawData()
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		provider, err := database.GetProvider(ctx, database.GetProvidersFilters{UUID: &providerID})
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		current := utility.ProviderPutRequestBodySchema{
+			Name:   provider.Name,
+			Fields: make([]utility.KeyValueSchema, 0),
+		}
+		for _, field := range provider.Fields {
+			current.Fields = append(current.Fields, utility.KeyValueSchema{
 				Key:      field.Key,
 				Value:    field.Value,
 				Type:     field.Type,
-				Required: required,
-			}
-			provider.Fields = append(provider.Fields, providerField)
+				Required: utility.Pointer(field.Required),
+			})
+		}
+		body, status, err := utility.ApplyPatch(ctx.GetHeader("Content-Type"), current, patch)
+		if err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
 		}
 
-		if err := database.UpdateProvider(ctx, provider); err != nil {
-			ctx.Set("Status", ctx.GetInt("errorCode"))
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
 			ctx.Set("Body", &utility.ErrorResponseSchema{
 				Error: err.Error(),
 			})
 			ctx.Next()
 			return
 		}
-		ctx.Set("Status", http.StatusNoContent)
+
+		updateProvider(ctx, provider, body)
 	}
 }
 
+// Replace the name and fields of a provider with those of a validated PUT or patched body
+func updateProvider(ctx *gin.Context, provider *database.Provider, body *utility.ProviderPutRequestBodySchema) {
+	if !checkIfMatch(ctx, provider.Version, newProviderResponse(provider)) {
+		return
+	}
+
+	provider.Name = body.Name
+	provider.Fields = []database.ProviderField{}
+	for _, field := range body.Fields {
+		var required bool = false
+		if field.Required != nil {
+			required = *field.Required
+		}
+		providerField := database.ProviderField{
+			Key:      field.Key,
+			Value:    field.Value,
+			Type:     field.Type,
+			Required: required,
+		}
+		provider.Fields = append(provider.Fields, providerField)
+	}
+
+	if err := database.UpdateProvider(ctx, provider); err != nil {
+		ctx.Set("Status", ctx.GetInt("errorCode"))
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return
+	}
+	ctx.Set("Status", http.StatusNoContent)
+}
+
 // DeleteProvider godoc
 //
 //	@Summary		Delete a provider
@@ -312,10 +444,12 @@ func UpdateProvider() gin.HandlerFunc {
 //	@Accept			json
 //	@Produce		json
 //	@Param			provider_id	path	string	true	"Provider ID"	format(uuid)
+//	@Param			If-Match	header	string	false	"ETag of the version being changed, to get a 412 if another request has changed it since"
 //	@Success		204
 //	@Failure		401	{object}	utility.ErrorResponseSchema
 //	@Failure		403	{object}	utility.ErrorResponseSchema
 //	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		412	{object}	utility.ErrorResponseSchema
 //	@Failure		500	{object}	utility.ErrorResponseSchema
 //	@Router			/providers/{provider_id} [delete]
 func DeleteProvider() gin.HandlerFunc {
@@ -330,7 +464,7 @@ func DeleteProvider() gin.HandlerFunc {
 			return
 		}
 
-		_, err := database.GetProvider(ctx, database.GetProvidersFilters{UUID: &providerID})
+		provider, err := database.GetProvider(ctx, database.GetProvidersFilters{UUID: &providerID})
 		if err != nil {
 			ctx.Set("Status", http.StatusNotFound)
 			ctx.Set("Body", &utility.ErrorResponseSchema{
@@ -339,6 +473,9 @@ func DeleteProvider() gin.HandlerFunc {
 			ctx.Next()
 			return
 		}
+		if !checkIfMatch(ctx, provider.Version, newProviderResponse(provider)) {
+			return
+		}
 
 		if err := database.DeleteProvider(ctx, providerID); err != nil {
 			ctx.Set("Status", ctx.GetInt("errorCode"))
@@ -351,3 +488,269 @@ func DeleteProvider() gin.HandlerFunc {
 		ctx.Set("Status", http.StatusNoContent)
 	}
 }
+
+// GetPriorityMatrix godoc
+//
+//	@Summary		Get the priority matrix
+//	@Description	Get the impact x urgency matrix used to calculate incident priority
+//	@Tags			Settings
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	utility.PriorityMatrixSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/priority-matrix [get]
+func GetPriorityMatrix() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		entries, err := database.GetPriorityMatrix(ctx)
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		resp := &utility.PriorityMatrixSchema{
+			Entries: make([]utility.PriorityMatrixEntrySchema, 0),
+		}
+		for _, entry := range entries {
+			resp.Entries = append(resp.Entries, utility.PriorityMatrixEntrySchema{
+				Impact:   entry.Impact,
+				Urgency:  entry.Urgency,
+				Priority: entry.Priority,
+			})
+		}
+		ctx.Set("Status", http.StatusOK)
+		ctx.Set("Body", resp)
+	}
+}
+
+// UpdatePriorityMatrix godoc
+//
+//	@Summary		Update the priority matrix
+//	@Description	Replace the impact x urgency matrix and recalculate the priority of all incidents
+//	@Tags			Settings
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			matrix	body	utility.PriorityMatrixSchema	true	"The request body"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/priority-matrix [put]
+func UpdatePriorityMatrix() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var body *utility.PriorityMatrixSchema
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		entries := make([]*database.PriorityMatrixEntry, 0)
+		for _, entry := range body.Entries {
+			entries = append(entries, &database.PriorityMatrixEntry{
+				Impact:   entry.Impact,
+				Urgency:  entry.Urgency,
+				Priority: entry.Priority,
+			})
+		}
+		if err := database.UpdatePriorityMatrix(ctx, entries); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// GetFingerprintRules godoc
+//
+//	@Summary		Get a list of fingerprint rules
+//	@Description	Get the rules applied, in order, when fingerprinting an incident's stack trace
+//	@Tags			Settings
+//	@Security		JWT
+//	@Produce		json
+//	@Param			page		query		int	false	"Page number"
+//	@Param			pageSize	query		int	false	"Number of items per page"
+//	@Success		200			{object}	GetManyFingerprintRulesResponseSchema
+//	@Failure		400			{object}	utility.ErrorResponseSchema
+//	@Failure		401			{object}	utility.ErrorResponseSchema
+//	@Failure		500			{object}	utility.ErrorResponseSchema
+//	@Router			/fingerprint-rules [get]
+func GetFingerprintRules() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		params, err := getCommonParams(ctx)
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		page := params["page"].(int)
+		pageSize := params["pageSize"].(int)
+
+		rules, count, err := database.GetFingerprintRules(ctx, database.GetFingerprintRulesFilters{
+			Page:     &page,
+			PageSize: &pageSize,
+		})
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		resp := &utility.GetManyResponseSchema[*utility.FingerprintRuleGetResponseBodySchema]{
+			Data: make([]*utility.FingerprintRuleGetResponseBodySchema, 0),
+			Meta: utility.MetaSchema{
+				TotalItems: count,
+				Pages:      int(math.Ceil(float64(count) / float64(pageSize))),
+				Page:       page,
+				PageSize:   pageSize,
+			},
+		}
+		for _, rule := range rules {
+			resp.Data = append(resp.Data, &utility.FingerprintRuleGetResponseBodySchema{
+				UUID:      rule.UUID,
+				Name:      rule.Name,
+				Type:      rule.Type,
+				Pattern:   rule.Pattern,
+				GroupKey:  rule.GroupKey,
+				CreatedAt: rule.CreatedAt,
+			})
+		}
+		ctx.Set("Status", http.StatusOK)
+		ctx.Set("Body", resp)
+	}
+}
+
+// CreateFingerprintRule godoc
+//
+//	@Summary		Create a fingerprint rule
+//	@Description	Create a rule which either groups matching stack traces under one fingerprint or strips matching text before fingerprinting
+//	@Tags			Settings
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body	utility.FingerprintRulePostRequestBodySchema	true	"Fingerprint rule data"
+//	@Success		201
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/fingerprint-rules [post]
+func CreateFingerprintRule() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var body *utility.FingerprintRulePostRequestBodySchema
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		rule := &database.FingerprintRule{
+			Name:     body.Name,
+			Type:     body.Type,
+			Pattern:  body.Pattern,
+			GroupKey: body.GroupKey,
+		}
+		if err := database.CreateFingerprintRule(ctx, rule); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		ctx.Set("Status", http.StatusCreated)
+		ctx.Header("Location", fmt.Sprintf("%s://%s/fingerprint-rules/%s", ctx.Request.URL.Scheme, ctx.Request.URL.Host, rule.UUID))
+	}
+}
+
+// DeleteFingerprintRule godoc
+//
+//	@Summary		Delete a fingerprint rule
+//	@Description	Delete a fingerprint rule. Incidents already fingerprinted by it keep their hash
+//	@Tags			Settings
+//	@Security		JWT
+//	@Produce		json
+//	@Param			rule_id	path	string	true	"Fingerprint rule ID"	format(uuid)
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/fingerprint-rules/{rule_id} [delete]
+func DeleteFingerprintRule() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ruleID := ctx.Param("rule_id")
+		if _, err := uuid.Parse(ruleID); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "Invalid fingerprint rule ID",
+			})
+			ctx.Next()
+			return
+		}
+
+		if _, err := database.GetFingerprintRule(ctx, ruleID); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if err := database.DeleteFingerprintRule(ctx, ruleID); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
@@ -3,10 +3,12 @@ package controller
 import (
 	"com668-backend/database"
 	"com668-backend/utility"
+	"fmt"
 	"math"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type GetManyTeamsResponseSchema utility.GetManyResponseSchema[*utility.TeamGetResponseBodySchema]
@@ -19,13 +21,18 @@ type GetManyTeamsResponseSchema utility.GetManyResponseSchema[*utility.TeamGetRe
 //	@Security		JWT
 //	@Accept			json
 //	@Produce		json
-//	@Param			page		query		int	false	"Page number"
-//	@Param			pageSize	query		int	false	"Number of items per page"
-//	@Success		200			{object}	GetManyTeamsResponseSchema
-//	@Failure		400			{object}	utility.ErrorResponseSchema
-//	@Failure		401			{object}	utility.ErrorResponseSchema
-//	@Failure		403			{object}	utility.ErrorResponseSchema
-//	@Failure		500			{object}	utility.ErrorResponseSchema
+//	@Param			page			query		int		false	"Page number"
+//	@Param			pageSize		query		int		false	"Number of items per page"
+//	@Param			cursor			query		string	false	"Cursor of the page to get, from the meta of another page"
+//	@Param			sort			query		string	false	"Comma separated list of fields to sort by, each prefixed with '-' for descending order. One of name"
+//	@Param			If-None-Match	header		string	false	"ETag of a copy already held, to get a 304 if it is still current"
+//	@Success		200				{object}	GetManyTeamsResponseSchema
+//	@Header			200				{string}	ETag	"Version of the response"
+//	@Success		304
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
 //	@Router			/teams [get]
 func GetTeams() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -41,9 +48,23 @@ func GetTeams() gin.HandlerFunc {
 		page := params["page"].(int)
 		pageSize := params["pageSize"].(int)
 
+		sort, err := getSortParam(ctx, database.TeamSortFields)
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		cursors := &database.PageCursors{}
 		teams, count, err := database.GetTeams(ctx, database.GetTeamsFilters{
 			Page:     &page,
 			PageSize: &pageSize,
+			Sort:     sort,
+			Cursor:   params["cursor"].(*string),
+			Cursors:  cursors,
 		})
 		if err != nil {
 			ctx.Set("Status", ctx.GetInt("errorCode"))
@@ -61,26 +82,386 @@ func GetTeams() gin.HandlerFunc {
 				Pages:      int(math.Ceil(float64(count) / float64(pageSize))),
 				Page:       page,
 				PageSize:   pageSize,
+				Next:       cursors.Next,
+				Prev:       cursors.Prev,
 			},
 		}
 		for _, team := range teams {
-			users := make([]utility.UserGetResponseBodySchema, 0)
-			for _, user := range team.Users {
-				users = append(users, utility.UserGetResponseBodySchema{
-					UUID:    user.UUID,
-					Name:    user.Name,
-					SlackID: user.SlackID,
-					Admin:   &user.Admin,
+			resp.Data = append(resp.Data, newTeamResponse(team))
+		}
+		ctx.Set("Status", http.StatusOK)
+		ctx.Set("Body", resp)
+	}
+}
+
+// GetTeam godoc
+//
+//	@Summary		Get a Team
+//	@Description	Get a Team and its users, along with their role in it
+//	@Tags			Teams
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			team_id			path		string	true	"Team UUID"
+//	@Param			If-None-Match	header		string	false	"ETag of a copy already held, to get a 304 if it is still current"
+//	@Success		200				{object}	utility.TeamGetResponseBodySchema
+//	@Header			200				{string}	ETag	"Version of the response"
+//	@Success		304
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/teams/{team_id} [get]
+func GetTeam() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		team, ok := getTeamParam(ctx)
+		if !ok {
+			return
+		}
+
+		response := newTeamResponse(team)
+		ctx.Header("ETag", utility.ResourceETag(team.Version, response))
+		ctx.Set("Status", http.StatusOK)
+		ctx.Set("Body", response)
+	}
+}
+
+// CreateTeam godoc
+//
+//	@Summary		Create a Team
+//	@Description	Create a Team with no users
+//	@Tags			Teams
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body	utility.TeamPostPutRequestBodySchema	true	"Team creation request"
+//	@Header			201		header	string									"GET URL"
+//	@Success		201
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/teams [post]
+func CreateTeam() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var body *utility.TeamPostPutRequestBodySchema
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		team := &database.Team{
+			Name:    body.Name,
+			Private: body.Private,
+		}
+		if err := database.CreateTeam(ctx, team); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Header("Location", fmt.Sprintf("%s://%s/teams/%s", ctx.Request.URL.Scheme, ctx.Request.URL.Host, team.UUID))
+		ctx.Set("Status", http.StatusCreated)
+	}
+}
+
+// UpdateTeam godoc
+//
+//	@Summary		Update a Team
+//	@Description	Rename a Team, or change whether only its members can see the incidents and hosts it owns
+//	@Tags			Teams
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			team_id		path	string									true	"Team UUID"
+//	@Param			body		body	utility.TeamPostPutRequestBodySchema	true	"Team update request"
+//	@Param			If-Match	header	string									false	"ETag of the version being changed, to get a 412 if another request has changed it since"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		412	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/teams/{team_id} [put]
+func UpdateTeam() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var body *utility.TeamPostPutRequestBodySchema
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		team, ok := getTeamParam(ctx)
+		if !ok || !checkIfMatch(ctx, team.Version, newTeamResponse(team)) {
+			return
+		}
+
+		team.Name = body.Name
+		team.Private = body.Private
+		if err := database.UpdateTeam(ctx, team); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// DeleteTeam godoc
+//
+//	@Summary		Delete a Team
+//	@Description	Delete a Team. A team which owns hosts or is a resolution team of incidents is only deleted if another team is given to reassign them to
+//	@Tags			Teams
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			team_id		path	string	true	"Team UUID"
+//	@Param			reassignTo	query	string	false	"UUID of the team to move the team's hosts and incidents to"
+//	@Param			If-Match	header	string	false	"ETag of the version being changed, to get a 412 if another request has changed it since"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		412	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/teams/{team_id} [delete]
+func DeleteTeam() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		team, ok := getTeamParam(ctx)
+		if !ok || !checkIfMatch(ctx, team.Version, newTeamResponse(team)) {
+			return
+		}
+
+		var reassignTo *database.Team
+		if reassignUUID := ctx.Query("reassignTo"); reassignUUID != "" {
+			if _, err := uuid.Parse(reassignUUID); err != nil {
+				ctx.Set("Status", http.StatusBadRequest)
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: "reassignTo query parameter must be a valid UUID",
 				})
+				ctx.Next()
+				return
 			}
-			t := &utility.TeamGetResponseBodySchema{
-				UUID:  team.UUID,
-				Name:  team.Name,
-				Users: users,
+			var err error
+			reassignTo, err = database.GetTeam(ctx, database.GetTeamsFilters{
+				UUIDs: []string{reassignUUID},
+			})
+			if err != nil {
+				ctx.Set("Status", ctx.GetInt("errorCode"))
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: err.Error(),
+				})
+				ctx.Next()
+				return
 			}
-			resp.Data = append(resp.Data, t)
 		}
-		ctx.Set("Status", http.StatusOK)
-		ctx.Set("Body", resp)
+
+		if err := database.DeleteTeam(ctx, team, reassignTo); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// SetTeamMember godoc
+//
+//	@Summary		Add a user to a Team
+//	@Description	Add a user to a Team, or change the role of a user already in it. Only users with the team:write permission and the team's leads can manage its members. Only an admin can add a user to or promote a lead of a Team holding the admin role, and a lead without the team:write permission cannot manage the members of a Team whose roles give permissions they lack
+//	@Tags			Teams
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			team_id	path	string									true	"Team UUID"
+//	@Param			user_id	path	string									true	"User UUID"
+//	@Param			body	body	utility.TeamMemberPutRequestBodySchema	true	"The user's role in the team, which is 'member' if not given"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/teams/{team_id}/members/{user_id} [put]
+func SetTeamMember() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var body *utility.TeamMemberPutRequestBodySchema
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		role := body.Role
+		if role == "" {
+			role = database.TeamRoleMember
+		}
+
+		team, user, ok := getTeamMemberParams(ctx)
+		if !ok {
+			return
+		}
+
+		if err := database.SetTeamMember(ctx, team, user, role); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// RemoveTeamMember godoc
+//
+//	@Summary		Remove a user from a Team
+//	@Description	Remove a user from a Team. Only users with the team:write permission and the team's leads can manage its members, and a lead without the team:write permission cannot manage the members of a Team whose roles give permissions they lack
+//	@Tags			Teams
+//	@Security		JWT
+//	@Produce		json
+//	@Param			team_id	path	string	true	"Team UUID"
+//	@Param			user_id	path	string	true	"User UUID"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/teams/{team_id}/members/{user_id} [delete]
+func RemoveTeamMember() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		team, user, ok := getTeamMemberParams(ctx)
+		if !ok {
+			return
+		}
+
+		if err := database.RemoveTeamMember(ctx, team, user); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// Get the team given by the team_id path parameter, setting the response if it is invalid or not found
+func getTeamParam(ctx *gin.Context) (*database.Team, bool) {
+	teamUUID := ctx.Param("team_id")
+	if _, err := uuid.Parse(teamUUID); err != nil {
+		ctx.Set("Status", http.StatusBadRequest)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: "invalid team UUID",
+		})
+		ctx.Next()
+		return nil, false
+	}
+
+	team, err := database.GetTeam(ctx, database.GetTeamsFilters{
+		UUIDs: []string{teamUUID},
+	})
+	if err != nil {
+		ctx.Set("Status", ctx.GetInt("errorCode"))
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return nil, false
+	}
+	return team, true
+}
+
+// Get the team and user given by the team_id and user_id path parameters, checking the logged in user can manage the
+// team's members. Sets the response if they cannot or either is invalid or not found
+func getTeamMemberParams(ctx *gin.Context) (*database.Team, *database.User, bool) {
+	team, ok := getTeamParam(ctx)
+	if !ok {
+		return nil, nil, false
+	}
+	if current := ctx.MustGet("user").(*database.User); !database.HasPermission(ctx, database.PermissionTeamWrite) && team.RoleOf(current) != database.TeamRoleLead {
+		ctx.Set("Status", http.StatusForbidden)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: fmt.Sprintf("logged in user must have the '%s' permission or be a lead of the team", database.PermissionTeamWrite),
+		})
+		ctx.Next()
+		return nil, nil, false
+	}
+
+	user, ok := getUserParam(ctx)
+	if !ok {
+		return nil, nil, false
+	}
+	return team, user, true
+}
+
+func newTeamResponse(team *database.Team) *utility.TeamGetResponseBodySchema {
+	users := make([]utility.UserGetResponseBodySchema, 0)
+	for _, user := range team.Users {
+		users = append(users, utility.UserGetResponseBodySchema{
+			UUID:    user.UUID,
+			Name:    user.Name,
+			SlackID: user.SlackID,
+			Admin:   &user.Admin,
+			Role:    team.RoleOf(&user),
+		})
+	}
+	return &utility.TeamGetResponseBodySchema{
+		UUID:    team.UUID,
+		Name:    team.Name,
+		Private: &team.Private,
+		Users:   users,
 	}
 }
@@ -5,54 +5,190 @@ import (
 	"com668-backend/middleware"
 	"com668-backend/utility"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
-	"os"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 // GetUser godoc
 //
 //	@Summary		Get basic details about the currently logged in user
-//	@Description	Get basic details about the currently logged in user
+//	@Description	Get basic details about the currently logged in user, and the permissions they hold
 //	@Tags			Users
 //	@Accept			json
 //	@Produce		json
 //	@Security		JWT
 //	@Success		200	{object}	utility.UserGetResponseBodySchema
 //	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
 //	@Router			/me [get]
 func GetUser() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		user := ctx.MustGet("user").(*database.User)
 
-		teams := make([]utility.TeamGetResponseBodySchema, len(user.Teams))
-		for i, team := range user.Teams {
-			teams[i] = utility.TeamGetResponseBodySchema{
-				UUID: team.UUID,
-				Name: team.Name,
+		resp := newUserResponse(user)
+		resp.Permissions = ctx.GetStringSlice("permissions")
+		ctx.Set("Status", http.StatusOK)
+		ctx.Set("Body", resp)
+	}
+}
+
+type GetManyUsersResponseSchema utility.GetManyResponseSchema[*utility.UserGetResponseBodySchema]
+
+// GetUsers godoc
+//
+//	@Summary		Get a list of users
+//	@Description	Get a list of users
+//	@Tags			Users
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			page			query		int		false	"Page number"
+//	@Param			pageSize		query		int		false	"Number of items per page"
+//	@Param			cursor			query		string	false	"Cursor of the page to get, from the meta of another page"
+//	@Param			sort			query		string	false	"Comma separated list of fields to sort by, each prefixed with '-' for descending order. One of name, email"
+//	@Param			q				query		string	false	"Search for users whose name or email contains this"
+//	@Param			active			query		bool	false	"Filter by whether the user is active or deactivated"
+//	@Param			If-None-Match	header		string	false	"ETag of a copy already held, to get a 304 if it is still current"
+//	@Success		200				{object}	GetManyUsersResponseSchema
+//	@Header			200				{string}	ETag	"Version of the response"
+//	@Success		304
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/users [get]
+func GetUsers() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		params, err := getCommonParams(ctx)
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		page := params["page"].(int)
+		pageSize := params["pageSize"].(int)
+
+		sort, err := getSortParam(ctx, database.UserSortFields)
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		cursors := &database.PageCursors{}
+		filters := database.GetUsersFilters{
+			Page:     &page,
+			PageSize: &pageSize,
+			Sort:     sort,
+			Cursor:   params["cursor"].(*string),
+			Cursors:  cursors,
+		}
+		if search := ctx.Query("q"); search != "" {
+			filters.Search = &search
+		}
+		if active := ctx.Query("active"); active != "" {
+			activeBool, err := strconv.ParseBool(active)
+			if err != nil {
+				ctx.Set("Status", http.StatusBadRequest)
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: err.Error(),
+				})
+				ctx.Next()
+				return
 			}
+			filters.Active = &activeBool
+		}
+
+		users, count, err := database.GetUsers(ctx, filters)
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		resp := &utility.GetManyResponseSchema[*utility.UserGetResponseBodySchema]{
+			Data: make([]*utility.UserGetResponseBodySchema, 0),
+			Meta: utility.MetaSchema{
+				TotalItems: count,
+				Pages:      int(math.Ceil(float64(count) / float64(pageSize))),
+				Page:       page,
+				PageSize:   pageSize,
+				Next:       cursors.Next,
+				Prev:       cursors.Prev,
+			},
+		}
+		for _, user := range users {
+			resp.Data = append(resp.Data, newUserResponse(user))
 		}
 		ctx.Set("Status", http.StatusOK)
-		ctx.Set("Body", &utility.UserGetResponseBodySchema{
-			UUID:    user.UUID,
-			Name:    user.Name,
-			Email:   user.Email,
-			Teams:   teams,
-			SlackID: user.SlackID,
-			Admin:   &user.Admin,
-		})
+		ctx.Set("Body", resp)
+	}
+}
+
+// GetUserByID godoc
+//
+//	@Summary		Get a user
+//	@Description	Get a user, and the permissions they hold
+//	@Tags			Users
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			user_id			path		string	true	"User UUID"
+//	@Param			If-None-Match	header		string	false	"ETag of a copy already held, to get a 304 if it is still current"
+//	@Success		200				{object}	utility.UserGetResponseBodySchema
+//	@Header			200				{string}	ETag	"Version of the response"
+//	@Success		304
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/users/{user_id} [get]
+func GetUserByID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := getUserParam(ctx)
+		if !ok {
+			return
+		}
+
+		permissions, err := database.GetUserPermissions(ctx, user)
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		resp := newUserResponse(user)
+		resp.Permissions = permissions
+		ctx.Set("Status", http.StatusOK)
+		ctx.Set("Body", resp)
 	}
 }
 
 // CreateUser godoc
 //
 //	@Summary		Create a user
-//	@Description	Create a user
+//	@Description	Create a user, or a service account which has no password and authenticates with API keys instead. Only an admin can add the user to a team holding the admin role
 //	@Tags			Users
 //	@Accept			json
 //	@Produce		json
@@ -101,16 +237,19 @@ func CreateUser() gin.HandlerFunc {
 // LoginUser godoc
 //
 //	@Summary		Login as a user
-//	@Description	Login as a user
+//	@Description	Login as a user, getting a short lived access token and a refresh token to get new ones with from POST /users/refresh. Users from the LDAP directory log in with their password there, and are created the first time they do. Only admins can log in with a password kept here if DISABLE_LOCAL_PASSWORDS is set
 //	@Tags			Users
 //	@Accept			json
 //	@Produce		json
 //	@Param			request_body	body	utility.UserLoginRequestBodySchema	true	"Request Body"
 //	@Header			204				header	string								"JWT Token"
+//	@Header			204				header	string								"Refresh-Token"
 //	@Success		204
 //	@Failure		400	{object}	utility.ErrorResponseSchema
 //	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
 //	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Failure		502	{object}	utility.ErrorResponseSchema
 //	@Router			/users/login [post]
 func LoginUser() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -146,10 +285,22 @@ func LoginUser() gin.HandlerFunc {
 		user, err := database.GetUser(ctx, database.GetUserFilters{
 			Email: &body.Email,
 		})
-		if user == nil || err != nil || !user.ValidatePassword(body.Password) {
+		if err != nil {
+			log.Default().Println(err)
+		}
+		// a user from the directory logs in with their password there, rather than any password they had before
+		local := user != nil && err == nil && !fromIdentityProvider(user, ldapIdentityProvider) && user.ValidatePassword(body.Password)
+		if config, ok := getLDAPConfig(); !local && ok {
+			user, err = ldapLogin(ctx, config, body.Email, body.Password)
 			if err != nil {
-				log.Default().Println(err)
+				ctx.Set("Status", ctx.GetInt("errorCode"))
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: err.Error(),
+				})
+				ctx.Next()
+				return
 			}
+		} else if !local {
 			ctx.Set("Status", http.StatusBadRequest)
 			ctx.Set("Body", &utility.ErrorResponseSchema{
 				Error: "invalid email or password",
@@ -157,17 +308,205 @@ func LoginUser() gin.HandlerFunc {
 			ctx.Next()
 			return
 		}
+		if user.DeactivatedAt != nil {
+			ctx.Set("Status", http.StatusForbidden)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "user account has been deactivated",
+			})
+			ctx.Next()
+			return
+		}
+		// admins can still use their password, so nobody is locked out if the identity provider is down
+		if local && localPasswordsDisabled() && !user.Admin {
+			ctx.Set("Status", http.StatusForbidden)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "password login is disabled, log in with single sign-on",
+			})
+			ctx.Next()
+			return
+		}
+
+		session, refreshToken, err := database.CreateSession(ctx, user, middleware.RefreshTokenLifetime)
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		issueTokens(ctx, user, session, refreshToken)
+	}
+}
+
+// RefreshUserToken godoc
+//
+//	@Summary		Get a new access token
+//	@Description	Swap a refresh token, from the Refresh-Token header or cookie, for a new access token and refresh token. A refresh token can only be used once, and using it again revokes its session
+//	@Tags			Users
+//	@Produce		json
+//	@Param			Refresh-Token	header	string	false	"Refresh token, if not sent as a cookie"
+//	@Header			204				header	string	"JWT Token"
+//	@Success		204
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/users/refresh [post]
+func RefreshUserToken() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		refreshToken := ctx.GetHeader(middleware.RefreshHeaderNameString)
+		if refreshToken == "" {
+			refreshToken, _ = ctx.Cookie(middleware.RefreshHeaderNameString)
+		}
+		if refreshToken == "" {
+			ctx.Set("Status", http.StatusUnauthorized)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "no refresh token specified",
+			})
+			ctx.Next()
+			return
+		}
+
+		session, refreshToken, err := database.RefreshSession(ctx, refreshToken)
+		if err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		if session.User.DeactivatedAt != nil {
+			ctx.Set("Status", http.StatusUnauthorized)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "user account has been deactivated",
+			})
+			ctx.Next()
+			return
+		}
+		issueTokens(ctx, &session.User, session, refreshToken)
+	}
+}
+
+// LogoutUser godoc
+//
+//	@Summary		Log out
+//	@Description	Revoke the session of the logged in user, so its access token and refresh token stop working
+//	@Tags			Users
+//	@Security		JWT
+//	@Produce		json
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/users/logout [post]
+func LogoutUser() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		session, ok := ctx.Get("session")
+		if !ok {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "only a logged in session can be logged out of, revoke api keys instead",
+			})
+			ctx.Next()
+			return
+		}
+
+		if err := database.RevokeSession(ctx, session.(*database.Session)); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.SetCookie(middleware.AuthHeaderNameString, "", -1, "/", ctx.Request.URL.Host, true, false)
+		ctx.SetCookie(middleware.RefreshHeaderNameString, "", -1, "/", ctx.Request.URL.Host, true, true)
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// RevokeUserSessions godoc
+//
+//	@Summary		Log a user out everywhere
+//	@Description	Revoke every session of a user, so all of their access tokens and refresh tokens stop working. Their API keys are revoked separately
+//	@Tags			Users
+//	@Security		JWT
+//	@Produce		json
+//	@Param			user_id	path	string	true	"User UUID"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/users/{user_id}/sessions [delete]
+func RevokeUserSessions() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := getUserParam(ctx)
+		if !ok {
+			return
+		}
+
+		if err := database.RevokeUserSessions(ctx, user, nil); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// PatchUser godoc
+//
+//	@Summary		Partially update a user
+//	@Description	Update the name, email, admin flag or teams of a user with a JSON Merge Patch (RFC 7386) or a JSON Patch (RFC 6902) of a utility.UserPutRequestBodySchema. Only an admin can change the admin flag or add the user to a team holding the admin role
+//	@Tags			Users
+//	@Security		JWT
+//	@Accept			application/merge-patch+json,application/json-patch+json
+//	@Produce		json
+//	@Param			user_id	path	string	true	"User UUID"
+//	@Param			body	body	object	true	"The patch"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		415	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/users/{user_id} [patch]
+func PatchUser() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := getUserParam(ctx)
+		if !ok {
+			return
+		}
+
+		patch, err := ctx.GetRawData()
+		if err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
 
-		token := jwt.New(middleware.JWTSigningMethod)
-		claims := jwt.MapClaims{}
-		claims["iss"] = "COM668"
-		claims["iat"] = jwt.NewNumericDate(time.Now())
-		claims["exp"] = jwt.NewNumericDate(time.Now().Add(time.Hour * 24))
-		claims["sub"] = base64.StdEncoding.EncodeToString([]byte(user.UUID))
-		token.Claims = claims
-		jwtString, err := token.SignedString([]byte(os.Getenv("JWT_SIGNING_KEY")))
+		current := utility.UserPutRequestBodySchema{
+			Name:  user.Name,
+			Email: user.Email,
+			Admin: &user.Admin,
+			Teams: make([]string, 0),
+		}
+		for _, team := range user.Teams {
+			current.Teams = append(current.Teams, team.UUID)
+		}
+		body, status, err := utility.ApplyPatch(ctx.GetHeader("Content-Type"), current, patch)
 		if err != nil {
-			ctx.Set("Status", http.StatusInternalServerError)
+			ctx.Set("Status", status)
 			ctx.Set("Body", &utility.ErrorResponseSchema{
 				Error: err.Error(),
 			})
@@ -175,8 +514,483 @@ func LoginUser() gin.HandlerFunc {
 			return
 		}
 
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if err := updateUser(ctx, user, body); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
 		ctx.Set("Status", http.StatusNoContent)
-		ctx.Header(middleware.AuthHeaderNameString, fmt.Sprintf("Bearer %s", jwtString))
-		ctx.SetCookie(middleware.AuthHeaderNameString, jwtString, int(time.Hour)*24, "/", ctx.Request.URL.Host, true, false)
 	}
 }
+
+// UpdateUser godoc
+//
+//	@Summary		Update a user
+//	@Description	Replace the name, email, admin flag and teams of a user. Only an admin can change the admin flag or add the user to a team holding the admin role
+//	@Tags			Users
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			user_id	path	string								true	"User UUID"
+//	@Param			body	body	utility.UserPutRequestBodySchema	true	"User update request"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/users/{user_id} [put]
+func UpdateUser() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var body *utility.UserPutRequestBodySchema
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		user, ok := getUserParam(ctx)
+		if !ok {
+			return
+		}
+
+		if err := updateUser(ctx, user, body); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// LinkUserIdentity godoc
+//
+//	@Summary		Link a user to an identity provider
+//	@Description	Link a user to their id in the LDAP directory or the OpenID Connect identity provider, so that they log in with it as themselves. A user who already has the email of somebody logging in with single sign-on for the first time is never linked to them otherwise. Only an admin can link a user, and service accounts cannot be linked
+//	@Tags			Users
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			user_id	path	string										true	"User UUID"
+//	@Param			body	body	utility.UserIdentityPutRequestBodySchema	true	"User identity request"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/users/{user_id}/identity [put]
+func LinkUserIdentity() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := getUserParam(ctx)
+		if !ok {
+			return
+		}
+
+		var body *utility.UserIdentityPutRequestBodySchema
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if err := database.LinkExternalUser(ctx, user, body.Provider, body.ExternalID); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// DeactivateUser godoc
+//
+//	@Summary		Deactivate a user
+//	@Description	Stop a user from logging in or using a token they already have, keeping everything they have done on incidents. The user is taken off the incidents they are assigned to or responding to, and the last admin cannot be deactivated
+//	@Tags			Users
+//	@Security		JWT
+//	@Produce		json
+//	@Param			user_id	path	string	true	"User UUID"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/users/{user_id}/deactivate [post]
+func DeactivateUser() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := getUserParam(ctx)
+		if !ok {
+			return
+		}
+		if user.ID == ctx.MustGet("user").(*database.User).ID {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "cannot deactivate the logged in user",
+			})
+			ctx.Next()
+			return
+		}
+
+		if err := database.DeactivateUser(ctx, user); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// ReactivateUser godoc
+//
+//	@Summary		Reactivate a user
+//	@Description	Let a deactivated user log in again
+//	@Tags			Users
+//	@Security		JWT
+//	@Produce		json
+//	@Param			user_id	path	string	true	"User UUID"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/users/{user_id}/reactivate [post]
+func ReactivateUser() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := getUserParam(ctx)
+		if !ok {
+			return
+		}
+
+		if err := database.ReactivateUser(ctx, user); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// DeleteUser godoc
+//
+//	@Summary		Delete a user
+//	@Description	Delete a user. A user who has commented on or resolved incidents is only deleted if another user is given to reassign them to, otherwise deactivate them instead. The rest of the incidents' history keeps the user's name. The last admin cannot be deleted
+//	@Tags			Users
+//	@Security		JWT
+//	@Produce		json
+//	@Param			user_id		path	string	true	"User UUID"
+//	@Param			reassignTo	query	string	false	"UUID of the user to move the user's comments and resolved incidents to"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		404	{object}	utility.ErrorResponseSchema
+//	@Failure		409	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/users/{user_id} [delete]
+func DeleteUser() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := getUserParam(ctx)
+		if !ok {
+			return
+		}
+		if user.ID == ctx.MustGet("user").(*database.User).ID {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "cannot delete the logged in user",
+			})
+			ctx.Next()
+			return
+		}
+
+		var reassignTo *database.User
+		if reassignUUID := ctx.Query("reassignTo"); reassignUUID != "" {
+			if _, err := uuid.Parse(reassignUUID); err != nil {
+				ctx.Set("Status", http.StatusBadRequest)
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: "reassignTo query parameter must be a valid UUID",
+				})
+				ctx.Next()
+				return
+			}
+			var err error
+			reassignTo, err = database.GetUser(ctx, database.GetUserFilters{UUID: &reassignUUID})
+			if err != nil {
+				ctx.Set("Status", ctx.GetInt("errorCode"))
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: err.Error(),
+				})
+				ctx.Next()
+				return
+			}
+			if reassignTo == nil {
+				ctx.Set("Status", http.StatusNotFound)
+				ctx.Set("Body", &utility.ErrorResponseSchema{
+					Error: "user to reassign to not found",
+				})
+				ctx.Next()
+				return
+			}
+		}
+
+		if err := database.DeleteUser(ctx, user, reassignTo); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// ChangePassword godoc
+//
+//	@Summary		Change the password of the logged in user
+//	@Description	Change the password of the logged in user, which needs their current password. Their other sessions are revoked. Only admins can change their password if DISABLE_LOCAL_PASSWORDS is set
+//	@Tags			Users
+//	@Security		JWT
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body	utility.UserPasswordPutRequestBodySchema	true	"Password change request"
+//	@Success		204
+//	@Failure		400	{object}	utility.ErrorResponseSchema
+//	@Failure		401	{object}	utility.ErrorResponseSchema
+//	@Failure		403	{object}	utility.ErrorResponseSchema
+//	@Failure		500	{object}	utility.ErrorResponseSchema
+//	@Router			/me/password [put]
+func ChangePassword() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var body *utility.UserPasswordPutRequestBodySchema
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.Set("Status", http.StatusBadRequest)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		if status, err := body.Validate(); err != nil {
+			ctx.Set("Status", status)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		user := ctx.MustGet("user").(*database.User)
+		if localPasswordsDisabled() && !user.Admin {
+			ctx.Set("Status", http.StatusForbidden)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "password login is disabled, so passwords cannot be changed",
+			})
+			ctx.Next()
+			return
+		}
+		if fromIdentityProvider(user, ldapIdentityProvider) {
+			ctx.Set("Status", http.StatusForbidden)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "the password of a user from the directory can only be changed there",
+			})
+			ctx.Next()
+			return
+		}
+		if !user.ValidatePassword(body.CurrentPassword) {
+			ctx.Set("Status", http.StatusForbidden)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: "current password is not correct",
+			})
+			ctx.Next()
+			return
+		}
+
+		if err := database.UpdateUserPassword(ctx, user, body.NewPassword); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		// whoever knew the old password is logged out, but the user stays logged in where they changed it
+		var current *database.Session
+		if session, ok := ctx.Get("session"); ok {
+			current = session.(*database.Session)
+		}
+		if err := database.RevokeUserSessions(ctx, user, current); err != nil {
+			ctx.Set("Status", ctx.GetInt("errorCode"))
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		ctx.Set("Status", http.StatusNoContent)
+	}
+}
+
+// Get the user given by the user_id path parameter, setting the response if it is invalid or not found
+func getUserParam(ctx *gin.Context) (*database.User, bool) {
+	userUUID := ctx.Param("user_id")
+	if _, err := uuid.Parse(userUUID); err != nil {
+		ctx.Set("Status", http.StatusBadRequest)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: "invalid user UUID",
+		})
+		ctx.Next()
+		return nil, false
+	}
+
+	user, err := database.GetUser(ctx, database.GetUserFilters{UUID: &userUUID})
+	if err != nil {
+		ctx.Set("Status", ctx.GetInt("errorCode"))
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return nil, false
+	}
+	if user == nil {
+		ctx.Set("Status", http.StatusNotFound)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: "user not found",
+		})
+		ctx.Next()
+		return nil, false
+	}
+	return user, true
+}
+
+// Give out a short lived access token for a session along with its refresh token, in headers and cookies
+func issueTokens(ctx *gin.Context, user *database.User, session *database.Session, refreshToken string) {
+	claims := jwt.MapClaims{}
+	claims["iss"] = "COM668"
+	claims["iat"] = jwt.NewNumericDate(time.Now())
+	claims["exp"] = jwt.NewNumericDate(time.Now().Add(middleware.AccessTokenLifetime))
+	claims["sub"] = base64.StdEncoding.EncodeToString([]byte(user.UUID))
+	claims["sid"] = session.UUID
+	jwtString, err := middleware.SignJWT(ctx, claims)
+	if err != nil {
+		ctx.Set("Status", http.StatusInternalServerError)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return
+	}
+
+	ctx.Set("Status", http.StatusNoContent)
+	ctx.Header(middleware.AuthHeaderNameString, fmt.Sprintf("Bearer %s", jwtString))
+	ctx.SetCookie(middleware.AuthHeaderNameString, jwtString, int(middleware.AccessTokenLifetime.Seconds()), "/", ctx.Request.URL.Host, true, false)
+	ctx.Header(middleware.RefreshHeaderNameString, refreshToken)
+	// scripts on the page never need the refresh token, so it is kept from them
+	ctx.SetCookie(middleware.RefreshHeaderNameString, refreshToken, int(time.Until(session.ExpiresAt).Seconds()), "/", ctx.Request.URL.Host, true, true)
+}
+
+// Whether a user is linked to an identity provider
+func fromIdentityProvider(user *database.User, provider string) bool {
+	return user.IdentityProvider != nil && *user.IdentityProvider == provider
+}
+
+func newUserResponse(user *database.User) *utility.UserGetResponseBodySchema {
+	teams := make([]utility.TeamGetResponseBodySchema, len(user.Teams))
+	for i, team := range user.Teams {
+		teams[i] = utility.TeamGetResponseBodySchema{
+			UUID: team.UUID,
+			Name: team.Name,
+		}
+	}
+	return &utility.UserGetResponseBodySchema{
+		UUID:           user.UUID,
+		Name:           user.Name,
+		Email:          user.Email,
+		Teams:          teams,
+		SlackID:        user.SlackID,
+		Admin:          &user.Admin,
+		DeactivatedAt:  user.DeactivatedAt,
+		ServiceAccount: user.ServiceAccount,
+	}
+}
+
+// Replace the details and teams of a user with those of a validated body
+func updateUser(ctx *gin.Context, user *database.User, body *utility.UserPutRequestBodySchema) error {
+	teams := make([]database.Team, 0)
+	if len(body.Teams) > 0 {
+		ts, count, err := database.GetTeams(ctx, database.GetTeamsFilters{
+			UUIDs:    body.Teams,
+			PageSize: utility.Pointer(len(body.Teams)),
+		})
+		if err != nil {
+			return err
+		}
+		if int(count) != len(body.Teams) {
+			ctx.Set("errorCode", http.StatusBadRequest)
+			return errors.New("one or more teams not found")
+		}
+		for _, team := range ts {
+			teams = append(teams, *team)
+		}
+	}
+	user.Name = body.Name
+	user.Email = body.Email
+	user.Admin = *body.Admin
+	user.Teams = teams
+	return database.UpdateUser(ctx, user)
+}
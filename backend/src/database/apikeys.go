@@ -0,0 +1,239 @@
+package database
+
+import (
+	"com668-backend/utility"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	apiKeySortColumns map[string]string = map[string]string{
+		"name":      "name",
+		"createdAt": "created_at",
+		"expiresAt": "expires_at",
+	}
+	APIKeySortFields []string = []string{"name", "createdAt", "expiresAt"}
+	// how often the use of a key is recorded, as its last used time only needs to be roughly right
+	apiKeyUseInterval time.Duration = time.Minute
+)
+
+// A key a user or service account authenticates with instead of a JWT, which only holds the permissions in its scopes
+// that its user holds too
+type APIKey struct {
+	ID   uint   `gorm:"column:id;primaryKey;autoIncrement"`
+	UUID string `gorm:"column:uuid;size:36;unique;not null"`
+	Name string `gorm:"column:name;size:50;not null"`
+	// the sha256 hash of the secret part of the key, which is only shown when the key is created
+	Hash      string        `gorm:"column:hash;size:64;not null"`
+	UserID    uint          `gorm:"column:user_id;not null"`
+	User      User          `gorm:"foreignKey:user_id;references:id"`
+	Scopes    []APIKeyScope `gorm:"foreignKey:api_key_id;references:id"`
+	CreatedAt time.Time     `gorm:"column:created_at;not null"`
+	ExpiresAt time.Time     `gorm:"column:expires_at;not null"`
+	// when the key was last used to authenticate, or nil if it never has been
+	LastUsedAt *time.Time `gorm:"column:last_used_at"`
+	// when the key was revoked, or nil if it can still be used
+	RevokedAt *time.Time `gorm:"column:revoked_at"`
+}
+
+func (key *APIKey) BeforeCreate(tx *gorm.DB) error {
+	if key.UUID == "" {
+		uuid, err := utility.GenerateRandomUUID()
+		if err != nil {
+			if ctx := GetContext(tx); ctx != nil {
+				ctx.Set("errorCode", http.StatusInternalServerError)
+			}
+			return errors.New("failed to create an api key uuid")
+		}
+		key.UUID = uuid
+	}
+	return nil
+}
+
+func (key *APIKey) ScopeNames() []string {
+	names := make([]string, 0)
+	for _, scope := range key.Scopes {
+		names = append(names, scope.Permission)
+	}
+	return names
+}
+
+type APIKeyScope struct {
+	APIKeyID   uint   `gorm:"column:api_key_id;primaryKey"`
+	Permission string `gorm:"column:permission;size:30;primaryKey"`
+}
+
+type GetAPIKeysFilters struct {
+	UserID   *uint
+	UUIDs    []string
+	Page     *int
+	PageSize *int
+	Sort     []SortKey
+	Cursor   *string
+	// filled with the cursors of the neighbouring pages, if set
+	Cursors *PageCursors
+}
+
+func GetAPIKey(ctx *gin.Context, filters GetAPIKeysFilters) (*APIKey, error) {
+	keys, count, err := GetAPIKeys(ctx, GetAPIKeysFilters{
+		UserID:   filters.UserID,
+		UUIDs:    filters.UUIDs,
+		PageSize: utility.Pointer(len(filters.UUIDs)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		ctx.Set("errorCode", http.StatusNotFound)
+		return nil, errors.New("api key not found")
+	}
+	return keys[0], nil
+}
+
+func GetAPIKeys(ctx *gin.Context, filters GetAPIKeysFilters) ([]*APIKey, int64, error) {
+	tx := GetDBTransaction(ctx).Model(&APIKey{})
+	tx = tx.Preload("Scopes")
+
+	if filters.UserID != nil {
+		tx = tx.Where("user_id = ?", *filters.UserID)
+	}
+	if len(filters.UUIDs) > 0 {
+		tx = tx.Where("uuid IN (?)", filters.UUIDs)
+	}
+
+	var count int64
+	tx = tx.Count(&count)
+	pages, err := newPaginator(ctx, &APIKey{}, apiKeySortColumns, filters.Sort, filters.Cursor, filters.Page, filters.PageSize)
+	if err != nil {
+		return nil, -1, err
+	}
+	tx = pages.apply(tx)
+
+	var keys []*APIKey
+	tx = tx.Find(&keys)
+	if tx.Error != nil {
+		return nil, -1, handleError(ctx, tx.Error)
+	}
+	if err := pages.finish(ctx, &keys, filters.Cursors); err != nil {
+		return nil, -1, err
+	}
+	return keys, count, nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create a random secret for an API key or refresh token, of which only the hash is kept
+func generateSecret(ctx *gin.Context) (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		ctx.Set("errorCode", http.StatusInternalServerError)
+		return "", errors.New("failed to create a secret")
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// Create an API key, returning the key to authenticate with, which cannot be got again as only its hash is kept
+func CreateAPIKey(ctx *gin.Context, key *APIKey) (string, error) {
+	secret, err := generateSecret(ctx)
+	if err != nil {
+		return "", err
+	}
+	key.Hash = hashSecret(secret)
+
+	tx := GetDBTransaction(ctx).Model(&APIKey{}).Create(key)
+	if tx.Error != nil {
+		return "", handleError(ctx, tx.Error)
+	}
+	// the uuid finds the key without having to hash the secret of every key to compare them
+	return key.UUID + "." + secret, nil
+}
+
+func RevokeAPIKey(ctx *gin.Context, key *APIKey) error {
+	if key.RevokedAt != nil {
+		ctx.Set("errorCode", http.StatusConflict)
+		return errors.New("api key has already been revoked")
+	}
+	now := time.Now()
+	tx := GetDBTransaction(ctx).Model(&APIKey{}).Where("id = ?", key.ID).Update("revoked_at", now)
+	if tx.Error != nil {
+		return handleError(ctx, tx.Error)
+	}
+	key.RevokedAt = &now
+	return nil
+}
+
+// Find the API key a request is authenticated with, failing with a 401 if it does not exist, does not match, has expired
+// or has been revoked. Its use is recorded by RecordAPIKeyUse at most once a minute
+func AuthenticateAPIKey(ctx *gin.Context, value string) (*APIKey, error) {
+	invalid := errors.New("invalid api key")
+	keyUUID, secret, ok := strings.Cut(value, ".")
+	if !ok {
+		ctx.Set("errorCode", http.StatusUnauthorized)
+		return nil, invalid
+	}
+
+	var keys []*APIKey
+	tx := GetDBTransaction(ctx).Model(&APIKey{}).Preload("Scopes").Preload("User.Teams").Where("uuid = ?", keyUUID).Find(&keys)
+	if tx.Error != nil {
+		return nil, handleError(ctx, tx.Error)
+	}
+	if len(keys) == 0 || subtle.ConstantTimeCompare([]byte(keys[0].Hash), []byte(hashSecret(secret))) != 1 {
+		ctx.Set("errorCode", http.StatusUnauthorized)
+		return nil, invalid
+	}
+	key := keys[0]
+	if key.RevokedAt != nil {
+		ctx.Set("errorCode", http.StatusUnauthorized)
+		return nil, errors.New("api key has been revoked")
+	}
+	now := time.Now()
+	if now.After(key.ExpiresAt) {
+		ctx.Set("errorCode", http.StatusUnauthorized)
+		return nil, errors.New("api key has expired")
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyUseInterval {
+		ctx.Set("apiKeyUsed", key)
+	}
+	return key, nil
+}
+
+// Record the use of the API key a request is authenticated with, once the request's transaction has ended so that the use
+// is kept if the request fails and is not held up behind the request's locks
+func RecordAPIKeyUse(ctx *gin.Context) {
+	value, ok := ctx.Get("apiKeyUsed")
+	if !ok {
+		return
+	}
+	key := value.(*APIKey)
+	now := time.Now()
+	// a concurrent request with the same key may have recorded its use already
+	tx := GetDBConn().Model(&APIKey{}).Where("id = ?", key.ID).
+		Where("last_used_at IS NULL OR last_used_at <= ?", now.Add(-apiKeyUseInterval)).
+		Update("last_used_at", now)
+	if tx.Error != nil {
+		log.Default().Printf("[%s] failed to record the use of api key %s: %s\n", ctx.GetString("ReqID"), key.UUID, tx.Error)
+	}
+}
+
+// Delete the API keys of a user, as part of deleting them
+func deleteAPIKeys(tx *gorm.DB, user *User) error {
+	keys := tx.Model(&APIKey{}).Select("id").Where("user_id = ?", user.ID)
+	if err := tx.Where("api_key_id IN (?)", keys).Delete(&APIKeyScope{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ?", user.ID).Delete(&APIKey{}).Error
+}
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"com668-backend/utility"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// A group of an identity provider, whose members are given a team membership, a role or the admin flag when they log in
+// with it. Teams and roles named by a mapping are kept in step with the user's groups on every login, so a user who
+// leaves a group loses what it gave them
+type GroupMapping struct {
+	ID   uint   `gorm:"column:id;primaryKey;autoIncrement"`
+	UUID string `gorm:"column:uuid;size:36;unique;not null"`
+	// the name of the group as the identity provider gives it
+	Group  string `gorm:"column:group_name;size:255;not null"`
+	TeamID *uint  `gorm:"column:team_id"`
+	Team   *Team  `gorm:"foreignKey:team_id;references:id"`
+	// the role the group's members have in the team
+	TeamRole string `gorm:"column:team_role;size:6;not null;default:'member'"`
+	RoleID   *uint  `gorm:"column:role_id"`
+	Role     *Role  `gorm:"foreignKey:role_id;references:id"`
+	Admin    bool   `gorm:"column:admin;not null"`
+}
+
+func (mapping *GroupMapping) BeforeCreate(tx *gorm.DB) error {
+	if mapping.UUID == "" {
+		uuid, err := utility.GenerateRandomUUID()
+		if err != nil {
+			if ctx := GetContext(tx); ctx != nil {
+				ctx.Set("errorCode", http.StatusInternalServerError)
+			}
+			return errors.New("failed to create a group mapping uuid")
+		}
+		mapping.UUID = uuid
+	}
+	return nil
+}
+
+func GetGroupMapping(ctx *gin.Context, mappingUUID string) (*GroupMapping, error) {
+	var mappings []*GroupMapping
+	tx := GetDBTransaction(ctx).Model(&GroupMapping{}).Preload("Team").Preload("Role").Where("uuid = ?", mappingUUID).Find(&mappings)
+	if tx.Error != nil {
+		return nil, handleError(ctx, tx.Error)
+	}
+	if len(mappings) == 0 {
+		ctx.Set("errorCode", http.StatusNotFound)
+		return nil, errors.New("group mapping not found")
+	}
+	return mappings[0], nil
+}
+
+func GetGroupMappings(ctx *gin.Context) ([]*GroupMapping, error) {
+	var mappings []*GroupMapping
+	tx := GetDBTransaction(ctx).Model(&GroupMapping{}).Preload("Team.Members").Preload("Role").Order("group_name").Order("id").Find(&mappings)
+	if tx.Error != nil {
+		return nil, handleError(ctx, tx.Error)
+	}
+	return mappings, nil
+}
+
+func CreateGroupMapping(ctx *gin.Context, mapping *GroupMapping) error {
+	// members of the group would be made admins when they log in
+	if mapping.Admin || (mapping.Role != nil && mapping.Role.UUID == AdminRoleUUID) {
+		if err := checkAdmin(ctx, "map a group to the admin flag or role"); err != nil {
+			return err
+		}
+	}
+	tx := GetDBTransaction(ctx).Omit("Team", "Role").Create(mapping)
+	if tx.Error != nil {
+		return handleError(ctx, tx.Error)
+	}
+	return nil
+}
+
+func DeleteGroupMapping(ctx *gin.Context, mapping *GroupMapping) error {
+	tx := GetDBTransaction(ctx).Delete(&GroupMapping{}, mapping.ID)
+	if tx.Error != nil {
+		return handleError(ctx, tx.Error)
+	}
+	return nil
+}
+
+// Give a user who has logged in with an identity provider the teams, roles and admin flag their groups are mapped to,
+// and take away those mapped to groups they are not in. Teams and roles no mapping names are left alone, as is the admin
+// flag if no mapping gives it
+func SyncGroupMappings(ctx *gin.Context, user *User, groups []string) error {
+	mappings, err := GetGroupMappings(ctx)
+	if err != nil {
+		return err
+	}
+	tx := GetDBTransaction(ctx)
+
+	teams := make(map[uint]*Team)
+	teamRoles := make(map[uint]string)
+	roles := make(map[uint]bool)
+	admin := false
+	mapsAdmin := false
+	for _, mapping := range mappings {
+		matched := slices.Contains(groups, mapping.Group)
+		if mapping.Team != nil {
+			teams[mapping.Team.ID] = mapping.Team
+			// a lead through any of their groups is a lead of the team
+			if matched && teamRoles[mapping.Team.ID] != TeamRoleLead {
+				teamRoles[mapping.Team.ID] = mapping.TeamRole
+			}
+		}
+		if mapping.RoleID != nil {
+			roles[*mapping.RoleID] = roles[*mapping.RoleID] || matched
+		}
+		if mapping.Admin {
+			mapsAdmin = true
+			admin = admin || matched
+		}
+	}
+
+	for id, team := range teams {
+		role, wanted := teamRoles[id]
+		current := team.RoleOf(user)
+		switch {
+		case wanted && current != role:
+			err = SetTeamMember(ctx, team, user, role)
+		case !wanted && current != "":
+			err = RemoveTeamMember(ctx, team, user)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for id, wanted := range roles {
+		// the assignment is replaced rather than checked for, as it may or may not be there already
+		err := tx.Where("user_id = ? AND role_id = ?", user.ID, id).Delete(&UserRoleAssignment{}).Error
+		if err == nil && wanted {
+			err = tx.Omit("User", "Role").Create(&UserRoleAssignment{UserID: user.ID, RoleID: id}).Error
+		}
+		if err != nil {
+			return handleError(ctx, err)
+		}
+	}
+	if mapsAdmin && user.Admin != admin {
+		if err := tx.Model(&User{}).Where("id = ?", user.ID).Update("admin", admin).Error; err != nil {
+			return handleError(ctx, err)
+		}
+		user.Admin = admin
+	}
+	return nil
+}
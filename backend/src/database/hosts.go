@@ -18,6 +18,8 @@ type HostMachine struct {
 	IP6      *string `gorm:"column:ip6;size:39;unique"`
 	TeamID   uint    `gorm:"column:team_id;not null"`
 	Team     Team    `gorm:"foreignKey:team_id;references:id"`
+	// bumped on every change, and part of the host's ETag
+	Version uint `gorm:"column:version;not null;default:1"`
 }
 
 func (host *HostMachine) BeforeCreate(tx *gorm.DB) error {
@@ -35,17 +37,33 @@ func (host *HostMachine) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+var (
+	// the fields hosts can be sorted by, mapped to their column
+	hostSortColumns map[string]string = map[string]string{
+		"hostname": "hostname",
+		"os":       "os",
+	}
+	HostSortFields []string = []string{"hostname", "os"}
+)
+
 type GetHostsFilters struct {
 	UUIDs     []string
 	Page      *int
 	PageSize  *int
 	Hostnames *[]string
+	Sort      []SortKey
+	Cursor    *string
+	// filled with the cursors of the neighbouring pages, if set
+	Cursors *PageCursors
+	// get hosts owned by private teams the user is not in too, for lookups made on the backend's own behalf
+	Unscoped bool
 }
 
 func GetHost(ctx *gin.Context, filters GetHostsFilters) (*HostMachine, error) {
 	hosts, count, err := GetHosts(ctx, GetHostsFilters{
 		UUIDs:    filters.UUIDs,
 		PageSize: utility.Pointer(len(filters.UUIDs)),
+		Unscoped: filters.Unscoped,
 	})
 	if err != nil {
 		return nil, err
@@ -67,24 +85,34 @@ func GetHosts(ctx *gin.Context, filters GetHostsFilters) ([]*HostMachine, int64,
 	if filters.Hostnames != nil {
 		tx = tx.Where("hostname IN (?)", *filters.Hostnames)
 	}
+	if mine, private := teamVisibility(ctx, PermissionHostManage); mine != nil && !filters.Unscoped {
+		tx = tx.Where("tbl_host_machine.team_id IN (?) OR tbl_host_machine.team_id NOT IN (?)", mine, private)
+	}
 
 	var count int64
 	tx.Count(&count)
-	if filters.PageSize != nil {
-		tx = tx.Limit(*filters.PageSize)
-		if filters.Page != nil {
-			tx = tx.Offset(*filters.PageSize * (*filters.Page - 1))
-		}
+	pages, err := newPaginator(ctx, &HostMachine{}, hostSortColumns, filters.Sort, filters.Cursor, filters.Page, filters.PageSize)
+	if err != nil {
+		return nil, -1, err
 	}
+	tx = pages.apply(tx)
 
 	var hosts []*HostMachine
 	tx = tx.Find(&hosts)
 	if tx.Error != nil {
 		return nil, -1, handleError(ctx, tx.Error)
 	}
+	if err := pages.finish(ctx, &hosts, filters.Cursors); err != nil {
+		return nil, -1, err
+	}
 	return hosts, count, nil
 }
 
+// Whether a user can change a host, which they can if they are a member of the team owning it
+func (host *HostMachine) OwnedBy(user *User) bool {
+	return memberOfAny(user, host.TeamID)
+}
+
 func CreateHost(ctx *gin.Context, host *HostMachine) error {
 	tx := GetDBTransaction(ctx).Model(&HostMachine{})
 	tx = tx.Create(host)
@@ -95,6 +123,9 @@ func CreateHost(ctx *gin.Context, host *HostMachine) error {
 }
 
 func UpdateHost(ctx *gin.Context, host *HostMachine) error {
+	if err := claimVersion(ctx, &HostMachine{}, host.ID, host.Version); err != nil {
+		return err
+	}
 	tx := GetDBTransaction(ctx).Model(&HostMachine{})
 	tx = tx.Where("uuid = ?", host.UUID)
 	fields := map[string]any{"os": host.OS, "hostname": host.Hostname, "ip4": host.IP4, "ip6": host.IP6, "team_id": host.TeamID}
@@ -1,28 +1,211 @@
 package database
 
 import (
+	"cmp"
 	"com668-backend/utility"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DefaultIncidentSeverity uint = 3
+	DefaultIncidentImpact   uint = 2
+	DefaultIncidentUrgency  uint = 2
+)
+
+const (
+	IncidentAssignmentRoleAssignee  string = "assignee"
+	IncidentAssignmentRoleResponder string = "responder"
+)
+
+const (
+	IncidentEventCreated        string = "created"
+	IncidentEventOccurred       string = "occurred"
+	IncidentEventRegressed      string = "regressed"
+	IncidentEventFieldChanged   string = "field_changed"
+	IncidentEventStatusChanged  string = "status_changed"
+	IncidentEventCommentAdded   string = "comment_added"
+	IncidentEventCommentDeleted string = "comment_deleted"
+	IncidentEventHostAdded      string = "host_added"
+	IncidentEventHostRemoved    string = "host_removed"
+	IncidentEventTeamAdded      string = "team_added"
+	IncidentEventTeamRemoved    string = "team_removed"
+	IncidentEventAssigned       string = "assigned"
+	IncidentEventUnassigned     string = "unassigned"
+	IncidentEventMerged         string = "merged"
+	IncidentEventLinkAdded      string = "link_added"
+	IncidentEventLinkRemoved    string = "link_removed"
+)
+
+const (
+	IncidentLinkDuplicateOf string = "duplicate-of"
+	IncidentLinkCausedBy    string = "caused-by"
+	IncidentLinkRelatedTo   string = "related-to"
+)
+
+const (
+	IncidentStatusOpen          string = "open"
+	IncidentStatusAcknowledged  string = "acknowledged"
+	IncidentStatusInvestigating string = "investigating"
+	IncidentStatusMitigated     string = "mitigated"
+	IncidentStatusResolved      string = "resolved"
+)
+
+var (
+	IncidentLinkTypes []string = []string{
+		IncidentLinkDuplicateOf,
+		IncidentLinkCausedBy,
+		IncidentLinkRelatedTo,
+	}
+	IncidentStatuses []string = []string{
+		IncidentStatusOpen,
+		IncidentStatusAcknowledged,
+		IncidentStatusInvestigating,
+		IncidentStatusMitigated,
+		IncidentStatusResolved,
+	}
+	// the statuses an incident is allowed to move to from its current status
+	incidentStatusTransitions map[string][]string = map[string][]string{
+		IncidentStatusOpen:          {IncidentStatusAcknowledged, IncidentStatusResolved},
+		IncidentStatusAcknowledged:  {IncidentStatusInvestigating, IncidentStatusResolved},
+		IncidentStatusInvestigating: {IncidentStatusMitigated, IncidentStatusResolved},
+		IncidentStatusMitigated:     {IncidentStatusResolved},
+		IncidentStatusResolved:      {IncidentStatusOpen},
+	}
+	// the fields incidents can be sorted by, mapped to their column
+	incidentSortColumns map[string]string = map[string]string{
+		"severity":    "severity",
+		"priority":    "priority",
+		"impact":      "impact",
+		"urgency":     "urgency",
+		"status":      "status",
+		"occurrences": "occurrence_count",
+		"createdAt":   "created_at",
+		"firstSeen":   "first_seen_at",
+		"lastSeen":    "last_seen_at",
+	}
+	IncidentSortFields []string = []string{"severity", "priority", "impact", "urgency", "status", "occurrences", "createdAt", "firstSeen", "lastSeen"}
+	// the relations of an incident which can be expanded, by their path in the response. A nested relation is only
+	// loaded along with its parent, so its parent must be expanded too
+	incidentExpansions map[string]func(tx *gorm.DB) *gorm.DB = map[string]func(tx *gorm.DB) *gorm.DB{
+		"hostsAffected": func(tx *gorm.DB) *gorm.DB {
+			return tx.Preload("HostsAffected")
+		},
+		"hostsAffected.team": func(tx *gorm.DB) *gorm.DB {
+			return tx.Preload("HostsAffected.Team")
+		},
+		"resolutionTeams": func(tx *gorm.DB) *gorm.DB {
+			return tx.Preload("ResolutionTeams")
+		},
+		"resolutionTeams.users": func(tx *gorm.DB) *gorm.DB {
+			return tx.Preload("ResolutionTeams.Users")
+		},
+		"resolvedBy": func(tx *gorm.DB) *gorm.DB {
+			return tx.Preload("ResolvedBy")
+		},
+		"resolvedBy.teams": func(tx *gorm.DB) *gorm.DB {
+			return tx.Preload("ResolvedBy.Teams")
+		},
+		"comments": func(tx *gorm.DB) *gorm.DB {
+			return tx.Preload("Comments", func(t *gorm.DB) *gorm.DB {
+				// get comments in descending order by created_at timestamp
+				return t.Order("commented_at DESC")
+			}).Preload("Comments.CommentedBy")
+		},
+		"comments.commentedBy.teams": func(tx *gorm.DB) *gorm.DB {
+			return tx.Preload("Comments.CommentedBy.Teams")
+		},
+		"statusHistory": func(tx *gorm.DB) *gorm.DB {
+			return tx.Preload("StatusChanges", func(t *gorm.DB) *gorm.DB {
+				return t.Order("changed_at ASC")
+			}).Preload("StatusChanges.ChangedBy")
+		},
+		"assignee": func(tx *gorm.DB) *gorm.DB {
+			return tx.Preload("Assignee")
+		},
+		"responders": func(tx *gorm.DB) *gorm.DB {
+			return tx.Preload("Responders")
+		},
+		"assignmentHistory": func(tx *gorm.DB) *gorm.DB {
+			return tx.Preload("Assignments", func(t *gorm.DB) *gorm.DB {
+				return t.Order("changed_at ASC")
+			}).Preload("Assignments.User").Preload("Assignments.ChangedBy")
+		},
+		"hashAliases": func(tx *gorm.DB) *gorm.DB {
+			return tx.Preload("HashAliases")
+		},
+		"stackFrames": func(tx *gorm.DB) *gorm.DB {
+			return tx.Preload("StackFrames", func(t *gorm.DB) *gorm.DB {
+				return t.Order("position ASC")
+			})
+		},
+		"links": func(tx *gorm.DB) *gorm.DB {
+			return tx.Preload("Links").Preload("Links.LinkedIncident").Preload("Links.CreatedBy")
+		},
+		"linkedBy": func(tx *gorm.DB) *gorm.DB {
+			return tx.Preload("LinkedBy").Preload("LinkedBy.Incident").Preload("LinkedBy.CreatedBy")
+		},
+		"mergedInto": func(tx *gorm.DB) *gorm.DB {
+			return tx.Preload("MergedInto")
+		},
+	}
+	IncidentExpansions []string = []string{
+		"hostsAffected", "hostsAffected.team", "resolutionTeams", "resolutionTeams.users", "resolvedBy", "resolvedBy.teams",
+		"comments", "comments.commentedBy.teams", "statusHistory", "assignee", "responders", "assignmentHistory", "hashAliases",
+		"stackFrames", "links", "linkedBy", "mergedInto",
+	}
 )
 
 type Incident struct {
-	ID              uint              `gorm:"column:id;primaryKey;autoIncrement"`
-	UUID            string            `gorm:"column:uuid;size:36;unique;not null;uniqueIndex"`
-	HostsAffected   []HostMachine     `gorm:"many2many:incident_host"`
-	Description     string            `gorm:"column:description;size:500"`
-	Summary         string            `gorm:"column:summary;size:100;not null"`
-	Comments        []IncidentComment `gorm:"foreignKey:incident_id;constraint:OnDelete:CASCADE"`
-	CreatedAt       time.Time         `gorm:"column:created_at;autoCreateTime;not null"`
-	ResolvedAt      *time.Time        `gorm:"column:resolved_at;index"`
-	ResolvedByID    *uint             `gorm:"column:resolved_by_id"`
-	ResolvedBy      *User             `gorm:"foreignKey:resolved_by_id;references:id"`
-	ResolutionTeams []Team            `gorm:"many2many:incident_resolution_team"`
-	Hash            string            `gorm:"column:hash;size:64;not null;uniqueIndex"`
+	ID              uint                   `gorm:"column:id;primaryKey;autoIncrement"`
+	UUID            string                 `gorm:"column:uuid;size:36;unique;not null;uniqueIndex"`
+	HostsAffected   []HostMachine          `gorm:"many2many:incident_host"`
+	Description     string                 `gorm:"column:description;size:500"`
+	Summary         string                 `gorm:"column:summary;size:100;not null"`
+	Comments        []IncidentComment      `gorm:"foreignKey:incident_id;constraint:OnDelete:CASCADE"`
+	CreatedAt       time.Time              `gorm:"column:created_at;autoCreateTime;not null"`
+	ResolvedAt      *time.Time             `gorm:"column:resolved_at;index"`
+	ResolvedByID    *uint                  `gorm:"column:resolved_by_id"`
+	ResolvedBy      *User                  `gorm:"foreignKey:resolved_by_id;references:id"`
+	ResolutionTeams []Team                 `gorm:"many2many:incident_resolution_team"`
+	Hash            string                 `gorm:"column:hash;size:64;not null;uniqueIndex"`
+	FirstSeenAt     time.Time              `gorm:"column:first_seen_at;autoCreateTime;not null"`
+	LastSeenAt      time.Time              `gorm:"column:last_seen_at;autoCreateTime;not null;index"`
+	OccurrenceCount uint                   `gorm:"column:occurrence_count;not null;default:1"`
+	Regressed       bool                   `gorm:"column:regressed;not null;default:false"`
+	RegressionCount uint                   `gorm:"column:regression_count;not null;default:0"`
+	Status          string                 `gorm:"column:status;size:13;not null;default:'open';index;check:status IN ('open','acknowledged','investigating','mitigated','resolved')"`
+	StatusChanges   []IncidentStatusChange `gorm:"foreignKey:incident_id;constraint:OnDelete:CASCADE"`
+	Severity        uint                   `gorm:"column:severity;not null;default:3;index;check:severity BETWEEN 1 AND 5"`
+	Impact          uint                   `gorm:"column:impact;not null;default:2;check:impact BETWEEN 1 AND 3"`
+	Urgency         uint                   `gorm:"column:urgency;not null;default:2;check:urgency BETWEEN 1 AND 3"`
+	Priority        uint                   `gorm:"column:priority;not null;default:3;index;check:priority BETWEEN 1 AND 5"`
+	AssigneeID      *uint                  `gorm:"column:assignee_id;index"`
+	Assignee        *User                  `gorm:"foreignKey:assignee_id;references:id"`
+	Responders      []User                 `gorm:"many2many:incident_responder"`
+	Assignments     []IncidentAssignment   `gorm:"foreignKey:incident_id;constraint:OnDelete:CASCADE"`
+	HashAliases     []IncidentHashAlias    `gorm:"foreignKey:incident_id;constraint:OnDelete:CASCADE"`
+	Links           []IncidentLink         `gorm:"foreignKey:incident_id;constraint:OnDelete:CASCADE"`
+	LinkedBy        []IncidentLink         `gorm:"foreignKey:linked_incident_id;constraint:OnDelete:CASCADE"`
+	StackTrace      string                 `gorm:"column:stack_trace;type:text"`
+	StackLanguage   string                 `gorm:"column:stack_language;size:10"`
+	StackFrames     []IncidentStackFrame   `gorm:"foreignKey:incident_id;constraint:OnDelete:CASCADE"`
+	// the incident this one was merged into, which keeps it and its history as a tombstone
+	MergedIntoID *uint     `gorm:"column:merged_into_id;index"`
+	MergedInto   *Incident `gorm:"foreignKey:merged_into_id;references:id"`
+	// the version of the fingerprinting algorithm which computed the hash, or 0 if the reporter supplied the hash
+	FingerprintVersion uint `gorm:"column:fingerprint_version;not null;default:0"`
+	// bumped on every change, including every event recorded against the incident, and part of the incident's ETag
+	Version uint `gorm:"column:version;not null;default:1"`
+	// the relevance of the incident to a full-text search, which is only set when searching
+	Search *IncidentSearchHit `gorm:"-"`
 }
 
 func (incident *Incident) BeforeCreate(tx *gorm.DB) error {
@@ -41,11 +224,13 @@ func (incident *Incident) BeforeCreate(tx *gorm.DB) error {
 }
 
 type IncidentComment struct {
-	ID            uint      `gorm:"column:id;primaryKey;autoIncrement"`
-	UUID          string    `gorm:"column:uuid;size:36;unique;not null"`
-	Comment       string    `gorm:"column:comment;size:200;not null"`
-	CommentedByID uint      `gorm:"column:commented_by_id;not null"`
-	CommentedBy   User      `gorm:"foreignKey:commented_by_id;references:id"`
+	ID      uint   `gorm:"column:id;primaryKey;autoIncrement"`
+	UUID    string `gorm:"column:uuid;size:36;unique;not null"`
+	Comment string `gorm:"column:comment;size:200;not null"`
+	// nil for comments the backend leaves by itself, which are automated
+	CommentedByID *uint     `gorm:"column:commented_by_id"`
+	CommentedBy   *User     `gorm:"foreignKey:commented_by_id;references:id"`
+	Automated     bool      `gorm:"column:automated;not null;default:false"`
 	CommentedAt   time.Time `gorm:"column:commented_at;autoCreateTime;not null"`
 	IncidentID    uint      `gorm:"column:incident_id;not null"`
 	Incident      Incident  `gorm:"foreignKey:incident_id;references:id"`
@@ -66,6 +251,177 @@ func (comment *IncidentComment) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+type IncidentStatusChange struct {
+	ID          uint     `gorm:"column:id;primaryKey;autoIncrement"`
+	UUID        string   `gorm:"column:uuid;size:36;unique;not null"`
+	IncidentID  uint     `gorm:"column:incident_id;not null"`
+	Incident    Incident `gorm:"foreignKey:incident_id;references:id"`
+	FromStatus  string   `gorm:"column:from_status;size:13;not null"`
+	ToStatus    string   `gorm:"column:to_status;size:13;not null"`
+	ChangedByID *uint    `gorm:"column:changed_by_id"`
+	ChangedBy   *User    `gorm:"foreignKey:changed_by_id;references:id;constraint:OnDelete:SET NULL"`
+	// the name of the user who made the change, which is kept if they are deleted
+	ChangedByName *string   `gorm:"column:changed_by_name;size:30"`
+	ChangedAt     time.Time `gorm:"column:changed_at;autoCreateTime;not null"`
+}
+
+func (change *IncidentStatusChange) BeforeCreate(tx *gorm.DB) error {
+	ctx := GetContext(tx)
+	if change.UUID == "" {
+		uuid, err := utility.GenerateRandomUUID()
+		if err != nil {
+			if ctx != nil {
+				ctx.Set("errorCode", http.StatusInternalServerError)
+			}
+			return errors.New("failed to create a status change uuid")
+		}
+		change.UUID = uuid
+	}
+	return nil
+}
+
+type IncidentAssignment struct {
+	ID          uint     `gorm:"column:id;primaryKey;autoIncrement"`
+	UUID        string   `gorm:"column:uuid;size:36;unique;not null"`
+	IncidentID  uint     `gorm:"column:incident_id;not null"`
+	Incident    Incident `gorm:"foreignKey:incident_id;references:id"`
+	UserID      *uint    `gorm:"column:user_id"`
+	User        *User    `gorm:"foreignKey:user_id;references:id;constraint:OnDelete:SET NULL"`
+	UserName    *string  `gorm:"column:user_name;size:30"`
+	Role        string   `gorm:"column:role;size:9;not null;check:role IN ('assignee','responder')"`
+	Assigned    bool     `gorm:"column:assigned;not null"`
+	ChangedByID *uint    `gorm:"column:changed_by_id"`
+	ChangedBy   *User    `gorm:"foreignKey:changed_by_id;references:id;constraint:OnDelete:SET NULL"`
+	// the users' names, which are kept if they are deleted. Nobody made a change which was made automatically
+	ChangedByName *string   `gorm:"column:changed_by_name;size:30"`
+	ChangedAt     time.Time `gorm:"column:changed_at;autoCreateTime;not null"`
+}
+
+func (assignment *IncidentAssignment) BeforeCreate(tx *gorm.DB) error {
+	ctx := GetContext(tx)
+	if assignment.UUID == "" {
+		uuid, err := utility.GenerateRandomUUID()
+		if err != nil {
+			if ctx != nil {
+				ctx.Set("errorCode", http.StatusInternalServerError)
+			}
+			return errors.New("failed to create an assignment uuid")
+		}
+		assignment.UUID = uuid
+	}
+	return nil
+}
+
+// An append-only record of something that happened to an incident, used to build its timeline
+type IncidentEvent struct {
+	ID         uint     `gorm:"column:id;primaryKey;autoIncrement"`
+	UUID       string   `gorm:"column:uuid;size:36;unique;not null"`
+	IncidentID uint     `gorm:"column:incident_id;not null;index"`
+	Incident   Incident `gorm:"foreignKey:incident_id;references:id"`
+	Type       string   `gorm:"column:type;size:20;not null"`
+	Field      *string  `gorm:"column:field;size:20"`
+	OldValue   *string  `gorm:"column:old_value;type:text"`
+	NewValue   *string  `gorm:"column:new_value;type:text"`
+	ActorID    *uint    `gorm:"column:actor_id"`
+	Actor      *User    `gorm:"foreignKey:actor_id;references:id;constraint:OnDelete:SET NULL"`
+	// the name of the actor, which is kept if they are deleted
+	ActorName *string   `gorm:"column:actor_name;size:30"`
+	Automated bool      `gorm:"column:automated;not null;default:false"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime;not null"`
+}
+
+func (event *IncidentEvent) BeforeCreate(tx *gorm.DB) error {
+	ctx := GetContext(tx)
+	if event.UUID == "" {
+		uuid, err := utility.GenerateRandomUUID()
+		if err != nil {
+			if ctx != nil {
+				ctx.Set("errorCode", http.StatusInternalServerError)
+			}
+			return errors.New("failed to create an incident event uuid")
+		}
+		event.UUID = uuid
+	}
+	return nil
+}
+
+// Refuse any update or delete of incident events, however the statement names their table, so the timeline can only
+// be added to
+func registerIncidentEventCallbacks(db *gorm.DB) error {
+	appendOnly := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			if tx.Statement.Table == "tbl_incident_event" {
+				tx.AddError(fmt.Errorf("incident events cannot be %s", operation))
+			}
+		}
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("incident_event:append_only", appendOnly("changed")); err != nil {
+		return err
+	}
+	return db.Callback().Delete().Before("gorm:delete").Register("incident_event:append_only", appendOnly("deleted"))
+}
+
+// A single frame of an incident's stack trace. Position 0 is the most recent call
+type IncidentStackFrame struct {
+	ID         uint     `gorm:"column:id;primaryKey;autoIncrement"`
+	IncidentID uint     `gorm:"column:incident_id;not null;index"`
+	Incident   Incident `gorm:"foreignKey:incident_id;references:id"`
+	Position   uint     `gorm:"column:position;not null"`
+	File       string   `gorm:"column:file;size:500;not null"`
+	Function   string   `gorm:"column:function_name;size:500;not null"`
+	Line       *uint    `gorm:"column:line_number"`
+	Column     *uint    `gorm:"column:column_number"`
+	InApp      bool     `gorm:"column:in_app;not null;default:false"`
+	Culprit    bool     `gorm:"column:culprit;not null;default:false"`
+}
+
+// A hash of an incident that was merged into another, so future occurrences of it are routed to the surviving incident
+type IncidentHashAlias struct {
+	ID         uint      `gorm:"column:id;primaryKey;autoIncrement"`
+	IncidentID uint      `gorm:"column:incident_id;not null"`
+	Incident   Incident  `gorm:"foreignKey:incident_id;references:id"`
+	Hash       string    `gorm:"column:hash;size:64;not null;uniqueIndex"`
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime;not null"`
+}
+
+type IncidentLink struct {
+	ID               uint     `gorm:"column:id;primaryKey;autoIncrement"`
+	UUID             string   `gorm:"column:uuid;size:36;unique;not null"`
+	IncidentID       uint     `gorm:"column:incident_id;not null;uniqueIndex:idx_incident_link"`
+	Incident         Incident `gorm:"foreignKey:incident_id;references:id"`
+	LinkedIncidentID uint     `gorm:"column:linked_incident_id;not null;uniqueIndex:idx_incident_link"`
+	LinkedIncident   Incident `gorm:"foreignKey:linked_incident_id;references:id"`
+	Type             string   `gorm:"column:type;size:12;not null;uniqueIndex:idx_incident_link;check:type IN ('duplicate-of','caused-by','related-to')"`
+	CreatedByID      *uint    `gorm:"column:created_by_id"`
+	CreatedBy        *User    `gorm:"foreignKey:created_by_id;references:id;constraint:OnDelete:SET NULL"`
+	// the name of the user who created the link, which is kept if they are deleted
+	CreatedByName *string   `gorm:"column:created_by_name;size:30"`
+	CreatedAt     time.Time `gorm:"column:created_at;autoCreateTime;not null"`
+}
+
+func (link *IncidentLink) BeforeCreate(tx *gorm.DB) error {
+	ctx := GetContext(tx)
+	if link.UUID == "" {
+		uuid, err := utility.GenerateRandomUUID()
+		if err != nil {
+			if ctx != nil {
+				ctx.Set("errorCode", http.StatusInternalServerError)
+			}
+			return errors.New("failed to create an incident link uuid")
+		}
+		link.UUID = uuid
+	}
+	return nil
+}
+
+type IncidentResponder struct {
+	ID         uint     `gorm:"column:id;primaryKey;autoIncrement"`
+	IncidentID uint     `gorm:"column:incident_id"`
+	Incident   Incident `gorm:"foreignKey:incident_id;references:id"`
+	UserID     uint     `gorm:"column:user_id"`
+	User       User     `gorm:"foreignKey:user_id;references:id"`
+}
+
 type IncidentHost struct {
 	ID            uint        `gorm:"column:id;primaryKey;autoIncrement"`
 	IncidentID    uint        `gorm:"column:incident_id"`
@@ -82,12 +438,29 @@ type IncidentResolutionTeam struct {
 }
 
 type GetIncidentsFilters struct {
-	MyTeams  bool
-	UUID     *string
-	Resolved *bool
+	MyTeams    bool
+	MyAssigned bool
+	UUID       *string
+	Resolved   *bool
+	Regressed  *bool
+	Status     *string
+	Severities []uint
+	Priorities []uint
+	Sort       []SortKey
+	Cursor     *string
+	// filled with the cursors of the neighbouring pages, if set
+	Cursors  *PageCursors
 	Page     *int
 	PageSize *int
 	Hash     *string
+	Search   *string
+	Query    utility.QueryNode
+	// the relations to preload, by their path in the response, or every relation if nil
+	Expand []string
+	// leave out the raw stack trace, which can be up to 64KB, when it is not shown
+	OmitStackTrace bool
+	// get incidents owned by private teams the user is not in too, for lookups made on the backend's own behalf
+	Unscoped bool
 }
 
 func GetIncident(ctx *gin.Context, uuid string) (*Incident, error) {
@@ -106,20 +479,37 @@ func GetIncident(ctx *gin.Context, uuid string) (*Incident, error) {
 	return incidents[0], nil
 }
 
+// The IDs of the incidents owned by any of a set of teams, through being a resolution team of them and through owning a
+// host they affect, as the same relationship the MyTeams filter joins on
+func incidentsOwnedBy(ctx *gin.Context, teams *gorm.DB) (*gorm.DB, *gorm.DB) {
+	db := GetDBTransaction(ctx)
+	resolving := db.Model(&IncidentResolutionTeam{}).Select("incident_id").Where("team_id IN (?)", teams)
+	hosting := db.Model(&IncidentHost{}).Select("tbl_incident_host.incident_id").
+		Joins("JOIN tbl_host_machine ON tbl_host_machine.id = tbl_incident_host.host_machine_id").
+		Where("tbl_host_machine.team_id IN (?)", teams)
+	return resolving, hosting
+}
+
+// Whether a user can change an incident, which they can if they are a member of one of its resolution teams or of a
+// team owning a host it affects. An incident no team owns yet can be changed by anyone, so that it can be triaged
+func (incident *Incident) OwnedBy(user *User) bool {
+	teams := make([]uint, 0)
+	for _, team := range incident.ResolutionTeams {
+		teams = append(teams, team.ID)
+	}
+	for _, host := range incident.HostsAffected {
+		teams = append(teams, host.TeamID)
+	}
+	return len(teams) == 0 || memberOfAny(user, teams...)
+}
+
 func GetIncidents(ctx *gin.Context, filters GetIncidentsFilters) ([]*Incident, int64, error) {
 	tx := GetDBTransaction(ctx).Model(&Incident{})
-	tx = tx.Preload("HostsAffected").
-		Preload("HostsAffected.Team").
-		Preload("ResolutionTeams").
-		Preload("ResolutionTeams.Users").
-		Preload("ResolvedBy").
-		Preload("ResolvedBy.Teams").
-		Preload("Comments", func(t *gorm.DB) *gorm.DB {
-			// get comments in descending order by created_at timestamp
-			return t.Order("commented_at DESC")
-		}).
-		Preload("Comments.CommentedBy").
-		Preload("Comments.CommentedBy.Teams")
+	for _, path := range IncidentExpansions {
+		if filters.Expand == nil || slices.Contains(filters.Expand, path) {
+			tx = incidentExpansions[path](tx)
+		}
+	}
 	incidents := make([]*Incident, 0)
 
 	// apply filters
@@ -130,6 +520,18 @@ func GetIncidents(ctx *gin.Context, filters GetIncidentsFilters) ([]*Incident, i
 			tx = tx.Where("resolved_at IS NULL")
 		}
 	}
+	if filters.Regressed != nil {
+		tx = tx.Where("tbl_incident.regressed = ?", *filters.Regressed)
+	}
+	if filters.Status != nil {
+		tx = tx.Where("tbl_incident.status = ?", *filters.Status)
+	}
+	if len(filters.Severities) > 0 {
+		tx = tx.Where("tbl_incident.severity IN (?)", filters.Severities)
+	}
+	if len(filters.Priorities) > 0 {
+		tx = tx.Where("tbl_incident.priority IN (?)", filters.Priorities)
+	}
 	if filters.MyTeams {
 		user := ctx.MustGet("user").(*User)
 		tx = tx.Joins("LEFT JOIN tbl_incident_resolution_team ON tbl_incident_resolution_team.incident_id = tbl_incident.id").
@@ -139,54 +541,904 @@ func GetIncidents(ctx *gin.Context, filters GetIncidentsFilters) ([]*Incident, i
 			Joins("LEFT JOIN tbl_user ON tbl_user.id = tbl_team_user.user_id").
 			Where("tbl_user.uuid = ?", user.UUID)
 	}
+	if mine, private := teamVisibility(ctx, PermissionIncidentManage); mine != nil && !filters.Unscoped {
+		ownedByMine, ownedByMineThroughHosts := incidentsOwnedBy(ctx, mine)
+		ownedByPrivate, ownedByPrivateThroughHosts := incidentsOwnedBy(ctx, private)
+		tx = tx.Where(
+			"tbl_incident.id IN (?) OR tbl_incident.id IN (?) OR (tbl_incident.id NOT IN (?) AND tbl_incident.id NOT IN (?))",
+			ownedByMine, ownedByMineThroughHosts, ownedByPrivate, ownedByPrivateThroughHosts,
+		)
+	}
+	if filters.MyAssigned {
+		user := ctx.MustGet("user").(*User)
+		tx = tx.Where(
+			"tbl_incident.assignee_id = ? OR tbl_incident.id IN (?)",
+			user.ID,
+			GetDBTransaction(ctx).Model(&IncidentResponder{}).Select("incident_id").Where("user_id = ?", user.ID),
+		)
+	}
 	if filters.UUID != nil {
 		tx = tx.Where("tbl_incident.uuid = ?", *filters.UUID)
+	} else {
+		// incidents merged into another are only kept for their history, so are only got by their uuid
+		tx = tx.Where("tbl_incident.merged_into_id IS NULL")
 	}
 	if filters.Hash != nil {
-		tx = tx.Where("tbl_incident.hash = ?", *filters.Hash)
+		// hashes of incidents merged into another resolve to the surviving incident
+		tx = tx.Where(
+			"tbl_incident.hash = ? OR tbl_incident.id IN (?)",
+			*filters.Hash,
+			GetDBTransaction(ctx).Model(&IncidentHashAlias{}).Select("incident_id").Where("hash = ?", *filters.Hash),
+		)
+	}
+
+	search := filters.Search
+	if filters.Query != nil {
+		text, condition, args, err := parseIncidentQuery(ctx, filters.Query)
+		if err != nil {
+			return nil, -1, err
+		}
+		if condition != "" {
+			tx = tx.Where(fmt.Sprintf("(%s)", condition), args...)
+		}
+		if text != "" {
+			if search != nil {
+				text = fmt.Sprintf("%s %s", *search, text)
+			}
+			search = &text
+		}
+	}
+	var hits map[string]*IncidentSearchHit
+	if search != nil {
+		var err error
+		hits, err = searchIncidents(ctx, *search)
+		if err != nil {
+			return nil, -1, err
+		}
+		uuids := make([]string, 0)
+		for uuid := range hits {
+			uuids = append(uuids, uuid)
+		}
+		tx = tx.Where("tbl_incident.uuid IN (?)", uuids)
 	}
 
 	var count int64
 	tx.Count(&count)
-	if filters.PageSize != nil {
-		tx = tx.Limit(*filters.PageSize)
-		if filters.Page != nil {
-			tx = tx.Offset(*filters.PageSize * (*filters.Page - 1))
+	// search results are ranked by relevance, which only the index knows, so they are paged after sorting
+	rankBySearch := search != nil && len(filters.Sort) == 0
+	var pages *paginator
+	if rankBySearch {
+		if filters.Cursor != nil {
+			ctx.Set("errorCode", http.StatusBadRequest)
+			return nil, -1, errors.New("cursor query parameter cannot be used when search results are ranked by relevance, sort them instead")
+		}
+		// only the ids of every match are loaded to rank them, and then just the page of incidents is loaded in full
+		type match struct {
+			ID   uint
+			UUID string
+		}
+		matches := make([]match, 0)
+		if err := tx.Session(&gorm.Session{}).Select("tbl_incident.id, tbl_incident.uuid").Order("tbl_incident.id ASC").Scan(&matches).Error; err != nil {
+			return nil, -1, handleError(ctx, err)
 		}
+		slices.SortStableFunc(matches, func(a, b match) int {
+			return cmp.Compare(hits[b.UUID].Score, hits[a.UUID].Score)
+		})
+		if filters.PageSize != nil {
+			start := 0
+			if filters.Page != nil {
+				start = min(*filters.PageSize*(*filters.Page-1), len(matches))
+			}
+			matches = matches[start:min(start+*filters.PageSize, len(matches))]
+		}
+		ids := make([]uint, 0, len(matches))
+		for _, match := range matches {
+			ids = append(ids, match.ID)
+		}
+		tx = tx.Where("tbl_incident.id IN (?)", ids).Order("tbl_incident.id ASC")
+	} else {
+		var err error
+		pages, err = newPaginator(ctx, &Incident{}, incidentSortColumns, filters.Sort, filters.Cursor, filters.Page, filters.PageSize)
+		if err != nil {
+			return nil, -1, err
+		}
+		tx = pages.apply(tx)
 	}
 
+	if filters.OmitStackTrace {
+		tx = tx.Omit("stack_trace")
+	}
 	tx = tx.Find(&incidents)
 	if tx.Error != nil {
 		return nil, -1, handleError(ctx, tx.Error)
 	}
+	if rankBySearch {
+		slices.SortStableFunc(incidents, func(a, b *Incident) int {
+			return cmp.Compare(hits[b.UUID].Score, hits[a.UUID].Score)
+		})
+	} else if err := pages.finish(ctx, &incidents, filters.Cursors); err != nil {
+		return nil, -1, err
+	}
+	if search != nil {
+		uuids := make([]string, 0, len(incidents))
+		for _, incident := range incidents {
+			uuids = append(uuids, incident.UUID)
+			incident.Search = hits[incident.UUID]
+		}
+		if err := highlightIncidentSearchHits(ctx, *search, hits, uuids); err != nil {
+			return nil, -1, err
+		}
+	}
 	return incidents, count, nil
 }
 
 func CreateIncident(ctx *gin.Context, body *utility.IncidentPostRequestBodySchema) (*Incident, error) {
-	tx := GetDBTransaction(ctx).Model(&Incident{})
-	incident := &Incident{
-		Summary:     body.Summary,
-		Description: body.Description,
-		CreatedAt:   time.Now(),
-		Hash:        body.Hash,
+	incident, err := newIncident(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	alias, err := getIncidentHashAlias(ctx, incident.Hash)
+	if err != nil {
+		return nil, err
 	}
+	if alias != nil {
+		ctx.Set("errorCode", http.StatusBadRequest)
+		return nil, errors.New("an incident with this hash already exists")
+	}
+	tx := GetDBTransaction(ctx).Model(&Incident{})
 	tx = tx.Create(incident)
 	if tx.Error != nil {
 		return nil, handleError(ctx, tx.Error)
 	}
+	if err := recordIncidentEvent(ctx, &IncidentEvent{
+		IncidentID: incident.ID,
+		Type:       IncidentEventCreated,
+		NewValue:   &incident.Summary,
+	}); err != nil {
+		return nil, err
+	}
 
-	// insert hosts - m2m
+	if err := addIncidentHosts(ctx, incident, body.HostsAffected); err != nil {
+		return nil, err
+	}
+	if err := addIncidentResolutionTeams(ctx, incident, body.ResolutionTeams); err != nil {
+		return nil, err
+	}
+	if err := addIncidentStackFrames(ctx, incident); err != nil {
+		return nil, err
+	}
+	if err := indexIncident(ctx, incident.ID); err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
+
+// Build a new open incident from a request body, filling in the default severity, impact and urgency
+func newIncident(ctx *gin.Context, body *utility.IncidentPostRequestBodySchema) (*Incident, error) {
+	severity := DefaultIncidentSeverity
+	if body.Severity != nil {
+		severity = *body.Severity
+	}
+	impact := DefaultIncidentImpact
+	if body.Impact != nil {
+		impact = *body.Impact
+	}
+	urgency := DefaultIncidentUrgency
+	if body.Urgency != nil {
+		urgency = *body.Urgency
+	}
+	priority, err := GetPriority(ctx, impact, urgency)
+	if err != nil {
+		return nil, err
+	}
+
+	// the backend owns fingerprinting whenever it is given a stack trace, so every reporter dedupes the same way
+	hash := body.Hash
+	fingerprintVersion := uint(0)
+	if body.StackTrace != "" {
+		hash, err = ComputeFingerprint(ctx, body.Summary, body.StackTrace)
+		if err != nil {
+			return nil, err
+		}
+		fingerprintVersion = utility.FingerprintVersion
+	}
+
+	now := time.Now()
+	return &Incident{
+		Summary:            body.Summary,
+		Description:        body.Description,
+		CreatedAt:          now,
+		Hash:               hash,
+		FingerprintVersion: fingerprintVersion,
+		StackTrace:         body.StackTrace,
+		FirstSeenAt:        now,
+		LastSeenAt:         now,
+		OccurrenceCount:    1,
+		Status:             IncidentStatusOpen,
+		Severity:           severity,
+		Impact:             impact,
+		Urgency:            urgency,
+		Priority:           priority,
+	}, nil
+}
+
+// Report an occurrence of an incident by its hash.
+// The incident is created if the hash has not been seen before, otherwise its occurrence count and last seen time are bumped.
+// A resolved incident that occurs again is reopened as a regression.
+// Any hosts not already attached to the incident are attached. The returned bool is true if the incident was created
+func ReportIncidentOccurrence(ctx *gin.Context, body *utility.IncidentPostRequestBodySchema) (*Incident, bool, error) {
+	incident, err := newIncident(ctx, body)
+	if err != nil {
+		return nil, false, err
+	}
+	// reporters which hashed stack traces themselves before the backend fingerprinted them still send their own hash. An
+	// incident already known by that hash keeps counting under it, and otherwise it becomes an alias of the new incident
+	clientHash := ""
+	if body.Hash != "" && body.Hash != incident.Hash {
+		var known int64
+		aliased := GetDBTransaction(ctx).Model(&IncidentHashAlias{}).Select("incident_id").Where("hash = ?", body.Hash)
+		if err := GetDBTransaction(ctx).Model(&Incident{}).Where("hash = ?", body.Hash).Or("id IN (?)", aliased).Count(&known).Error; err != nil {
+			return nil, false, handleError(ctx, err)
+		}
+		if known > 0 {
+			incident.Hash = body.Hash
+			incident.FingerprintVersion = 0
+		} else {
+			clientHash = body.Hash
+		}
+	}
+	now := incident.LastSeenAt
+	occurrence := map[string]any{
+		"occurrence_count": gorm.Expr("occurrence_count + 1"),
+		"last_seen_at":     now,
+	}
+	alias, err := getIncidentHashAlias(ctx, incident.Hash)
+	if err != nil {
+		return nil, false, err
+	}
+	if alias != nil {
+		// the hash belongs to an incident which was merged away, so count the occurrence against the survivor
+		if err := GetDBTransaction(ctx).Model(&Incident{}).Where("id = ?", alias.IncidentID).Updates(occurrence).Error; err != nil {
+			return nil, false, handleError(ctx, err)
+		}
+	} else {
+		// a single upsert statement means concurrent reports of the same hash cannot race on the unique index
+		tx := GetDBTransaction(ctx).Model(&Incident{}).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "hash"}},
+			DoUpdates: clause.Assignments(occurrence),
+		}).Create(incident)
+		if tx.Error != nil {
+			return nil, false, handleError(ctx, tx.Error)
+		}
+	}
+
+	// the reporter may not be able to see the incident, for one owned by a private team they are not in
+	incidents, count, err := GetIncidents(ctx, GetIncidentsFilters{
+		PageSize: utility.Pointer(1),
+		Hash:     &incident.Hash,
+		Unscoped: true,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if count == 0 {
+		ctx.Set("errorCode", http.StatusInternalServerError)
+		return nil, false, errors.New("failed to report incident occurrence")
+	}
+	existing := incidents[0]
+	// the uuid is generated before insert, so it only matches if our row was the one inserted
+	created := existing.UUID == incident.UUID
+	if clientHash != "" {
+		alias := &IncidentHashAlias{IncidentID: existing.ID, Hash: clientHash}
+		if err := GetDBTransaction(ctx).Model(&IncidentHashAlias{}).Clauses(clause.OnConflict{DoNothing: true}).Create(alias).Error; err != nil {
+			return nil, false, handleError(ctx, err)
+		}
+		existing.HashAliases = append(existing.HashAliases, *alias)
+	}
+	event := &IncidentEvent{
+		IncidentID: existing.ID,
+		Type:       IncidentEventOccurred,
+		NewValue:   utility.Pointer(fmt.Sprint(existing.OccurrenceCount)),
+		Automated:  true,
+	}
+	if created {
+		event.Type = IncidentEventCreated
+		event.NewValue = &existing.Summary
+	}
+	if err := recordIncidentEvent(ctx, event); err != nil {
+		return nil, false, err
+	}
+
+	newHosts := make([]string, 0)
+	for _, hostUUID := range body.HostsAffected {
+		found := false
+		for _, host := range existing.HostsAffected {
+			if host.UUID == hostUUID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			newHosts = append(newHosts, hostUUID)
+		}
+	}
+	if err := addIncidentHosts(ctx, existing, newHosts); err != nil {
+		return nil, false, err
+	}
+	if created {
+		if err := addIncidentResolutionTeams(ctx, existing, body.ResolutionTeams); err != nil {
+			return nil, false, err
+		}
+		if err := addIncidentStackFrames(ctx, existing); err != nil {
+			return nil, false, err
+		}
+		if err := indexIncident(ctx, existing.ID); err != nil {
+			return nil, false, err
+		}
+	} else if existing.ResolvedAt != nil {
+		if err := regressIncident(ctx, existing); err != nil {
+			return nil, false, err
+		}
+	}
+	return existing, created, nil
+}
+
+// Reopen a resolved incident which has occurred again, leaving an automated comment on who last resolved it
+func regressIncident(ctx *gin.Context, incident *Incident) error {
+	resolvedBy := "an unknown user"
+	if incident.ResolvedBy != nil {
+		resolvedBy = incident.ResolvedBy.Name
+	}
+	comment := &IncidentComment{
+		Comment:    fmt.Sprintf("Incident regressed: it occurred again after being resolved by %s at %s", resolvedBy, incident.ResolvedAt.Format(time.RFC3339)),
+		IncidentID: incident.ID,
+		Automated:  true,
+	}
+	if _, err := CreateIncidentComment(ctx, comment); err != nil {
+		return err
+	}
+
+	if err := TransitionIncidentStatus(ctx, incident, IncidentStatusOpen); err != nil {
+		return err
+	}
+	fields := map[string]any{
+		"regressed":        true,
+		"regression_count": gorm.Expr("regression_count + 1"),
+	}
+	if err := GetDBTransaction(ctx).Model(&Incident{}).Where("id = ?", incident.ID).Updates(fields).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	incident.Regressed = true
+	incident.RegressionCount++
+	return recordIncidentEvent(ctx, &IncidentEvent{
+		IncidentID: incident.ID,
+		Type:       IncidentEventRegressed,
+		NewValue:   utility.Pointer(fmt.Sprint(incident.RegressionCount)),
+		Automated:  true,
+	})
+}
+
+// Move an incident to a new status, recording who made the change, which is nobody for background jobs.
+// Fails if the transition is not allowed from the incident's current status
+func TransitionIncidentStatus(ctx *gin.Context, incident *Incident, status string) error {
+	if !slices.Contains(incidentStatusTransitions[incident.Status], status) {
+		ctx.Set("errorCode", http.StatusConflict)
+		return fmt.Errorf("cannot move an incident from '%s' to '%s'", incident.Status, status)
+	}
+	user := actingUser(ctx)
+	var userID *uint
+	var userName *string
+	if user != nil {
+		userID = &user.ID
+		userName = &user.Name
+	}
+	now := time.Now()
+
+	fields := map[string]any{"status": status}
+	if status == IncidentStatusResolved {
+		fields["resolved_at"] = now
+		fields["resolved_by_id"] = userID
+		// resolving a regression closes it out again
+		fields["regressed"] = false
+	} else if incident.Status == IncidentStatusResolved {
+		fields["resolved_at"] = nil
+		fields["resolved_by_id"] = nil
+	}
+	if err := GetDBTransaction(ctx).Model(&Incident{}).Where("id = ?", incident.ID).Updates(fields).Error; err != nil {
+		return handleError(ctx, err)
+	}
+
+	change := &IncidentStatusChange{
+		IncidentID:    incident.ID,
+		FromStatus:    incident.Status,
+		ToStatus:      status,
+		ChangedByID:   userID,
+		ChangedByName: userName,
+		ChangedAt:     now,
+	}
+	if err := GetDBTransaction(ctx).Model(&IncidentStatusChange{}).Create(change).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	if err := recordIncidentEvent(ctx, &IncidentEvent{
+		IncidentID: incident.ID,
+		Type:       IncidentEventStatusChanged,
+		OldValue:   &change.FromStatus,
+		NewValue:   &change.ToStatus,
+	}); err != nil {
+		return err
+	}
+
+	if status == IncidentStatusResolved {
+		incident.ResolvedAt = &now
+		incident.ResolvedByID = userID
+		incident.ResolvedBy = user
+		incident.Regressed = false
+	} else if incident.Status == IncidentStatusResolved {
+		incident.ResolvedAt = nil
+		incident.ResolvedByID = nil
+		incident.ResolvedBy = nil
+	}
+	change.ChangedBy = user
+	incident.StatusChanges = append(incident.StatusChanges, *change)
+	incident.Status = status
+	return nil
+}
+
+// Assign a user to an incident as either its assignee or an additional responder.
+// The assignee must be a member of one of the incident's resolution teams and replaces any existing assignee
+func AssignIncident(ctx *gin.Context, incident *Incident, user *User, role string) error {
+	tx := GetDBTransaction(ctx)
+	if role == IncidentAssignmentRoleAssignee {
+		member := false
+		for _, team := range incident.ResolutionTeams {
+			for _, u := range team.Users {
+				if u.ID == user.ID {
+					member = true
+				}
+			}
+		}
+		if !member {
+			ctx.Set("errorCode", http.StatusBadRequest)
+			return errors.New("the assignee must be a member of one of the incident's resolution teams")
+		}
+		if incident.AssigneeID != nil && *incident.AssigneeID == user.ID {
+			ctx.Set("errorCode", http.StatusConflict)
+			return errors.New("user is already the assignee of this incident")
+		}
+		if incident.Assignee != nil {
+			if err := recordIncidentAssignment(ctx, incident, incident.Assignee, role, false); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&Incident{}).Where("id = ?", incident.ID).Update("assignee_id", user.ID).Error; err != nil {
+			return handleError(ctx, err)
+		}
+		incident.AssigneeID = &user.ID
+		incident.Assignee = user
+	} else {
+		for _, responder := range incident.Responders {
+			if responder.ID == user.ID {
+				ctx.Set("errorCode", http.StatusConflict)
+				return errors.New("user is already a responder to this incident")
+			}
+		}
+		if err := tx.Model(&IncidentResponder{}).Create(&IncidentResponder{IncidentID: incident.ID, UserID: user.ID}).Error; err != nil {
+			return handleError(ctx, err)
+		}
+		incident.Responders = append(incident.Responders, *user)
+	}
+	return recordIncidentAssignment(ctx, incident, user, role, true)
+}
+
+// Remove a user from an incident as either its assignee or an additional responder
+func UnassignIncident(ctx *gin.Context, incident *Incident, user *User, role string) error {
+	tx := GetDBTransaction(ctx)
+	if role == IncidentAssignmentRoleAssignee {
+		if incident.AssigneeID == nil || *incident.AssigneeID != user.ID {
+			ctx.Set("errorCode", http.StatusBadRequest)
+			return errors.New("user is not the assignee of this incident")
+		}
+		if err := tx.Model(&Incident{}).Where("id = ?", incident.ID).Update("assignee_id", nil).Error; err != nil {
+			return handleError(ctx, err)
+		}
+		incident.AssigneeID = nil
+		incident.Assignee = nil
+	} else {
+		responders := make([]User, 0)
+		for _, responder := range incident.Responders {
+			if responder.ID != user.ID {
+				responders = append(responders, responder)
+			}
+		}
+		if len(responders) == len(incident.Responders) {
+			ctx.Set("errorCode", http.StatusBadRequest)
+			return errors.New("user is not a responder to this incident")
+		}
+		if err := tx.Model(&IncidentResponder{}).Where("incident_id = ? AND user_id = ?", incident.ID, user.ID).Delete(&IncidentResponder{}).Error; err != nil {
+			return handleError(ctx, err)
+		}
+		incident.Responders = responders
+	}
+	return recordIncidentAssignment(ctx, incident, user, role, false)
+}
+
+// Take a user off every incident they are assigned to or responding to
+func unassignUser(ctx *gin.Context, user *User) error {
+	tx := GetDBTransaction(ctx)
+	responding := tx.Model(&IncidentResponder{}).Select("incident_id").Where("user_id = ?", user.ID)
+	incidents := make([]*Incident, 0)
+	if err := tx.Model(&Incident{}).Preload("Responders").Where("assignee_id = ?", user.ID).Or("id IN (?)", responding).Find(&incidents).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	for _, incident := range incidents {
+		if incident.AssigneeID != nil && *incident.AssigneeID == user.ID {
+			if err := UnassignIncident(ctx, incident, user, IncidentAssignmentRoleAssignee); err != nil {
+				return err
+			}
+		}
+		for _, responder := range incident.Responders {
+			if responder.ID == user.ID {
+				if err := UnassignIncident(ctx, incident, user, IncidentAssignmentRoleResponder); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// Unassign the assignee of each incident the scope matches who is no longer a member of any of its resolution teams,
+// as the assignee of an incident must be
+func unassignIneligibleAssignees(ctx *gin.Context, scope func(*gorm.DB) *gorm.DB) error {
+	tx := GetDBTransaction(ctx)
+	eligible := tx.Table("tbl_incident_resolution_team").Select("1").
+		Joins("JOIN tbl_team_user ON tbl_team_user.team_id = tbl_incident_resolution_team.team_id").
+		Where("tbl_incident_resolution_team.incident_id = tbl_incident.id AND tbl_team_user.user_id = tbl_incident.assignee_id")
+	incidents := make([]*Incident, 0)
+	if err := scope(tx.Model(&Incident{})).Preload("Assignee").Where("tbl_incident.assignee_id IS NOT NULL AND NOT EXISTS (?)", eligible).Find(&incidents).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	for _, incident := range incidents {
+		if err := UnassignIncident(ctx, incident, incident.Assignee, IncidentAssignmentRoleAssignee); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func recordIncidentAssignment(ctx *gin.Context, incident *Incident, user *User, role string, assigned bool) error {
+	changedBy := actingUser(ctx)
+	assignment := &IncidentAssignment{
+		IncidentID: incident.ID,
+		UserID:     &user.ID,
+		UserName:   &user.Name,
+		Role:       role,
+		Assigned:   assigned,
+		ChangedAt:  time.Now(),
+	}
+	if changedBy != nil {
+		assignment.ChangedByID = &changedBy.ID
+		assignment.ChangedByName = &changedBy.Name
+	}
+	if err := GetDBTransaction(ctx).Model(&IncidentAssignment{}).Create(assignment).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	event := &IncidentEvent{
+		IncidentID: incident.ID,
+		Type:       IncidentEventAssigned,
+		Field:      &role,
+		NewValue:   &user.Name,
+	}
+	if !assigned {
+		event.Type = IncidentEventUnassigned
+		event.NewValue = nil
+		event.OldValue = &user.Name
+	}
+	if err := recordIncidentEvent(ctx, event); err != nil {
+		return err
+	}
+	assignment.User = user
+	assignment.ChangedBy = changedBy
+	incident.Assignments = append(incident.Assignments, *assignment)
+	return nil
+}
+
+func getIncidentHashAlias(ctx *gin.Context, hash string) (*IncidentHashAlias, error) {
+	aliases := make([]*IncidentHashAlias, 0)
+	if err := GetDBTransaction(ctx).Model(&IncidentHashAlias{}).Where("hash = ?", hash).Limit(1).Find(&aliases).Error; err != nil {
+		return nil, handleError(ctx, err)
+	}
+	if len(aliases) == 0 {
+		return nil, nil
+	}
+	return aliases[0], nil
+}
+
+// Set the severity of an incident without touching its other fields
+func SetIncidentSeverity(ctx *gin.Context, incident *Incident, severity uint) error {
+	if err := GetDBTransaction(ctx).Model(&Incident{}).Where("id = ?", incident.ID).Update("severity", severity).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	return recordIncidentFieldChange(ctx, incident.ID, "severity", incident.Severity, severity, false)
+}
+
+// Add a team to an incident's resolution teams, doing nothing if it is already one of them
+func AddIncidentResolutionTeam(ctx *gin.Context, incident *Incident, team *Team) error {
+	if slices.ContainsFunc(incident.ResolutionTeams, func(t Team) bool { return t.ID == team.ID }) {
+		return nil
+	}
+	return addIncidentResolutionTeams(ctx, incident, []string{team.UUID})
+}
+
+// Fold the source incident into the target incident.
+// The source's comments, hosts and resolution teams move to the target, its hash (and any hashes already merged into it)
+// become aliases of the target, and its occurrences are added to the target's. The source is kept as a tombstone pointing
+// at the target, with its timeline and history left as they were, and a merged event is recorded on both incidents
+func MergeIncidents(ctx *gin.Context, target *Incident, source *Incident) error {
+	if target.ID == source.ID {
+		ctx.Set("errorCode", http.StatusBadRequest)
+		return errors.New("an incident cannot be merged into itself")
+	}
+	if target.MergedIntoID != nil || source.MergedIntoID != nil {
+		ctx.Set("errorCode", http.StatusConflict)
+		return errors.New("an incident which has already been merged cannot be merged again")
+	}
+	tx := GetDBTransaction(ctx)
+
+	if err := tx.Model(&IncidentComment{}).Where("incident_id = ?", source.ID).Update("incident_id", target.ID).Error; err != nil {
+		return handleError(ctx, err)
+	}
+
+	newHosts := make([]string, 0)
+	for _, host := range source.HostsAffected {
+		if !slices.ContainsFunc(target.HostsAffected, func(h HostMachine) bool { return h.ID == host.ID }) {
+			newHosts = append(newHosts, host.UUID)
+		}
+	}
+	if err := addIncidentHosts(ctx, target, newHosts); err != nil {
+		return err
+	}
+	newTeams := make([]string, 0)
+	for _, team := range source.ResolutionTeams {
+		if !slices.ContainsFunc(target.ResolutionTeams, func(t Team) bool { return t.ID == team.ID }) {
+			newTeams = append(newTeams, team.UUID)
+		}
+	}
+	if err := addIncidentResolutionTeams(ctx, target, newTeams); err != nil {
+		return err
+	}
+
+	if err := tx.Model(&IncidentHashAlias{}).Where("incident_id = ?", source.ID).Update("incident_id", target.ID).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	firstSeenAt := target.FirstSeenAt
+	if source.FirstSeenAt.Before(firstSeenAt) {
+		firstSeenAt = source.FirstSeenAt
+	}
+	lastSeenAt := target.LastSeenAt
+	if source.LastSeenAt.After(lastSeenAt) {
+		lastSeenAt = source.LastSeenAt
+	}
+	fields := map[string]any{
+		"occurrence_count": target.OccurrenceCount + source.OccurrenceCount,
+		"first_seen_at":    firstSeenAt,
+		"last_seen_at":     lastSeenAt,
+	}
+	if err := tx.Model(&Incident{}).Where("id = ?", target.ID).Updates(fields).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	if err := tx.Model(&Incident{}).Where("id = ?", source.ID).Update("merged_into_id", target.ID).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	source.MergedIntoID = &target.ID
+	if err := tx.Model(&IncidentHashAlias{}).Create(&IncidentHashAlias{IncidentID: target.ID, Hash: source.Hash}).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	if err := unindexIncident(ctx, source.UUID); err != nil {
+		return err
+	}
+	// the source's comments now belong to the target
+	if err := indexIncident(ctx, target.ID); err != nil {
+		return err
+	}
+
+	if err := recordIncidentEvent(ctx, &IncidentEvent{
+		IncidentID: source.ID,
+		Type:       IncidentEventMerged,
+		Field:      utility.Pointer("mergedInto"),
+		OldValue:   &source.Hash,
+		NewValue:   &target.UUID,
+	}); err != nil {
+		return err
+	}
+	return recordIncidentEvent(ctx, &IncidentEvent{
+		IncidentID: target.ID,
+		Type:       IncidentEventMerged,
+		OldValue:   &source.Hash,
+		NewValue:   &source.Summary,
+	})
+}
+
+// Link an incident to another with a typed relationship, such as one incident being caused by another
+func CreateIncidentLink(ctx *gin.Context, incident *Incident, linked *Incident, linkType string) (*IncidentLink, error) {
+	if incident.ID == linked.ID {
+		ctx.Set("errorCode", http.StatusBadRequest)
+		return nil, errors.New("an incident cannot be linked to itself")
+	}
+	for _, link := range incident.Links {
+		if link.LinkedIncidentID == linked.ID && link.Type == linkType {
+			ctx.Set("errorCode", http.StatusConflict)
+			return nil, fmt.Errorf("incident is already linked as '%s'", linkType)
+		}
+	}
+	createdBy := ctx.MustGet("user").(*User)
+	link := &IncidentLink{
+		IncidentID:       incident.ID,
+		LinkedIncidentID: linked.ID,
+		Type:             linkType,
+		CreatedByID:      &createdBy.ID,
+		CreatedByName:    &createdBy.Name,
+		CreatedAt:        time.Now(),
+	}
+	if err := GetDBTransaction(ctx).Model(&IncidentLink{}).Create(link).Error; err != nil {
+		return nil, handleError(ctx, err)
+	}
+	if err := recordIncidentEvent(ctx, &IncidentEvent{
+		IncidentID: incident.ID,
+		Type:       IncidentEventLinkAdded,
+		Field:      &linkType,
+		NewValue:   &linked.UUID,
+	}); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+func DeleteIncidentLink(ctx *gin.Context, incident *Incident, linkUUID string) error {
+	var link *IncidentLink = nil
+	for _, l := range incident.Links {
+		if l.UUID == linkUUID {
+			link = &l
+			break
+		}
+	}
+	if link == nil {
+		ctx.Set("errorCode", http.StatusNotFound)
+		return errors.New("link not found")
+	}
+	if err := GetDBTransaction(ctx).Model(&IncidentLink{}).Where("id = ?", link.ID).Delete(&IncidentLink{}).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	return recordIncidentEvent(ctx, &IncidentEvent{
+		IncidentID: incident.ID,
+		Type:       IncidentEventLinkRemoved,
+		Field:      &link.Type,
+		OldValue:   &link.LinkedIncident.UUID,
+	})
+}
+
+type GetIncidentEventsFilters struct {
+	IncidentID *uint
+	Page       *int
+	PageSize   *int
+}
+
+// Get the events of an incident's timeline, oldest first
+func GetIncidentEvents(ctx *gin.Context, filters GetIncidentEventsFilters) ([]*IncidentEvent, int64, error) {
+	tx := GetDBTransaction(ctx).Model(&IncidentEvent{}).Preload("Actor")
+	events := make([]*IncidentEvent, 0)
+
+	if filters.IncidentID != nil {
+		tx = tx.Where("incident_id = ?", *filters.IncidentID)
+	}
+
+	var count int64
+	tx.Count(&count)
+	tx = tx.Order("created_at ASC").Order("id ASC")
+	if filters.PageSize != nil {
+		tx = tx.Limit(*filters.PageSize)
+		if filters.Page != nil {
+			tx = tx.Offset(*filters.PageSize * (*filters.Page - 1))
+		}
+	}
+
+	tx = tx.Find(&events)
+	if tx.Error != nil {
+		return nil, -1, handleError(ctx, tx.Error)
+	}
+	return events, count, nil
+}
+
+// Get the user making the request, or nil for background jobs
+func actingUser(ctx *gin.Context) *User {
+	if user, exists := ctx.Get("user"); exists {
+		return user.(*User)
+	}
+	return nil
+}
+
+// Append an event to an incident's timeline, with the requesting user as the actor
+func recordIncidentEvent(ctx *gin.Context, event *IncidentEvent) error {
+	if user := actingUser(ctx); user != nil {
+		event.ActorID = &user.ID
+		event.ActorName = &user.Name
+	}
+	if err := GetDBTransaction(ctx).Model(&IncidentEvent{}).Create(event).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	// anything worth an event changes the incident, so its ETag must change too
+	return bumpVersion(ctx, &Incident{}, event.IncidentID)
+}
+
+// Record a change to one of an incident's fields, if the value actually changed
+func recordIncidentFieldChange(ctx *gin.Context, incidentID uint, field string, oldValue, newValue any, automated bool) error {
+	oldString := fmt.Sprint(oldValue)
+	newString := fmt.Sprint(newValue)
+	if oldString == newString {
+		return nil
+	}
+	return recordIncidentEvent(ctx, &IncidentEvent{
+		IncidentID: incidentID,
+		Type:       IncidentEventFieldChanged,
+		Field:      &field,
+		OldValue:   &oldString,
+		NewValue:   &newString,
+		Automated:  automated,
+	})
+}
+
+// The size of the file and function columns of a stack frame, which longer values are cut down to
+const maxStackFrameFieldLength = 500
+
+// Parse the incident's raw stack trace and store its frames, marking the most likely culprit
+func addIncidentStackFrames(ctx *gin.Context, incident *Incident) error {
+	language, parsed := utility.ParseStackTrace(incident.StackTrace)
+	if len(parsed) == 0 {
+		return nil
+	}
+	frames := make([]IncidentStackFrame, 0)
+	for i, frame := range parsed {
+		frames = append(frames, IncidentStackFrame{
+			IncidentID: incident.ID,
+			Position:   uint(i),
+			File:       utility.Truncate(frame.File, maxStackFrameFieldLength),
+			Function:   utility.Truncate(frame.Function, maxStackFrameFieldLength),
+			Line:       frame.Line,
+			Column:     frame.Column,
+			InApp:      frame.InApp,
+			Culprit:    frame.Culprit,
+		})
+	}
+	tx := GetDBTransaction(ctx)
+	if err := tx.Model(&IncidentStackFrame{}).CreateInBatches(frames, 100).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	if err := tx.Model(&Incident{}).Where("id = ?", incident.ID).Update("stack_language", language).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	incident.StackLanguage = language
+	incident.StackFrames = frames
+	return nil
+}
+
+func addIncidentHosts(ctx *gin.Context, incident *Incident, hostUUIDs []string) error {
+	if len(hostUUIDs) == 0 {
+		return nil
+	}
 	hosts := make([]*IncidentHost, 0)
+	// a reporter names the hosts it runs on, whether or not it can see them
 	hs, count, err := GetHosts(ctx, GetHostsFilters{
-		UUIDs:    body.HostsAffected,
-		PageSize: utility.Pointer(len(body.HostsAffected)),
+		UUIDs:    hostUUIDs,
+		PageSize: utility.Pointer(len(hostUUIDs)),
+		Unscoped: true,
 	})
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if int(count) != len(body.HostsAffected) {
+	if int(count) != len(hostUUIDs) {
 		ctx.Set("errorCode", http.StatusBadRequest)
-		return nil, errors.New("one or more hosts not found")
+		return errors.New("one or more hosts not found")
 	}
 	for _, host := range hs {
 		hosts = append(hosts, &IncidentHost{
@@ -194,23 +1446,37 @@ func CreateIncident(ctx *gin.Context, body *utility.IncidentPostRequestBodySchem
 			HostMachineID: host.ID,
 		})
 	}
-	tx = GetDBTransaction(ctx).Model(&IncidentHost{}).CreateInBatches(hosts, 1)
+	tx := GetDBTransaction(ctx).Model(&IncidentHost{}).CreateInBatches(hosts, 1)
 	if tx.Error != nil {
-		return nil, handleError(ctx, tx.Error)
+		return handleError(ctx, tx.Error)
 	}
+	for _, host := range hs {
+		if err := recordIncidentEvent(ctx, &IncidentEvent{
+			IncidentID: incident.ID,
+			Type:       IncidentEventHostAdded,
+			NewValue:   &host.Hostname,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// insert resolution teams - m2m
+func addIncidentResolutionTeams(ctx *gin.Context, incident *Incident, teamUUIDs []string) error {
+	if len(teamUUIDs) == 0 {
+		return nil
+	}
 	teams := make([]*IncidentResolutionTeam, 0)
 	ts, count, err := GetTeams(ctx, GetTeamsFilters{
-		UUIDs:    body.ResolutionTeams,
-		PageSize: utility.Pointer(len(body.ResolutionTeams)),
+		UUIDs:    teamUUIDs,
+		PageSize: utility.Pointer(len(teamUUIDs)),
 	})
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if int(count) != len(body.ResolutionTeams) {
+	if int(count) != len(teamUUIDs) {
 		ctx.Set("errorCode", http.StatusBadRequest)
-		return nil, errors.New("one or more teams not found")
+		return errors.New("one or more teams not found")
 	}
 	for _, team := range ts {
 		teams = append(teams, &IncidentResolutionTeam{
@@ -218,29 +1484,65 @@ func CreateIncident(ctx *gin.Context, body *utility.IncidentPostRequestBodySchem
 			TeamID:     team.ID,
 		})
 	}
-	tx = GetDBTransaction(ctx).Model(&IncidentResolutionTeam{}).CreateInBatches(teams, 1)
+	tx := GetDBTransaction(ctx).Model(&IncidentResolutionTeam{}).CreateInBatches(teams, 1)
 	if tx.Error != nil {
-		return nil, handleError(ctx, tx.Error)
+		return handleError(ctx, tx.Error)
 	}
-	return incident, nil
+	for _, team := range ts {
+		if err := recordIncidentEvent(ctx, &IncidentEvent{
+			IncidentID: incident.ID,
+			Type:       IncidentEventTeamAdded,
+			NewValue:   &team.Name,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func UpdateIncident(ctx *gin.Context, filters GetIncidentsFilters, incident *Incident) error {
 	tx := GetDBTransaction(ctx)
+	if err := claimVersion(ctx, &Incident{}, incident.ID, incident.Version); err != nil {
+		return err
+	}
+	old, err := GetIncident(ctx, incident.UUID)
+	if err != nil {
+		return err
+	}
 
 	temp1 := tx.Model(&Incident{})
 	if filters.UUID != nil {
 		temp1 = temp1.Where("uuid = ?", *filters.UUID)
 	}
 	fields := map[string]any{
-		"summary":        incident.Summary,
-		"description":    incident.Description,
-		"resolved_at":    incident.ResolvedAt,
-		"resolved_by_id": incident.ResolvedByID,
+		"summary":     incident.Summary,
+		"description": incident.Description,
+		"severity":    incident.Severity,
+		"impact":      incident.Impact,
+		"urgency":     incident.Urgency,
+		"priority":    incident.Priority,
 	}
 	if err := temp1.Updates(fields).Error; err != nil {
 		return handleError(ctx, err)
 	}
+	oldFields := map[string]any{
+		"summary":     old.Summary,
+		"description": old.Description,
+		"severity":    old.Severity,
+		"impact":      old.Impact,
+		"urgency":     old.Urgency,
+		"priority":    old.Priority,
+	}
+	for _, field := range []string{"summary", "description", "severity", "impact", "urgency", "priority"} {
+		if err := recordIncidentFieldChange(ctx, incident.ID, field, oldFields[field], fields[field], false); err != nil {
+			return err
+		}
+	}
+	if old.Summary != incident.Summary || old.Description != incident.Description {
+		if err := indexIncident(ctx, incident.ID); err != nil {
+			return err
+		}
+	}
 
 	// replace hosts - m2m
 	hosts := make([]IncidentHost, 0)
@@ -258,6 +1560,17 @@ func UpdateIncident(ctx *gin.Context, filters GetIncidentsFilters, incident *Inc
 			return handleError(ctx, err)
 		}
 	}
+	oldHosts := make([]string, 0)
+	for _, host := range old.HostsAffected {
+		oldHosts = append(oldHosts, host.Hostname)
+	}
+	newHosts := make([]string, 0)
+	for _, host := range incident.HostsAffected {
+		newHosts = append(newHosts, host.Hostname)
+	}
+	if err := recordIncidentSetChanges(ctx, incident.ID, oldHosts, newHosts, IncidentEventHostAdded, IncidentEventHostRemoved); err != nil {
+		return err
+	}
 
 	// replace resolution teams - m2m
 	teams := make([]IncidentResolutionTeam, 0)
@@ -276,6 +1589,39 @@ func UpdateIncident(ctx *gin.Context, filters GetIncidentsFilters, incident *Inc
 			return handleError(ctx, tx.Error)
 		}
 	}
+	oldTeams := make([]string, 0)
+	for _, team := range old.ResolutionTeams {
+		oldTeams = append(oldTeams, team.Name)
+	}
+	newTeams := make([]string, 0)
+	for _, team := range incident.ResolutionTeams {
+		newTeams = append(newTeams, team.Name)
+	}
+	if err := recordIncidentSetChanges(ctx, incident.ID, oldTeams, newTeams, IncidentEventTeamAdded, IncidentEventTeamRemoved); err != nil {
+		return err
+	}
+	// the assignee may not be in any of the teams which are left
+	return unassignIneligibleAssignees(ctx, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("tbl_incident.id = ?", incident.ID)
+	})
+}
+
+// Record an event for every value added to or removed from a set of values, such as an incident's hosts or teams
+func recordIncidentSetChanges(ctx *gin.Context, incidentID uint, oldValues, newValues []string, addedType, removedType string) error {
+	for _, value := range newValues {
+		if !slices.Contains(oldValues, value) {
+			if err := recordIncidentEvent(ctx, &IncidentEvent{IncidentID: incidentID, Type: addedType, NewValue: &value}); err != nil {
+				return err
+			}
+		}
+	}
+	for _, value := range oldValues {
+		if !slices.Contains(newValues, value) {
+			if err := recordIncidentEvent(ctx, &IncidentEvent{IncidentID: incidentID, Type: removedType, OldValue: &value}); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -304,15 +1650,37 @@ func CreateIncidentComment(ctx *gin.Context, comment *IncidentComment) (*Inciden
 	if tx.Error != nil {
 		return nil, handleError(ctx, tx.Error)
 	}
+	if err := recordIncidentEvent(ctx, &IncidentEvent{
+		IncidentID: comment.IncidentID,
+		Type:       IncidentEventCommentAdded,
+		NewValue:   &comment.Comment,
+		Automated:  comment.Automated,
+	}); err != nil {
+		return nil, err
+	}
+	if err := indexIncident(ctx, comment.IncidentID); err != nil {
+		return nil, err
+	}
 	return comment, nil
 }
 
 func DeleteIncidentComment(ctx *gin.Context, uuid string) error {
+	comment := &IncidentComment{}
+	if err := GetDBTransaction(ctx).Model(&IncidentComment{}).Where("uuid = ?", uuid).First(comment).Error; err != nil {
+		return handleError(ctx, err)
+	}
 	tx := GetDBTransaction(ctx).Model(&IncidentComment{})
 	tx = tx.Where("uuid = ?", uuid)
 	tx = tx.Delete(&IncidentComment{})
 	if tx.Error != nil {
 		return handleError(ctx, tx.Error)
 	}
-	return nil
+	if err := recordIncidentEvent(ctx, &IncidentEvent{
+		IncidentID: comment.IncidentID,
+		Type:       IncidentEventCommentDeleted,
+		OldValue:   &comment.Comment,
+	}); err != nil {
+		return err
+	}
+	return indexIncident(ctx, comment.IncidentID)
 }
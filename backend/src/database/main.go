@@ -14,6 +14,7 @@ import (
 	mysql2 "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/schema"
 )
 
@@ -85,6 +86,29 @@ var (
 			Required:   true,
 		},
 	}
+	// inserted whenever they are missing, so that every deployment has them
+	defaultRoles []*Role = []*Role{
+		{
+			UUID:        AdminRoleUUID,
+			Name:        "admin",
+			Description: "Everything, held by every user with the admin flag",
+			Permissions: rolePermissions(Permissions...),
+		},
+		{
+			UUID:        MemberRoleUUID,
+			Name:        "member",
+			Description: "Work on incidents and see the teams, hosts and settings, held by every user",
+			Default:     true,
+			Permissions: rolePermissions(
+				PermissionIncidentRead,
+				PermissionIncidentWrite,
+				PermissionIncidentResolve,
+				PermissionHostRead,
+				PermissionSettingsRead,
+				PermissionTeamRead,
+			),
+		},
+	}
 	defaultTeams []*Team = []*Team{
 		{
 			UUID: "574b5d6a-1fcd-43bf-bb31-7e870ca458d4",
@@ -135,6 +159,18 @@ var (
 			TeamID:   1,
 		},
 	}
+	// ITIL style matrix, 1 is the highest impact/urgency/priority
+	defaultPriorityMatrix []*PriorityMatrixEntry = []*PriorityMatrixEntry{
+		{Impact: 1, Urgency: 1, Priority: 1},
+		{Impact: 1, Urgency: 2, Priority: 2},
+		{Impact: 1, Urgency: 3, Priority: 3},
+		{Impact: 2, Urgency: 1, Priority: 2},
+		{Impact: 2, Urgency: 2, Priority: 3},
+		{Impact: 2, Urgency: 3, Priority: 4},
+		{Impact: 3, Urgency: 1, Priority: 3},
+		{Impact: 3, Urgency: 2, Priority: 4},
+		{Impact: 3, Urgency: 3, Priority: 5},
+	}
 	defaultIncidents []*Incident = []*Incident{
 		{
 			UUID:         "eddb82c0-50fd-4faa-adc0-95db00df52da",
@@ -145,6 +181,13 @@ var (
 			ResolvedAt:   nil,
 			ResolvedByID: nil,
 			Hash:         "b10c4cf0a834c7f5b07f395c633d906fe7d969b9",
+			FirstSeenAt:  time.Now(),
+			LastSeenAt:   time.Now(),
+			Status:       IncidentStatusOpen,
+			Severity:     2,
+			Impact:       1,
+			Urgency:      2,
+			Priority:     2,
 		},
 		{
 			UUID:         "30daaadd-596c-4676-9194-c8f48a654931",
@@ -154,6 +197,13 @@ var (
 			ResolvedAt:   utility.Pointer(time.Now()),
 			ResolvedByID: utility.Pointer(uint(2)),
 			Hash:         "5326ca01ce201c73f9b5d112c7d4c6eb0d14abbc",
+			FirstSeenAt:  time.Now().Add(time.Hour * -3),
+			LastSeenAt:   time.Now().Add(time.Hour * -1),
+			Status:       IncidentStatusResolved,
+			Severity:     4,
+			Impact:       3,
+			Urgency:      2,
+			Priority:     4,
 		},
 	}
 	defaultIncidentComments []*IncidentComment = []*IncidentComment{
@@ -161,7 +211,7 @@ var (
 			UUID:          "3ca620e9-b90f-492d-9597-5677f762ec00",
 			Comment:       "This is a test comment",
 			IncidentID:    2,
-			CommentedByID: 1,
+			CommentedByID: utility.Pointer(uint(1)),
 			CommentedAt:   time.Now().Add(time.Hour * -2),
 		},
 	}
@@ -213,8 +263,14 @@ func Connect() error {
 	if err != nil {
 		return err
 	}
+	if err := registerIncidentEventCallbacks(db); err != nil {
+		return err
+	}
 	db = db.Session(&gorm.Session{Context: db.Statement.Context, NewDB: true})
 	migrate(db)
+	if err := openSearchIndex(db); err != nil {
+		return err
+	}
 	conn = db
 	return nil
 }
@@ -226,17 +282,114 @@ func GetDBConn() *gorm.DB {
 	return conn
 }
 
+// Store the names of the users in incidents' history, which was created before they were kept, and make deleting a
+// user clear their references from the history rather than being refused
+func keepHistoryOfDeletedUsers(tx *gorm.DB) error {
+	references := []struct {
+		model  any
+		table  string
+		fields map[string]string // relation name to the ID and name columns
+	}{
+		{&IncidentStatusChange{}, "tbl_incident_status_change", map[string]string{"ChangedBy": "changed_by"}},
+		{&IncidentAssignment{}, "tbl_incident_assignment", map[string]string{"User": "user", "ChangedBy": "changed_by"}},
+		{&IncidentEvent{}, "tbl_incident_event", map[string]string{"Actor": "actor"}},
+		{&IncidentLink{}, "tbl_incident_link", map[string]string{"CreatedBy": "created_by"}},
+	}
+	for _, reference := range references {
+		for _, column := range reference.fields {
+			if err := tx.Exec(fmt.Sprintf("UPDATE %[1]s SET %[2]s_name = (SELECT name FROM tbl_user WHERE tbl_user.id = %[1]s.%[2]s_id) WHERE %[2]s_name IS NULL AND %[2]s_id IS NOT NULL", reference.table, column)).Error; err != nil {
+				return err
+			}
+		}
+		var constraints []string
+		if err := tx.Raw("SELECT constraint_name FROM information_schema.referential_constraints WHERE constraint_schema = DATABASE() AND table_name = ? AND referenced_table_name = 'tbl_user' AND delete_rule <> 'SET NULL'", reference.table).Scan(&constraints).Error; err != nil {
+			return err
+		}
+		if len(constraints) == 0 {
+			continue
+		}
+		for _, constraint := range constraints {
+			if err := tx.Migrator().DropConstraint(reference.model, constraint); err != nil {
+				return err
+			}
+		}
+		for field := range reference.fields {
+			if tx.Migrator().HasConstraint(reference.model, field) {
+				continue
+			}
+			if err := tx.Migrator().CreateConstraint(reference.model, field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Make a column which was made not null nullable, as its field now is
+func allowNullColumn(tx *gorm.DB, model any, column string, field string) error {
+	columns, err := tx.Migrator().ColumnTypes(model)
+	if err != nil {
+		return err
+	}
+	for _, columnType := range columns {
+		if nullable, ok := columnType.Nullable(); ok && !nullable && columnType.Name() == column {
+			return tx.Migrator().AlterColumn(model, field)
+		}
+	}
+	return nil
+}
+
+// Add when incidents were first and last seen to incidents made before occurrences were counted, which were only seen
+// when they were made. The columns are added nullable and filled in, and AutoMigrate then makes them not null, as a not
+// null column added to rows which already exist would be given a zero date
+func addIncidentSeenColumns(tx *gorm.DB) error {
+	if !tx.Migrator().HasTable(&Incident{}) {
+		return nil
+	}
+	for _, column := range []string{"first_seen_at", "last_seen_at"} {
+		if tx.Migrator().HasColumn(&Incident{}, column) {
+			continue
+		}
+		if err := tx.Exec(fmt.Sprintf("ALTER TABLE tbl_incident ADD %s datetime(3) NULL", column)).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&Incident{}).Where(column+" IS NULL").UpdateColumn(column, gorm.Expr("created_at")).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func migrate(conn *gorm.DB) {
 	tx := conn.Begin()
 	structs := []interface{}{
 		Team{},
 		User{},
 		TeamUser{},
+		Role{},
+		RolePermission{},
+		UserRoleAssignment{},
+		TeamRoleAssignment{},
+		APIKey{},
+		APIKeyScope{},
+		Session{},
+		OIDCLogin{},
+		SigningKey{},
+		GroupMapping{},
 		Provider{},
 		ProviderField{},
+		PriorityMatrixEntry{},
 		HostMachine{},
 		Incident{},
 		IncidentComment{},
+		IncidentStatusChange{},
+		IncidentAssignment{},
+		IncidentEvent{},
+		IncidentHashAlias{},
+		IncidentStackFrame{},
+		FingerprintRule{},
+		IncidentLink{},
+		IncidentResponder{},
 		IncidentHost{},
 		IncidentResolutionTeam{},
 	}
@@ -248,10 +401,37 @@ func migrate(conn *gorm.DB) {
 		}
 	}
 	log.Default().Println("Migrating database")
+	if err := addIncidentSeenColumns(tx); err != nil {
+		tx.Rollback()
+		panic(err)
+	}
 	if err := tx.AutoMigrate(structs...); err != nil {
 		tx.Rollback()
 		panic(err)
 	}
+	// comments were all left by users before the backend left automated ones, and AutoMigrate never makes a column nullable
+	if err := allowNullColumn(tx, &IncidentComment{}, "commented_by_id", "CommentedByID"); err != nil {
+		tx.Rollback()
+		panic(err)
+	}
+	// incidents resolved before they had a status were given the default when the column was added
+	if err := tx.Model(&Incident{}).Where("resolved_at IS NOT NULL AND status <> ?", IncidentStatusResolved).UpdateColumn("status", IncidentStatusResolved).Error; err != nil {
+		tx.Rollback()
+		panic(err)
+	}
+	// signing keys made before they were published ahead of signing signed from when they were made
+	if err := tx.Model(&SigningKey{}).Where("signs_from IS NULL").UpdateColumn("signs_from", gorm.Expr("created_at")).Error; err != nil {
+		tx.Rollback()
+		panic(err)
+	}
+	if err := keepHistoryOfDeletedUsers(tx); err != nil {
+		tx.Rollback()
+		panic(err)
+	}
+	if err := insertDefaultRoles(tx); err != nil {
+		tx.Rollback()
+		panic(err)
+	}
 	if gin.IsDebugging() {
 		log.Default().Println("Inserting default data")
 		if err := insertDefaultData(tx); err != nil {
@@ -269,6 +449,11 @@ func migrate(conn *gorm.DB) {
 		tx.Rollback()
 		panic(err)
 	}
+	err = tx.SetupJoinTable(&Incident{}, "Responders", &IncidentResponder{})
+	if err != nil {
+		tx.Rollback()
+		panic(err)
+	}
 	tx = tx.Commit()
 	if tx.Error != nil {
 		tx.Rollback()
@@ -276,13 +461,60 @@ func migrate(conn *gorm.DB) {
 	}
 }
 
+func rolePermissions(permissions ...string) []RolePermission {
+	rolePermissions := make([]RolePermission, 0)
+	for _, permission := range permissions {
+		rolePermissions = append(rolePermissions, RolePermission{Permission: permission})
+	}
+	return rolePermissions
+}
+
+// Insert the built in roles which do not exist yet, leaving any which have been changed alone. The admin role cannot be
+// changed, so it is given any permissions added since it was inserted
+func insertDefaultRoles(tx *gorm.DB) error {
+	for _, role := range defaultRoles {
+		existing := make([]*Role, 0)
+		if err := tx.Model(&Role{}).Where("uuid = ?", role.UUID).Find(&existing).Error; err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			if role.UUID == AdminRoleUUID {
+				permissions := rolePermissions(Permissions...)
+				for i := range permissions {
+					permissions[i].RoleID = existing[0].ID
+				}
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&permissions).Error; err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		log.Default().Printf("Inserting the %s role\n", role.Name)
+		if err := tx.Create(role).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func insertDefaultData(tx *gorm.DB) error {
+	users := make([]*User, 0, len(defaultUsers))
+	for _, user := range defaultUsers {
+		password, err := hashPassword(user.Password)
+		if err != nil {
+			return err
+		}
+		hashed := *user
+		hashed.Password = password
+		users = append(users, &hashed)
+	}
 	data := []any{
 		defaultTeams,
-		defaultUsers,
+		users,
 		defaultTeamUsers,
 		defaultProviders,
 		defaultProvidersFields,
+		defaultPriorityMatrix,
 		defaultHosts,
 		defaultIncidents,
 		defaultIncidentComments,
@@ -305,6 +537,21 @@ func GetDBTransaction(ctx *gin.Context) *gorm.DB {
 	return transaction
 }
 
+// Run a background job in a transaction, with a context the database functions can use as they would that of a request.
+// The transaction is committed unless the job fails
+func RunJob(job func(ctx *gin.Context) error) error {
+	ctx := &gin.Context{}
+	err := GetDBConn().Transaction(func(tx *gorm.DB) error {
+		ctx.Set("transaction", tx)
+		tx.Set("context", ctx)
+		return job(ctx)
+	})
+	if err == nil {
+		ApplySearchIndexOperations(ctx)
+	}
+	return err
+}
+
 func GetContext(tx *gorm.DB) *gin.Context {
 	context, exists := tx.Get("context")
 	if !exists {
@@ -342,3 +589,40 @@ func handleError(ctx *gin.Context, err error) error {
 		}
 	}
 }
+
+// Bump the version of a row if it is still at the version the caller read, failing with a 412 error code if another
+// request changed it since. The row stays locked until the transaction ends, so no other request can change it in between
+func claimVersion(ctx *gin.Context, model any, id uint, version uint) error {
+	tx := GetDBTransaction(ctx).Model(model).Where("id = ? AND version = ?", id, version).UpdateColumn("version", gorm.Expr("version + 1"))
+	if tx.Error != nil {
+		return handleError(ctx, tx.Error)
+	}
+	if tx.RowsAffected == 0 {
+		ctx.Set("errorCode", http.StatusPreconditionFailed)
+		return errors.New("the resource was changed by another request, get it again and retry")
+	}
+	return nil
+}
+
+// Bump the version of a row whatever version it is at, for changes which are not made through a claimed version
+func bumpVersion(ctx *gin.Context, model any, id uint) error {
+	if err := GetDBTransaction(ctx).Model(model).Where("id = ?", id).UpdateColumn("version", gorm.Expr("version + 1")).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	return nil
+}
+
+// Undo everything the request has changed so far, carrying on in a new transaction for whatever it does next
+func RollbackDBTransaction(ctx *gin.Context) error {
+	if err := GetDBTransaction(ctx).Rollback().Error; err != nil {
+		return handleError(ctx, err)
+	}
+	DiscardSearchIndexOperations(ctx)
+	tx := GetDBConn().Begin()
+	if tx.Error != nil {
+		return handleError(ctx, tx.Error)
+	}
+	ctx.Set("transaction", tx)
+	tx.Set("context", ctx)
+	return nil
+}
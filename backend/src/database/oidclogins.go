@@ -0,0 +1,78 @@
+package database
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A login which has been sent to the OpenID Connect identity provider and not come back yet, found by the hash of the
+// state sent with it so that the table does not hold anything which finishes a login
+type OIDCLogin struct {
+	ID        uint   `gorm:"column:id;primaryKey;autoIncrement"`
+	StateHash string `gorm:"column:state_hash;size:64;unique;not null"`
+	// the PKCE code verifier, which only this server knows, so a stolen code cannot be swapped for tokens
+	Verifier  string    `gorm:"column:verifier;size:128;not null"`
+	Nonce     string    `gorm:"column:nonce;size:64;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;not null"`
+}
+
+// The table is named for OIDC as one word, where the naming strategy would split it around ID
+func (OIDCLogin) TableName() string {
+	return "tbl_oidc_login"
+}
+
+// Keep a login started with a state until it comes back, dropping the logins older than the lifetime and then the
+// oldest logins so that no more than the limit are kept
+func CreateOIDCLogin(ctx *gin.Context, login *OIDCLogin, state string, lifetime time.Duration, limit int) error {
+	tx := GetDBTransaction(ctx)
+	if err := tx.Where("created_at < ?", time.Now().Add(-lifetime)).Delete(&OIDCLogin{}).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	var count int64
+	if err := tx.Model(&OIDCLogin{}).Count(&count).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	if count >= int64(limit) {
+		var oldest []uint
+		if err := tx.Model(&OIDCLogin{}).Order("id ASC").Limit(int(count)-limit+1).Pluck("id", &oldest).Error; err != nil {
+			return handleError(ctx, err)
+		}
+		if err := tx.Where("id IN (?)", oldest).Delete(&OIDCLogin{}).Error; err != nil {
+			return handleError(ctx, err)
+		}
+	}
+
+	login.StateHash = hashSecret(state)
+	if err := tx.Create(login).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	return nil
+}
+
+// Get the login started with a state and forget it, so that it can only be finished once, failing with a 403 if it
+// is unknown or older than the lifetime
+func TakeOIDCLogin(ctx *gin.Context, state string, lifetime time.Duration) (*OIDCLogin, error) {
+	tx := GetDBTransaction(ctx)
+	var logins []*OIDCLogin
+	if err := tx.Model(&OIDCLogin{}).Where("state_hash = ?", hashSecret(state)).Find(&logins).Error; err != nil {
+		return nil, handleError(ctx, err)
+	}
+	if len(logins) == 0 {
+		ctx.Set("errorCode", http.StatusForbidden)
+		return nil, errors.New("login state is unknown or has expired")
+	}
+	login := logins[0]
+	// of two requests finishing the same login, only the one which deletes it carries on
+	deleted := tx.Where("id = ?", login.ID).Delete(&OIDCLogin{})
+	if deleted.Error != nil {
+		return nil, handleError(ctx, deleted.Error)
+	}
+	if deleted.RowsAffected == 0 || time.Since(login.CreatedAt) > lifetime {
+		ctx.Set("errorCode", http.StatusForbidden)
+		return nil, errors.New("login state is unknown or has expired")
+	}
+	return login, nil
+}
@@ -0,0 +1,214 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// The opaque cursors of the pages either side of the current page, or nil if there is no such page
+type PageCursors struct {
+	Next *string
+	Prev *string
+}
+
+// The decoded form of an opaque cursor, holding the sort values of the row the page starts after (or before)
+type pageCursor struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+	Before bool              `json:"b"`
+}
+
+type sortColumn struct {
+	field *schema.Field
+	name  string
+	desc  bool
+}
+
+// Keyset pagination over a sorted list. Every sort has the primary key appended as a tie-breaker, so rows
+// are always in a total order and a cursor points between two rows regardless of rows inserted or deleted since
+type paginator struct {
+	table    string
+	sort     string
+	columns  []sortColumn
+	cursor   *pageCursor
+	values   []any
+	page     *int
+	pageSize *int
+}
+
+// Build a paginator for a model, where sortFields maps the name of each field which can be sorted by to its column.
+// Fails with a 400 error code if a field cannot be sorted by, or the cursor is malformed or was made for a different sort
+func newPaginator(ctx *gin.Context, model any, sortFields map[string]string, sort []SortKey, cursor *string, page *int, pageSize *int) (*paginator, error) {
+	stmt := &gorm.Statement{DB: GetDBTransaction(ctx)}
+	if err := stmt.Parse(model); err != nil {
+		return nil, handleError(ctx, err)
+	}
+	p := &paginator{table: stmt.Schema.Table, page: page, pageSize: pageSize}
+
+	names := make([]string, 0)
+	for _, key := range sort {
+		column, ok := sortFields[key.Field]
+		if !ok {
+			ctx.Set("errorCode", http.StatusBadRequest)
+			return nil, fmt.Errorf("cannot sort by '%s'", key.Field)
+		}
+		p.columns = append(p.columns, sortColumn{field: stmt.Schema.LookUpField(column), name: column, desc: key.Desc})
+		if key.Desc {
+			names = append(names, "-"+key.Field)
+		} else {
+			names = append(names, key.Field)
+		}
+	}
+	p.columns = append(p.columns, sortColumn{field: stmt.Schema.PrioritizedPrimaryField, name: stmt.Schema.PrioritizedPrimaryField.DBName})
+	p.sort = strings.Join(names, ",")
+
+	if cursor != nil {
+		decoded, err := p.decodeCursor(*cursor)
+		if err != nil {
+			ctx.Set("errorCode", http.StatusBadRequest)
+			return nil, err
+		}
+		p.cursor = decoded
+		for i, column := range p.columns {
+			value := reflect.New(column.field.FieldType)
+			if err := json.Unmarshal(decoded.Values[i], value.Interface()); err != nil {
+				ctx.Set("errorCode", http.StatusBadRequest)
+				return nil, errors.New("cursor query parameter is invalid")
+			}
+			p.values = append(p.values, value.Elem().Interface())
+		}
+	}
+	return p, nil
+}
+
+// Order the query and restrict it to the requested page. One row more than the page size is fetched,
+// so that finish can tell whether there is another page
+func (p *paginator) apply(tx *gorm.DB) *gorm.DB {
+	before := p.cursor != nil && p.cursor.Before
+	for _, column := range p.columns {
+		direction := "ASC"
+		// paging backwards reads the rows in reverse, and finish puts them back in order
+		if column.desc != before {
+			direction = "DESC"
+		}
+		tx = tx.Order(fmt.Sprintf("%s.%s %s", p.table, column.name, direction))
+	}
+
+	if p.cursor != nil {
+		condition, args := p.cursorCondition()
+		tx = tx.Where(condition, args...)
+	}
+	if p.pageSize != nil {
+		tx = tx.Limit(*p.pageSize + 1)
+		if p.cursor == nil && p.page != nil {
+			tx = tx.Offset(*p.pageSize * (*p.page - 1))
+		}
+	}
+	return tx
+}
+
+// Build the condition for rows after the cursor in the sort order, which for sorting by a then b is
+// (a > ?) OR (a = ? AND b > ?), with the comparisons flipped for descending keys and when paging backwards
+func (p *paginator) cursorCondition() (string, []any) {
+	conditions := make([]string, 0)
+	args := make([]any, 0)
+	for i, column := range p.columns {
+		parts := make([]string, 0)
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s.%s = ?", p.table, p.columns[j].name))
+			args = append(args, p.values[j])
+		}
+		operator := ">"
+		if column.desc != p.cursor.Before {
+			operator = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s.%s %s ?", p.table, column.name, operator))
+		args = append(args, p.values[i])
+		conditions = append(conditions, fmt.Sprintf("(%s)", strings.Join(parts, " AND ")))
+	}
+	return fmt.Sprintf("(%s)", strings.Join(conditions, " OR ")), args
+}
+
+// Trim the extra row fetched by apply, put rows read backwards back into order and fill in the cursors
+// of the neighbouring pages. rows must be a pointer to the slice the query was read into
+func (p *paginator) finish(ctx *gin.Context, rows any, cursors *PageCursors) error {
+	slice := reflect.ValueOf(rows).Elem()
+	more := p.pageSize != nil && slice.Len() > *p.pageSize
+	if more {
+		slice.Set(slice.Slice(0, *p.pageSize))
+	}
+	before := p.cursor != nil && p.cursor.Before
+	if before {
+		swap := reflect.Swapper(slice.Interface())
+		for i, j := 0, slice.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+	if cursors == nil || slice.Len() == 0 {
+		return nil
+	}
+
+	hasNext := more
+	hasPrev := p.cursor != nil || (p.page != nil && *p.page > 1)
+	if before {
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		cursor, err := p.encodeCursor(ctx, slice.Index(slice.Len()-1), false)
+		if err != nil {
+			return err
+		}
+		cursors.Next = &cursor
+	}
+	if hasPrev {
+		cursor, err := p.encodeCursor(ctx, slice.Index(0), true)
+		if err != nil {
+			return err
+		}
+		cursors.Prev = &cursor
+	}
+	return nil
+}
+
+func (p *paginator) encodeCursor(ctx *gin.Context, row reflect.Value, before bool) (string, error) {
+	cursor := pageCursor{Sort: p.sort, Values: make([]json.RawMessage, 0), Before: before}
+	for _, column := range p.columns {
+		value, _ := column.field.ValueOf(ctx, reflect.Indirect(row))
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return "", handleError(ctx, err)
+		}
+		cursor.Values = append(cursor.Values, encoded)
+	}
+	encoded, err := json.Marshal(cursor)
+	if err != nil {
+		return "", handleError(ctx, err)
+	}
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+func (p *paginator) decodeCursor(cursor string) (*pageCursor, error) {
+	decoded := &pageCursor{}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || json.Unmarshal(data, decoded) != nil || len(decoded.Values) != len(p.columns) {
+		return nil, errors.New("cursor query parameter is invalid")
+	}
+	if decoded.Sort != p.sort {
+		return nil, errors.New("cursor query parameter was made for a different sort order")
+	}
+	return decoded, nil
+}
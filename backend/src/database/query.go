@@ -0,0 +1,328 @@
+package database
+
+import (
+	"com668-backend/utility"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type incidentQueryFieldType int
+
+const (
+	incidentQueryEnum incidentQueryFieldType = iota
+	incidentQueryLevel
+	incidentQueryNumber
+	incidentQueryBool
+	incidentQueryString
+	incidentQueryTime
+)
+
+type incidentQueryField struct {
+	Type   incidentQueryFieldType
+	Column string
+	// the allowed values of an enum, or the highest value of a level
+	Enum    []string
+	Highest uint64
+	// whether the column can be null, which never matches a term so that negating the term does match it
+	Nullable bool
+}
+
+var (
+	incidentQueryFields map[string]incidentQueryField = map[string]incidentQueryField{
+		"status":      {Type: incidentQueryEnum, Column: "tbl_incident.status", Enum: IncidentStatuses},
+		"severity":    {Type: incidentQueryLevel, Column: "tbl_incident.severity", Highest: 5},
+		"priority":    {Type: incidentQueryLevel, Column: "tbl_incident.priority", Highest: 5},
+		"impact":      {Type: incidentQueryLevel, Column: "tbl_incident.impact", Highest: 3},
+		"urgency":     {Type: incidentQueryLevel, Column: "tbl_incident.urgency", Highest: 3},
+		"occurrences": {Type: incidentQueryNumber, Column: "tbl_incident.occurrence_count"},
+		"regressions": {Type: incidentQueryNumber, Column: "tbl_incident.regression_count"},
+		"regressed":   {Type: incidentQueryBool, Column: "tbl_incident.regressed"},
+		"resolved":    {Type: incidentQueryBool},
+		"hash":        {Type: incidentQueryString},
+		"language":    {Type: incidentQueryString, Column: "tbl_incident.stack_language", Nullable: true},
+		"team":        {Type: incidentQueryString},
+		"host":        {Type: incidentQueryString},
+		"assignee":    {Type: incidentQueryString},
+		"created":     {Type: incidentQueryTime, Column: "tbl_incident.created_at"},
+		"firstSeen":   {Type: incidentQueryTime, Column: "tbl_incident.first_seen_at"},
+		"lastSeen":    {Type: incidentQueryTime, Column: "tbl_incident.last_seen_at"},
+		"resolvedAt":  {Type: incidentQueryTime, Column: "tbl_incident.resolved_at", Nullable: true},
+	}
+	IncidentQueryFields []string = []string{
+		"status", "severity", "priority", "impact", "urgency", "occurrences", "regressions", "regressed", "resolved",
+		"hash", "language", "team", "host", "assignee", "created", "firstSeen", "lastSeen", "resolvedAt",
+	}
+	// -7d, -12h, -30m or -2w
+	relativeTimeRegex *regexp.Regexp    = regexp.MustCompile(`^-(\d+)([mhdw])$`)
+	queryOperatorSQL  map[string]string = map[string]string{
+		utility.QueryOperatorMatch:        "=",
+		utility.QueryOperatorEqual:        "=",
+		utility.QueryOperatorGreater:      ">",
+		utility.QueryOperatorGreaterEqual: ">=",
+		utility.QueryOperatorLess:         "<",
+		utility.QueryOperatorLessEqual:    "<=",
+	}
+)
+
+// Split a parsed incident query into the free text to search for and the structured conditions.
+// Free text is only allowed alongside the top level terms, since the search index cannot be combined with OR or NOT
+func splitIncidentQuery(node utility.QueryNode) (string, utility.QueryNode, error) {
+	switch n := node.(type) {
+	case utility.QueryTextNode:
+		return n.Text, nil, nil
+	case utility.QueryAndNode:
+		texts := make([]string, 0)
+		operands := make([]utility.QueryNode, 0)
+		for _, operand := range n.Operands {
+			if text, ok := operand.(utility.QueryTextNode); ok {
+				texts = append(texts, text.Text)
+				continue
+			}
+			if err := checkNoIncidentQueryText(operand); err != nil {
+				return "", nil, err
+			}
+			operands = append(operands, operand)
+		}
+		if len(operands) == 0 {
+			return strings.Join(texts, " "), nil, nil
+		}
+		return strings.Join(texts, " "), utility.QueryAndNode{Pos: n.Pos, Operands: operands}, nil
+	default:
+		return "", node, checkNoIncidentQueryText(node)
+	}
+}
+
+func checkNoIncidentQueryText(node utility.QueryNode) error {
+	switch n := node.(type) {
+	case utility.QueryTextNode:
+		return &utility.QueryError{Pos: n.Pos, Message: fmt.Sprintf("free text '%s' cannot be used inside OR or NOT", n.Text)}
+	case utility.QueryAndNode:
+		for _, operand := range n.Operands {
+			if err := checkNoIncidentQueryText(operand); err != nil {
+				return err
+			}
+		}
+	case utility.QueryOrNode:
+		for _, operand := range n.Operands {
+			if err := checkNoIncidentQueryText(operand); err != nil {
+				return err
+			}
+		}
+	case utility.QueryNotNode:
+		return checkNoIncidentQueryText(n.Operand)
+	}
+	return nil
+}
+
+// Translate a parsed incident query into a SQL condition and its arguments.
+// Field names are checked against a whitelist and every value is passed as an argument, so the query cannot inject SQL
+func incidentQueryCondition(ctx *gin.Context, node utility.QueryNode) (string, []any, error) {
+	switch n := node.(type) {
+	case utility.QueryAndNode:
+		return joinIncidentQueryConditions(ctx, n.Operands, " AND ")
+	case utility.QueryOrNode:
+		return joinIncidentQueryConditions(ctx, n.Operands, " OR ")
+	case utility.QueryNotNode:
+		condition, args, err := incidentQueryCondition(ctx, n.Operand)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("NOT (%s)", condition), args, nil
+	case utility.QueryTermNode:
+		return incidentQueryTermCondition(ctx, n)
+	default:
+		return "", nil, &utility.QueryError{Pos: node.Position(), Message: "unexpected free text"}
+	}
+}
+
+func joinIncidentQueryConditions(ctx *gin.Context, nodes []utility.QueryNode, separator string) (string, []any, error) {
+	conditions := make([]string, 0)
+	args := make([]any, 0)
+	for _, node := range nodes {
+		condition, nodeArgs, err := incidentQueryCondition(ctx, node)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s)", condition))
+		args = append(args, nodeArgs...)
+	}
+	return strings.Join(conditions, separator), args, nil
+}
+
+// Translate a term into a SQL condition which is only ever true or false. A comparison with a null column is unknown
+// rather than false, which NOT would leave unknown and so drop the rows with a null column instead of matching them
+func incidentQueryTermCondition(ctx *gin.Context, term utility.QueryTermNode) (string, []any, error) {
+	condition, args, err := incidentQueryTermComparison(ctx, term)
+	if err != nil {
+		return "", nil, err
+	}
+	if field := incidentQueryFields[term.Field]; field.Nullable {
+		condition = fmt.Sprintf("(%s) AND %s IS NOT NULL", condition, field.Column)
+	}
+	return condition, args, nil
+}
+
+func incidentQueryTermComparison(ctx *gin.Context, term utility.QueryTermNode) (string, []any, error) {
+	field, ok := incidentQueryFields[term.Field]
+	if !ok {
+		return "", nil, &utility.QueryError{Pos: term.Pos, Message: fmt.Sprintf("unknown field '%s', expected one of '%s'", term.Field, strings.Join(IncidentQueryFields, "', '"))}
+	}
+	comparison := term.Operator != utility.QueryOperatorMatch && term.Operator != utility.QueryOperatorEqual
+	if comparison && field.Type != incidentQueryLevel && field.Type != incidentQueryNumber && field.Type != incidentQueryTime {
+		return "", nil, &utility.QueryError{Pos: term.Pos, Message: fmt.Sprintf("'%s' cannot be compared with '%s'", term.Field, term.Operator)}
+	}
+	if comparison && len(term.Values) > 1 {
+		return "", nil, &utility.QueryError{Pos: term.Values[1].Pos, Message: fmt.Sprintf("'%s' can only be compared with a single value", term.Operator)}
+	}
+	values := make([]string, 0)
+	for _, value := range term.Values {
+		values = append(values, value.Value)
+	}
+
+	switch field.Type {
+	case incidentQueryEnum:
+		for _, value := range term.Values {
+			if !slices.Contains(field.Enum, value.Value) {
+				return "", nil, &utility.QueryError{Pos: value.Pos, Message: fmt.Sprintf("'%s' must be one of '%s'", term.Field, strings.Join(field.Enum, "', '"))}
+			}
+		}
+		return fmt.Sprintf("%s IN ?", field.Column), []any{values}, nil
+
+	case incidentQueryLevel, incidentQueryNumber:
+		numbers := make([]uint64, 0)
+		for _, value := range term.Values {
+			number, err := strconv.ParseUint(value.Value, 10, 0)
+			if err != nil || (field.Type == incidentQueryLevel && (number < 1 || number > field.Highest)) {
+				message := fmt.Sprintf("'%s' must be a positive integer", term.Field)
+				if field.Type == incidentQueryLevel {
+					message = fmt.Sprintf("'%s' must be an integer between 1 and %d", term.Field, field.Highest)
+				}
+				return "", nil, &utility.QueryError{Pos: value.Pos, Message: message}
+			}
+			numbers = append(numbers, number)
+		}
+		if comparison {
+			return fmt.Sprintf("%s %s ?", field.Column, queryOperatorSQL[term.Operator]), []any{numbers[0]}, nil
+		}
+		return fmt.Sprintf("%s IN ?", field.Column), []any{numbers}, nil
+
+	case incidentQueryBool:
+		if len(term.Values) > 1 {
+			return "", nil, &utility.QueryError{Pos: term.Values[1].Pos, Message: fmt.Sprintf("'%s' only accepts a single value", term.Field)}
+		}
+		value, err := strconv.ParseBool(values[0])
+		if err != nil {
+			return "", nil, &utility.QueryError{Pos: term.Values[0].Pos, Message: fmt.Sprintf("'%s' must be true or false", term.Field)}
+		}
+		if term.Field == "resolved" {
+			if value {
+				return "tbl_incident.resolved_at IS NOT NULL", []any{}, nil
+			}
+			return "tbl_incident.resolved_at IS NULL", []any{}, nil
+		}
+		return fmt.Sprintf("%s = ?", field.Column), []any{value}, nil
+
+	case incidentQueryTime:
+		conditions := make([]string, 0)
+		args := make([]any, 0)
+		for _, value := range term.Values {
+			from, to, err := parseIncidentQueryTime(value.Value)
+			if err != nil {
+				return "", nil, &utility.QueryError{Pos: value.Pos, Message: fmt.Sprintf("'%s' must be a relative time such as -7d, a date such as 2006-01-02 or an RFC3339 timestamp", term.Field)}
+			}
+			isDate := !to.Equal(from)
+			switch {
+			case isDate && term.Operator == utility.QueryOperatorGreater:
+				// after a date means from the start of the next day
+				conditions = append(conditions, fmt.Sprintf("%s >= ?", field.Column))
+				args = append(args, to)
+			case isDate && term.Operator == utility.QueryOperatorLessEqual:
+				conditions = append(conditions, fmt.Sprintf("%s < ?", field.Column))
+				args = append(args, to)
+			case comparison:
+				conditions = append(conditions, fmt.Sprintf("%s %s ?", field.Column, queryOperatorSQL[term.Operator]))
+				args = append(args, from)
+			case !isDate:
+				// a relative time or timestamp without an operator matches anything since then
+				conditions = append(conditions, fmt.Sprintf("%s >= ?", field.Column))
+				args = append(args, from)
+			default:
+				conditions = append(conditions, fmt.Sprintf("(%s >= ? AND %s < ?)", field.Column, field.Column))
+				args = append(args, from, to)
+			}
+		}
+		return strings.Join(conditions, " OR "), args, nil
+	}
+
+	switch term.Field {
+	case "hash":
+		// hashes of incidents merged into another resolve to the surviving incident
+		return "tbl_incident.hash IN ? OR tbl_incident.id IN (SELECT incident_id FROM tbl_incident_hash_alias WHERE hash IN ?)", []any{values, values}, nil
+	case "team":
+		return "tbl_incident.id IN (SELECT tbl_incident_resolution_team.incident_id FROM tbl_incident_resolution_team JOIN tbl_team ON tbl_team.id = tbl_incident_resolution_team.team_id WHERE tbl_team.name IN ?)", []any{values}, nil
+	case "host":
+		return "tbl_incident.id IN (SELECT tbl_incident_host.incident_id FROM tbl_incident_host JOIN tbl_host_machine ON tbl_host_machine.id = tbl_incident_host.host_machine_id WHERE tbl_host_machine.hostname IN ?)", []any{values}, nil
+	case "assignee":
+		conditions := make([]string, 0)
+		args := make([]any, 0)
+		for _, value := range values {
+			switch value {
+			case "none":
+				conditions = append(conditions, "tbl_incident.assignee_id IS NULL")
+			case "me":
+				conditions = append(conditions, "(tbl_incident.assignee_id IS NOT NULL AND tbl_incident.assignee_id = ?)")
+				args = append(args, ctx.MustGet("user").(*User).ID)
+			default:
+				conditions = append(conditions, "(tbl_incident.assignee_id IS NOT NULL AND tbl_incident.assignee_id IN (SELECT id FROM tbl_user WHERE email = ?))")
+				args = append(args, value)
+			}
+		}
+		return strings.Join(conditions, " OR "), args, nil
+	default:
+		return fmt.Sprintf("%s IN ?", field.Column), []any{values}, nil
+	}
+}
+
+// Parse a relative time (-7d), a date (2006-01-02) or an RFC3339 timestamp into the range of time it covers.
+// A date covers the whole day, whereas relative times and timestamps are a single instant
+func parseIncidentQueryTime(value string) (time.Time, time.Time, error) {
+	if match := relativeTimeRegex.FindStringSubmatch(value); match != nil {
+		amount, err := strconv.Atoi(match[1])
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		unit := map[string]time.Duration{"m": time.Minute, "h": time.Hour, "d": time.Hour * 24, "w": time.Hour * 24 * 7}[match[2]]
+		at := time.Now().Add(-unit * time.Duration(amount))
+		return at, at, nil
+	}
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		return date, date.AddDate(0, 0, 1), nil
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	return at, at, err
+}
+
+// Translate a parsed incident query into the free text to search for and a SQL condition for the remaining terms
+func parseIncidentQuery(ctx *gin.Context, query utility.QueryNode) (string, string, []any, error) {
+	text, node, err := splitIncidentQuery(query)
+	if err != nil {
+		ctx.Set("errorCode", http.StatusBadRequest)
+		return "", "", nil, err
+	}
+	if node == nil {
+		return text, "", nil, nil
+	}
+	condition, args, err := incidentQueryCondition(ctx, node)
+	if err != nil {
+		ctx.Set("errorCode", http.StatusBadRequest)
+		return "", "", nil, err
+	}
+	return text, condition, args, nil
+}
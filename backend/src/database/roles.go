@@ -0,0 +1,345 @@
+package database
+
+import (
+	"com668-backend/utility"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	PermissionIncidentRead    string = "incident:read"
+	PermissionIncidentCreate  string = "incident:create"
+	PermissionIncidentWrite   string = "incident:write"
+	PermissionIncidentResolve string = "incident:resolve"
+	// see and change the incidents of every team, delete other users' comments and act on incidents selected by a query
+	PermissionIncidentManage string = "incident:manage"
+	PermissionHostRead       string = "host:read"
+	PermissionHostWrite      string = "host:write"
+	// see and change the hosts of every team
+	PermissionHostManage    string = "host:manage"
+	PermissionProviderRead  string = "provider:read"
+	PermissionProviderWrite string = "provider:write"
+	PermissionSettingsRead  string = "settings:read"
+	PermissionSettingsWrite string = "settings:write"
+	PermissionTeamRead      string = "team:read"
+	PermissionTeamWrite     string = "team:write"
+	PermissionUserRead      string = "user:read"
+	PermissionUserWrite     string = "user:write"
+	PermissionRoleRead      string = "role:read"
+	PermissionRoleWrite     string = "role:write"
+
+	// the built in role held by every user with the admin flag, which cannot be changed or deleted
+	AdminRoleUUID string = "b0c8a4a5-6a1e-4f7c-9c57-3d1f0e7a2b11"
+	// the built in role every user holds, which reproduces what any logged in user could do before roles
+	MemberRoleUUID string = "5d2f7e0c-8b3a-4c1e-a6d4-9f0b2c7e1a38"
+)
+
+var (
+	Permissions []string = []string{
+		PermissionIncidentRead,
+		PermissionIncidentCreate,
+		PermissionIncidentWrite,
+		PermissionIncidentResolve,
+		PermissionIncidentManage,
+		PermissionHostRead,
+		PermissionHostWrite,
+		PermissionHostManage,
+		PermissionProviderRead,
+		PermissionProviderWrite,
+		PermissionSettingsRead,
+		PermissionSettingsWrite,
+		PermissionTeamRead,
+		PermissionTeamWrite,
+		PermissionUserRead,
+		PermissionUserWrite,
+		PermissionRoleRead,
+		PermissionRoleWrite,
+	}
+	roleSortColumns map[string]string = map[string]string{
+		"name": "name",
+	}
+	RoleSortFields []string = []string{"name"}
+)
+
+// A named set of permissions, held by the users it is assigned to and by the members of the teams it is assigned to
+type Role struct {
+	ID          uint   `gorm:"column:id;primaryKey;autoIncrement"`
+	UUID        string `gorm:"column:uuid;size:36;unique;not null"`
+	Name        string `gorm:"column:name;size:30;unique;not null"`
+	Description string `gorm:"column:description;size:255"`
+	// held by every user without being assigned to them
+	Default     bool             `gorm:"column:is_default;not null"`
+	Permissions []RolePermission `gorm:"foreignKey:role_id;references:id"`
+}
+
+func (role *Role) BeforeCreate(tx *gorm.DB) error {
+	if role.UUID == "" {
+		uuid, err := utility.GenerateRandomUUID()
+		if err != nil {
+			if ctx := GetContext(tx); ctx != nil {
+				ctx.Set("errorCode", http.StatusInternalServerError)
+			}
+			return errors.New("failed to create a role uuid")
+		}
+		role.UUID = uuid
+	}
+	return nil
+}
+
+func (role *Role) PermissionNames() []string {
+	names := make([]string, 0)
+	for _, permission := range role.Permissions {
+		names = append(names, permission.Permission)
+	}
+	return names
+}
+
+type RolePermission struct {
+	RoleID     uint   `gorm:"column:role_id;primaryKey"`
+	Permission string `gorm:"column:permission;size:30;primaryKey"`
+}
+
+// The assignment of a role to a user
+type UserRoleAssignment struct {
+	UserID uint `gorm:"column:user_id;primaryKey"`
+	User   User `gorm:"foreignKey:user_id;references:id"`
+	RoleID uint `gorm:"column:role_id;primaryKey"`
+	Role   Role `gorm:"foreignKey:role_id;references:id"`
+}
+
+// The assignment of a role to a team, whose members all hold it
+type TeamRoleAssignment struct {
+	TeamID uint `gorm:"column:team_id;primaryKey"`
+	Team   Team `gorm:"foreignKey:team_id;references:id"`
+	RoleID uint `gorm:"column:role_id;primaryKey"`
+	Role   Role `gorm:"foreignKey:role_id;references:id"`
+}
+
+type GetRolesFilters struct {
+	UUIDs    []string
+	Page     *int
+	PageSize *int
+	Sort     []SortKey
+	Cursor   *string
+	// filled with the cursors of the neighbouring pages, if set
+	Cursors *PageCursors
+}
+
+func GetRole(ctx *gin.Context, filters GetRolesFilters) (*Role, error) {
+	roles, count, err := GetRoles(ctx, GetRolesFilters{
+		UUIDs:    filters.UUIDs,
+		PageSize: utility.Pointer(len(filters.UUIDs)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		ctx.Set("errorCode", http.StatusNotFound)
+		return nil, errors.New("role not found")
+	}
+	return roles[0], nil
+}
+
+func GetRoles(ctx *gin.Context, filters GetRolesFilters) ([]*Role, int64, error) {
+	tx := GetDBTransaction(ctx).Model(&Role{})
+	tx = tx.Preload("Permissions")
+
+	if len(filters.UUIDs) > 0 {
+		tx = tx.Where("uuid IN (?)", filters.UUIDs)
+	}
+
+	var count int64
+	tx = tx.Count(&count)
+	pages, err := newPaginator(ctx, &Role{}, roleSortColumns, filters.Sort, filters.Cursor, filters.Page, filters.PageSize)
+	if err != nil {
+		return nil, -1, err
+	}
+	tx = pages.apply(tx)
+
+	var roles []*Role
+	tx = tx.Find(&roles)
+	if tx.Error != nil {
+		return nil, -1, handleError(ctx, tx.Error)
+	}
+	if err := pages.finish(ctx, &roles, filters.Cursors); err != nil {
+		return nil, -1, err
+	}
+	return roles, count, nil
+}
+
+// Get the permissions a user holds through the default roles, the admin role if they have the admin flag, the roles
+// assigned to them and the roles assigned to their teams
+func GetUserPermissions(ctx *gin.Context, user *User) ([]string, error) {
+	tx := GetDBTransaction(ctx)
+	assigned, throughTeams := userRoleIDs(tx, user)
+
+	roles := tx.Model(&Role{}).Select("id").Where("is_default = ?", true).
+		Or("id IN (?)", assigned).
+		Or("id IN (?)", throughTeams)
+	if user.Admin {
+		roles = roles.Or("uuid = ?", AdminRoleUUID)
+	}
+
+	permissions := make([]string, 0)
+	err := tx.Model(&RolePermission{}).Distinct("permission").Where("role_id IN (?)", roles).Pluck("permission", &permissions).Error
+	if err != nil {
+		return nil, handleError(ctx, err)
+	}
+	return permissions, nil
+}
+
+// The IDs of the roles assigned to a user, and of those assigned to their teams
+func userRoleIDs(tx *gorm.DB, user *User) (*gorm.DB, *gorm.DB) {
+	assigned := tx.Model(&UserRoleAssignment{}).Select("role_id").Where("user_id = ?", user.ID)
+	throughTeams := tx.Table("tbl_team_role_assignment").Select("tbl_team_role_assignment.role_id").
+		Joins("JOIN tbl_team_user ON tbl_team_user.team_id = tbl_team_role_assignment.team_id").
+		Where("tbl_team_user.user_id = ?", user.ID)
+	return assigned, throughTeams
+}
+
+// Whether the logged in user holds a permission, as loaded when they were authenticated
+func HasPermission(ctx *gin.Context, permission string) bool {
+	return slices.Contains(ctx.GetStringSlice("permissions"), permission)
+}
+
+// Check the logged in user is an admin, through the admin flag or the admin role being assigned to them or one of their
+// teams, setting the error code if they are not. Holding the permissions of the admin role through other roles is not
+// enough, and nor is an API key, as a key is limited to its scopes
+func checkAdmin(ctx *gin.Context, action string) error {
+	user := ctx.MustGet("user").(*User)
+	if _, ok := ctx.Get("apiKey"); !ok {
+		if user.Admin {
+			return nil
+		}
+		tx := GetDBTransaction(ctx)
+		assigned, throughTeams := userRoleIDs(tx, user)
+		var count int64
+		err := tx.Model(&Role{}).Where("uuid = ? AND (id IN (?) OR id IN (?))", AdminRoleUUID, assigned, throughTeams).Count(&count).Error
+		if err != nil {
+			return handleError(ctx, err)
+		}
+		if count > 0 {
+			return nil
+		}
+	}
+	ctx.Set("errorCode", http.StatusForbidden)
+	return fmt.Errorf("only an admin can %s", action)
+}
+
+func CreateRole(ctx *gin.Context, role *Role) error {
+	tx := GetDBTransaction(ctx).Model(&Role{}).Create(role)
+	if tx.Error != nil {
+		return handleError(ctx, tx.Error)
+	}
+	return nil
+}
+
+// Replace the details and permissions of a role
+func UpdateRole(ctx *gin.Context, role *Role) error {
+	if role.UUID == AdminRoleUUID {
+		ctx.Set("errorCode", http.StatusBadRequest)
+		return errors.New("the admin role cannot be changed")
+	}
+	tx := GetDBTransaction(ctx)
+	err := tx.Model(&Role{}).Where("id = ?", role.ID).Updates(map[string]any{
+		"name":        role.Name,
+		"description": role.Description,
+		"is_default":  role.Default,
+	}).Error
+	if err != nil {
+		return handleError(ctx, err)
+	}
+	if err := tx.Where("role_id = ?", role.ID).Delete(&RolePermission{}).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	if len(role.Permissions) > 0 {
+		for i := range role.Permissions {
+			role.Permissions[i].RoleID = role.ID
+		}
+		if err := tx.Create(&role.Permissions).Error; err != nil {
+			return handleError(ctx, err)
+		}
+	}
+	return nil
+}
+
+// Delete a role, taking it away from the users and teams it is assigned to and the groups mapped to it
+func DeleteRole(ctx *gin.Context, role *Role) error {
+	if role.UUID == AdminRoleUUID {
+		ctx.Set("errorCode", http.StatusBadRequest)
+		return errors.New("the admin role cannot be deleted")
+	}
+	tx := GetDBTransaction(ctx)
+	for _, model := range []any{&UserRoleAssignment{}, &TeamRoleAssignment{}, &RolePermission{}, &GroupMapping{}} {
+		if err := tx.Where("role_id = ?", role.ID).Delete(model).Error; err != nil {
+			return handleError(ctx, err)
+		}
+	}
+	if err := tx.Delete(&Role{}, role.ID).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	return nil
+}
+
+func AssignUserRole(ctx *gin.Context, user *User, role *Role) error {
+	if role.UUID == AdminRoleUUID {
+		if err := checkAdmin(ctx, "assign the admin role"); err != nil {
+			return err
+		}
+	}
+	if err := GetDBTransaction(ctx).Model(user).Association("Roles").Append(role); err != nil {
+		return handleError(ctx, err)
+	}
+	return nil
+}
+
+func UnassignUserRole(ctx *gin.Context, user *User, role *Role) error {
+	if role.UUID == AdminRoleUUID {
+		if err := checkAdmin(ctx, "unassign the admin role"); err != nil {
+			return err
+		}
+	}
+	tx := GetDBTransaction(ctx).Where("user_id = ? AND role_id = ?", user.ID, role.ID).Delete(&UserRoleAssignment{})
+	if tx.Error != nil {
+		return handleError(ctx, tx.Error)
+	}
+	if tx.RowsAffected == 0 {
+		ctx.Set("errorCode", http.StatusNotFound)
+		return errors.New("role is not assigned to the user")
+	}
+	return nil
+}
+
+func AssignTeamRole(ctx *gin.Context, team *Team, role *Role) error {
+	if role.UUID == AdminRoleUUID {
+		if err := checkAdmin(ctx, "assign the admin role"); err != nil {
+			return err
+		}
+	}
+	if err := GetDBTransaction(ctx).Model(team).Association("Roles").Append(role); err != nil {
+		return handleError(ctx, err)
+	}
+	return nil
+}
+
+func UnassignTeamRole(ctx *gin.Context, team *Team, role *Role) error {
+	if role.UUID == AdminRoleUUID {
+		if err := checkAdmin(ctx, "unassign the admin role"); err != nil {
+			return err
+		}
+	}
+	tx := GetDBTransaction(ctx).Where("team_id = ? AND role_id = ?", team.ID, role.ID).Delete(&TeamRoleAssignment{})
+	if tx.Error != nil {
+		return handleError(ctx, tx.Error)
+	}
+	if tx.RowsAffected == 0 {
+		ctx.Set("errorCode", http.StatusNotFound)
+		return errors.New("role is not assigned to the team")
+	}
+	return nil
+}
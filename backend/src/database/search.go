@@ -0,0 +1,257 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/lang/en"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	searchReindexBatchSize int = 100
+)
+
+var (
+	searchIndex bleve.Index = nil
+	// how much a match in each field counts towards an incident's relevance
+	incidentSearchFieldBoosts map[string]float64 = map[string]float64{
+		"summary":     3,
+		"description": 2,
+		"comments":    1,
+		"stackFrames": 1,
+	}
+)
+
+type incidentSearchDocument struct {
+	Summary     string `json:"summary"`
+	Description string `json:"description"`
+	Comments    string `json:"comments"`
+	StackFrames string `json:"stackFrames"`
+}
+
+type IncidentSearchHit struct {
+	Score      float64
+	Highlights map[string][]string
+}
+
+// Open the full-text search index at SEARCH_INDEX_PATH, or an in-memory index if it is not set.
+// The index is rebuilt from the database whenever it is newly created or the tables were just recreated
+func openSearchIndex(conn *gorm.DB) error {
+	path := os.Getenv("SEARCH_INDEX_PATH")
+	rebuild := gin.IsDebugging()
+	var index bleve.Index
+	var err error
+	if path == "" {
+		index, err = bleve.NewMemOnly(newIncidentSearchMapping())
+		rebuild = true
+	} else {
+		index, err = bleve.Open(path)
+		if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+			index, err = bleve.New(path, newIncidentSearchMapping())
+			rebuild = true
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if rebuild {
+		log.Default().Println("Rebuilding the search index")
+		if err := reindexIncidents(conn, index); err != nil {
+			index.Close()
+			return err
+		}
+	}
+	searchIndex = index
+	return nil
+}
+
+func newIncidentSearchMapping() *mapping.IndexMappingImpl {
+	incidentMapping := bleve.NewDocumentMapping()
+	for field := range incidentSearchFieldBoosts {
+		fieldMapping := bleve.NewTextFieldMapping()
+		fieldMapping.Analyzer = en.AnalyzerName
+		// stored fields and term vectors are needed to highlight the matches
+		fieldMapping.Store = true
+		fieldMapping.IncludeTermVectors = true
+		incidentMapping.AddFieldMappingsAt(field, fieldMapping)
+	}
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = incidentMapping
+	indexMapping.DefaultAnalyzer = en.AnalyzerName
+	return indexMapping
+}
+
+func reindexIncidents(conn *gorm.DB, index bleve.Index) error {
+	incidents := make([]*Incident, 0)
+	return conn.Model(&Incident{}).
+		Preload("Comments").
+		Preload("StackFrames", func(t *gorm.DB) *gorm.DB {
+			return t.Order("position ASC")
+		}).
+		FindInBatches(&incidents, searchReindexBatchSize, func(tx *gorm.DB, batch int) error {
+			b := index.NewBatch()
+			for _, incident := range incidents {
+				if err := b.Index(incident.UUID, newIncidentSearchDocument(incident)); err != nil {
+					return err
+				}
+			}
+			return index.Batch(b)
+		}).Error
+}
+
+func newIncidentSearchDocument(incident *Incident) incidentSearchDocument {
+	comments := make([]string, 0)
+	for _, comment := range incident.Comments {
+		comments = append(comments, comment.Comment)
+	}
+	frames := make([]string, 0)
+	for _, frame := range incident.StackFrames {
+		frames = append(frames, fmt.Sprintf("%s %s", frame.Function, frame.File))
+	}
+	return incidentSearchDocument{
+		Summary:     incident.Summary,
+		Description: incident.Description,
+		Comments:    strings.Join(comments, "\n"),
+		StackFrames: strings.Join(frames, "\n"),
+	}
+}
+
+// A change to the search index, which is queued until the transaction making it is committed
+type searchIndexOperation struct {
+	incidentID uint
+	// set to remove the incident from the index rather than index it
+	uuid string
+}
+
+// Queue an update of the search index with the summary, description, comments and stack frames of an incident. It is
+// made once the transaction is committed, so the index never holds anything which was rolled back
+func indexIncident(ctx *gin.Context, incidentID uint) error {
+	queueSearchIndexOperation(ctx, searchIndexOperation{incidentID: incidentID})
+	return nil
+}
+
+// Queue the removal of an incident from the search index, made once the transaction is committed
+func unindexIncident(ctx *gin.Context, uuid string) error {
+	queueSearchIndexOperation(ctx, searchIndexOperation{uuid: uuid})
+	return nil
+}
+
+func queueSearchIndexOperation(ctx *gin.Context, operation searchIndexOperation) {
+	operations, _ := ctx.Get("searchIndexOperations")
+	queue, _ := operations.([]searchIndexOperation)
+	ctx.Set("searchIndexOperations", append(queue, operation))
+}
+
+// Forget the changes to the search index queued by a transaction which was rolled back
+func DiscardSearchIndexOperations(ctx *gin.Context) {
+	ctx.Set("searchIndexOperations", []searchIndexOperation{})
+}
+
+// Make the changes to the search index queued by a transaction which was just committed. The documents are built from
+// the committed rows, and any failure is logged as the transaction cannot be undone
+func ApplySearchIndexOperations(ctx *gin.Context) {
+	operations, _ := ctx.Get("searchIndexOperations")
+	queue, _ := operations.([]searchIndexOperation)
+	DiscardSearchIndexOperations(ctx)
+	if len(queue) == 0 {
+		return
+	}
+	batch := searchIndex.NewBatch()
+	for _, operation := range queue {
+		if operation.uuid != "" {
+			batch.Delete(operation.uuid)
+			continue
+		}
+		incidents := make([]*Incident, 0)
+		err := GetDBConn().Model(&Incident{}).
+			Preload("Comments").
+			Preload("StackFrames", func(t *gorm.DB) *gorm.DB {
+				return t.Order("position ASC")
+			}).
+			Where("id = ?", operation.incidentID).
+			Find(&incidents).Error
+		if err != nil {
+			log.Default().Printf("[%s] failed to load incident %d to index: %s\n", ctx.GetString("ReqID"), operation.incidentID, err)
+			continue
+		}
+		for _, incident := range incidents {
+			if err := batch.Index(incident.UUID, newIncidentSearchDocument(incident)); err != nil {
+				log.Default().Printf("[%s] failed to index incident %s: %s\n", ctx.GetString("ReqID"), incident.UUID, err)
+			}
+		}
+	}
+	if err := searchIndex.Batch(batch); err != nil {
+		log.Default().Printf("[%s] search index error: %s\n", ctx.GetString("ReqID"), err)
+	}
+}
+
+func newIncidentSearchQuery(text string) query.Query {
+	queries := make([]query.Query, 0)
+	for field, boost := range incidentSearchFieldBoosts {
+		match := bleve.NewMatchQuery(text)
+		match.SetField(field)
+		match.SetBoost(boost)
+		queries = append(queries, match)
+	}
+	return bleve.NewDisjunctionQuery(queries...)
+}
+
+// Search the index for every incident matching free text, returning the relevance of each keyed by UUID.
+// Matches in the summary are ranked above the description, which is ranked above comments and stack frames
+func searchIncidents(ctx *gin.Context, text string) (map[string]*IncidentSearchHit, error) {
+	// count the matches first, so every one of them is returned however many there are
+	request := bleve.NewSearchRequestOptions(newIncidentSearchQuery(text), 0, 0, false)
+	result, err := searchIndex.SearchInContext(ctx, request)
+	if err != nil {
+		return nil, handleSearchError(ctx, err)
+	}
+	hits := make(map[string]*IncidentSearchHit)
+	if result.Total == 0 {
+		return hits, nil
+	}
+	request.Size = int(result.Total)
+	result, err = searchIndex.SearchInContext(ctx, request)
+	if err != nil {
+		return nil, handleSearchError(ctx, err)
+	}
+	for _, hit := range result.Hits {
+		hits[hit.ID] = &IncidentSearchHit{Score: hit.Score}
+	}
+	return hits, nil
+}
+
+// Fill in the highlighted snippets of the search hits of a page of incidents, which are only worked out for the
+// incidents actually returned
+func highlightIncidentSearchHits(ctx *gin.Context, text string, hits map[string]*IncidentSearchHit, uuids []string) error {
+	if len(uuids) == 0 {
+		return nil
+	}
+	page := bleve.NewConjunctionQuery(newIncidentSearchQuery(text), bleve.NewDocIDQuery(uuids))
+	request := bleve.NewSearchRequestOptions(page, len(uuids), 0, false)
+	request.Highlight = bleve.NewHighlightWithStyle("html")
+	result, err := searchIndex.SearchInContext(ctx, request)
+	if err != nil {
+		return handleSearchError(ctx, err)
+	}
+	for _, hit := range result.Hits {
+		if match, ok := hits[hit.ID]; ok {
+			match.Highlights = hit.Fragments
+		}
+	}
+	return nil
+}
+
+func handleSearchError(ctx *gin.Context, err error) error {
+	log.Default().Printf("[%s] search index error: %e\n", ctx.GetString("ReqID"), err)
+	ctx.Set("errorCode", http.StatusInternalServerError)
+	return errors.New("an error occurred with the search index")
+}
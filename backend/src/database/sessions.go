@@ -0,0 +1,163 @@
+package database
+
+import (
+	"com668-backend/utility"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// A login of a user, which the access tokens given out for it name so they stop working when it is revoked, and which
+// keeps the hash of the refresh token used to get new ones
+type Session struct {
+	ID     uint   `gorm:"column:id;primaryKey;autoIncrement"`
+	UUID   string `gorm:"column:uuid;size:36;unique;not null"`
+	UserID uint   `gorm:"column:user_id;not null"`
+	User   User   `gorm:"foreignKey:user_id;references:id"`
+	// the sha256 hash of the current refresh token, which is replaced every time the session is refreshed
+	RefreshHash string    `gorm:"column:refresh_hash;size:64;not null"`
+	CreatedAt   time.Time `gorm:"column:created_at;not null"`
+	// when the refresh token stops working, so the user has to log in again
+	ExpiresAt time.Time `gorm:"column:expires_at;not null"`
+	// when the session was logged out of or revoked, or nil if it can still be used
+	RevokedAt *time.Time `gorm:"column:revoked_at"`
+}
+
+// Give the session a new refresh token, returning it
+func (session *Session) rotate(ctx *gin.Context) (string, error) {
+	secret, err := generateSecret(ctx)
+	if err != nil {
+		return "", err
+	}
+	session.RefreshHash = hashSecret(secret)
+	// the uuid finds the session without having to hash the secret of every session to compare them
+	return session.UUID + "." + secret, nil
+}
+
+// Start a session for a user who has logged in, returning its refresh token
+func CreateSession(ctx *gin.Context, user *User, lifetime time.Duration) (*Session, string, error) {
+	// the refresh token names the session, so its uuid is needed before it is created
+	uuid, err := utility.GenerateRandomUUID()
+	if err != nil {
+		ctx.Set("errorCode", http.StatusInternalServerError)
+		return nil, "", errors.New("failed to create a session uuid")
+	}
+	session := &Session{
+		UUID:      uuid,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(lifetime),
+	}
+	refreshToken, err := session.rotate(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := GetDBTransaction(ctx).Model(&Session{}).Create(session).Error; err != nil {
+		return nil, "", handleError(ctx, err)
+	}
+	return session, refreshToken, nil
+}
+
+func GetSession(ctx *gin.Context, sessionUUID string) (*Session, error) {
+	var sessions []*Session
+	tx := GetDBTransaction(ctx).Model(&Session{}).Preload("User.Teams").Where("uuid = ?", sessionUUID).Find(&sessions)
+	if tx.Error != nil {
+		return nil, handleError(ctx, tx.Error)
+	}
+	if len(sessions) == 0 {
+		ctx.Set("errorCode", http.StatusNotFound)
+		return nil, errors.New("session not found")
+	}
+	return sessions[0], nil
+}
+
+// Swap a refresh token for a new one, failing with a 401 if it does not exist, has expired or has been revoked. A
+// refresh token that has already been swapped is only used again if it was stolen, so the whole session is revoked
+func RefreshSession(ctx *gin.Context, refreshToken string) (*Session, string, error) {
+	invalid := errors.New("invalid refresh token")
+	sessionUUID, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok {
+		ctx.Set("errorCode", http.StatusUnauthorized)
+		return nil, "", invalid
+	}
+	session, err := GetSession(ctx, sessionUUID)
+	if err != nil {
+		if ctx.GetInt("errorCode") == http.StatusNotFound {
+			ctx.Set("errorCode", http.StatusUnauthorized)
+			return nil, "", invalid
+		}
+		return nil, "", err
+	}
+	if session.RevokedAt != nil {
+		ctx.Set("errorCode", http.StatusUnauthorized)
+		return nil, "", errors.New("session has been revoked")
+	}
+	if time.Now().After(session.ExpiresAt) {
+		ctx.Set("errorCode", http.StatusUnauthorized)
+		return nil, "", errors.New("session has expired")
+	}
+	reused := func() (*Session, string, error) {
+		if err := RevokeSession(ctx, session); err != nil {
+			return nil, "", err
+		}
+		ctx.Set("errorCode", http.StatusUnauthorized)
+		return nil, "", errors.New("refresh token has already been used, so the session has been revoked")
+	}
+	previousHash := hashSecret(secret)
+	if subtle.ConstantTimeCompare([]byte(session.RefreshHash), []byte(previousHash)) != 1 {
+		return reused()
+	}
+
+	refreshToken, err = session.rotate(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	// only swapped if it still has the token just checked, so of two requests racing to use the same token only one wins
+	tx := GetDBTransaction(ctx).Model(&Session{}).
+		Where("id = ? AND refresh_hash = ? AND revoked_at IS NULL", session.ID, previousHash).
+		Update("refresh_hash", session.RefreshHash)
+	if tx.Error != nil {
+		return nil, "", handleError(ctx, tx.Error)
+	}
+	if tx.RowsAffected == 0 {
+		return reused()
+	}
+	return session, refreshToken, nil
+}
+
+func RevokeSession(ctx *gin.Context, session *Session) error {
+	now := time.Now()
+	tx := GetDBTransaction(ctx).Model(&Session{}).Where("id = ?", session.ID).Update("revoked_at", now)
+	if tx.Error != nil {
+		return handleError(ctx, tx.Error)
+	}
+	session.RevokedAt = &now
+	return nil
+}
+
+// Revoke every session of a user, logging them out everywhere, except for the session to keep if it is not nil
+func RevokeUserSessions(ctx *gin.Context, user *User, keep *Session) error {
+	tx := GetDBTransaction(ctx).Model(&Session{}).Where("user_id = ? AND revoked_at IS NULL", user.ID)
+	if keep != nil {
+		tx = tx.Where("id <> ?", keep.ID)
+	}
+	if err := tx.Update("revoked_at", time.Now()).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	return nil
+}
+
+// Delete the sessions which have expired or been revoked, as they can no longer be used. An access token given out for
+// a deleted session is refused, as it is for a revoked one
+func PurgeSessions(db *gorm.DB) error {
+	return db.Where("expires_at < ? OR revoked_at IS NOT NULL", time.Now()).Delete(&Session{}).Error
+}
+
+// Delete the sessions of a user, as part of deleting them
+func deleteSessions(tx *gorm.DB, user *User) error {
+	return tx.Where("user_id = ?", user.ID).Delete(&Session{}).Error
+}
@@ -3,18 +3,34 @@ package database
 import (
 	"com668-backend/utility"
 	"errors"
+	"fmt"
 	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+var (
+	// the fields providers can be sorted by, mapped to their column
+	providerSortColumns map[string]string = map[string]string{
+		"name": "name",
+		"type": "type",
+	}
+	ProviderSortFields []string = []string{"name", "type"}
+)
+
 type Provider struct {
 	ID     uint            `gorm:"column:id;primaryKey;autoIncrement"`
 	UUID   string          `gorm:"column:uuid;size:36;unique;not null;uniqueIndex"`
 	Name   string          `gorm:"column:name;size:30;unique;not null"`
 	Fields []ProviderField `gorm:"foreignKey:provider_id;constraint:OnDelete:CASCADE"`
 	Type   string          `gorm:"column:type;check:type IN ('log','alert');size:5;not null"`
+	// bumped on every change, and part of the provider's ETag
+	Version uint `gorm:"column:version;not null;default:1"`
 }
 type ProviderField struct {
 	ID         uint     `gorm:"column:id;primaryKey;autoIncrement"`
@@ -47,6 +63,10 @@ type GetProvidersFilters struct {
 	Name         *string
 	Page         *int
 	PageSize     *int
+	Sort         []SortKey
+	Cursor       *string
+	// filled with the cursors of the neighbouring pages, if set
+	Cursors *PageCursors
 }
 
 // Get a single Provider by UUID
@@ -84,18 +104,20 @@ func GetProviders(ctx *gin.Context, filters GetProvidersFilters) ([]*Provider, i
 
 	var count int64
 	tx.Count(&count)
-	if filters.PageSize != nil {
-		tx = tx.Limit(*filters.PageSize)
-		if filters.Page != nil {
-			tx = tx.Offset((*filters.Page - 1) * *filters.PageSize)
-		}
+	pages, err := newPaginator(ctx, &Provider{}, providerSortColumns, filters.Sort, filters.Cursor, filters.Page, filters.PageSize)
+	if err != nil {
+		return nil, -1, err
 	}
+	tx = pages.apply(tx)
 
 	providers := make([]*Provider, 0)
 	tx = tx.Find(&providers)
 	if tx.Error != nil {
 		return nil, -1, handleError(ctx, tx.Error)
 	}
+	if err := pages.finish(ctx, &providers, filters.Cursors); err != nil {
+		return nil, -1, err
+	}
 	return providers, count, nil
 }
 
@@ -112,6 +134,9 @@ func CreateProvider(ctx *gin.Context, provider *Provider) error {
 // Update a Provider
 func UpdateProvider(ctx *gin.Context, provider *Provider) error {
 	tx := GetDBTransaction(ctx)
+	if err := claimVersion(ctx, &Provider{}, provider.ID, provider.Version); err != nil {
+		return err
+	}
 
 	// update name
 	if err := tx.Model(&Provider{}).Where("id = ?", provider.ID).Update("name", provider.Name).Error; err != nil {
@@ -147,3 +172,238 @@ func DeleteProvider(ctx *gin.Context, uuid string) error {
 	}
 	return nil
 }
+
+type PriorityMatrixEntry struct {
+	ID       uint `gorm:"column:id;primaryKey;autoIncrement"`
+	Impact   uint `gorm:"column:impact;not null;uniqueIndex:idx_priority_matrix_impact_urgency;check:impact BETWEEN 1 AND 3"`
+	Urgency  uint `gorm:"column:urgency;not null;uniqueIndex:idx_priority_matrix_impact_urgency;check:urgency BETWEEN 1 AND 3"`
+	Priority uint `gorm:"column:priority;not null;check:priority BETWEEN 1 AND 5"`
+}
+
+// Get the impact x urgency priority matrix, ordered by impact then urgency
+func GetPriorityMatrix(ctx *gin.Context) ([]*PriorityMatrixEntry, error) {
+	tx := GetDBTransaction(ctx).Model(&PriorityMatrixEntry{})
+	entries := make([]*PriorityMatrixEntry, 0)
+	tx = tx.Order("impact ASC").Order("urgency ASC").Find(&entries)
+	if tx.Error != nil {
+		return nil, handleError(ctx, tx.Error)
+	}
+	// fall back to the built in matrix if an admin has never configured one
+	if len(entries) == 0 {
+		return defaultPriorityMatrix, nil
+	}
+	return entries, nil
+}
+
+// Get the priority for an impact and urgency from the priority matrix
+func GetPriority(ctx *gin.Context, impact, urgency uint) (uint, error) {
+	entries, err := GetPriorityMatrix(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, entry := range entries {
+		if entry.Impact == impact && entry.Urgency == urgency {
+			return entry.Priority, nil
+		}
+	}
+	ctx.Set("errorCode", http.StatusInternalServerError)
+	return 0, errors.New("priority matrix has no entry for the given impact and urgency")
+}
+
+// Replace the priority matrix and recalculate the priority of every incident
+func UpdatePriorityMatrix(ctx *gin.Context, entries []*PriorityMatrixEntry) error {
+	tx := GetDBTransaction(ctx)
+	if err := tx.Model(&PriorityMatrixEntry{}).Where("1 = 1").Delete(&PriorityMatrixEntry{}).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	if err := tx.Model(&PriorityMatrixEntry{}).Create(&entries).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	for _, entry := range entries {
+		changed := make([]*Incident, 0)
+		if err := tx.Model(&Incident{}).
+			Where("impact = ? AND urgency = ? AND priority <> ?", entry.Impact, entry.Urgency, entry.Priority).
+			Find(&changed).Error; err != nil {
+			return handleError(ctx, err)
+		}
+		for _, incident := range changed {
+			if err := recordIncidentFieldChange(ctx, incident.ID, "priority", incident.Priority, entry.Priority, true); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&Incident{}).
+			Where("impact = ? AND urgency = ?", entry.Impact, entry.Urgency).
+			Update("priority", entry.Priority).Error; err != nil {
+			return handleError(ctx, err)
+		}
+	}
+	return nil
+}
+
+const (
+	FingerprintRuleGroup string = "group"
+	FingerprintRuleStrip string = "strip"
+)
+
+// An admin defined rule applied when fingerprinting a stack trace.
+// A 'group' rule gives every incident whose summary or stack trace matches the pattern the same fingerprint,
+// while a 'strip' rule removes any text matching the pattern before the trace is fingerprinted
+type FingerprintRule struct {
+	ID        uint      `gorm:"column:id;primaryKey;autoIncrement"`
+	UUID      string    `gorm:"column:uuid;size:36;unique;not null;uniqueIndex"`
+	Name      string    `gorm:"column:name;size:50;unique;not null"`
+	Type      string    `gorm:"column:type;check:type IN ('group','strip');size:5;not null"`
+	Pattern   string    `gorm:"column:pattern;size:500;not null"`
+	GroupKey  *string   `gorm:"column:group_key;size:100"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime;not null"`
+}
+
+func (rule *FingerprintRule) BeforeCreate(tx *gorm.DB) error {
+	ctx := GetContext(tx)
+	if rule.UUID == "" {
+		uuid, err := utility.GenerateRandomUUID()
+		if err != nil {
+			if ctx != nil {
+				ctx.Set("errorCode", http.StatusInternalServerError)
+			}
+			return errors.New("failed to create a fingerprint rule uuid")
+		}
+		rule.UUID = uuid
+	}
+	return nil
+}
+
+type GetFingerprintRulesFilters struct {
+	UUID     *string
+	Page     *int
+	PageSize *int
+}
+
+// Get a single fingerprint rule by UUID
+func GetFingerprintRule(ctx *gin.Context, uuid string) (*FingerprintRule, error) {
+	rules, count, err := GetFingerprintRules(ctx, GetFingerprintRulesFilters{
+		UUID:     &uuid,
+		PageSize: utility.Pointer(1),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		ctx.Set("errorCode", http.StatusNotFound)
+		return nil, errors.New("fingerprint rule not found")
+	}
+	return rules[0], nil
+}
+
+// Get a list of fingerprint rules in the order they are applied
+func GetFingerprintRules(ctx *gin.Context, filters GetFingerprintRulesFilters) ([]*FingerprintRule, int64, error) {
+	tx := GetDBTransaction(ctx).Model(&FingerprintRule{})
+
+	if filters.UUID != nil {
+		tx = tx.Where("uuid = ?", *filters.UUID)
+	}
+
+	var count int64
+	tx.Count(&count)
+	tx = tx.Order("id ASC")
+	if filters.PageSize != nil {
+		tx = tx.Limit(*filters.PageSize)
+		if filters.Page != nil {
+			tx = tx.Offset((*filters.Page - 1) * *filters.PageSize)
+		}
+	}
+
+	rules := make([]*FingerprintRule, 0)
+	tx = tx.Find(&rules)
+	if tx.Error != nil {
+		return nil, -1, handleError(ctx, tx.Error)
+	}
+	return rules, count, nil
+}
+
+// Create a fingerprint rule
+func CreateFingerprintRule(ctx *gin.Context, rule *FingerprintRule) error {
+	tx := GetDBTransaction(ctx).Model(&FingerprintRule{})
+	tx = tx.Create(rule)
+	if tx.Error != nil {
+		return handleError(ctx, tx.Error)
+	}
+	return nil
+}
+
+// Delete a fingerprint rule
+func DeleteFingerprintRule(ctx *gin.Context, uuid string) error {
+	tx := GetDBTransaction(ctx).Model(&FingerprintRule{})
+	tx = tx.Where("uuid = ?", uuid).Delete(&FingerprintRule{})
+	if tx.Error != nil {
+		return handleError(ctx, tx.Error)
+	}
+	return nil
+}
+
+// A fingerprint rule with its pattern compiled
+type compiledFingerprintRule struct {
+	rule    *FingerprintRule
+	pattern *regexp.Regexp
+}
+
+// The compiled fingerprint rules, which are used on every report. Rules are only ever created or deleted, so the number
+// of rules and the newest rule's id change whenever they do, including when another replica changes them
+var fingerprintRuleCache struct {
+	sync.Mutex
+	count  int64
+	lastID uint
+	rules  []compiledFingerprintRule
+}
+
+// Get the fingerprint rules in the order they are applied, compiling them only if they have changed since last time
+func getCompiledFingerprintRules(ctx *gin.Context) ([]compiledFingerprintRule, error) {
+	var state struct {
+		Count  int64
+		LastID uint
+	}
+	if err := GetDBTransaction(ctx).Model(&FingerprintRule{}).Select("COUNT(*) AS count, COALESCE(MAX(id), 0) AS last_id").Scan(&state).Error; err != nil {
+		return nil, handleError(ctx, err)
+	}
+	fingerprintRuleCache.Lock()
+	defer fingerprintRuleCache.Unlock()
+	if fingerprintRuleCache.rules != nil && fingerprintRuleCache.count == state.Count && fingerprintRuleCache.lastID == state.LastID {
+		return fingerprintRuleCache.rules, nil
+	}
+
+	rules, _, err := GetFingerprintRules(ctx, GetFingerprintRulesFilters{})
+	if err != nil {
+		return nil, err
+	}
+	compiled := make([]compiledFingerprintRule, 0, len(rules))
+	for _, rule := range rules {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			ctx.Set("errorCode", http.StatusInternalServerError)
+			return nil, fmt.Errorf("fingerprint rule '%s' has an invalid pattern", rule.Name)
+		}
+		compiled = append(compiled, compiledFingerprintRule{rule: rule, pattern: pattern})
+	}
+	fingerprintRuleCache.count = state.Count
+	fingerprintRuleCache.lastID = state.LastID
+	fingerprintRuleCache.rules = compiled
+	return compiled, nil
+}
+
+// Compute the server side fingerprint of an incident's stack trace, applying the fingerprint rules in order.
+// The first matching 'group' rule decides the fingerprint, otherwise the normalised stack trace is hashed
+func ComputeFingerprint(ctx *gin.Context, summary string, trace string) (string, error) {
+	rules, err := getCompiledFingerprintRules(ctx)
+	if err != nil {
+		return "", err
+	}
+	strip := make([]*regexp.Regexp, 0)
+	for _, compiled := range rules {
+		if compiled.rule.Type == FingerprintRuleStrip {
+			strip = append(strip, compiled.pattern)
+		} else if compiled.pattern.MatchString(summary) || compiled.pattern.MatchString(trace) {
+			return utility.FingerprintFromKey(strings.TrimSpace(*compiled.rule.GroupKey)), nil
+		}
+	}
+	return utility.Fingerprint(trace, strip), nil
+}
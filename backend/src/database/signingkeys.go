@@ -0,0 +1,265 @@
+package database
+
+import (
+	"com668-backend/utility"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	SigningAlgorithmRS256 string = "RS256"
+	SigningAlgorithmES256 string = "ES256"
+)
+
+var (
+	SigningAlgorithms []string = []string{SigningAlgorithmRS256, SigningAlgorithmES256}
+)
+
+// A key access tokens are signed with, named by the kid header of the tokens it signs so they can still be verified
+// once a newer key has taken over signing. A key is published before it starts signing, so that services which cache
+// the published keys already have it when they see a token it signed
+type SigningKey struct {
+	ID        uint   `gorm:"column:id;primaryKey;autoIncrement"`
+	KID       string `gorm:"column:kid;size:36;unique;not null"`
+	Algorithm string `gorm:"column:algorithm;size:10;not null"`
+	// the PKCS #8 PEM of the private key, encrypted with JWT_KEY_ENCRYPTION_KEY
+	PrivateKey string    `gorm:"column:private_key;type:text;not null"`
+	CreatedAt  time.Time `gorm:"column:created_at;not null"`
+	// when the key takes over signing
+	SignsFrom time.Time `gorm:"column:signs_from"`
+	// when a newer key took over signing, after which the key only verifies the tokens it has already signed
+	RetiredAt *time.Time `gorm:"column:retired_at"`
+}
+
+// The cipher the private keys are encrypted with, keyed by the sha256 hash of JWT_KEY_ENCRYPTION_KEY
+func signingKeyCipher() (cipher.AEAD, error) {
+	secret := os.Getenv("JWT_KEY_ENCRYPTION_KEY")
+	if secret == "" {
+		return nil, errors.New("JWT_KEY_ENCRYPTION_KEY must be set to encrypt the jwt signing keys")
+	}
+	hash := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(hash[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Whether the private key was stored before the private keys were encrypted, so it is still a plain PEM
+func (key *SigningKey) unencrypted() bool {
+	return strings.HasPrefix(key.PrivateKey, "-----BEGIN")
+}
+
+// Store the PEM of the private key encrypted, authenticating the kid with it so it cannot be moved to another key
+func (key *SigningKey) encrypt(private []byte) error {
+	aead, err := signingKeyCipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	key.PrivateKey = base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, private, []byte(key.KID)))
+	return nil
+}
+
+func (key *SigningKey) decrypt() ([]byte, error) {
+	if key.unencrypted() {
+		return []byte(key.PrivateKey), nil
+	}
+	aead, err := signingKeyCipher()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(key.PrivateKey)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("signing key %s is not encrypted", key.KID)
+	}
+	private, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(key.KID))
+	if err != nil {
+		return nil, fmt.Errorf("signing key %s cannot be decrypted with JWT_KEY_ENCRYPTION_KEY", key.KID)
+	}
+	return private, nil
+}
+
+// The private key, which is an *rsa.PrivateKey or an *ecdsa.PrivateKey depending on the algorithm
+func (key *SigningKey) Signer() (crypto.Signer, error) {
+	encoded, err := key.decrypt()
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(encoded)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s is not PEM encoded", key.KID)
+	}
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("signing key %s cannot sign", key.KID)
+	}
+	return signer, nil
+}
+
+// Make a key for an algorithm which signs from a given time
+func newSigningKey(algorithm string, signsFrom time.Time) (*SigningKey, error) {
+	var private crypto.Signer
+	var err error
+	switch algorithm {
+	case SigningAlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case SigningAlgorithmES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported jwt signing algorithm '%s', must be one of %v", algorithm, SigningAlgorithms)
+	}
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := utility.GenerateRandomUUID()
+	if err != nil {
+		return nil, err
+	}
+	key := &SigningKey{
+		KID:       kid,
+		Algorithm: algorithm,
+		SignsFrom: signsFrom,
+	}
+	if err := key.encrypt(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func GetSigningKey(ctx *gin.Context, kid string) (*SigningKey, error) {
+	var keys []*SigningKey
+	tx := GetDBTransaction(ctx).Model(&SigningKey{}).Where("kid = ?", kid).Find(&keys)
+	if tx.Error != nil {
+		return nil, handleError(ctx, tx.Error)
+	}
+	if len(keys) == 0 {
+		ctx.Set("errorCode", http.StatusNotFound)
+		return nil, errors.New("signing key not found")
+	}
+	return keys[0], nil
+}
+
+// Get every key tokens can be verified with, including any which has not started signing yet, newest first
+func GetSigningKeys(ctx *gin.Context) ([]*SigningKey, error) {
+	var keys []*SigningKey
+	tx := GetDBTransaction(ctx).Model(&SigningKey{}).Order("signs_from DESC").Order("id DESC").Find(&keys)
+	if tx.Error != nil {
+		return nil, handleError(ctx, tx.Error)
+	}
+	return keys, nil
+}
+
+// Get the key new tokens are signed with, making one for an algorithm if there is none yet
+func GetActiveSigningKey(ctx *gin.Context, algorithm string) (*SigningKey, error) {
+	tx := GetDBTransaction(ctx)
+	now := time.Now()
+	var keys []*SigningKey
+	err := tx.Model(&SigningKey{}).Where("signs_from <= ? AND (retired_at IS NULL OR retired_at > ?)", now, now).
+		Order("signs_from DESC").Order("id DESC").Limit(1).Find(&keys).Error
+	if err != nil {
+		return nil, handleError(ctx, err)
+	}
+	if len(keys) > 0 {
+		return keys[0], nil
+	}
+
+	key, err := newSigningKey(algorithm, now.Truncate(time.Millisecond))
+	if err != nil {
+		ctx.Set("errorCode", http.StatusInternalServerError)
+		return nil, err
+	}
+	if err := tx.Create(key).Error; err != nil {
+		return nil, handleError(ctx, err)
+	}
+	return key, nil
+}
+
+// Make a new signing key if the newest one has signed for the rotation interval or is for a different algorithm,
+// publishing it for a while before it takes over signing from the keys before it, and delete the keys retired long
+// enough ago that every token they signed has expired. Keys stored before the private keys were encrypted are encrypted
+func RotateSigningKeys(db *gorm.DB, algorithm string, interval time.Duration, publish time.Duration, grace time.Duration) error {
+	if !slices.Contains(SigningAlgorithms, algorithm) {
+		return fmt.Errorf("unsupported jwt signing algorithm '%s', must be one of %v", algorithm, SigningAlgorithms)
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now().Truncate(time.Millisecond)
+		// every key is locked, so a rotation on another server waits for this one and then finds the key it made
+		var keys []*SigningKey
+		err := tx.Model(&SigningKey{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Order("signs_from DESC").Order("id DESC").Find(&keys).Error
+		if err != nil {
+			return err
+		}
+		var newest *SigningKey
+		unretired := make([]uint, 0)
+		for _, key := range keys {
+			if key.unencrypted() {
+				if err := key.encrypt([]byte(key.PrivateKey)); err != nil {
+					return err
+				}
+				if err := tx.Model(&SigningKey{}).Where("id = ?", key.ID).Update("private_key", key.PrivateKey).Error; err != nil {
+					return err
+				}
+			}
+			if key.RetiredAt == nil {
+				unretired = append(unretired, key.ID)
+				if newest == nil {
+					newest = key
+				}
+			}
+		}
+
+		// a key waiting to take over is not replaced until it has signed for the interval, unless the algorithm changed
+		if newest == nil || newest.Algorithm != algorithm || now.Sub(newest.SignsFrom) >= interval-publish {
+			signsFrom := now
+			if newest != nil {
+				signsFrom = now.Add(publish)
+			}
+			key, err := newSigningKey(algorithm, signsFrom)
+			if err != nil {
+				return err
+			}
+			if err := tx.Create(key).Error; err != nil {
+				return err
+			}
+			if len(unretired) > 0 {
+				if err := tx.Model(&SigningKey{}).Where("id IN (?)", unretired).Update("retired_at", signsFrom).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Where("retired_at < ?", now.Add(-grace)).Delete(&SigningKey{}).Error
+	})
+}
@@ -3,7 +3,9 @@ package database
 import (
 	"com668-backend/utility"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -14,6 +16,13 @@ type Team struct {
 	UUID  string `gorm:"column:uuid;size:36;unique;not null;uniqueIndex"`
 	Name  string `gorm:"column:name;size:30;unique;not null"`
 	Users []User `gorm:"many2many:team_user"`
+	Roles []Role `gorm:"many2many:team_role_assignment"`
+	// the memberships of the team's users, which hold their role in the team
+	Members []TeamUser `gorm:"foreignKey:team_id;references:id"`
+	// whether only the team's members can see the incidents and hosts it owns
+	Private bool `gorm:"column:private;not null;default:false"`
+	// bumped on every change, and part of the team's ETag
+	Version uint `gorm:"column:version;not null;default:1"`
 }
 
 func (team *Team) BeforeCreate(tx *gorm.DB) error {
@@ -31,10 +40,28 @@ func (team *Team) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+const (
+	TeamRoleMember string = "member"
+	TeamRoleLead   string = "lead"
+)
+
+var (
+	TeamRoles []string = []string{TeamRoleMember, TeamRoleLead}
+	// the fields teams can be sorted by, mapped to their column
+	teamSortColumns map[string]string = map[string]string{
+		"name": "name",
+	}
+	TeamSortFields []string = []string{"name"}
+)
+
 type GetTeamsFilters struct {
 	UUIDs    []string
 	Page     *int
 	PageSize *int
+	Sort     []SortKey
+	Cursor   *string
+	// filled with the cursors of the neighbouring pages, if set
+	Cursors *PageCursors
 }
 
 func GetTeam(ctx *gin.Context, filters GetTeamsFilters) (*Team, error) {
@@ -54,7 +81,7 @@ func GetTeam(ctx *gin.Context, filters GetTeamsFilters) (*Team, error) {
 
 func GetTeams(ctx *gin.Context, filters GetTeamsFilters) ([]*Team, int64, error) {
 	tx := GetDBTransaction(ctx).Model(&Team{})
-	tx = tx.Preload("Users")
+	tx = tx.Preload("Users").Preload("Members")
 
 	if len(filters.UUIDs) > 0 {
 		tx = tx.Where("uuid IN (?)", filters.UUIDs)
@@ -62,17 +89,257 @@ func GetTeams(ctx *gin.Context, filters GetTeamsFilters) ([]*Team, int64, error)
 
 	var count int64
 	tx = tx.Count(&count)
-	if filters.PageSize != nil {
-		tx = tx.Limit(*filters.PageSize)
-		if filters.Page != nil {
-			tx = tx.Offset((*filters.Page - 1) * *filters.PageSize)
-		}
+	pages, err := newPaginator(ctx, &Team{}, teamSortColumns, filters.Sort, filters.Cursor, filters.Page, filters.PageSize)
+	if err != nil {
+		return nil, -1, err
 	}
+	tx = pages.apply(tx)
 
 	var teams []*Team
 	tx = tx.Find(&teams)
 	if tx.Error != nil {
 		return nil, -1, handleError(ctx, tx.Error)
 	}
+	if err := pages.finish(ctx, &teams, filters.Cursors); err != nil {
+		return nil, -1, err
+	}
 	return teams, count, nil
 }
+
+// Whether a user is a member of any of a set of teams
+func memberOfAny(user *User, teamIDs ...uint) bool {
+	for _, team := range user.Teams {
+		if slices.Contains(teamIDs, team.ID) {
+			return true
+		}
+	}
+	return false
+}
+
+// The teams of the logged in user and the private teams, to restrict what they can see to what is owned by one of
+// their teams or by no private team. Returns nil if nothing is hidden from them, as they hold the exempt permission or
+// the request is not made by a user
+func teamVisibility(ctx *gin.Context, exempt string) (mine *gorm.DB, private *gorm.DB) {
+	value, ok := ctx.Get("user")
+	if !ok || HasPermission(ctx, exempt) {
+		return nil, nil
+	}
+	db := GetDBTransaction(ctx)
+	mine = db.Model(&TeamUser{}).Select("team_id").Where("user_id = ?", value.(*User).ID)
+	private = db.Model(&Team{}).Select("id").Where("private = ?", true)
+	return mine, private
+}
+
+// Get the role of a user in a team, or an empty string if they are not a member of it
+func (team *Team) RoleOf(user *User) string {
+	for _, member := range team.Members {
+		if member.UserID == user.ID {
+			return member.Role
+		}
+	}
+	return ""
+}
+
+func CreateTeam(ctx *gin.Context, team *Team) error {
+	tx := GetDBTransaction(ctx).Model(&Team{}).Omit("Users", "Members", "Roles").Create(team)
+	if tx.Error != nil {
+		return handleError(ctx, tx.Error)
+	}
+	return nil
+}
+
+func UpdateTeam(ctx *gin.Context, team *Team) error {
+	if err := claimVersion(ctx, &Team{}, team.ID, team.Version); err != nil {
+		return err
+	}
+	tx := GetDBTransaction(ctx).Model(&Team{}).Where("id = ?", team.ID).Updates(map[string]any{
+		"name":    team.Name,
+		"private": team.Private,
+	})
+	if tx.Error != nil {
+		return handleError(ctx, tx.Error)
+	}
+	return bumpTeamDependents(ctx, team)
+}
+
+// Bump the versions of the hosts a team owns and the incidents it owns, which embed its name and members
+func bumpTeamDependents(ctx *gin.Context, team *Team) error {
+	tx := GetDBTransaction(ctx)
+	err := tx.Model(&HostMachine{}).Where("team_id = ?", team.ID).UpdateColumn("version", gorm.Expr("version + 1")).Error
+	if err == nil {
+		resolving, hosting := incidentsOwnedBy(ctx, tx.Model(&Team{}).Select("id").Where("id = ?", team.ID))
+		err = tx.Model(&Incident{}).Where("id IN (?) OR id IN (?)", resolving, hosting).UpdateColumn("version", gorm.Expr("version + 1")).Error
+	}
+	if err != nil {
+		return handleError(ctx, err)
+	}
+	return nil
+}
+
+// Delete a team. Hosts owned by the team and incidents it is a resolution team of are moved to reassignTo, and the team
+// is not deleted if anything still references it and reassignTo is nil
+func DeleteTeam(ctx *gin.Context, team *Team, reassignTo *Team) error {
+	if err := claimVersion(ctx, &Team{}, team.ID, team.Version); err != nil {
+		return err
+	}
+	if err := bumpTeamDependents(ctx, team); err != nil {
+		return err
+	}
+	tx := GetDBTransaction(ctx)
+
+	var hosts int64
+	if err := tx.Model(&HostMachine{}).Where("team_id = ?", team.ID).Count(&hosts).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	incidents := make([]*IncidentResolutionTeam, 0)
+	if err := tx.Model(&IncidentResolutionTeam{}).Where("team_id = ?", team.ID).Find(&incidents).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	if reassignTo == nil && (hosts > 0 || len(incidents) > 0) {
+		ctx.Set("errorCode", http.StatusConflict)
+		return fmt.Errorf("the team owns %d hosts and is a resolution team of %d incidents, give a team to reassign them to", hosts, len(incidents))
+	}
+	if reassignTo != nil && reassignTo.ID == team.ID {
+		ctx.Set("errorCode", http.StatusBadRequest)
+		return errors.New("a team cannot be reassigned to itself")
+	}
+
+	if hosts > 0 {
+		if err := tx.Model(&HostMachine{}).Where("team_id = ?", team.ID).Update("team_id", reassignTo.ID).Error; err != nil {
+			return handleError(ctx, err)
+		}
+	}
+	for _, incident := range incidents {
+		var existing int64
+		if err := tx.Model(&IncidentResolutionTeam{}).Where("incident_id = ? AND team_id = ?", incident.IncidentID, reassignTo.ID).Count(&existing).Error; err != nil {
+			return handleError(ctx, err)
+		}
+		// an incident the other team already resolves just loses this team
+		newTeams := []string{}
+		if existing == 0 {
+			if err := tx.Create(&IncidentResolutionTeam{IncidentID: incident.IncidentID, TeamID: reassignTo.ID}).Error; err != nil {
+				return handleError(ctx, err)
+			}
+			newTeams = append(newTeams, reassignTo.Name)
+		}
+		if err := recordIncidentSetChanges(ctx, incident.IncidentID, []string{team.Name}, newTeams, IncidentEventTeamAdded, IncidentEventTeamRemoved); err != nil {
+			return err
+		}
+	}
+	if err := tx.Where("team_id = ?", team.ID).Delete(&IncidentResolutionTeam{}).Error; err != nil {
+		return handleError(ctx, err)
+	}
+
+	for _, model := range []any{&TeamUser{}, &TeamRoleAssignment{}, &GroupMapping{}} {
+		if err := tx.Where("team_id = ?", team.ID).Delete(model).Error; err != nil {
+			return handleError(ctx, err)
+		}
+	}
+	if err := tx.Where("id = ?", team.ID).Delete(&Team{}).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	// the members of the team may not be in the team the incidents were reassigned to
+	incidentIDs := make([]uint, 0)
+	for _, incident := range incidents {
+		incidentIDs = append(incidentIDs, incident.IncidentID)
+	}
+	if len(incidentIDs) == 0 {
+		return nil
+	}
+	return unassignIneligibleAssignees(ctx, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("tbl_incident.id IN (?)", incidentIDs)
+	})
+}
+
+// Check the logged in user is an admin if any of the teams a user is joining hold the admin role, as joining them
+// makes the user an admin
+func checkAdminTeams(ctx *gin.Context, teamIDs []uint) error {
+	if len(teamIDs) == 0 {
+		return nil
+	}
+	tx := GetDBTransaction(ctx)
+	roleIDs := tx.Model(&TeamRoleAssignment{}).Select("role_id").Where("team_id IN ?", teamIDs)
+	var admins int64
+	if err := tx.Model(&Role{}).Where("uuid = ? AND id IN (?)", AdminRoleUUID, roleIDs).Count(&admins).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	if admins > 0 {
+		return checkAdmin(ctx, "add a user to a team holding the admin role")
+	}
+	return nil
+}
+
+// Check the logged in user may change the members of a team, given the roles the team holds. Only an admin can add a
+// member to or promote a lead of a team holding the admin role, and a lead without the 'team:write' permission can
+// only change the members of a team whose roles give no permissions they lack
+func checkTeamMemberChange(ctx *gin.Context, team *Team, granting bool) error {
+	if granting {
+		if err := checkAdminTeams(ctx, []uint{team.ID}); err != nil {
+			return err
+		}
+	}
+	if HasPermission(ctx, PermissionTeamWrite) {
+		return nil
+	}
+	tx := GetDBTransaction(ctx)
+	roleIDs := tx.Model(&TeamRoleAssignment{}).Select("role_id").Where("team_id = ?", team.ID)
+	var permissions []string
+	if err := tx.Model(&RolePermission{}).Distinct("permission").Where("role_id IN (?)", roleIDs).Pluck("permission", &permissions).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	for _, permission := range permissions {
+		if !HasPermission(ctx, permission) {
+			ctx.Set("errorCode", http.StatusForbidden)
+			return fmt.Errorf("a team lead cannot change the members of a team whose roles give the '%s' permission, which they do not have", permission)
+		}
+	}
+	return nil
+}
+
+// Add a user to a team with a role, or change their role if they are already a member of it
+func SetTeamMember(ctx *gin.Context, team *Team, user *User, role string) error {
+	if err := checkTeamMemberChange(ctx, team, team.RoleOf(user) == "" || role == TeamRoleLead); err != nil {
+		return err
+	}
+	tx := GetDBTransaction(ctx)
+	member := &TeamUser{TeamID: team.ID, UserID: user.ID, Role: role}
+	if team.RoleOf(user) == "" {
+		tx = tx.Omit("Team", "User").Create(member)
+	} else {
+		tx = tx.Model(&TeamUser{}).Where("team_id = ? AND user_id = ?", team.ID, user.ID).Update("role", role)
+	}
+	if tx.Error != nil {
+		return handleError(ctx, tx.Error)
+	}
+	if err := bumpTeamDependents(ctx, team); err != nil {
+		return err
+	}
+	return bumpVersion(ctx, &Team{}, team.ID)
+}
+
+// Remove a user from a team, taking them off as the assignee of the team's incidents unless they are a member of
+// another of its resolution teams
+func RemoveTeamMember(ctx *gin.Context, team *Team, user *User) error {
+	if team.RoleOf(user) == "" {
+		ctx.Set("errorCode", http.StatusNotFound)
+		return errors.New("the user is not a member of the team")
+	}
+	if err := checkTeamMemberChange(ctx, team, false); err != nil {
+		return err
+	}
+	tx := GetDBTransaction(ctx).Where("team_id = ? AND user_id = ?", team.ID, user.ID).Delete(&TeamUser{})
+	if tx.Error != nil {
+		return handleError(ctx, tx.Error)
+	}
+	if err := bumpTeamDependents(ctx, team); err != nil {
+		return err
+	}
+	resolving := GetDBTransaction(ctx).Model(&IncidentResolutionTeam{}).Select("incident_id").Where("team_id = ?", team.ID)
+	err := unassignIneligibleAssignees(ctx, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("tbl_incident.assignee_id = ? AND tbl_incident.id IN (?)", user.ID, resolving)
+	})
+	if err != nil {
+		return err
+	}
+	return bumpVersion(ctx, &Team{}, team.ID)
+}
@@ -3,7 +3,10 @@ package database
 import (
 	"com668-backend/utility"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
@@ -17,13 +20,33 @@ type User struct {
 	Name     string `gorm:"column:name;size:30;unique;not null"`
 	Email    string `gorm:"column:email;size:30;unique;not null;uniqueIndex"`
 	Password string `gorm:"column:password;size:72;not null"`
-	Admin    bool   `gorm:"column:admin;not null"`
-	Teams    []Team `gorm:"many2many:team_user"`
-	SlackID  string `gorm:"column:slack_id;size:20"`
+	// whether the user holds the built in admin role
+	Admin   bool   `gorm:"column:admin;not null"`
+	Teams   []Team `gorm:"many2many:team_user"`
+	Roles   []Role `gorm:"many2many:user_role_assignment"`
+	SlackID string `gorm:"column:slack_id;size:20"`
+	// when the user was deactivated, or nil if they can still log in
+	DeactivatedAt *time.Time `gorm:"column:deactivated_at"`
+	// whether the user is used by automation, which cannot log in with a password and authenticates with API keys
+	ServiceAccount bool     `gorm:"column:service_account;not null;default:false"`
+	APIKeys        []APIKey `gorm:"foreignKey:user_id;references:id"`
+	// the identity provider the user logs in with and their id in it, or nil for a user who only has a password
+	IdentityProvider *string `gorm:"column:identity_provider;size:10;uniqueIndex:idx_user_identity"`
+	ExternalID       *string `gorm:"column:external_id;size:255;uniqueIndex:idx_user_identity"`
 }
 
-func (user *User) hashPassword() (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+var (
+	userSortColumns map[string]string = map[string]string{
+		"name":  "name",
+		"email": "email",
+	}
+	UserSortFields []string = []string{"name", "email"}
+)
+
+// Hash a plain text password to store it. Passwords are hashed explicitly wherever one is set, rather than in a hook,
+// so a stored hash is never hashed again
+func hashPassword(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return "", err
 	}
@@ -47,35 +70,15 @@ func (user *User) BeforeCreate(tx *gorm.DB) error {
 		}
 		user.UUID = uuid
 	}
-	password, err := user.hashPassword()
-	if err != nil {
-		if ctx != nil {
-			ctx.Set("errorCode", http.StatusInternalServerError)
-		}
-		return err
-	}
-	user.Password = password
-	return nil
-}
-
-func (user *User) BeforeUpdate(tx *gorm.DB) error {
-	ctx := GetContext(tx)
-	password, err := user.hashPassword()
-	if err != nil {
-		if ctx != nil {
-			ctx.Set("errorCode", http.StatusInternalServerError)
-		}
-		return err
-	}
-	user.Password = password
 	return nil
 }
 
 type TeamUser struct {
-	TeamID uint `gorm:"column:team_id;primaryKey"`
-	Team   Team `gorm:"foreignKey:team_id;references:id"`
-	UserID uint `gorm:"column:user_id;primaryKey"`
-	User   User `gorm:"foreignKey:user_id;references:id"`
+	TeamID uint   `gorm:"column:team_id;primaryKey"`
+	Team   Team   `gorm:"foreignKey:team_id;references:id"`
+	UserID uint   `gorm:"column:user_id;primaryKey"`
+	User   User   `gorm:"foreignKey:user_id;references:id"`
+	Role   string `gorm:"column:role;size:6;not null;default:'member';check:role IN ('member','lead')"`
 }
 
 type GetUserFilters struct {
@@ -83,6 +86,19 @@ type GetUserFilters struct {
 	Email *string
 }
 
+type GetUsersFilters struct {
+	// matched against part of the user's name or email
+	Search *string
+	// whether to only get active users, or only deactivated ones
+	Active   *bool
+	Page     *int
+	PageSize *int
+	Sort     []SortKey
+	Cursor   *string
+	// filled with the cursors of the neighbouring pages, if set
+	Cursors *PageCursors
+}
+
 func GetUser(ctx *gin.Context, filters GetUserFilters) (*User, error) {
 	tx := GetDBTransaction(ctx).Model(&User{})
 	if filters.UUID != nil {
@@ -103,19 +119,86 @@ func GetUser(ctx *gin.Context, filters GetUserFilters) (*User, error) {
 	return users[0], nil
 }
 
-func CreateUser(ctx *gin.Context, body *utility.UserPostRequestBodySchema) (*User, error) {
+func GetUsers(ctx *gin.Context, filters GetUsersFilters) ([]*User, int64, error) {
 	tx := GetDBTransaction(ctx).Model(&User{})
+	tx = tx.Preload("Teams")
+
+	if filters.Search != nil && *filters.Search != "" {
+		search := fmt.Sprintf("%%%s%%", strings.ToLower(*filters.Search))
+		tx = tx.Where("(LOWER(name) LIKE ? OR LOWER(email) LIKE ?)", search, search)
+	}
+	if filters.Active != nil {
+		if *filters.Active {
+			tx = tx.Where("deactivated_at IS NULL")
+		} else {
+			tx = tx.Where("deactivated_at IS NOT NULL")
+		}
+	}
+
+	var count int64
+	tx = tx.Count(&count)
+	pages, err := newPaginator(ctx, &User{}, userSortColumns, filters.Sort, filters.Cursor, filters.Page, filters.PageSize)
+	if err != nil {
+		return nil, -1, err
+	}
+	tx = pages.apply(tx)
+
+	var users []*User
+	tx = tx.Find(&users)
+	if tx.Error != nil {
+		return nil, -1, handleError(ctx, tx.Error)
+	}
+	if err := pages.finish(ctx, &users, filters.Cursors); err != nil {
+		return nil, -1, err
+	}
+	return users, count, nil
+}
+
+func CreateUser(ctx *gin.Context, body *utility.UserPostRequestBodySchema) (*User, error) {
 	teams := make([]Team, 0)
-	for _, team := range body.Teams {
-		teams = append(teams, Team{
-			UUID: team,
+	if len(body.Teams) > 0 {
+		ts, count, err := GetTeams(ctx, GetTeamsFilters{
+			UUIDs:    body.Teams,
+			PageSize: utility.Pointer(len(body.Teams)),
 		})
+		if err != nil {
+			return nil, err
+		}
+		if int(count) != len(body.Teams) {
+			ctx.Set("errorCode", http.StatusBadRequest)
+			return nil, errors.New("one or more teams not found")
+		}
+		teamIDs := make([]uint, 0, len(ts))
+		for _, team := range ts {
+			teams = append(teams, *team)
+			teamIDs = append(teamIDs, team.ID)
+		}
+		if err := checkAdminTeams(ctx, teamIDs); err != nil {
+			return nil, err
+		}
+	}
+	password := body.Password
+	if body.ServiceAccount {
+		// service accounts never log in, so they are given a password nobody knows
+		uuid, err := utility.GenerateRandomUUID()
+		if err != nil {
+			ctx.Set("errorCode", http.StatusInternalServerError)
+			return nil, errors.New("failed to create a service account password")
+		}
+		password = uuid
+	}
+	password, err := hashPassword(password)
+	if err != nil {
+		ctx.Set("errorCode", http.StatusInternalServerError)
+		return nil, errors.New("failed to hash the password")
 	}
+	tx := GetDBTransaction(ctx).Model(&User{})
 	user := &User{
-		Name:     body.Name,
-		Email:    body.Email,
-		Password: body.Password,
-		Teams:    teams,
+		Name:           body.Name,
+		Email:          body.Email,
+		Password:       password,
+		Teams:          teams,
+		ServiceAccount: body.ServiceAccount,
 	}
 	tx = tx.Clauses(clause.OnConflict{DoNothing: true}).Save(user)
 	if tx.Error != nil {
@@ -125,14 +208,307 @@ func CreateUser(ctx *gin.Context, body *utility.UserPostRequestBodySchema) (*Use
 }
 
 func UpdateUser(ctx *gin.Context, user *User) error {
+	var admins int64
+	if err := GetDBTransaction(ctx).Model(&User{}).Where("uuid = ? AND admin = ?", user.UUID, true).Count(&admins).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	if wasAdmin := admins > 0; wasAdmin != user.Admin {
+		if err := checkAdmin(ctx, "change whether a user is an admin"); err != nil {
+			return err
+		}
+		if wasAdmin {
+			if err := checkNotLastAdmin(ctx, user); err != nil {
+				return err
+			}
+		}
+	}
+	// only the teams the user is joining matter, so one already in a team holding the admin role can still be updated
+	var listed, joined []uint
+	for _, team := range user.Teams {
+		listed = append(listed, team.ID)
+	}
+	current := GetDBTransaction(ctx).Model(&TeamUser{}).Select("team_id").Where("user_id = ?", user.ID)
+	if len(listed) > 0 {
+		if err := GetDBTransaction(ctx).Model(&Team{}).Where("id IN ? AND id NOT IN (?)", listed, current).Pluck("id", &joined).Error; err != nil {
+			return handleError(ctx, err)
+		}
+	}
+	if err := checkAdminTeams(ctx, joined); err != nil {
+		return err
+	}
 	tx := GetDBTransaction(ctx).Model(&User{})
-	tx = tx.Where("uuid = ?", user.UUID).Save(user)
+	tx = tx.Where("uuid = ?", user.UUID).Omit("Teams", "Roles", "APIKeys").Save(user)
+	if tx.Error != nil {
+		return handleError(ctx, tx.Error)
+	}
+	// replace the memberships rather than adding to them, so the user leaves any teams no longer listed
+	if err := GetDBTransaction(ctx).Model(user).Association("Teams").Replace(user.Teams); err != nil {
+		return handleError(ctx, err)
+	}
+	// and is taken off as the assignee of incidents none of their teams resolve any more
+	return unassignIneligibleAssignees(ctx, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("tbl_incident.assignee_id = ?", user.ID)
+	})
+}
+
+// Set a new password for a user, which is hashed before it is stored
+func UpdateUserPassword(ctx *gin.Context, user *User, password string) error {
+	password, err := hashPassword(password)
+	if err != nil {
+		ctx.Set("errorCode", http.StatusInternalServerError)
+		return errors.New("failed to hash the password")
+	}
+	user.Password = password
+	tx := GetDBTransaction(ctx).Model(user).Select("password").Updates(user)
+	if tx.Error != nil {
+		return handleError(ctx, tx.Error)
+	}
+	return nil
+}
+
+// Stop a user from logging in or using any token they already have, keeping everything they have done
+func DeactivateUser(ctx *gin.Context, user *User) error {
+	if user.DeactivatedAt != nil {
+		ctx.Set("errorCode", http.StatusConflict)
+		return errors.New("user is already deactivated")
+	}
+	if user.Admin {
+		if err := checkNotLastAdmin(ctx, user); err != nil {
+			return err
+		}
+	}
+	// a deactivated user can't work on incidents, so they are taken off them
+	if err := unassignUser(ctx, user); err != nil {
+		return err
+	}
+	now := time.Now()
+	tx := GetDBTransaction(ctx).Model(&User{}).Where("id = ?", user.ID).Update("deactivated_at", now)
+	if tx.Error != nil {
+		return handleError(ctx, tx.Error)
+	}
+	user.DeactivatedAt = &now
+	return nil
+}
+
+// Refuse to take away the admin flag of a user if no other active user is an admin, so there is always someone who can
+// manage the users
+func checkNotLastAdmin(ctx *gin.Context, user *User) error {
+	tx := GetDBTransaction(ctx)
+	adminRole := tx.Model(&Role{}).Select("id").Where("uuid = ?", AdminRoleUUID)
+	assigned := tx.Model(&UserRoleAssignment{}).Select("user_id").Where("role_id IN (?)", adminRole)
+	throughTeams := tx.Table("tbl_team_user").Select("tbl_team_user.user_id").
+		Joins("JOIN tbl_team_role_assignment ON tbl_team_role_assignment.team_id = tbl_team_user.team_id").
+		Where("tbl_team_role_assignment.role_id IN (?)", adminRole)
+	var admins int64
+	err := tx.Model(&User{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("uuid <> ? AND deactivated_at IS NULL", user.UUID).
+		Where(tx.Where("admin = ?", true).Or("id IN (?)", assigned).Or("id IN (?)", throughTeams)).
+		Count(&admins).Error
+	if err != nil {
+		return handleError(ctx, err)
+	}
+	if admins == 0 {
+		ctx.Set("errorCode", http.StatusConflict)
+		return errors.New("user is the last admin")
+	}
+	return nil
+}
+
+func ReactivateUser(ctx *gin.Context, user *User) error {
+	if user.DeactivatedAt == nil {
+		ctx.Set("errorCode", http.StatusConflict)
+		return errors.New("user is not deactivated")
+	}
+	tx := GetDBTransaction(ctx).Model(&User{}).Where("id = ?", user.ID).Update("deactivated_at", nil)
 	if tx.Error != nil {
 		return handleError(ctx, tx.Error)
 	}
+	user.DeactivatedAt = nil
+	return nil
+}
+
+// Who an identity provider says has logged in with it
+type ExternalIdentity struct {
+	Provider string
+	// the id of the user in the identity provider, which never changes even if their email does
+	Subject string
+	Name    string
+	Email   string
+}
+
+// Find the user an identity provider has logged in by their id there, creating them the first time they log in. A user
+// who already has the email is never linked to the identity provider here, since whoever runs it can give anybody any
+// email, so an admin has to link them with LinkExternalUser first
+func ProvisionExternalUser(ctx *gin.Context, identity ExternalIdentity) (*User, error) {
+	tx := GetDBTransaction(ctx)
+	users := make([]*User, 0)
+	if err := tx.Model(&User{}).Preload("Teams").Where("identity_provider = ? AND external_id = ?", identity.Provider, identity.Subject).Find(&users).Error; err != nil {
+		return nil, handleError(ctx, err)
+	}
+	if len(users) > 0 {
+		return users[0], nil
+	}
+	if identity.Email == "" {
+		ctx.Set("errorCode", http.StatusBadRequest)
+		return nil, errors.New("the identity provider did not give the user's email")
+	}
+
+	user, err := GetUser(ctx, GetUserFilters{Email: &identity.Email})
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		ctx.Set("errorCode", http.StatusConflict)
+		return nil, errors.New("a user with the email already exists, and must be linked to the identity provider by an admin")
+	}
+
+	name, err := uniqueUserName(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	// users from an identity provider log in with it, so they are given a password nobody knows
+	password, err := utility.GenerateRandomUUID()
+	if err == nil {
+		password, err = hashPassword(password)
+	}
+	if err != nil {
+		ctx.Set("errorCode", http.StatusInternalServerError)
+		return nil, errors.New("failed to create a user password")
+	}
+	user = &User{
+		Name:             name,
+		Email:            identity.Email,
+		Password:         password,
+		Teams:            []Team{},
+		IdentityProvider: &identity.Provider,
+		ExternalID:       &identity.Subject,
+	}
+	if err := tx.Model(&User{}).Create(user).Error; err != nil {
+		return nil, handleError(ctx, err)
+	}
+	return user, nil
+}
+
+// Link a user to their id in an identity provider, so they log in with it from then on. Only an admin can link a
+// user, and service accounts and users another user's id is linked to are refused with a 409
+func LinkExternalUser(ctx *gin.Context, user *User, provider string, subject string) error {
+	if err := checkAdmin(ctx, "link a user to an identity provider"); err != nil {
+		return err
+	}
+	if user.ServiceAccount {
+		ctx.Set("errorCode", http.StatusConflict)
+		return errors.New("a service account cannot be linked to an identity provider")
+	}
+	tx := GetDBTransaction(ctx)
+	var count int64
+	if err := tx.Model(&User{}).Where("identity_provider = ? AND external_id = ? AND id != ?", provider, subject, user.ID).Count(&count).Error; err != nil {
+		return handleError(ctx, err)
+	}
+	if count > 0 {
+		ctx.Set("errorCode", http.StatusConflict)
+		return errors.New("another user is already linked to the id in the identity provider")
+	}
+	user.IdentityProvider = &provider
+	user.ExternalID = &subject
+	if err := tx.Model(&User{}).Where("id = ?", user.ID).Select("identity_provider", "external_id").Updates(user).Error; err != nil {
+		return handleError(ctx, err)
+	}
 	return nil
 }
 
-func DeleteUser(tx *gorm.DB) error {
+// Get every user linked to an identity provider
+func GetExternalUsers(ctx *gin.Context, provider string) ([]*User, error) {
+	users := make([]*User, 0)
+	if err := GetDBTransaction(ctx).Model(&User{}).Preload("Teams").Where("identity_provider = ?", provider).Order("id").Find(&users).Error; err != nil {
+		return nil, handleError(ctx, err)
+	}
+	return users, nil
+}
+
+// Pick a name for a user from an identity provider, which is their name there if no other user has it
+func uniqueUserName(ctx *gin.Context, identity ExternalIdentity) (string, error) {
+	name := identity.Name
+	if name == "" {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+	if runes := []rune(name); len(runes) > 30 {
+		name = string(runes[:30])
+	}
+	var count int64
+	if err := GetDBTransaction(ctx).Model(&User{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return "", handleError(ctx, err)
+	}
+	if count == 0 {
+		return name, nil
+	}
+	suffix, err := utility.GenerateRandomUUID()
+	if err != nil {
+		ctx.Set("errorCode", http.StatusInternalServerError)
+		return "", errors.New("failed to create a user name")
+	}
+	if runes := []rune(name); len(runes) > 21 {
+		name = string(runes[:21])
+	}
+	return name + "-" + suffix[:8], nil
+}
+
+// The columns which reference the user who owns something on an incident, which is moved to another user when they
+// are deleted. The rest of an incident's history keeps the name of a deleted user instead
+var userOwnedColumns = []struct {
+	model  any
+	column string
+}{
+	{&Incident{}, "resolved_by_id"},
+	{&IncidentComment{}, "commented_by_id"},
+}
+
+// Delete a user. What they own on incidents is moved to reassignTo, and the user is not deleted if they own anything
+// and reassignTo is nil. The user is taken off any incidents they are assigned to or responding to, and the rest of
+// the history keeps their name
+func DeleteUser(ctx *gin.Context, user *User, reassignTo *User) error {
+	tx := GetDBTransaction(ctx)
+	if reassignTo != nil && reassignTo.ID == user.ID {
+		ctx.Set("errorCode", http.StatusBadRequest)
+		return errors.New("cannot reassign a user's history to the same user")
+	}
+	if user.Admin {
+		if err := checkNotLastAdmin(ctx, user); err != nil {
+			return err
+		}
+	}
+
+	for _, reference := range userOwnedColumns {
+		if reassignTo == nil {
+			var count int64
+			if err := tx.Model(reference.model).Where(fmt.Sprintf("%s = ?", reference.column), user.ID).Count(&count).Error; err != nil {
+				return handleError(ctx, err)
+			}
+			if count > 0 {
+				ctx.Set("errorCode", http.StatusConflict)
+				return errors.New("user has history on incidents, give a user to reassign it to or deactivate them instead")
+			}
+		} else if err := tx.Model(reference.model).Where(fmt.Sprintf("%s = ?", reference.column), user.ID).Update(reference.column, reassignTo.ID).Error; err != nil {
+			return handleError(ctx, err)
+		}
+	}
+
+	if err := unassignUser(ctx, user); err != nil {
+		return err
+	}
+
+	for _, model := range []any{&TeamUser{}, &UserRoleAssignment{}} {
+		if err := tx.Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+			return handleError(ctx, err)
+		}
+	}
+	if err := deleteAPIKeys(tx, user); err != nil {
+		return handleError(ctx, err)
+	}
+	if err := deleteSessions(tx, user); err != nil {
+		return handleError(ctx, err)
+	}
+	if err := tx.Delete(&User{}, user.ID).Error; err != nil {
+		return handleError(ctx, err)
+	}
 	return nil
 }
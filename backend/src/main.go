@@ -26,6 +26,10 @@ import (
 // @in							header
 // @name						Authorization
 // @description				The JWT
+// @securitydefinitions.apikey	APIKey
+// @in							header
+// @name						X-API-Key
+// @description				An API key, used instead of the JWT
 func main() {
 	if err := database.Connect(); err != nil {
 		panic(err)
@@ -39,6 +43,13 @@ func main() {
 	engine.HandleMethodNotAllowed = true
 	controller.RegisterControllers(engine)
 
+	// Rotate the jwt signing keys in the background
+	go middleware.RunJWTKeyRotation()
+	// Delete the sessions which can no longer be used in the background
+	go middleware.RunSessionPurge()
+	// Sync the users from the LDAP directory in the background, if there is one
+	go controller.RunLDAPSync()
+
 	// Run the webserver in a goroutine (non blocking call)
 	go (func() {
 		addr := fmt.Sprintf(":%d", 5000)
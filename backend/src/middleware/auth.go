@@ -4,9 +4,10 @@ import (
 	"com668-backend/database"
 	"com668-backend/utility"
 	"encoding/base64"
+	"fmt"
 	"log"
 	"net/http"
-	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -15,141 +16,240 @@ import (
 )
 
 var (
-	JWTSigningMethod     *jwt.SigningMethodHMAC = jwt.SigningMethodHS256
-	AuthHeaderNameString string                 = "Authorization"
+	AuthHeaderNameString string = "Authorization"
+	// the header an API key is given in, which is used instead of a JWT when it is set
+	APIKeyHeaderNameString string = "X-API-Key"
+	// the header and cookie the refresh token is given out in, and sent back in to get a new access token
+	RefreshHeaderNameString string = "Refresh-Token"
+	// access tokens are short lived, as they are only refused before they expire if their session is revoked
+	AccessTokenLifetime time.Duration = 15 * time.Minute
+	// how long a user stays logged in for without logging in again, if they keep refreshing their access token
+	RefreshTokenLifetime time.Duration = 30 * 24 * time.Hour
 )
 
-func UserAuthRequestMW(adminAuth bool) gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		authType := "header"
-		jwtString := ctx.GetHeader(AuthHeaderNameString)
-		// if jwt not in header, check cookies
-		if jwtString == "" {
-			authType = "cookie"
-			jwt, err := ctx.Cookie(AuthHeaderNameString)
-			if err != nil {
-				jwtString = ""
-			}
-			jwtString = jwt
-		}
-		// if jwt not in header or cookies, fail
-		if jwtString == "" {
-			ctx.Set("Status", http.StatusUnauthorized)
-			ctx.Set("Body", &utility.ErrorResponseSchema{
-				Error: "no jwt auth token specified",
-			})
-			ctx.Next()
-			return
+// Delete the sessions which have expired or been revoked, checking every hour until the server stops
+func RunSessionPurge() {
+	for {
+		if err := database.PurgeSessions(database.GetDBConn()); err != nil {
+			log.Default().Printf("Failed to purge the expired and revoked sessions: %s\n", err)
 		}
+		time.Sleep(time.Hour)
+	}
+}
 
-		// only attempt to parse `Bearer <token>` format if jwt is in header
-		if authType == "header" {
-			parts := strings.Split(jwtString, " ")
-			if len(parts) != 2 || (len(parts) > 0 && strings.ToLower(parts[0]) != "bearer") {
-				ctx.Set("Status", http.StatusUnauthorized)
+func UserAuthRequestMW() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var user *database.User
+		var apiKey *database.APIKey
+		if key := ctx.GetHeader(APIKeyHeaderNameString); key != "" {
+			var err error
+			apiKey, err = database.AuthenticateAPIKey(ctx, key)
+			if err != nil {
+				ctx.Set("Status", ctx.GetInt("errorCode"))
 				ctx.Set("Body", &utility.ErrorResponseSchema{
-					Error: "jwt auth token does not follow the format `Bearer <token>`",
+					Error: err.Error(),
 				})
 				ctx.Next()
 				return
 			}
-			jwtString = parts[1]
-		}
-
-		token, err := jwt.Parse(
-			jwtString,
-			func(t *jwt.Token) (interface{}, error) {
-				return []byte(os.Getenv("JWT_SIGNING_KEY")), nil
-			},
-			jwt.WithValidMethods([]string{
-				JWTSigningMethod.Name,
-			}),
-		)
-		if err != nil {
-			log.Default().Println(err)
-			ctx.Set("Status", http.StatusUnauthorized)
-			ctx.Set("Body", &utility.ErrorResponseSchema{
-				Error: "could not parse jwt auth token",
-			})
-			ctx.Next()
-			return
+			user = &apiKey.User
+		} else {
+			var ok bool
+			if user, ok = jwtUser(ctx); !ok {
+				return
+			}
 		}
-		if !token.Valid {
+		// tokens given out before the user was deactivated are still signed, so they are refused here
+		if user.DeactivatedAt != nil {
 			ctx.Set("Status", http.StatusUnauthorized)
 			ctx.Set("Body", &utility.ErrorResponseSchema{
-				Error: "jwt auth token is not valid",
+				Error: "user account has been deactivated",
 			})
 			ctx.Next()
 			return
 		}
 
-		exp, err := token.Claims.GetExpirationTime()
+		permissions, err := database.GetUserPermissions(ctx, user)
 		if err != nil {
-			log.Default().Println(err)
-			ctx.Set("Status", http.StatusUnauthorized)
+			ctx.Set("Status", ctx.GetInt("errorCode"))
 			ctx.Set("Body", &utility.ErrorResponseSchema{
-				Error: "could not parse jwt auth claims",
+				Error: err.Error(),
 			})
 			ctx.Next()
 			return
 		}
-		if time.Now().After(exp.Time) {
-			ctx.Set("Status", http.StatusUnauthorized)
-			ctx.Set("Body", &utility.ErrorResponseSchema{
-				Error: "jwt auth token is no longer valid",
+		if apiKey != nil {
+			// a key can only be used for the permissions in its scopes, and only while its user still holds them
+			scopes := apiKey.ScopeNames()
+			permissions = slices.DeleteFunc(permissions, func(permission string) bool {
+				return !slices.Contains(scopes, permission)
 			})
-			ctx.Next()
-			return
+			ctx.Set("apiKey", apiKey)
 		}
+		ctx.Set("user", user)
+		ctx.Set("permissions", permissions)
+	}
+}
 
-		sub, err := token.Claims.GetSubject()
+// Get the user a request is authenticated as by its JWT, setting the response if it cannot be
+func jwtUser(ctx *gin.Context) (*database.User, bool) {
+	authType := "header"
+	jwtString := ctx.GetHeader(AuthHeaderNameString)
+	// if jwt not in header, check cookies
+	if jwtString == "" {
+		authType = "cookie"
+		jwt, err := ctx.Cookie(AuthHeaderNameString)
 		if err != nil {
-			log.Default().Println(err)
-			ctx.Set("Status", http.StatusUnauthorized)
-			ctx.Set("Body", &utility.ErrorResponseSchema{
-				Error: "could not parse jwt auth claims",
-			})
-			ctx.Next()
-			return
+			jwtString = ""
 		}
-		subBytes, err := base64.StdEncoding.DecodeString(sub)
-		if err != nil {
-			ctx.Set("Status", http.StatusInternalServerError)
-			ctx.Set("Body", &utility.ErrorResponseSchema{
-				Error: err.Error(),
-			})
-			ctx.Next()
-			return
-		}
-		sub = string(subBytes)
-
-		user, err := database.GetUser(ctx, database.GetUserFilters{
-			UUID: &sub,
+		jwtString = jwt
+	}
+	// if jwt not in header or cookies, fail
+	if jwtString == "" {
+		ctx.Set("Status", http.StatusUnauthorized)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: "no jwt auth token specified",
 		})
-		if err != nil {
+		ctx.Next()
+		return nil, false
+	}
+
+	// only attempt to parse `Bearer <token>` format if jwt is in header
+	if authType == "header" {
+		parts := strings.Split(jwtString, " ")
+		if len(parts) != 2 || (len(parts) > 0 && strings.ToLower(parts[0]) != "bearer") {
 			ctx.Set("Status", http.StatusUnauthorized)
 			ctx.Set("Body", &utility.ErrorResponseSchema{
-				Error: err.Error(),
+				Error: "jwt auth token does not follow the format `Bearer <token>`",
 			})
 			ctx.Next()
-			return
+			return nil, false
 		}
-		if user == nil {
-			ctx.Set("Status", http.StatusUnauthorized)
-			ctx.Set("Body", &utility.ErrorResponseSchema{
-				Error: "invalid user credentials",
-			})
-			ctx.Next()
+		jwtString = parts[1]
+	}
+
+	token, err := jwt.Parse(
+		jwtString,
+		func(t *jwt.Token) (interface{}, error) {
+			return verificationKey(ctx, t)
+		},
+		jwt.WithValidMethods(database.SigningAlgorithms),
+	)
+	if err != nil {
+		log.Default().Println(err)
+		ctx.Set("Status", http.StatusUnauthorized)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: "could not parse jwt auth token",
+		})
+		ctx.Next()
+		return nil, false
+	}
+	if !token.Valid {
+		ctx.Set("Status", http.StatusUnauthorized)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: "jwt auth token is not valid",
+		})
+		ctx.Next()
+		return nil, false
+	}
+
+	exp, err := token.Claims.GetExpirationTime()
+	if err != nil {
+		log.Default().Println(err)
+		ctx.Set("Status", http.StatusUnauthorized)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: "could not parse jwt auth claims",
+		})
+		ctx.Next()
+		return nil, false
+	}
+	if time.Now().After(exp.Time) {
+		ctx.Set("Status", http.StatusUnauthorized)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: "jwt auth token is no longer valid",
+		})
+		ctx.Next()
+		return nil, false
+	}
+
+	sub, err := token.Claims.GetSubject()
+	if err != nil {
+		log.Default().Println(err)
+		ctx.Set("Status", http.StatusUnauthorized)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: "could not parse jwt auth claims",
+		})
+		ctx.Next()
+		return nil, false
+	}
+	subBytes, err := base64.StdEncoding.DecodeString(sub)
+	if err != nil {
+		ctx.Set("Status", http.StatusInternalServerError)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return nil, false
+	}
+	sub = string(subBytes)
+
+	user, err := database.GetUser(ctx, database.GetUserFilters{
+		UUID: &sub,
+	})
+	if err != nil {
+		ctx.Set("Status", http.StatusUnauthorized)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return nil, false
+	}
+	if user == nil {
+		ctx.Set("Status", http.StatusUnauthorized)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: "invalid user credentials",
+		})
+		ctx.Next()
+		return nil, false
+	}
+
+	// the token is refused once the session it was given out for is logged out of or revoked
+	sid, _ := token.Claims.(jwt.MapClaims)["sid"].(string)
+	session, err := database.GetSession(ctx, sid)
+	if err != nil && ctx.GetInt("errorCode") != http.StatusNotFound {
+		ctx.Set("Status", ctx.GetInt("errorCode"))
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: err.Error(),
+		})
+		ctx.Next()
+		return nil, false
+	}
+	if err != nil || session.RevokedAt != nil || session.UserID != user.ID {
+		ctx.Set("Status", http.StatusUnauthorized)
+		ctx.Set("Body", &utility.ErrorResponseSchema{
+			Error: "jwt auth token has been revoked",
+		})
+		ctx.Next()
+		return nil, false
+	}
+	ctx.Set("session", session)
+	return user, true
+}
+
+// Check the logged in user holds a permission, which must come after UserAuthRequestMW
+func PermissionRequestMW(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		// the user could not be authenticated, and the response already says why
+		if _, ok := ctx.Get("user"); !ok {
 			return
 		}
-		if adminAuth && !user.Admin {
+		if !database.HasPermission(ctx, permission) {
 			ctx.Set("Status", http.StatusForbidden)
 			ctx.Set("Body", &utility.ErrorResponseSchema{
-				Error: "logged in user must be an admin",
+				Error: fmt.Sprintf("logged in user does not have the '%s' permission", permission),
 			})
 			ctx.Next()
 			return
 		}
-		ctx.Set("user", user)
 	}
 }
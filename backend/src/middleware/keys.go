@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"com668-backend/database"
+	"crypto"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// how long other services may cache the published signing keys for, which is how long a new key is published
+	// before it starts signing
+	JWKSCacheLifetime time.Duration = time.Hour
+	// the public keys tokens have been verified with by their kid, which are kept as a key never changes once it is made
+	verificationKeys sync.Map
+)
+
+type cachedVerificationKey struct {
+	algorithm string
+	public    crypto.PublicKey
+}
+
+// The algorithm new signing keys are made for, from JWT_SIGNING_ALGORITHM, which is RS256 unless it is set
+func JWTSigningAlgorithm() string {
+	if algorithm := os.Getenv("JWT_SIGNING_ALGORITHM"); algorithm != "" {
+		return algorithm
+	}
+	return database.SigningAlgorithmRS256
+}
+
+// How long a signing key signs tokens for before a new one takes over, from JWT_KEY_ROTATION_INTERVAL, which is a week
+// unless it is set
+func JWTKeyRotationInterval() time.Duration {
+	if interval, err := time.ParseDuration(os.Getenv("JWT_KEY_ROTATION_INTERVAL")); err == nil && interval > 0 {
+		return interval
+	}
+	return 7 * 24 * time.Hour
+}
+
+// Rotate the signing keys when they are due, checking every hour until the server stops. A new key is published for
+// JWKSCacheLifetime before it signs, and a retired key is kept until every access token it signed has expired
+func RunJWTKeyRotation() {
+	for {
+		err := database.RotateSigningKeys(database.GetDBConn(), JWTSigningAlgorithm(), JWTKeyRotationInterval(), JWKSCacheLifetime, AccessTokenLifetime)
+		if err != nil {
+			log.Default().Printf("Failed to rotate the jwt signing keys: %s\n", err)
+		}
+		time.Sleep(time.Hour)
+	}
+}
+
+// Sign the claims of an access token with the active signing key, naming it in the kid header
+func SignJWT(ctx *gin.Context, claims jwt.MapClaims) (string, error) {
+	key, err := database.GetActiveSigningKey(ctx, JWTSigningAlgorithm())
+	if err != nil {
+		return "", err
+	}
+	signer, err := key.Signer()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.KID
+	return token.SignedString(signer)
+}
+
+// Get the public key a token is verified with from its kid header, refusing a token signed with any other algorithm
+// than the key is for
+func verificationKey(ctx *gin.Context, token *jwt.Token) (any, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, errors.New("jwt auth token does not name its signing key")
+	}
+	cached, ok := verificationKeys.Load(kid)
+	if !ok {
+		key, err := database.GetSigningKey(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		signer, err := key.Signer()
+		if err != nil {
+			return nil, err
+		}
+		cached, _ = verificationKeys.LoadOrStore(kid, &cachedVerificationKey{algorithm: key.Algorithm, public: signer.Public()})
+	}
+	key := cached.(*cachedVerificationKey)
+	if token.Method.Alg() != key.algorithm {
+		return nil, fmt.Errorf("jwt auth token is signed with %s, but its signing key is for %s", token.Method.Alg(), key.algorithm)
+	}
+	return key.public, nil
+}
@@ -2,6 +2,9 @@ package middleware
 
 import (
 	"com668-backend/utility"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -20,8 +23,9 @@ func FormatResponseMW() gin.HandlerFunc {
 		}
 		body, bodyOk := ctx.Get("Body")
 		if strings.HasPrefix(strconv.Itoa(status.(int)), "2") {
-			if http.MethodPost == ctx.Request.Method {
-				log.Default().Printf("[%s] Body not set in context on POST. Assuming 201 Created\n", reqID)
+			// a POST which does not create anything, such as a bulk action, returns its body with 200 OK
+			if http.MethodPost == ctx.Request.Method && (status != http.StatusOK || !statusOk || !bodyOk) {
+				log.Default().Printf("[%s] POST did not return a body with 200 OK. Assuming 201 Created\n", reqID)
 				status = http.StatusCreated
 			}
 			if !bodyOk && ctx.Writer.Header().Get("Location") == "" {
@@ -38,6 +42,29 @@ func FormatResponseMW() gin.HandlerFunc {
 		if gin.IsDebugging() {
 			log.Default().Printf("[%s] Returning body with status %d %v\n", reqID, status.(int), body.(utility.ResponseSchema).String())
 		}
-		ctx.AbortWithStatusJSON(status.(int), body.(utility.ResponseSchema).JSON())
+		if ctx.Request.Method != http.MethodGet || status != http.StatusOK {
+			ctx.AbortWithStatusJSON(status.(int), body.(utility.ResponseSchema).JSON())
+			return
+		}
+
+		encoded, err := json.Marshal(body.(utility.ResponseSchema).JSON())
+		if err != nil {
+			log.Default().Printf("[%s] Failed to encode body: %e\n", reqID, err)
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		// a resource with a version is tagged by the handler, and anything else such as a list is tagged by its content
+		etag := ctx.Writer.Header().Get("ETag")
+		if etag == "" {
+			etag = fmt.Sprintf("W/\"%x\"", sha1.Sum(encoded))
+			ctx.Header("ETag", etag)
+		}
+		if utility.ETagMatches(ctx.GetHeader("If-None-Match"), etag, true) {
+			log.Default().Printf("[%s] Body matches If-None-Match. Returning with status %d\n", reqID, http.StatusNotModified)
+			ctx.AbortWithStatus(http.StatusNotModified)
+			return
+		}
+		ctx.Abort()
+		ctx.Data(http.StatusOK, "application/json; charset=utf-8", encoded)
 	}
 }
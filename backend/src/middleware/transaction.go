@@ -29,8 +29,11 @@ func TransactionRequestMW() gin.HandlerFunc {
 func TransactionResponseMW() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		tx := database.GetDBTransaction(ctx)
+		// the use of an api key is recorded once the transaction has ended, whether it was committed or not
+		defer database.RecordAPIKeyUse(ctx)
 		if tx.Error != nil {
 			tx.Rollback()
+			database.DiscardSearchIndexOperations(ctx)
 			ctx.Set("Status", http.StatusInternalServerError)
 			ctx.Set("Body", &utility.ErrorResponseSchema{
 				Error: tx.Error.Error(),
@@ -38,7 +41,17 @@ func TransactionResponseMW() gin.HandlerFunc {
 			ctx.Next()
 			return
 		}
-		tx.Commit()
+		if err := tx.Commit().Error; err != nil {
+			database.DiscardSearchIndexOperations(ctx)
+			ctx.Set("Status", http.StatusInternalServerError)
+			ctx.Set("Body", &utility.ErrorResponseSchema{
+				Error: err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+		// the search index is only changed once what it indexes has been committed
+		database.ApplySearchIndexOperations(ctx)
 		ctx.Next()
 	}
 }
@@ -0,0 +1,229 @@
+package test_test
+
+import (
+	"com668-backend/database"
+	"com668-backend/middleware"
+	"com668-backend/utility"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIKeys(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userJWT, err := getJWT(engine, TestUserEmail, TestUserPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := func(jwtString string, method string, url string, body map[string]any) *httptest.ResponseRecorder {
+		reader, err := getJSONBodyAsReader(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(method, url, reader)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		return makeRequest(engine, req)
+	}
+	withKey := func(key string, method string, url string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, nil)
+		req.Header.Add(middleware.APIKeyHeaderNameString, key)
+		return makeRequest(engine, req)
+	}
+	keyBody := func(scopes ...string) map[string]any {
+		return map[string]any{
+			"name":      "Test Key",
+			"scopes":    scopes,
+			"expiresAt": time.Now().Add(time.Hour),
+		}
+	}
+
+	var key *utility.APIKeyPostResponseBodySchema
+	t.Run("CreateAPIKey", func(t *testing.T) {
+		writer := request(userJWT, http.MethodPost, "/me/api-keys", keyBody(database.PermissionIncidentRead))
+		if code := writer.Code; code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		key, err = utility.ReadJSONStruct[utility.APIKeyPostResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.Key == "" || !strings.HasSuffix(writer.Result().Header.Get("Location"), key.UUID) {
+			t.Fatal("the key was not returned")
+		}
+
+		tests := []struct {
+			body     map[string]any
+			expected int
+		}{
+			{keyBody(database.PermissionUserWrite), http.StatusForbidden},
+			{keyBody("incident:delete"), http.StatusBadRequest},
+			{keyBody(), http.StatusBadRequest},
+			{map[string]any{"name": "Test Key", "scopes": []string{database.PermissionIncidentRead}, "expiresAt": time.Now().Add(-time.Hour)}, http.StatusBadRequest},
+		}
+		for _, test := range tests {
+			if code := request(userJWT, http.MethodPost, "/me/api-keys", test.body).Code; code != test.expected {
+				t.Fatalf("status code %d != %d for %v", code, test.expected, test.body)
+			}
+		}
+		// keys are only made by the users they are for, unless they are for a service account
+		writer = request(jwtString, http.MethodPost, fmt.Sprintf("/users/%s/api-keys", TestUserUUID), keyBody(database.PermissionIncidentRead))
+		if code := writer.Code; code != http.StatusBadRequest {
+			t.Fatalf("status code %d != %d", code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("UseAPIKey", func(t *testing.T) {
+		if code := withKey(key.Key, http.MethodGet, "/incidents").Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+		// the user can read hosts, but the key is not scoped to
+		if code := withKey(key.Key, http.MethodGet, "/hosts").Code; code != http.StatusForbidden {
+			t.Fatalf("status code %d != %d", code, http.StatusForbidden)
+		}
+		if code := withKey(key.UUID+".wrong", http.MethodGet, "/incidents").Code; code != http.StatusUnauthorized {
+			t.Fatalf("status code %d != %d", code, http.StatusUnauthorized)
+		}
+		if code := withKey(key.Key, http.MethodPost, "/me/api-keys").Code; code != http.StatusForbidden {
+			t.Fatalf("status code %d != %d", code, http.StatusForbidden)
+		}
+
+		writer := request(userJWT, http.MethodGet, fmt.Sprintf("/me/api-keys/%s", key.UUID), nil)
+		if code := writer.Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+		got, err := utility.ReadJSONStruct[utility.APIKeyGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.LastUsedAt == nil {
+			t.Fatal("the key's use was not recorded")
+		}
+		// a key's use is only recorded once a minute
+		if code := withKey(key.Key, http.MethodGet, "/incidents").Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+		writer = request(userJWT, http.MethodGet, fmt.Sprintf("/me/api-keys/%s", key.UUID), nil)
+		again, err := utility.ReadJSONStruct[utility.APIKeyGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.LastUsedAt == nil || !again.LastUsedAt.Equal(*got.LastUsedAt) {
+			t.Fatalf("last used at %v != %v", again.LastUsedAt, got.LastUsedAt)
+		}
+		if strings.Contains(writer.Body.String(), key.Key) {
+			t.Fatal("the key was shown after it was created")
+		}
+	})
+
+	t.Run("UseAPIKey ResolveWithoutScope", func(t *testing.T) {
+		writer := request(jwtString, http.MethodPost, "/me/api-keys", keyBody(database.PermissionIncidentRead, database.PermissionIncidentWrite))
+		if code := writer.Code; code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		writeKey, err := utility.ReadJSONStruct[utility.APIKeyPostResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer = withKey(writeKey.Key, http.MethodGet, "/incidents?resolved=false&pageSize=1")
+		incidents, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(incidents.Data) == 0 {
+			t.Fatal("no data")
+		}
+		incident := incidents.Data[0]
+		body := map[string]any{
+			"summary":         incident.Summary,
+			"description":     incident.Description,
+			"hostsAffected":   []string{},
+			"resolutionTeams": []string{},
+			"resolved":        true,
+		}
+		for _, host := range incident.HostsAffected {
+			body["hostsAffected"] = append(body["hostsAffected"].([]string), host.UUID)
+		}
+		for _, team := range incident.ResolutionTeams {
+			body["resolutionTeams"] = append(body["resolutionTeams"].([]string), team.UUID)
+		}
+		reader, err := getJSONBodyAsReader(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// a key which cannot resolve incidents cannot resolve one by updating it either
+		req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/incidents/%s", incident.UUID), reader)
+		req.Header.Add(middleware.APIKeyHeaderNameString, writeKey.Key)
+		if code := makeRequest(engine, req).Code; code != http.StatusForbidden {
+			t.Fatalf("status code %d != %d", code, http.StatusForbidden)
+		}
+		writer = request(jwtString, http.MethodGet, fmt.Sprintf("/incidents/%s", incident.UUID), nil)
+		got, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != incident.Status {
+			t.Fatalf("status %s != %s", got.Status, incident.Status)
+		}
+	})
+
+	t.Run("ServiceAccount", func(t *testing.T) {
+		writer := request(jwtString, http.MethodPost, "/users", map[string]any{
+			"name":           "Test Automation",
+			"email":          "automation@example.com",
+			"serviceAccount": true,
+		})
+		if code := writer.Code; code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		location := strings.Split(writer.Result().Header.Get("Location"), "/")
+		accountUUID := location[len(location)-1]
+
+		writer = request(jwtString, http.MethodPost, fmt.Sprintf("/users/%s/api-keys", accountUUID), keyBody(database.PermissionIncidentRead))
+		if code := writer.Code; code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		accountKey, err := utility.ReadJSONStruct[utility.APIKeyPostResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		writer = withKey(accountKey.Key, http.MethodGet, "/me")
+		if code := writer.Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+		me, err := utility.ReadJSONStruct[utility.UserGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if me.UUID != accountUUID || !me.ServiceAccount {
+			t.Fatalf("authenticated as %s rather than the service account", me.UUID)
+		}
+		if code := withKey(accountKey.Key, http.MethodGet, "/incidents").Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+	})
+
+	t.Run("RevokeAPIKey", func(t *testing.T) {
+		url := fmt.Sprintf("/me/api-keys/%s", key.UUID)
+		if code := request(userJWT, http.MethodDelete, url, nil).Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		if code := request(userJWT, http.MethodDelete, url, nil).Code; code != http.StatusConflict {
+			t.Fatalf("status code %d != %d", code, http.StatusConflict)
+		}
+		if code := withKey(key.Key, http.MethodGet, "/incidents").Code; code != http.StatusUnauthorized {
+			t.Fatalf("status code %d != %d", code, http.StatusUnauthorized)
+		}
+		// the keys of one user are not found through another
+		if code := request(jwtString, http.MethodGet, fmt.Sprintf("/me/api-keys/%s", key.UUID), nil).Code; code != http.StatusNotFound {
+			t.Fatalf("status code %d != %d", code, http.StatusNotFound)
+		}
+	})
+}
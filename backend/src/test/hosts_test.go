@@ -1,10 +1,12 @@
 package test_test
 
 import (
+	"com668-backend/database"
 	"com668-backend/middleware"
 	"com668-backend/utility"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"slices"
 	"strings"
 	"testing"
@@ -522,6 +524,203 @@ func TestUpdateHost(t *testing.T) {
 	})
 }
 
+func TestPatchHost(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("PatchHost", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/hosts", nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			t.Fatalf("Status code %d != %d", code, expected)
+		}
+		resp, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[*utility.HostMachineGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Data) == 0 {
+			t.Fatal("no data was returned")
+		}
+		host := resp.Data[0]
+
+		// change the OS with a merge patch, then change it back with a JSON patch
+		patches := []struct {
+			contentType string
+			patch       string
+			os          string
+		}{
+			{utility.MergePatchContentType, `{"os": "MacOS"}`, "MacOS"},
+			{utility.JSONPatchContentType, fmt.Sprintf(`[{"op": "replace", "path": "/os", "value": "%s"}]`, host.OS), host.OS},
+		}
+		for _, patch := range patches {
+			req, _ = http.NewRequest(http.MethodPatch, fmt.Sprintf("/hosts/%s", host.UUID), strings.NewReader(patch.patch))
+			req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+			req.Header.Add("Content-Type", patch.contentType)
+			writer = makeRequest(engine, req)
+
+			expected = http.StatusNoContent
+			if code := writer.Code; code != expected {
+				errorResp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Log(errorResp.Error)
+				t.Fatalf("Status code %d != %d", code, expected)
+			}
+
+			req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/hosts/%s", host.UUID), nil)
+			req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+			writer = makeRequest(engine, req)
+			patched, err := utility.ReadJSONStruct[utility.HostMachineGetResponseBodySchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if patched.OS != patch.os || patched.Hostname != host.Hostname || patched.Team.UUID != host.Team.UUID {
+				t.Fatalf("host was not patched as expected: %s", patch.patch)
+			}
+		}
+	})
+
+	t.Run("PatchHost InvalidBody", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/hosts", nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+		resp, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[*utility.HostMachineGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Data) == 0 {
+			t.Fatal("no data was returned")
+		}
+
+		req, _ = http.NewRequest(http.MethodPatch, fmt.Sprintf("/hosts/%s", resp.Data[0].UUID), strings.NewReader(`{"os": "Solaris"}`))
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		req.Header.Add("Content-Type", utility.MergePatchContentType)
+		writer = makeRequest(engine, req)
+
+		expected := http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			t.Fatalf("Status code %d != %d", code, expected)
+		}
+		errResp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(errResp.Error, "'os' must be") {
+			t.Log(errResp.Error)
+			t.Fatal("error response message was not expected message")
+		}
+	})
+}
+
+func TestConditionalHost(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	getHosts := func(path string, ifNoneMatch string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		if ifNoneMatch != "" {
+			req.Header.Add("If-None-Match", ifNoneMatch)
+		}
+		return makeRequest(engine, req)
+	}
+
+	writer := getHosts("/hosts", "")
+	resp, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[*utility.HostMachineGetResponseBodySchema]](writer.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data) == 0 {
+		t.Fatal("no data was returned")
+	}
+	host := resp.Data[0]
+
+	t.Run("GetHosts IfNoneMatch", func(t *testing.T) {
+		writer := getHosts("/hosts", "")
+		etag := writer.Header().Get("ETag")
+		if !strings.HasPrefix(etag, "W/") {
+			t.Fatalf("list ETag %s is not weak", etag)
+		}
+
+		writer = getHosts("/hosts", etag)
+		expected := http.StatusNotModified
+		if code := writer.Code; code != expected {
+			t.Fatalf("Status code %d != %d", code, expected)
+		}
+		if writer.Body.Len() != 0 {
+			t.Fatal("body was returned with 304")
+		}
+	})
+
+	t.Run("GetHost IfNoneMatch", func(t *testing.T) {
+		writer := getHosts(fmt.Sprintf("/hosts/%s", host.UUID), "")
+		etag := writer.Header().Get("ETag")
+		if etag == "" || strings.HasPrefix(etag, "W/") {
+			t.Fatalf("host ETag %s is not strong", etag)
+		}
+
+		writer = getHosts(fmt.Sprintf("/hosts/%s", host.UUID), fmt.Sprintf(`"other", W/%s`, etag))
+		expected := http.StatusNotModified
+		if code := writer.Code; code != expected {
+			t.Fatalf("Status code %d != %d", code, expected)
+		}
+
+		writer = getHosts(fmt.Sprintf("/hosts/%s", host.UUID), `"other"`)
+		expected = http.StatusOK
+		if code := writer.Code; code != expected {
+			t.Fatalf("Status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("PatchHost IfMatch", func(t *testing.T) {
+		patchHost := func(ifMatch string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest(http.MethodPatch, fmt.Sprintf("/hosts/%s", host.UUID), strings.NewReader(`{"os": "Linux"}`))
+			req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+			req.Header.Add("Content-Type", utility.MergePatchContentType)
+			req.Header.Add("If-Match", ifMatch)
+			return makeRequest(engine, req)
+		}
+		etag := getHosts(fmt.Sprintf("/hosts/%s", host.UUID), "").Header().Get("ETag")
+
+		writer := patchHost(etag)
+		expected := http.StatusNoContent
+		if code := writer.Code; code != expected {
+			t.Fatalf("Status code %d != %d", code, expected)
+		}
+		newETag := getHosts(fmt.Sprintf("/hosts/%s", host.UUID), "").Header().Get("ETag")
+		if newETag == etag {
+			t.Fatal("ETag did not change when the host was updated")
+		}
+
+		// the host was changed since the first ETag was read
+		for _, ifMatch := range []string{etag, "W/" + newETag} {
+			writer = patchHost(ifMatch)
+			expected = http.StatusPreconditionFailed
+			if code := writer.Code; code != expected {
+				t.Fatalf("Status code %d != %d for %s", code, expected, ifMatch)
+			}
+		}
+
+		req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/hosts/%s", host.UUID), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		req.Header.Add("If-Match", etag)
+		writer = makeRequest(engine, req)
+		expected = http.StatusPreconditionFailed
+		if code := writer.Code; code != expected {
+			t.Fatalf("Status code %d != %d", code, expected)
+		}
+	})
+}
+
 func TestDeleteHost(t *testing.T) {
 	engine := setup()
 	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
@@ -588,3 +787,109 @@ func TestDeleteHost(t *testing.T) {
 		}
 	})
 }
+
+func TestHostOwnership(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userJWT, err := getJWT(engine, TestUserEmail, TestUserPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	teamUUID := createTeam(t, engine, jwtString, "Test Host Owners")
+	request := func(jwtString string, method string, url string, body map[string]any) *httptest.ResponseRecorder {
+		reader, err := getJSONBodyAsReader(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(method, url, reader)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		return makeRequest(engine, req)
+	}
+	// the user can change hosts, but only those of their teams
+	writer := request(jwtString, http.MethodPost, "/roles", map[string]any{
+		"name":        "Test Host Writers",
+		"permissions": []string{database.PermissionHostWrite},
+	})
+	if code := writer.Code; code != http.StatusCreated {
+		t.Fatalf("status code %d != %d", code, http.StatusCreated)
+	}
+	location := strings.Split(writer.Result().Header.Get("Location"), "/")
+	roleURL := fmt.Sprintf("/users/%s/roles/%s", TestUserUUID, location[len(location)-1])
+	if code := request(jwtString, http.MethodPut, roleURL, nil).Code; code != http.StatusNoContent {
+		t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+	}
+	t.Cleanup(func() {
+		request(jwtString, http.MethodDelete, roleURL, nil)
+	})
+	hostBody := func(teamUUID string) map[string]any {
+		return map[string]any{
+			"hostname": "owned-host",
+			"ip4":      "192.168.0.20",
+			"os":       "Linux",
+			"teamID":   teamUUID,
+		}
+	}
+
+	writer = request(jwtString, http.MethodPost, "/hosts", hostBody(teamUUID))
+	if code := writer.Code; code != http.StatusCreated {
+		t.Fatalf("status code %d != %d", code, http.StatusCreated)
+	}
+	location = strings.Split(writer.Result().Header.Get("Location"), "/")
+	hostURL := fmt.Sprintf("/hosts/%s", location[len(location)-1])
+
+	t.Run("NonMember", func(t *testing.T) {
+		expected := http.StatusForbidden
+		if code := request(userJWT, http.MethodPost, "/hosts", hostBody(teamUUID)).Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		if code := request(userJWT, http.MethodPut, hostURL, hostBody(teamUUID)).Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		if code := request(userJWT, http.MethodDelete, hostURL, nil).Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		// a member cannot give their host to a team they are not a member of
+		if code := request(userJWT, http.MethodPut, "/hosts/c3cb5381-7b79-4bbe-9337-8a27f94646a4", hostBody(teamUUID)).Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("Member", func(t *testing.T) {
+		writer := request(jwtString, http.MethodPut, fmt.Sprintf("/teams/%s/members/%s", teamUUID, TestUserUUID), map[string]any{})
+		if code := writer.Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		expected := http.StatusNoContent
+		if code := request(userJWT, http.MethodPut, hostURL, hostBody(teamUUID)).Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("PrivateTeam", func(t *testing.T) {
+		writer := request(jwtString, http.MethodDelete, fmt.Sprintf("/teams/%s/members/%s", teamUUID, TestUserUUID), nil)
+		if code := writer.Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		if code := request(userJWT, http.MethodGet, hostURL, nil).Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+
+		writer = request(jwtString, http.MethodPut, fmt.Sprintf("/teams/%s", teamUUID), map[string]any{
+			"name":    "Test Host Owners",
+			"private": true,
+		})
+		if code := writer.Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		if code := request(userJWT, http.MethodGet, hostURL, nil).Code; code != http.StatusNotFound {
+			t.Fatalf("status code %d != %d", code, http.StatusNotFound)
+		}
+		// users with the host:manage permission can see the hosts of every team
+		if code := request(jwtString, http.MethodGet, hostURL, nil).Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+	})
+}
@@ -1,14 +1,19 @@
 package test_test
 
 import (
+	"bytes"
+	"com668-backend/database"
 	"com668-backend/middleware"
 	"com668-backend/utility"
 	"crypto/sha1"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestGetIncidents(t *testing.T) {
@@ -41,6 +46,31 @@ func TestGetIncidents(t *testing.T) {
 		}
 	})
 
+	t.Run("GetIncidents MatchesGetIncident", func(t *testing.T) {
+		// an incident is given the same way in the list as on its own, so ETags made from either agree
+		req, _ := http.NewRequest(http.MethodGet, "/incidents?pageSize=1000", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, listed := range res.Data {
+			req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", listed.UUID), nil)
+			req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+			writer := makeRequest(engine, req)
+			incident, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, _ := json.Marshal(incident)
+			actual, _ := json.Marshal(listed)
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("listed incident %s != %s", actual, expected)
+			}
+		}
+	})
+
 	t.Run("GetIncidents ResolvedQuery", func(t *testing.T) {
 		// resolved
 		req, _ := http.NewRequest(http.MethodGet, "/incidents?resolved=true", nil)
@@ -143,8 +173,8 @@ func TestGetIncidents(t *testing.T) {
 		}
 	})
 
-	t.Run("GetIncidents HashQuery", func(t *testing.T) {
-		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+	t.Run("GetIncidents SeverityQuery", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/incidents?severity=1,2,3,4,5&sort=-severity", nil)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
 		writer := makeRequest(engine, req)
 
@@ -161,12 +191,17 @@ func TestGetIncidents(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		if len(res.Data) == 0 {
-			t.Fatal("no data")
+		if len(res.Data) < 2 {
+			t.Fatal("not enough data")
+		}
+		for i := 1; i < len(res.Data); i++ {
+			if res.Data[i].Severity > res.Data[i-1].Severity {
+				t.Fatal("incidents not sorted by descending severity")
+			}
 		}
+		severity := res.Data[0].Severity
 
-		hash := res.Data[0].Hash
-		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents?hash=%s", hash), nil)
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents?severity=%d", severity), nil)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
 		writer = makeRequest(engine, req)
 
@@ -183,24 +218,31 @@ func TestGetIncidents(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		if len(res.Data) != 1 {
-			t.Fatal("data length mismatch")
+		if len(res.Data) == 0 {
+			t.Fatal("no data")
 		}
-		if res.Data[0].Hash != hash {
-			t.Fatal("hash mismatch")
+		for _, incident := range res.Data {
+			if incident.Severity != severity {
+				t.Fatalf("severity %d != %d", incident.Severity, severity)
+			}
 		}
 	})
-}
 
-func TestCreateIncident(t *testing.T) {
-	engine := setup()
-	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
-	if err != nil {
-		t.Fatal(err)
-	}
+	t.Run("GetIncidents InvalidSeverityQuery", func(t *testing.T) {
+		for _, query := range []string{"severity=6", "severity=abc", "sort=summary"} {
+			req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents?%s", query), nil)
+			req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+			writer := makeRequest(engine, req)
 
-	t.Run("CreateIncident", func(t *testing.T) {
-		req, _ := http.NewRequest(http.MethodGet, "/hosts", nil)
+			expected := http.StatusBadRequest
+			if code := writer.Code; code != expected {
+				t.Fatalf("status code %d != %d", code, expected)
+			}
+		}
+	})
+
+	t.Run("GetIncidents HashQuery", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
 		writer := makeRequest(engine, req)
 
@@ -213,15 +255,16 @@ func TestCreateIncident(t *testing.T) {
 			t.Log(resp.Error)
 			t.Fatalf("status code %d != %d", code, expected)
 		}
-		hostsRes, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.HostMachineGetResponseBodySchema]](writer.Body.Bytes())
+		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
 		if err != nil {
 			t.Fatal(err)
 		}
-		if len(hostsRes.Data) == 0 {
+		if len(res.Data) == 0 {
 			t.Fatal("no data")
 		}
 
-		req, _ = http.NewRequest(http.MethodGet, "/teams", nil)
+		hash := res.Data[0].Hash
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents?hash=%s", hash), nil)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
 		writer = makeRequest(engine, req)
 
@@ -234,31 +277,34 @@ func TestCreateIncident(t *testing.T) {
 			t.Log(resp.Error)
 			t.Fatalf("status code %d != %d", code, expected)
 		}
-		teamsRes, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.TeamGetResponseBodySchema]](writer.Body.Bytes())
+		res, err = utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
 		if err != nil {
 			t.Fatal(err)
 		}
-		if len(teamsRes.Data) == 0 {
-			t.Fatal("no data")
+		if len(res.Data) != 1 {
+			t.Fatal("data length mismatch")
+		}
+		if res.Data[0].Hash != hash {
+			t.Fatal("hash mismatch")
 		}
+	})
 
-		hasher := sha1.New()
-		hasher.Write([]byte("Test Incident"))
+	t.Run("GetIncidents SearchQuery", func(t *testing.T) {
 		body, err := getJSONBodyAsReader(map[string]any{
-			"summary":         "Test Incident",
-			"description":     "Test Incident Details",
-			"resolutionTeams": []string{teamsRes.Data[0].UUID},
-			"hostsAffected":   []string{hostsRes.Data[0].UUID},
-			"hash":            fmt.Sprintf("%x", hasher.Sum(nil)),
+			"summary":         "Redis timeout during checkout",
+			"description":     "The basket service could not reach the cache",
+			"hash":            fmt.Sprintf("%x", sha1.Sum([]byte("Test Search Incident"))),
+			"resolutionTeams": []string{},
+			"hostsAffected":   []string{},
 		})
 		if err != nil {
 			t.Fatal(err)
 		}
-		req, _ = http.NewRequest(http.MethodPost, "/incidents", body)
+		req, _ := http.NewRequest(http.MethodPost, "/incidents", body)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
-		writer = makeRequest(engine, req)
+		writer := makeRequest(engine, req)
 
-		expected = http.StatusCreated
+		expected := http.StatusCreated
 		if code := writer.Code; code != expected {
 			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
 			if err != nil {
@@ -267,141 +313,285 @@ func TestCreateIncident(t *testing.T) {
 			t.Log(resp.Error)
 			t.Fatalf("status code %d != %d", code, expected)
 		}
-	})
+		location := strings.Split(writer.Result().Header.Get("Location"), "/")
+		incidentUUID := location[len(location)-1]
 
-	t.Run("CreateIncident InvalidBody", func(t *testing.T) {
-		body, err := getJSONBodyAsReader(map[string]any{
-			"invalidField": "invalidValue",
+		body, err = getJSONBodyAsReader(map[string]any{
+			"comment": "Failed over to the zookeeper replica",
 		})
 		if err != nil {
 			t.Fatal(err)
 		}
-		req, _ := http.NewRequest(http.MethodPost, "/incidents", body)
+		req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/comments", incidentUUID), body)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
-		writer := makeRequest(engine, req)
+		writer = makeRequest(engine, req)
 
-		expected := http.StatusBadRequest
+		expected = http.StatusCreated
 		if code := writer.Code; code != expected {
-			if !strings.Contains(fmt.Sprint(code), "2") {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+
+		// 'timeouts' is stemmed, so it matches the summary's 'timeout'
+		for query, field := range map[string]string{"redis+timeouts": "summary", "zookeeper": "comments"} {
+			req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents?q=%s", query), nil)
+			req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+			writer = makeRequest(engine, req)
+
+			expected = http.StatusOK
+			if code := writer.Code; code != expected {
 				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
 				if err != nil {
 					t.Fatal(err)
 				}
 				t.Log(resp.Error)
+				t.Fatalf("status code %d != %d", code, expected)
+			}
+			res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Data) != 1 {
+				t.Fatalf("data length %d != %d", len(res.Data), 1)
+			}
+			if res.Data[0].UUID != incidentUUID {
+				t.Fatal("uuid mismatch")
+			}
+			if res.Data[0].Search == nil || res.Data[0].Search.Score <= 0 {
+				t.Fatal("search score missing")
+			}
+			if fragments := res.Data[0].Search.Highlights[field]; len(fragments) == 0 || !strings.Contains(fragments[0], "<mark>") {
+				t.Fatalf("%s highlight missing", field)
 			}
-			t.Fatalf("status code %d != %d", code, expected)
 		}
-	})
-}
-
-func TestUpdateIncident(t *testing.T) {
-	engine := setup()
-	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	t.Run("UpdateIncident", func(t *testing.T) {
-		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+		req, _ = http.NewRequest(http.MethodGet, "/incidents?q=memcached", nil)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
-		writer := makeRequest(engine, req)
+		writer = makeRequest(engine, req)
 
-		expected := http.StatusOK
+		expected = http.StatusOK
 		if code := writer.Code; code != expected {
-			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
-			if err != nil {
-				t.Fatal(err)
-			}
-			t.Log(resp.Error)
 			t.Fatalf("status code %d != %d", code, expected)
 		}
 		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
 		if err != nil {
 			t.Fatal(err)
 		}
-		if len(res.Data) == 0 {
-			t.Fatal("no data")
+		if len(res.Data) != 0 {
+			t.Fatalf("data length %d != %d", len(res.Data), 0)
 		}
+	})
 
-		body, err := getJSONBodyAsReader(map[string]any{
-			"summary":         "Updated Incident",
-			"description":     "Updated Incident Details",
-			"resolutionTeams": []string{res.Data[0].ResolutionTeams[0].UUID},
-			"hostsAffected":   []string{res.Data[0].HostsAffected[0].UUID},
-			"resolved":        true,
-		})
-		if err != nil {
-			t.Fatal(err)
+	t.Run("GetIncidents SearchRanking", func(t *testing.T) {
+		// a match in the summary ranks above one in the description, across pages
+		incidentUUIDs := make([]string, 0)
+		for _, fields := range []map[string]any{
+			{"summary": "Broker lag", "description": "Kafkaranking consumers fell behind"},
+			{"summary": "Kafkaranking broker down", "description": "Consumers stopped"},
+		} {
+			fields["hash"] = fmt.Sprintf("%x", sha1.Sum([]byte(fields["summary"].(string))))
+			fields["resolutionTeams"] = []string{}
+			fields["hostsAffected"] = []string{}
+			body, err := getJSONBodyAsReader(fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ := http.NewRequest(http.MethodPost, "/incidents", body)
+			req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+			writer := makeRequest(engine, req)
+			if code := writer.Code; code != http.StatusCreated {
+				t.Fatalf("status code %d != %d", code, http.StatusCreated)
+			}
+			location := strings.Split(writer.Result().Header.Get("Location"), "/")
+			incidentUUIDs = append(incidentUUIDs, location[len(location)-1])
 		}
-		req, _ = http.NewRequest(http.MethodPut, fmt.Sprintf("/incidents/%s", res.Data[0].UUID), body)
-		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
-		writer = makeRequest(engine, req)
 
-		expected = http.StatusNoContent
-		if code := writer.Code; code != expected {
-			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+		for page, incidentUUID := range []string{incidentUUIDs[1], incidentUUIDs[0]} {
+			req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents?q=kafkaranking&pageSize=1&page=%d", page+1), nil)
+			req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+			writer := makeRequest(engine, req)
+			if code := writer.Code; code != http.StatusOK {
+				t.Fatalf("status code %d != %d", code, http.StatusOK)
+			}
+			res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
 			if err != nil {
 				t.Fatal(err)
 			}
-			t.Log(resp.Error)
-			t.Fatalf("status code %d != %d", code, expected)
+			if res.Meta.TotalItems != 2 || len(res.Data) != 1 {
+				t.Fatalf("total items %d and data length %d != 2 and 1", res.Meta.TotalItems, len(res.Data))
+			}
+			if res.Data[0].UUID != incidentUUID {
+				t.Fatalf("page %d uuid mismatch", page+1)
+			}
+			if res.Data[0].Search == nil || len(res.Data[0].Search.Highlights) == 0 {
+				t.Fatalf("page %d highlights missing", page+1)
+			}
 		}
 	})
 
-	t.Run("UpdateIncident InvalidBody", func(t *testing.T) {
-		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
-		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
-		writer := makeRequest(engine, req)
-
-		expected := http.StatusOK
-		if code := writer.Code; code != expected {
-			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+	t.Run("GetIncidents QueryParameter", func(t *testing.T) {
+		queries := map[string]func(utility.IncidentGetResponseBodySchema) bool{
+			"status:open severity:<=2": func(i utility.IncidentGetResponseBodySchema) bool {
+				return i.Status == "open" && i.Severity <= 2
+			},
+			"team:DevOps -host:unknown-host created:>-7d": func(i utility.IncidentGetResponseBodySchema) bool {
+				return slices.ContainsFunc(i.ResolutionTeams, func(t utility.TeamGetResponseBodySchema) bool { return t.Name == "DevOps" })
+			},
+			"-status:resolved (severity:1,2 OR NOT priority:>3)": func(i utility.IncidentGetResponseBodySchema) bool {
+				return i.Status != "resolved" && (i.Severity <= 2 || i.Priority <= 3)
+			},
+			"resolved:true": func(i utility.IncidentGetResponseBodySchema) bool {
+				return i.ResolvedAt != nil
+			},
+			// incidents which were never resolved have no resolution time, so are not resolved in the last week
+			"status:open -resolvedAt:>-7d": func(i utility.IncidentGetResponseBodySchema) bool {
+				return i.Status == "open" && i.ResolvedAt == nil
+			},
+		}
+		for query, matches := range queries {
+			req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+			q := req.URL.Query()
+			q.Set("query", query)
+			q.Set("pageSize", "100")
+			req.URL.RawQuery = q.Encode()
+			req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+			writer := makeRequest(engine, req)
+
+			expected := http.StatusOK
+			if code := writer.Code; code != expected {
+				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Log(resp.Error)
+				t.Fatalf("status code %d != %d", code, expected)
+			}
+			res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
 			if err != nil {
 				t.Fatal(err)
 			}
-			t.Log(resp.Error)
-			t.Fatalf("status code %d != %d", code, expected)
-		}
-		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
-		if err != nil {
-			t.Fatal(err)
-		}
-		if len(res.Data) == 0 {
-			t.Fatal("no data")
+			if len(res.Data) == 0 {
+				t.Fatalf("no data for query '%s'", query)
+			}
+			for _, incident := range res.Data {
+				if !matches(incident) {
+					t.Fatalf("incident %s does not match query '%s'", incident.UUID, query)
+				}
+			}
 		}
+	})
 
-		body, err := getJSONBodyAsReader(map[string]any{
-			"invalidField": "invalidValue",
-		})
-		if err != nil {
-			t.Fatal(err)
+	t.Run("GetIncidents InvalidQueryParameter", func(t *testing.T) {
+		queries := map[string]int{
+			"severity:<=":          12,
+			"status:open)":         12,
+			"(status:open":         1,
+			"status:open foo:bar":  13,
+			"severity:6":           10,
+			"redis OR status:open": 1,
+			"created:yesterday":    9,
+			strings.Repeat("a", utility.MaxQueryLength+1): utility.MaxQueryLength + 1,
+		}
+		for query, position := range queries {
+			req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+			q := req.URL.Query()
+			q.Set("query", query)
+			req.URL.RawQuery = q.Encode()
+			req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+			writer := makeRequest(engine, req)
+
+			expected := http.StatusBadRequest
+			if code := writer.Code; code != expected {
+				t.Fatalf("status code %d != %d for query '%s'", code, expected, query)
+			}
+			resp, err := utility.ReadJSONStruct[utility.QueryErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Position != position {
+				t.Log(resp.Error)
+				t.Fatalf("position %d != %d for query '%s'", resp.Position, position, query)
+			}
 		}
-		req, _ = http.NewRequest(http.MethodPut, fmt.Sprintf("/incidents/%s", res.Data[0].UUID), body)
-		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
-		writer = makeRequest(engine, req)
+	})
 
-		expected = http.StatusBadRequest
-		if code := writer.Code; code != expected {
-			if !strings.Contains(fmt.Sprint(code), "2") {
+	t.Run("GetIncidents SortAndCursor", func(t *testing.T) {
+		seen := make([]utility.IncidentGetResponseBodySchema, 0)
+		path := "/incidents?sort=-severity,createdAt&pageSize=2"
+		var total int64
+		var secondPrev *string
+		for {
+			req, _ := http.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+			writer := makeRequest(engine, req)
+
+			expected := http.StatusOK
+			if code := writer.Code; code != expected {
 				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
 				if err != nil {
 					t.Fatal(err)
 				}
 				t.Log(resp.Error)
+				t.Fatalf("status code %d != %d", code, expected)
+			}
+			res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(seen) == 2 {
+				secondPrev = res.Meta.Prev
+			}
+			seen = append(seen, res.Data...)
+			total = res.Meta.TotalItems
+			if res.Meta.Next == nil {
+				break
+			}
+			path = fmt.Sprintf("/incidents?sort=-severity,createdAt&pageSize=2&cursor=%s", *res.Meta.Next)
+		}
+		if int64(len(seen)) != total {
+			t.Fatalf("paged through %d incidents, expected %d", len(seen), total)
+		}
+		for i := 1; i < len(seen); i++ {
+			if seen[i-1].Severity < seen[i].Severity {
+				t.Fatal("incidents are not sorted by descending severity")
+			}
+			if seen[i-1].UUID == seen[i].UUID {
+				t.Fatal("incident returned twice")
 			}
+		}
+		if total <= 2 {
+			return
+		}
+		if secondPrev == nil {
+			t.Fatal("second page has no previous cursor")
+		}
+
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents?sort=-severity,createdAt&pageSize=2&cursor=%s", *secondPrev), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
 			t.Fatalf("status code %d != %d", code, expected)
 		}
-		errRes, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
 		if err != nil {
 			t.Fatal(err)
 		}
-		if !strings.Contains(errRes.Error, "' is required") {
-			t.Fatal("error message mismatch")
+		if len(res.Data) != 2 || res.Data[0].UUID != seen[0].UUID || res.Data[1].UUID != seen[1].UUID {
+			t.Fatal("previous page mismatch")
+		}
+		if res.Meta.Prev != nil {
+			t.Fatal("first page has a previous cursor")
 		}
 	})
 
-	t.Run("UpdateIncident InvalidUUID", func(t *testing.T) {
-		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+	t.Run("GetIncidents ExpandAndFields", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/incidents?expand=comments,hostsAffected&fields=uuid,summary,hostsAffected,resolutionTeams", nil)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
 		writer := makeRequest(engine, req)
 
@@ -414,55 +604,80 @@ func TestUpdateIncident(t *testing.T) {
 			t.Log(resp.Error)
 			t.Fatalf("status code %d != %d", code, expected)
 		}
-		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		res, err := utility.ReadJSONStruct[struct{ Data []map[string]any }](writer.Body.Bytes())
 		if err != nil {
 			t.Fatal(err)
 		}
 		if len(res.Data) == 0 {
 			t.Fatal("no data")
 		}
-
-		body, err := getJSONBodyAsReader(map[string]any{
-			"summary":         "Updated Incident",
-			"description":     "Updated Incident Details",
-			"resolutionTeams": []string{res.Data[0].ResolutionTeams[0].UUID},
-			"hostsAffected":   []string{res.Data[0].HostsAffected[0].UUID},
-			"resolved":        true,
-		})
-		if err != nil {
-			t.Fatal(err)
-		}
-		uuid, err := utility.GenerateRandomUUID()
-		if err != nil {
-			t.Fatal(err)
+		for _, incident := range res.Data {
+			// resolutionTeams is not expanded and comments is not among the fields
+			if len(incident) != 3 {
+				t.Fatalf("incident has fields %v, expected uuid, summary and hostsAffected", incident)
+			}
+			for _, field := range []string{"uuid", "summary", "hostsAffected"} {
+				if _, ok := incident[field]; !ok {
+					t.Fatalf("incident is missing %s", field)
+				}
+			}
+			for _, host := range incident["hostsAffected"].([]any) {
+				if _, ok := host.(map[string]any)["team"]; ok {
+					t.Fatal("hostsAffected.team is not expanded")
+				}
+			}
 		}
-		req, _ = http.NewRequest(http.MethodPut, fmt.Sprintf("/incidents/%s", uuid), body)
+
+		// a nested relation expands its parent
+		req, _ = http.NewRequest(http.MethodGet, "/incidents?expand=hostsAffected.team&fields=uuid,hostsAffected", nil)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
 		writer = makeRequest(engine, req)
 
-		expected = http.StatusNotFound
 		if code := writer.Code; code != expected {
-			if !strings.Contains(fmt.Sprint(code), "2") {
-				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
-				if err != nil {
-					t.Fatal(err)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err = utility.ReadJSONStruct[struct{ Data []map[string]any }](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, incident := range res.Data {
+			for _, host := range incident["hostsAffected"].([]any) {
+				team, ok := host.(map[string]any)["team"].(map[string]any)
+				if !ok || team["uuid"] == "" {
+					t.Fatal("hostsAffected.team is expanded but missing")
 				}
-				t.Log(resp.Error)
+				found = true
 			}
-			t.Fatalf("status code %d != %d", code, expected)
+		}
+		if !found {
+			t.Fatal("no incident has an affected host")
 		}
 	})
-}
 
-func TestGetIncident(t *testing.T) {
+	t.Run("GetIncidents InvalidExpandAndFields", func(t *testing.T) {
+		for _, query := range []string{"expand=comments,foo", "expand=hostsAffected.os", "fields=uuid,foo", "fields=uuid,"} {
+			req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents?%s", query), nil)
+			req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+			writer := makeRequest(engine, req)
+
+			expected := http.StatusBadRequest
+			if code := writer.Code; code != expected {
+				t.Fatalf("status code %d != %d for %s", code, expected, query)
+			}
+		}
+	})
+}
+
+func TestCreateIncident(t *testing.T) {
 	engine := setup()
 	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	t.Run("GetIncident", func(t *testing.T) {
-		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+	t.Run("CreateIncident", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/hosts", nil)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
 		writer := makeRequest(engine, req)
 
@@ -475,15 +690,15 @@ func TestGetIncident(t *testing.T) {
 			t.Log(resp.Error)
 			t.Fatalf("status code %d != %d", code, expected)
 		}
-		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		hostsRes, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.HostMachineGetResponseBodySchema]](writer.Body.Bytes())
 		if err != nil {
 			t.Fatal(err)
 		}
-		if len(res.Data) == 0 {
+		if len(hostsRes.Data) == 0 {
 			t.Fatal("no data")
 		}
 
-		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", res.Data[0].UUID), nil)
+		req, _ = http.NewRequest(http.MethodGet, "/teams", nil)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
 		writer = makeRequest(engine, req)
 
@@ -496,51 +711,31 @@ func TestGetIncident(t *testing.T) {
 			t.Log(resp.Error)
 			t.Fatalf("status code %d != %d", code, expected)
 		}
-		incidentRes, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
+		teamsRes, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.TeamGetResponseBodySchema]](writer.Body.Bytes())
 		if err != nil {
 			t.Fatal(err)
 		}
-		if incidentRes.UUID != res.Data[0].UUID {
-			t.Fatal("uuid mismatch")
+		if len(teamsRes.Data) == 0 {
+			t.Fatal("no data")
 		}
-	})
 
-	t.Run("GetIncident InvalidUUID", func(t *testing.T) {
-		uuid, err := utility.GenerateRandomUUID()
+		hasher := sha1.New()
+		hasher.Write([]byte("Test Incident"))
+		body, err := getJSONBodyAsReader(map[string]any{
+			"summary":         "Test Incident",
+			"description":     "Test Incident Details",
+			"resolutionTeams": []string{teamsRes.Data[0].UUID},
+			"hostsAffected":   []string{hostsRes.Data[0].UUID},
+			"hash":            fmt.Sprintf("%x", hasher.Sum(nil)),
+		})
 		if err != nil {
 			t.Fatal(err)
 		}
-		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", uuid), nil)
-		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
-		writer := makeRequest(engine, req)
-
-		expected := http.StatusNotFound
-		if code := writer.Code; code != expected {
-			if !strings.Contains(fmt.Sprint(code), "2") {
-				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
-				if err != nil {
-					t.Fatal(err)
-				}
-				t.Log(resp.Error)
-			}
-			t.Fatalf("status code %d != %d", code, expected)
-		}
-	})
-}
-
-func TestCreateIncidentComment(t *testing.T) {
-	engine := setup()
-	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	t.Run("CreateIncidentComment", func(t *testing.T) {
-		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+		req, _ = http.NewRequest(http.MethodPost, "/incidents", body)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
-		writer := makeRequest(engine, req)
+		writer = makeRequest(engine, req)
 
-		expected := http.StatusOK
+		expected = http.StatusCreated
 		if code := writer.Code; code != expected {
 			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
 			if err != nil {
@@ -549,25 +744,34 @@ func TestCreateIncidentComment(t *testing.T) {
 			t.Log(resp.Error)
 			t.Fatalf("status code %d != %d", code, expected)
 		}
-		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
-		if err != nil {
-			t.Fatal(err)
-		}
-		if len(res.Data) == 0 {
-			t.Fatal("no data")
-		}
+	})
 
+	t.Run("CreateIncident StackTrace", func(t *testing.T) {
+		hasher := sha1.New()
+		hasher.Write([]byte("Test Stack Trace Incident"))
 		body, err := getJSONBodyAsReader(map[string]any{
-			"comment": "Test Comment",
+			"summary":         "Test Stack Trace Incident",
+			"description":     "Test Stack Trace Incident Details",
+			"resolutionTeams": []string{},
+			"hostsAffected":   []string{},
+			"hash":            fmt.Sprintf("%x", hasher.Sum(nil)),
+			"stackTrace": strings.Join([]string{
+				"Traceback (most recent call last):",
+				`  File "/app/src/handler.py", line 12, in handle`,
+				"    process(event)",
+				`  File "/usr/lib/python3/site-packages/lib/core.py", line 40, in process`,
+				"    raise ValueError(event)",
+				"ValueError: invalid event",
+			}, "\n"),
 		})
 		if err != nil {
 			t.Fatal(err)
 		}
-		req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/comments", res.Data[0].UUID), body)
+		req, _ := http.NewRequest(http.MethodPost, "/incidents", body)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
-		writer = makeRequest(engine, req)
+		writer := makeRequest(engine, req)
 
-		expected = http.StatusCreated
+		expected := http.StatusCreated
 		if code := writer.Code; code != expected {
 			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
 			if err != nil {
@@ -576,14 +780,13 @@ func TestCreateIncidentComment(t *testing.T) {
 			t.Log(resp.Error)
 			t.Fatalf("status code %d != %d", code, expected)
 		}
-	})
+		location := strings.Split(writer.Result().Header.Get("Location"), "/")
 
-	t.Run("CreateIncidentComment InvalidBody", func(t *testing.T) {
-		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", location[len(location)-1]), nil)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
-		writer := makeRequest(engine, req)
+		writer = makeRequest(engine, req)
 
-		expected := http.StatusOK
+		expected = http.StatusOK
 		if code := writer.Code; code != expected {
 			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
 			if err != nil {
@@ -592,79 +795,119 @@ func TestCreateIncidentComment(t *testing.T) {
 			t.Log(resp.Error)
 			t.Fatalf("status code %d != %d", code, expected)
 		}
-		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		incident, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
 		if err != nil {
 			t.Fatal(err)
 		}
-		if len(res.Data) == 0 {
-			t.Fatal("no data")
+		if incident.StackLanguage != "python" {
+			t.Fatalf("stack language %s != %s", incident.StackLanguage, "python")
+		}
+		if len(incident.StackFrames) != 2 {
+			t.Fatalf("stack frames %d != %d", len(incident.StackFrames), 2)
+		}
+		// the most recent call comes first, but the culprit is the in-app frame that called into the library
+		if incident.StackFrames[0].InApp || incident.StackFrames[0].Function != "process" {
+			t.Fatal("most recent frame mismatch")
 		}
+		culprit := incident.StackFrames[1]
+		if !culprit.Culprit || !culprit.InApp || culprit.File != "/app/src/handler.py" || culprit.Line == nil || *culprit.Line != 12 {
+			t.Fatal("culprit frame mismatch")
+		}
+
+		// the raw stack trace is left out of lists unless it is asked for
+		for fields, listed := range map[string]bool{"": false, "&fields=uuid,stackTrace": true} {
+			req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents?hash=%s%s", incident.Hash, fields), nil)
+			req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+			writer = makeRequest(engine, req)
+			if code := writer.Code; code != http.StatusOK {
+				t.Fatalf("status code %d != %d", code, http.StatusOK)
+			}
+			if strings.Contains(writer.Body.String(), "ValueError: invalid event") != listed {
+				t.Fatalf("stack trace listed != %t with fields '%s'", listed, fields)
+			}
+		}
+	})
 
+	t.Run("CreateIncident LongStackFrame", func(t *testing.T) {
 		body, err := getJSONBodyAsReader(map[string]any{
-			"invalidField": "invalidValue",
+			"summary":         "Test Long Stack Frame Incident",
+			"description":     "Test Long Stack Frame Incident Details",
+			"resolutionTeams": []string{},
+			"hostsAffected":   []string{},
+			"hash":            "test-long-stack-frame-incident",
+			"stackTrace": strings.Join([]string{
+				"Traceback (most recent call last):",
+				fmt.Sprintf(`  File "/app/%s.py", line 1, in %s`, strings.Repeat("a", 600), strings.Repeat("b", 600)),
+				"ValueError: invalid event",
+			}, "\n"),
 		})
 		if err != nil {
 			t.Fatal(err)
 		}
-		req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/comments", res.Data[0].UUID), body)
+		req, _ := http.NewRequest(http.MethodPost, "/incidents", body)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
-		writer = makeRequest(engine, req)
-
-		expected = http.StatusBadRequest
-		if code := writer.Code; code != expected {
-			if !strings.Contains(fmt.Sprint(code), "2") {
-				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
-				if err != nil {
-					t.Fatal(err)
-				}
-				t.Log(resp.Error)
+		writer := makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusCreated {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
 			}
-			t.Fatalf("status code %d != %d", code, expected)
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
 		}
-		if !strings.Contains(writer.Body.String(), "'comment' is required") {
-			t.Fatal("error message mismatch")
+		location := strings.Split(writer.Result().Header.Get("Location"), "/")
+
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", location[len(location)-1]), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+		incident, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(incident.StackFrames) != 1 || len(incident.StackFrames[0].File) != 500 || len(incident.StackFrames[0].Function) != 500 {
+			t.Fatal("stack frame was not truncated")
 		}
 	})
 
-	t.Run("CreateIncidentComment InvalidUUID", func(t *testing.T) {
+	t.Run("CreateIncident InvalidSeverity", func(t *testing.T) {
 		body, err := getJSONBodyAsReader(map[string]any{
-			"comment": "Test Comment",
+			"summary":     "Test Incident",
+			"description": "Test Incident Details",
+			"hash":        "invalid severity",
+			"severity":    6,
 		})
 		if err != nil {
 			t.Fatal(err)
 		}
-		req, _ := http.NewRequest(http.MethodPost, "/incidents/invalidUUID/comments", body)
+		req, _ := http.NewRequest(http.MethodPost, "/incidents", body)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
 		writer := makeRequest(engine, req)
 
 		expected := http.StatusBadRequest
 		if code := writer.Code; code != expected {
-			if !strings.Contains(fmt.Sprint(code), "2") {
-				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
-				if err != nil {
-					t.Fatal(err)
-				}
-				t.Log(resp.Error)
-			}
 			t.Fatalf("status code %d != %d", code, expected)
 		}
 		res, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
 		if err != nil {
 			t.Fatal(err)
 		}
-		if !strings.Contains(res.Error, "invalid incident UUID") {
+		if !strings.Contains(res.Error, "'severity'") {
 			t.Fatal("error message mismatch")
 		}
+	})
 
-		uuid, err := utility.GenerateRandomUUID()
+	t.Run("CreateIncident InvalidBody", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"invalidField": "invalidValue",
+		})
 		if err != nil {
 			t.Fatal(err)
 		}
-		req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/comments", uuid), body)
+		req, _ := http.NewRequest(http.MethodPost, "/incidents", body)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
-		writer = makeRequest(engine, req)
+		writer := makeRequest(engine, req)
 
-		expected = http.StatusNotFound
+		expected := http.StatusBadRequest
 		if code := writer.Code; code != expected {
 			if !strings.Contains(fmt.Sprint(code), "2") {
 				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
@@ -678,15 +921,15 @@ func TestCreateIncidentComment(t *testing.T) {
 	})
 }
 
-func TestDeleteIncidentComment(t *testing.T) {
+func TestReportIncidentOccurrence(t *testing.T) {
 	engine := setup()
 	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	t.Run("DeleteIncidentComment InvalidIncidentUUID", func(t *testing.T) {
-		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+	t.Run("ReportIncidentOccurrence", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/hosts", nil)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
 		writer := makeRequest(engine, req)
 
@@ -699,63 +942,35 @@ func TestDeleteIncidentComment(t *testing.T) {
 			t.Log(resp.Error)
 			t.Fatalf("status code %d != %d", code, expected)
 		}
-		resp, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		hostsRes, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.HostMachineGetResponseBodySchema]](writer.Body.Bytes())
 		if err != nil {
 			t.Fatal(err)
 		}
-		if len(resp.Data) == 0 {
+		if len(hostsRes.Data) == 0 {
 			t.Fatal("no data")
 		}
-		if len(resp.Data[0].Comments) == 0 {
-			t.Fatal("no comments")
-		}
-
-		uuid, err := utility.GenerateRandomUUID()
-		if err != nil {
-			t.Fatal(err)
-		}
-		req, _ = http.NewRequest(http.MethodDelete, fmt.Sprintf("/incidents/%s/comments/%s", uuid, resp.Data[0].Comments[0].UUID), nil)
-		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
-		writer = makeRequest(engine, req)
 
-		expected = http.StatusNotFound
-		if code := writer.Code; code != expected {
-			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
-			if err != nil {
-				t.Fatal(err)
-			}
-			t.Log(resp.Error)
-			t.Fatalf("status code %d != %d", code, expected)
+		hasher := sha1.New()
+		hasher.Write([]byte("Test Occurrence"))
+		hash := fmt.Sprintf("%x", hasher.Sum(nil))
+		reqBody := map[string]any{
+			"summary":         "Test Occurrence",
+			"description":     "Test Occurrence Details",
+			"resolutionTeams": []string{hostsRes.Data[0].Team.UUID},
+			"hostsAffected":   []string{hostsRes.Data[0].UUID},
+			"hash":            hash,
 		}
 
-		req, _ = http.NewRequest(http.MethodDelete, fmt.Sprintf("/incidents/invalidUUID/comments/%s", resp.Data[0].Comments[0].UUID), nil)
-		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
-		writer = makeRequest(engine, req)
-
-		expected = http.StatusBadRequest
-		if code := writer.Code; code != expected {
-			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
-			if err != nil {
-				t.Fatal(err)
-			}
-			t.Log(resp.Error)
-			t.Fatalf("status code %d != %d", code, expected)
-		}
-		res, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+		// the first report creates the incident
+		body, err := getJSONBodyAsReader(reqBody)
 		if err != nil {
 			t.Fatal(err)
 		}
-		if !strings.Contains(res.Error, "invalid incident UUID") {
-			t.Fatal("error message mismatch")
-		}
-	})
-
-	t.Run("DeleteIncidentComment InvalidCommentUUID", func(t *testing.T) {
-		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+		req, _ = http.NewRequest(http.MethodPut, "/incidents/occurrences", body)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
-		writer := makeRequest(engine, req)
+		writer = makeRequest(engine, req)
 
-		expected := http.StatusOK
+		expected = http.StatusCreated
 		if code := writer.Code; code != expected {
 			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
 			if err != nil {
@@ -764,26 +979,21 @@ func TestDeleteIncidentComment(t *testing.T) {
 			t.Log(resp.Error)
 			t.Fatalf("status code %d != %d", code, expected)
 		}
-		resp, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
-		if err != nil {
-			t.Fatal(err)
-		}
-		if len(resp.Data) == 0 {
-			t.Fatal("no data")
-		}
-		if len(resp.Data[0].Comments) == 0 {
-			t.Fatal("no comments")
+		location := writer.Result().Header.Get("Location")
+		if location == "" {
+			t.Fatal("no location header")
 		}
 
-		uuid, err := utility.GenerateRandomUUID()
+		// the second report bumps the occurrence count of the same incident
+		body, err = getJSONBodyAsReader(reqBody)
 		if err != nil {
 			t.Fatal(err)
 		}
-		req, _ = http.NewRequest(http.MethodDelete, fmt.Sprintf("/incidents/%s/comments/%s", resp.Data[0].UUID, uuid), nil)
+		req, _ = http.NewRequest(http.MethodPut, "/incidents/occurrences", body)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
 		writer = makeRequest(engine, req)
 
-		expected = http.StatusNotFound
+		expected = http.StatusNoContent
 		if code := writer.Code; code != expected {
 			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
 			if err != nil {
@@ -792,12 +1002,15 @@ func TestDeleteIncidentComment(t *testing.T) {
 			t.Log(resp.Error)
 			t.Fatalf("status code %d != %d", code, expected)
 		}
+		if writer.Result().Header.Get("Location") != location {
+			t.Fatal("location mismatch")
+		}
 
-		req, _ = http.NewRequest(http.MethodDelete, fmt.Sprintf("/incidents/%s/comments/invalidUUID", resp.Data[0].UUID), nil)
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents?hash=%s", hash), nil)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
 		writer = makeRequest(engine, req)
 
-		expected = http.StatusBadRequest
+		expected = http.StatusOK
 		if code := writer.Code; code != expected {
 			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
 			if err != nil {
@@ -806,23 +1019,26 @@ func TestDeleteIncidentComment(t *testing.T) {
 			t.Log(resp.Error)
 			t.Fatalf("status code %d != %d", code, expected)
 		}
-		res, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
 		if err != nil {
 			t.Fatal(err)
 		}
-		if !strings.Contains(res.Error, "invalid comment UUID") {
-			t.Fatal("error message mismatch")
+		if len(res.Data) != 1 {
+			t.Fatal("data length mismatch")
 		}
-	})
-
-	t.Run("DeleteIncidentComment Forbidden", func(t *testing.T) {
-		jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
-		if err != nil {
-			t.Fatal(err)
+		if res.Data[0].OccurrenceCount != 2 {
+			t.Fatalf("occurrence count %d != %d", res.Data[0].OccurrenceCount, 2)
+		}
+		if res.Data[0].LastSeenAt.Before(res.Data[0].FirstSeenAt) {
+			t.Fatal("last seen before first seen")
 		}
+		if len(res.Data[0].HostsAffected) != 1 {
+			t.Fatal("hosts length mismatch")
+		}
+	})
 
-		// fetch incidents to get UUID for comment create
-		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+	t.Run("ReportIncidentOccurrence Regression", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/incidents?resolved=true", nil)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
 		writer := makeRequest(engine, req)
 
@@ -835,36 +1051,43 @@ func TestDeleteIncidentComment(t *testing.T) {
 			t.Log(resp.Error)
 			t.Fatalf("status code %d != %d", code, expected)
 		}
-		resp, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
 		if err != nil {
 			t.Fatal(err)
 		}
-		if len(resp.Data) == 0 {
+		if len(res.Data) == 0 {
 			t.Fatal("no data")
 		}
+		resolved := res.Data[0]
 
-		// create a comment as admin user
+		hosts := make([]string, 0)
+		for _, host := range resolved.HostsAffected {
+			hosts = append(hosts, host.UUID)
+		}
 		body, err := getJSONBodyAsReader(map[string]any{
-			"comment": "Test Comment",
+			"summary":       resolved.Summary,
+			"description":   resolved.Description,
+			"hostsAffected": hosts,
+			"hash":          resolved.Hash,
 		})
 		if err != nil {
 			t.Fatal(err)
 		}
-		req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/comments", resp.Data[0].UUID), body)
+		req, _ = http.NewRequest(http.MethodPut, "/incidents/occurrences", body)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
 		writer = makeRequest(engine, req)
 
-		expected = http.StatusCreated
+		expected = http.StatusNoContent
 		if code := writer.Code; code != expected {
 			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
 			if err != nil {
 				t.Fatal(err)
 			}
 			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
 		}
 
-		// fetch incidents to get newly created comment UUID
-		req, _ = http.NewRequest(http.MethodGet, "/incidents", nil)
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", resolved.UUID), nil)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
 		writer = makeRequest(engine, req)
 
@@ -877,24 +1100,140 @@ func TestDeleteIncidentComment(t *testing.T) {
 			t.Log(resp.Error)
 			t.Fatalf("status code %d != %d", code, expected)
 		}
-		resp, err = utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		incident, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
 		if err != nil {
 			t.Fatal(err)
 		}
-		if len(resp.Data) == 0 {
+		if incident.ResolvedAt != nil || incident.ResolvedBy != nil {
+			t.Fatal("incident still resolved")
+		}
+		if !incident.Regressed {
+			t.Fatal("incident not regressed")
+		}
+		if incident.RegressionCount != resolved.RegressionCount+1 {
+			t.Fatalf("regression count %d != %d", incident.RegressionCount, resolved.RegressionCount+1)
+		}
+		if len(incident.Comments) == 0 || !strings.Contains(incident.Comments[0].Comment, resolved.ResolvedBy.Name) {
+			t.Fatal("no regression comment")
+		}
+		// the backend leaves the comment itself, rather than whoever reported the occurrence
+		if incident.Comments[0].CommentedBy != nil || !incident.Comments[0].Automated {
+			t.Fatalf("regression comment was not automated: %v", incident.Comments[0])
+		}
+	})
+
+	t.Run("ReportIncidentOccurrence InvalidBody", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"invalidField": "invalidValue",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPut, "/incidents/occurrences", body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			if !strings.Contains(fmt.Sprint(code), "2") {
+				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Log(resp.Error)
+			}
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+}
+
+func TestUpdateIncident(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("UpdateIncident", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Data) == 0 {
 			t.Fatal("no data")
 		}
 
-		// delete the comment as non-admin user
-		jwtString, err = getJWT(engine, TestUserEmail, TestUserPassword)
+		body, err := getJSONBodyAsReader(map[string]any{
+			"summary":         "Updated Incident",
+			"description":     "Updated Incident Details",
+			"resolutionTeams": []string{res.Data[0].ResolutionTeams[0].UUID},
+			"hostsAffected":   []string{res.Data[0].HostsAffected[0].UUID},
+			"resolved":        true,
+		})
 		if err != nil {
 			t.Fatal(err)
 		}
-		req, _ = http.NewRequest(http.MethodDelete, fmt.Sprintf("/incidents/%s/comments/%s", resp.Data[0].UUID, resp.Data[0].Comments[0].UUID), nil)
+		req, _ = http.NewRequest(http.MethodPut, fmt.Sprintf("/incidents/%s", res.Data[0].UUID), body)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
 		writer = makeRequest(engine, req)
 
-		expected = http.StatusForbidden
+		expected = http.StatusNoContent
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("UpdateIncident InvalidBody", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Data) == 0 {
+			t.Fatal("no data")
+		}
+
+		body, err := getJSONBodyAsReader(map[string]any{
+			"invalidField": "invalidValue",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodPut, fmt.Sprintf("/incidents/%s", res.Data[0].UUID), body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusBadRequest
 		if code := writer.Code; code != expected {
 			if !strings.Contains(fmt.Sprint(code), "2") {
 				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
@@ -905,16 +1244,16 @@ func TestDeleteIncidentComment(t *testing.T) {
 			}
 			t.Fatalf("status code %d != %d", code, expected)
 		}
-		res, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+		errRes, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
 		if err != nil {
 			t.Fatal(err)
 		}
-		if !strings.Contains(res.Error, "you are not allowed to delete this comment") {
+		if !strings.Contains(errRes.Error, "' is required") {
 			t.Fatal("error message mismatch")
 		}
 	})
 
-	t.Run("DeleteIncidentComment", func(t *testing.T) {
+	t.Run("UpdateIncident InvalidUUID", func(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
 		writer := makeRequest(engine, req)
@@ -935,15 +1274,211 @@ func TestDeleteIncidentComment(t *testing.T) {
 		if len(res.Data) == 0 {
 			t.Fatal("no data")
 		}
-		if len(res.Data[0].Comments) == 0 {
-			t.Fatal("no comments")
-		}
 
-		req, _ = http.NewRequest(http.MethodDelete, fmt.Sprintf("/incidents/%s/comments/%s", res.Data[0].UUID, res.Data[0].Comments[0].UUID), nil)
+		body, err := getJSONBodyAsReader(map[string]any{
+			"summary":         "Updated Incident",
+			"description":     "Updated Incident Details",
+			"resolutionTeams": []string{res.Data[0].ResolutionTeams[0].UUID},
+			"hostsAffected":   []string{res.Data[0].HostsAffected[0].UUID},
+			"resolved":        true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		uuid, err := utility.GenerateRandomUUID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodPut, fmt.Sprintf("/incidents/%s", uuid), body)
 		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
 		writer = makeRequest(engine, req)
 
-		expected = http.StatusNoContent
+		expected = http.StatusNotFound
+		if code := writer.Code; code != expected {
+			if !strings.Contains(fmt.Sprint(code), "2") {
+				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Log(resp.Error)
+			}
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+}
+
+func TestPatchIncident(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patchIncident := func(incidentUUID string, contentType string, patch any) *httptest.ResponseRecorder {
+		body, err := json.Marshal(patch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPatch, fmt.Sprintf("/incidents/%s", incidentUUID), bytes.NewReader(body))
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		req.Header.Set("Content-Type", contentType)
+		return makeRequest(engine, req)
+	}
+	getIncident := func(incidentUUID string) *utility.IncidentGetResponseBodySchema {
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", incidentUUID), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+		res, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+	req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+	writer := makeRequest(engine, req)
+	if code := writer.Code; code != http.StatusOK {
+		t.Fatalf("status code %d != %d", code, http.StatusOK)
+	}
+	res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Data) == 0 {
+		t.Fatal("no data")
+	}
+	incident := res.Data[0]
+
+	t.Run("PatchIncident MergePatch", func(t *testing.T) {
+		writer := patchIncident(incident.UUID, utility.MergePatchContentType, map[string]any{
+			"description": "Patched Incident Details",
+			"severity":    1,
+		})
+
+		expected := http.StatusNoContent
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		patched := getIncident(incident.UUID)
+		if patched.Description != "Patched Incident Details" || patched.Severity != 1 {
+			t.Fatal("incident was not patched")
+		}
+		if patched.Summary != incident.Summary || len(patched.HostsAffected) != len(incident.HostsAffected) {
+			t.Fatal("fields missing from the patch were changed")
+		}
+	})
+
+	t.Run("PatchIncident JSONPatch", func(t *testing.T) {
+		operations := []map[string]any{
+			{"op": "test", "path": "/summary", "value": incident.Summary},
+			{"op": "replace", "path": "/summary", "value": "Patched Incident"},
+		}
+		writer := patchIncident(incident.UUID, utility.JSONPatchContentType, operations)
+
+		expected := http.StatusNoContent
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		if patched := getIncident(incident.UUID); patched.Summary != "Patched Incident" {
+			t.Fatal("incident was not patched")
+		}
+
+		// the summary no longer matches the test operation
+		writer = patchIncident(incident.UUID, utility.JSONPatchContentType, operations)
+		expected = http.StatusConflict
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("PatchIncident IfMatch", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", incident.UUID), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		etag := makeRequest(engine, req).Header().Get("ETag")
+
+		// adding a comment records an event, which changes the incident
+		body, err := getJSONBodyAsReader(map[string]any{"comment": "Conditional comment"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/comments", incident.UUID), body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		if code := makeRequest(engine, req).Code; code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+
+		req, _ = http.NewRequest(http.MethodPatch, fmt.Sprintf("/incidents/%s", incident.UUID), strings.NewReader(`{"severity": 3}`))
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		req.Header.Set("Content-Type", utility.MergePatchContentType)
+		req.Header.Set("If-Match", etag)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusPreconditionFailed
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		if patched := getIncident(incident.UUID); patched.Severity == 3 {
+			t.Fatal("incident was patched despite a stale If-Match")
+		}
+	})
+
+	t.Run("PatchIncident InvalidPatch", func(t *testing.T) {
+		tests := []struct {
+			contentType string
+			patch       any
+			expected    int
+		}{
+			{"application/json", map[string]any{"severity": 2}, http.StatusUnsupportedMediaType},
+			{utility.MergePatchContentType, map[string]any{"summary": nil}, http.StatusBadRequest},
+			{utility.MergePatchContentType, map[string]any{"invalidField": "invalidValue"}, http.StatusBadRequest},
+			{utility.MergePatchContentType, []any{}, http.StatusBadRequest},
+			{utility.JSONPatchContentType, []map[string]any{{"op": "invalid", "path": "/summary"}}, http.StatusBadRequest},
+			{utility.JSONPatchContentType, []map[string]any{{"op": "replace", "path": "/severity", "value": 6}}, http.StatusBadRequest},
+		}
+		for _, test := range tests {
+			writer := patchIncident(incident.UUID, test.contentType, test.patch)
+			if code := writer.Code; code != test.expected {
+				t.Fatalf("status code %d != %d for %v", code, test.expected, test.patch)
+			}
+		}
+
+		uuid, err := utility.GenerateRandomUUID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer := patchIncident(uuid, utility.MergePatchContentType, map[string]any{"severity": 2})
+		if code := writer.Code; code != http.StatusNotFound {
+			t.Fatalf("status code %d != %d", code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestIncidentStatusTransitions(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("IncidentStatusTransitions", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/incidents?status=open", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
 		if code := writer.Code; code != expected {
 			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
 			if err != nil {
@@ -952,5 +1487,1712 @@ func TestDeleteIncidentComment(t *testing.T) {
 			t.Log(resp.Error)
 			t.Fatalf("status code %d != %d", code, expected)
 		}
+		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Data) == 0 {
+			t.Fatal("no data")
+		}
+		incidentUUID := res.Data[0].UUID
+		historyLen := len(res.Data[0].StatusHistory)
+
+		for _, transition := range []string{"acknowledge", "investigate", "mitigate", "resolve"} {
+			req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/%s", incidentUUID, transition), nil)
+			req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+			writer = makeRequest(engine, req)
+
+			expected = http.StatusNoContent
+			if code := writer.Code; code != expected {
+				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Log(resp.Error)
+				t.Fatalf("status code %d != %d", code, expected)
+			}
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", incidentUUID), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		incident, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if incident.Status != "resolved" {
+			t.Fatalf("status %s != %s", incident.Status, "resolved")
+		}
+		if incident.ResolvedAt == nil || incident.ResolvedBy == nil {
+			t.Fatal("not resolved")
+		}
+		if len(incident.StatusHistory) != historyLen+4 {
+			t.Fatalf("status history length %d != %d", len(incident.StatusHistory), historyLen+4)
+		}
+		if last := incident.StatusHistory[len(incident.StatusHistory)-1]; last.FromStatus != "mitigated" || last.ToStatus != "resolved" || last.ChangedBy.Email != TestAdminEmail {
+			t.Fatal("status history mismatch")
+		}
+
+		req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/reopen", incidentUUID), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusNoContent
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("IncidentStatusTransitions InvalidTransition", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/incidents?status=open", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Data) == 0 {
+			t.Fatal("no data")
+		}
+
+		req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/mitigate", res.Data[0].UUID), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusConflict
+		if code := writer.Code; code != expected {
+			if !strings.Contains(fmt.Sprint(code), "2") {
+				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Log(resp.Error)
+			}
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("IncidentStatusTransitions InvalidStatusQuery", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/incidents?status=invalid", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+}
+
+func TestIncidentAssignment(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminUUID := "39ab8bf8-fd8c-43c2-b691-3acb4f5a3fab"
+	userUUID := "417cd42a-ddff-42dc-b358-801807522dbd"
+
+	var incidentUUID string
+	t.Run("IncidentAssignment", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/incidents?myTeams=true", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, incident := range res.Data {
+			member := false
+			for _, team := range incident.ResolutionTeams {
+				for _, user := range team.Users {
+					if user.Email == TestUserEmail {
+						member = true
+					}
+				}
+			}
+			if !member {
+				incidentUUID = incident.UUID
+				break
+			}
+		}
+		if incidentUUID == "" {
+			t.Fatal("no data")
+		}
+
+		for _, assignment := range []map[string]any{{"user": adminUUID}, {"user": userUUID, "role": "responder"}} {
+			body, err := getJSONBodyAsReader(assignment)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/assign", incidentUUID), body)
+			req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+			writer = makeRequest(engine, req)
+
+			expected = http.StatusNoContent
+			if code := writer.Code; code != expected {
+				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Log(resp.Error)
+				t.Fatalf("status code %d != %d", code, expected)
+			}
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, "/incidents?myAssigned=true", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err = utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Data) != 1 || res.Data[0].UUID != incidentUUID {
+			t.Fatal("assigned incidents mismatch")
+		}
+		incident := res.Data[0]
+		if incident.Assignee == nil || incident.Assignee.UUID != adminUUID {
+			t.Fatal("assignee mismatch")
+		}
+		if len(incident.Responders) != 1 || incident.Responders[0].UUID != userUUID {
+			t.Fatal("responders mismatch")
+		}
+
+		body, err := getJSONBodyAsReader(map[string]any{"user": adminUUID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/unassign", incidentUUID), body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusNoContent
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", incidentUUID), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		updated, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Assignee != nil {
+			t.Fatal("assignee not removed")
+		}
+		if len(updated.AssignmentHistory) != 3 {
+			t.Fatalf("assignment history length %d != %d", len(updated.AssignmentHistory), 3)
+		}
+		if last := updated.AssignmentHistory[2]; last.User.UUID != adminUUID || last.Role != "assignee" || last.Assigned {
+			t.Fatal("assignment history mismatch")
+		}
+	})
+
+	t.Run("IncidentAssignment NotTeamMember", func(t *testing.T) {
+		if incidentUUID == "" {
+			t.Skip("no incident to assign")
+		}
+		body, err := getJSONBodyAsReader(map[string]any{"user": userUUID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/assign", incidentUUID), body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("IncidentAssignment ResolutionTeamsReplaced", func(t *testing.T) {
+		teamUUID := createTeam(t, engine, jwtString, "Test Assigning Team")
+		req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/teams/%s/members/%s", teamUUID, adminUUID), strings.NewReader("{}"))
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		if code := makeRequest(engine, req).Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		body, err := getJSONBodyAsReader(map[string]any{
+			"summary":         "Test Unassigned Incident",
+			"description":     "Test Unassigned Details",
+			"resolutionTeams": []string{teamUUID},
+			"hash":            "test-unassigned-incident",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodPost, "/incidents", body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		location := strings.Split(writer.Result().Header.Get("Location"), "/")
+		incidentUUID := location[len(location)-1]
+
+		body, err = getJSONBodyAsReader(map[string]any{"user": adminUUID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/assign", incidentUUID), body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		if code := makeRequest(engine, req).Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+
+		// the admin is not a member of the new team, so can no longer be the assignee
+		teamUUID = createTeam(t, engine, jwtString, "Test Unassigning Team")
+		req, _ = http.NewRequest(http.MethodPatch, fmt.Sprintf("/incidents/%s", incidentUUID), strings.NewReader(fmt.Sprintf(`{"resolutionTeams": ["%s"]}`, teamUUID)))
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		writer = makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", incidentUUID), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+		updated, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Assignee != nil {
+			t.Fatal("assignee not removed")
+		}
+		if last := updated.AssignmentHistory[len(updated.AssignmentHistory)-1]; last.User.UUID != adminUUID || last.Assigned {
+			t.Fatal("assignment history mismatch")
+		}
+	})
+
+	t.Run("IncidentAssignment InvalidBody", func(t *testing.T) {
+		if incidentUUID == "" {
+			t.Skip("no incident to assign")
+		}
+		body, err := getJSONBodyAsReader(map[string]any{"user": adminUUID, "role": "invalid"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/assign", incidentUUID), body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+}
+
+func TestGetIncidentTimeline(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("GetIncidentTimeline", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Data) == 0 {
+			t.Fatal("no data")
+		}
+		incident := res.Data[0]
+
+		body, err := getJSONBodyAsReader(map[string]any{
+			"summary":         "Timeline summary",
+			"description":     incident.Description,
+			"hostsAffected":   []string{},
+			"resolutionTeams": []string{},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodPut, fmt.Sprintf("/incidents/%s", incident.UUID), body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusNoContent
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+
+		// the status is left alone when the deprecated 'resolved' is not given
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", incident.UUID), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+		updated, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Status != incident.Status {
+			t.Fatalf("status %s != %s", updated.Status, incident.Status)
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s/timeline?pageSize=1000", incident.UUID), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		timeline, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentEventGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		summaryChanged := false
+		for _, event := range timeline.Data {
+			if event.Type == "field_changed" && event.Field != nil && *event.Field == "summary" &&
+				event.NewValue != nil && *event.NewValue == "Timeline summary" && event.Actor != nil && event.Actor.Email == TestAdminEmail {
+				summaryChanged = true
+			}
+		}
+		if !summaryChanged {
+			t.Fatal("summary change not recorded")
+		}
+		removed := 0
+		for _, event := range timeline.Data {
+			if event.Type == "host_removed" || event.Type == "team_removed" {
+				removed++
+			}
+		}
+		if removed != len(incident.HostsAffected)+len(incident.ResolutionTeams) {
+			t.Fatalf("removed events %d != %d", removed, len(incident.HostsAffected)+len(incident.ResolutionTeams))
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s/timeline?pageSize=1", incident.UUID), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		page, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentEventGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Data) != 1 || page.Meta.TotalItems != int64(len(timeline.Data)) || page.Meta.Pages != len(timeline.Data) {
+			t.Fatal("pagination mismatch")
+		}
+	})
+
+	t.Run("GetIncidentTimeline InvalidUUID", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/incidents/invalid/timeline", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+	t.Run("IncidentEvent AppendOnly", func(t *testing.T) {
+		// events cannot be changed or deleted, whether through the model or straight through the table
+		db := database.GetDBConn()
+		if err := db.Model(&database.IncidentEvent{}).Where("1 = 1").Update("type", "changed").Error; err == nil {
+			t.Fatal("event was changed through the model")
+		}
+		if err := db.Table("tbl_incident_event").Where("1 = 1").Update("type", "changed").Error; err == nil {
+			t.Fatal("event was changed through the table")
+		}
+		if err := db.Table("tbl_incident_event").Where("1 = 1").Delete(nil).Error; err == nil {
+			t.Fatal("event was deleted through the table")
+		}
+	})
+}
+
+func TestGetIncident(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("GetIncident", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Data) == 0 {
+			t.Fatal("no data")
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", res.Data[0].UUID), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		incidentRes, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if incidentRes.UUID != res.Data[0].UUID {
+			t.Fatal("uuid mismatch")
+		}
+	})
+
+	t.Run("GetIncident InvalidUUID", func(t *testing.T) {
+		uuid, err := utility.GenerateRandomUUID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", uuid), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusNotFound
+		if code := writer.Code; code != expected {
+			if !strings.Contains(fmt.Sprint(code), "2") {
+				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Log(resp.Error)
+			}
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+}
+
+func TestCreateIncidentComment(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("CreateIncidentComment", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Data) == 0 {
+			t.Fatal("no data")
+		}
+
+		body, err := getJSONBodyAsReader(map[string]any{
+			"comment": "Test Comment",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/comments", res.Data[0].UUID), body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusCreated
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("CreateIncidentComment InvalidBody", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Data) == 0 {
+			t.Fatal("no data")
+		}
+
+		body, err := getJSONBodyAsReader(map[string]any{
+			"invalidField": "invalidValue",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/comments", res.Data[0].UUID), body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			if !strings.Contains(fmt.Sprint(code), "2") {
+				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Log(resp.Error)
+			}
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		if !strings.Contains(writer.Body.String(), "'comment' is required") {
+			t.Fatal("error message mismatch")
+		}
+	})
+
+	t.Run("CreateIncidentComment InvalidUUID", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"comment": "Test Comment",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, "/incidents/invalidUUID/comments", body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			if !strings.Contains(fmt.Sprint(code), "2") {
+				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Log(resp.Error)
+			}
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(res.Error, "invalid incident UUID") {
+			t.Fatal("error message mismatch")
+		}
+
+		uuid, err := utility.GenerateRandomUUID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/comments", uuid), body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusNotFound
+		if code := writer.Code; code != expected {
+			if !strings.Contains(fmt.Sprint(code), "2") {
+				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Log(resp.Error)
+			}
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+}
+
+func TestDeleteIncidentComment(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("DeleteIncidentComment InvalidIncidentUUID", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		resp, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Data) == 0 {
+			t.Fatal("no data")
+		}
+		if len(resp.Data[0].Comments) == 0 {
+			t.Fatal("no comments")
+		}
+
+		uuid, err := utility.GenerateRandomUUID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodDelete, fmt.Sprintf("/incidents/%s/comments/%s", uuid, resp.Data[0].Comments[0].UUID), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusNotFound
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+
+		req, _ = http.NewRequest(http.MethodDelete, fmt.Sprintf("/incidents/invalidUUID/comments/%s", resp.Data[0].Comments[0].UUID), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(res.Error, "invalid incident UUID") {
+			t.Fatal("error message mismatch")
+		}
+	})
+
+	t.Run("DeleteIncidentComment InvalidCommentUUID", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		resp, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Data) == 0 {
+			t.Fatal("no data")
+		}
+		if len(resp.Data[0].Comments) == 0 {
+			t.Fatal("no comments")
+		}
+
+		uuid, err := utility.GenerateRandomUUID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodDelete, fmt.Sprintf("/incidents/%s/comments/%s", resp.Data[0].UUID, uuid), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusNotFound
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+
+		req, _ = http.NewRequest(http.MethodDelete, fmt.Sprintf("/incidents/%s/comments/invalidUUID", resp.Data[0].UUID), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(res.Error, "invalid comment UUID") {
+			t.Fatal("error message mismatch")
+		}
+	})
+
+	t.Run("DeleteIncidentComment Forbidden", func(t *testing.T) {
+		jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// fetch incidents to get UUID for comment create
+		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		resp, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Data) == 0 {
+			t.Fatal("no data")
+		}
+
+		// create a comment as admin user
+		body, err := getJSONBodyAsReader(map[string]any{
+			"comment": "Test Comment",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/comments", resp.Data[0].UUID), body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusCreated
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+		}
+
+		// fetch incidents to get newly created comment UUID
+		req, _ = http.NewRequest(http.MethodGet, "/incidents", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		resp, err = utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Data) == 0 {
+			t.Fatal("no data")
+		}
+
+		// delete the comment as non-admin user
+		jwtString, err = getJWT(engine, TestUserEmail, TestUserPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodDelete, fmt.Sprintf("/incidents/%s/comments/%s", resp.Data[0].UUID, resp.Data[0].Comments[0].UUID), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusForbidden
+		if code := writer.Code; code != expected {
+			if !strings.Contains(fmt.Sprint(code), "2") {
+				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Log(resp.Error)
+			}
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(res.Error, "you are not allowed to delete this comment") {
+			t.Fatal("error message mismatch")
+		}
+	})
+
+	t.Run("DeleteIncidentComment", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Data) == 0 {
+			t.Fatal("no data")
+		}
+		if len(res.Data[0].Comments) == 0 {
+			t.Fatal("no comments")
+		}
+
+		req, _ = http.NewRequest(http.MethodDelete, fmt.Sprintf("/incidents/%s/comments/%s", res.Data[0].UUID, res.Data[0].Comments[0].UUID), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusNoContent
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+}
+
+func TestMergeIncidents(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/hosts", nil)
+	req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+	writer := makeRequest(engine, req)
+	hostsRes, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.HostMachineGetResponseBodySchema]](writer.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hostsRes.Data) == 0 {
+		t.Fatal("no data")
+	}
+
+	// report two incidents which are duplicates of the same bug
+	incidentUUIDs := make([]string, 0)
+	hashes := make([]string, 0)
+	for i, summary := range []string{"Test Merge Survivor", "Test Merge Duplicate"} {
+		hasher := sha1.New()
+		hasher.Write([]byte(summary))
+		hash := fmt.Sprintf("%x", hasher.Sum(nil))
+		body, err := getJSONBodyAsReader(map[string]any{
+			"summary":         summary,
+			"description":     "Test Merge Details",
+			"resolutionTeams": []string{hostsRes.Data[i%len(hostsRes.Data)].Team.UUID},
+			"hostsAffected":   []string{hostsRes.Data[i%len(hostsRes.Data)].UUID},
+			"hash":            hash,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPut, "/incidents/occurrences", body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		location := strings.Split(writer.Result().Header.Get("Location"), "/")
+		incidentUUIDs = append(incidentUUIDs, location[len(location)-1])
+		hashes = append(hashes, hash)
+	}
+
+	t.Run("CreateIncidentLink", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"incident": incidentUUIDs[1],
+			"type":     "duplicate-of",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/links", incidentUUIDs[0]), body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusCreated
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", incidentUUIDs[1]), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		incident, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(incident.LinkedBy) != 1 || incident.LinkedBy[0].Incident != incidentUUIDs[0] || incident.LinkedBy[0].Type != "duplicate-of" {
+			t.Fatal("link mismatch")
+		}
+	})
+
+	t.Run("CreateIncidentLink Duplicate", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"incident": incidentUUIDs[1],
+			"type":     "duplicate-of",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/links", incidentUUIDs[0]), body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusConflict
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("CreateIncidentLink InvalidBody", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"incident": incidentUUIDs[1],
+			"type":     "invalid",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/links", incidentUUIDs[0]), body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("MergeIncidents", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"incident": incidentUUIDs[1],
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/merge", incidentUUIDs[0]), body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusCreated
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+
+		// the duplicate is kept, with its history, pointing at the survivor
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", incidentUUIDs[1]), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusOK
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		duplicate, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if duplicate.MergedInto == nil || *duplicate.MergedInto != incidentUUIDs[0] {
+			t.Fatal("duplicate does not point at the survivor")
+		}
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s/timeline?pageSize=1000", incidentUUIDs[1]), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+		timeline, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentEventGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(timeline.Data) < 2 || timeline.Data[0].Type != "created" || timeline.Data[len(timeline.Data)-1].Type != "merged" {
+			t.Fatal("duplicate timeline not kept")
+		}
+
+		// the absorbed hash resolves to the survivor
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents?hash=%s", hashes[1]), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Data) != 1 || res.Data[0].UUID != incidentUUIDs[0] {
+			t.Fatal("hash alias not resolved")
+		}
+		incident := res.Data[0]
+		if !slices.Contains(incident.HashAliases, hashes[1]) {
+			t.Fatal("hash alias missing")
+		}
+		if incident.OccurrenceCount != 2 {
+			t.Fatalf("occurrence count %d != %d", incident.OccurrenceCount, 2)
+		}
+		if len(incident.Links) != 1 || incident.Links[0].Incident != incidentUUIDs[1] {
+			t.Fatal("links to the merged incident not kept")
+		}
+
+		// future occurrences of the absorbed hash are counted against the survivor
+		body, err = getJSONBodyAsReader(map[string]any{
+			"summary":         "Test Merge Duplicate",
+			"description":     "Test Merge Details",
+			"resolutionTeams": []string{},
+			"hostsAffected":   []string{},
+			"hash":            hashes[1],
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodPut, "/incidents/occurrences", body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusNoContent
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		if location := writer.Result().Header.Get("Location"); !strings.HasSuffix(location, incidentUUIDs[0]) {
+			t.Fatalf("location %s does not point to %s", location, incidentUUIDs[0])
+		}
+	})
+
+	t.Run("MergeIncidents AlreadyMerged", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"incident": incidentUUIDs[1],
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/merge", incidentUUIDs[0]), body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusConflict
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("MergeIncidents Self", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"incident": incidentUUIDs[0],
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/merge", incidentUUIDs[0]), body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+}
+
+func TestBulkIncidents(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bulk := func(jwtString string, body map[string]any) *httptest.ResponseRecorder {
+		reader, err := getJSONBodyAsReader(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, "/incidents/bulk", reader)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		return makeRequest(engine, req)
+	}
+	getIncident := func(incidentUUID string) *utility.IncidentGetResponseBodySchema {
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", incidentUUID), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+		res, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	getStatus := func(incidentUUID string) string {
+		return getIncident(incidentUUID).Status
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/hosts", nil)
+	req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+	writer := makeRequest(engine, req)
+	hostsRes, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.HostMachineGetResponseBodySchema]](writer.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hostsRes.Data) == 0 {
+		t.Fatal("no data")
+	}
+	incidentUUIDs := make([]string, 0)
+	for _, summary := range []string{"Test Bulk One", "Test Bulk Two", "Test Bulk Three"} {
+		hasher := sha1.New()
+		hasher.Write([]byte(summary))
+		body, err := getJSONBodyAsReader(map[string]any{
+			"summary":         summary,
+			"description":     "Test Bulk Details",
+			"resolutionTeams": []string{hostsRes.Data[0].Team.UUID},
+			"hostsAffected":   []string{hostsRes.Data[0].UUID},
+			"hash":            fmt.Sprintf("%x", hasher.Sum(nil)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPut, "/incidents/occurrences", body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		location := strings.Split(writer.Result().Header.Get("Location"), "/")
+		incidentUUIDs = append(incidentUUIDs, location[len(location)-1])
+	}
+
+	t.Run("BulkIncidents DryRun", func(t *testing.T) {
+		writer := bulk(jwtString, map[string]any{"incidents": incidentUUIDs, "action": "resolve", "dryRun": true})
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err := utility.ReadJSONStruct[utility.IncidentBulkResponseSchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Applied || len(res.Results) != len(incidentUUIDs) {
+			t.Fatalf("unexpected dry run report %s", writer.Body.String())
+		}
+		for _, result := range res.Results {
+			if result.Status != http.StatusOK {
+				t.Fatalf("incident %s would not be resolved: %s", result.Incident, result.Error)
+			}
+		}
+		for _, incidentUUID := range incidentUUIDs {
+			if status := getStatus(incidentUUID); status != "open" {
+				t.Fatalf("dry run changed the status of incident %s to %s", incidentUUID, status)
+			}
+		}
+	})
+
+	t.Run("BulkIncidents DryRunSearchIndex", func(t *testing.T) {
+		search := func(text string) []string {
+			req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents?q=%s", text), nil)
+			req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+			writer := makeRequest(engine, req)
+			if code := writer.Code; code != http.StatusOK {
+				t.Fatalf("status code %d != %d", code, http.StatusOK)
+			}
+			res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			uuids := make([]string, 0)
+			for _, incident := range res.Data {
+				uuids = append(uuids, incident.UUID)
+			}
+			return uuids
+		}
+
+		// the search index is only changed once the transaction is committed, so a dry run leaves it as it was
+		writer := bulk(jwtString, map[string]any{"incidents": incidentUUIDs[:1], "action": "comment", "comment": "quokkaphantom", "dryRun": true})
+		if code := writer.Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+		if found := search("quokkaphantom"); len(found) != 0 {
+			t.Fatal("a comment made in a dry run was indexed")
+		}
+
+		writer = bulk(jwtString, map[string]any{"incidents": incidentUUIDs[1:], "action": "mergeInto", "target": incidentUUIDs[0], "dryRun": true})
+		if code := writer.Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+		if found := search("Three"); !slices.Contains(found, incidentUUIDs[2]) {
+			t.Fatal("an incident merged in a dry run was removed from the index")
+		}
+	})
+
+	t.Run("BulkIncidents NotFound", func(t *testing.T) {
+		missing, err := utility.GenerateRandomUUID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, dryRun := range []bool{true, false} {
+			writer := bulk(jwtString, map[string]any{"incidents": []string{incidentUUIDs[2], missing}, "action": "setSeverity", "severity": 1, "dryRun": dryRun})
+
+			expected := http.StatusConflict
+			if dryRun {
+				expected = http.StatusOK
+			}
+			if code := writer.Code; code != expected {
+				t.Fatalf("status code %d != %d", code, expected)
+			}
+			res, err := utility.ReadJSONStruct[utility.IncidentBulkResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Applied || len(res.Results) != 2 || res.Results[0].Status != http.StatusOK ||
+				res.Results[1].Incident != missing || res.Results[1].Status != http.StatusNotFound {
+				t.Fatalf("unexpected report %s", writer.Body.String())
+			}
+		}
+		if incident := getIncident(incidentUUIDs[2]); incident.Severity == 1 {
+			t.Fatal("severity was set although an incident was not found")
+		}
+	})
+
+	t.Run("BulkIncidents Resolve", func(t *testing.T) {
+		writer := bulk(jwtString, map[string]any{"incidents": incidentUUIDs[:2], "action": "resolve"})
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		for _, incidentUUID := range incidentUUIDs[:2] {
+			if status := getStatus(incidentUUID); status != "resolved" {
+				t.Fatalf("incident %s was not resolved", incidentUUID)
+			}
+		}
+	})
+
+	t.Run("BulkIncidents PartialFailure", func(t *testing.T) {
+		// the second incident is already resolved, so nothing is resolved
+		writer := bulk(jwtString, map[string]any{"incidents": incidentUUIDs[1:], "action": "resolve"})
+
+		expected := http.StatusConflict
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err := utility.ReadJSONStruct[utility.IncidentBulkResponseSchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Applied || len(res.Results) != 2 || res.Results[0].Status != http.StatusConflict || res.Results[1].Status != http.StatusOK {
+			t.Fatalf("unexpected report %s", writer.Body.String())
+		}
+		if status := getStatus(incidentUUIDs[2]); status != "open" {
+			t.Fatal("incident was resolved although the bulk action failed")
+		}
+	})
+
+	t.Run("BulkIncidents Query", func(t *testing.T) {
+		writer := bulk(jwtString, map[string]any{"query": "status:open", "action": "setSeverity", "severity": 1, "dryRun": true})
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err := utility.ReadJSONStruct[utility.IncidentBulkResponseSchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.ContainsFunc(res.Results, func(r utility.IncidentBulkResultSchema) bool { return r.Incident == incidentUUIDs[2] }) {
+			t.Fatal("query did not select the open incident")
+		}
+		if slices.ContainsFunc(res.Results, func(r utility.IncidentBulkResultSchema) bool { return r.Incident == incidentUUIDs[0] }) {
+			t.Fatal("query selected a resolved incident")
+		}
+
+		userJWT, err := getJWT(engine, TestUserEmail, TestUserPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer = bulk(userJWT, map[string]any{"query": "status:open", "action": "resolve", "dryRun": true})
+		expected = http.StatusForbidden
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("BulkIncidents MergeInto", func(t *testing.T) {
+		writer := bulk(jwtString, map[string]any{"incidents": incidentUUIDs[1:], "action": "mergeInto", "target": incidentUUIDs[0]})
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d: %s", code, expected, writer.Body.String())
+		}
+		for _, incidentUUID := range incidentUUIDs[1:] {
+			if incident := getIncident(incidentUUID); incident.MergedInto == nil || *incident.MergedInto != incidentUUIDs[0] {
+				t.Fatalf("incident %s was not merged", incidentUUID)
+			}
+		}
+	})
+
+	t.Run("BulkIncidents InvalidBody", func(t *testing.T) {
+		missing, err := utility.GenerateRandomUUID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			body     map[string]any
+			expected int
+		}{
+			{map[string]any{"action": "resolve"}, http.StatusBadRequest},
+			{map[string]any{"incidents": incidentUUIDs[:1], "query": "status:open", "action": "resolve"}, http.StatusBadRequest},
+			{map[string]any{"incidents": []string{"invalid"}, "action": "resolve"}, http.StatusBadRequest},
+			{map[string]any{"incidents": incidentUUIDs[:1], "action": "invalid"}, http.StatusBadRequest},
+			{map[string]any{"incidents": incidentUUIDs[:1], "action": "setSeverity", "severity": 6}, http.StatusBadRequest},
+			{map[string]any{"incidents": incidentUUIDs[:1], "action": "comment"}, http.StatusBadRequest},
+			{map[string]any{"query": "status:", "action": "resolve"}, http.StatusBadRequest},
+			{map[string]any{"incidents": incidentUUIDs[:1], "action": "mergeInto", "target": missing}, http.StatusNotFound},
+		}
+		for _, test := range tests {
+			writer := bulk(jwtString, test.body)
+			if code := writer.Code; code != test.expected {
+				t.Fatalf("status code %d != %d for %v", code, test.expected, test.body)
+			}
+		}
+	})
+}
+
+func TestIncidentOwnership(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userJWT, err := getJWT(engine, TestUserEmail, TestUserPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	teamUUID := createTeam(t, engine, jwtString, "Test Incident Owners")
+	request := func(jwtString string, method string, url string, body map[string]any) *httptest.ResponseRecorder {
+		reader, err := getJSONBodyAsReader(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(method, url, reader)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		return makeRequest(engine, req)
+	}
+
+	writer := request(jwtString, http.MethodPost, "/incidents", map[string]any{
+		"summary":         "Owned Incident",
+		"description":     "Owned Incident Details",
+		"resolutionTeams": []string{teamUUID},
+		"hash":            "owned-incident",
+	})
+	if code := writer.Code; code != http.StatusCreated {
+		t.Fatalf("status code %d != %d", code, http.StatusCreated)
+	}
+	location := strings.Split(writer.Result().Header.Get("Location"), "/")
+	incidentURL := fmt.Sprintf("/incidents/%s", location[len(location)-1])
+
+	t.Run("NonMember", func(t *testing.T) {
+		expected := http.StatusForbidden
+		if code := request(userJWT, http.MethodPost, incidentURL+"/acknowledge", nil).Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		writer := request(userJWT, http.MethodPost, incidentURL+"/comments", map[string]any{"comment": "not my incident"})
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		// the incident can still be read, as the team is not private
+		if code := request(userJWT, http.MethodGet, incidentURL, nil).Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+	})
+
+	t.Run("Member", func(t *testing.T) {
+		writer := request(jwtString, http.MethodPut, fmt.Sprintf("/teams/%s/members/%s", teamUUID, TestUserUUID), map[string]any{})
+		if code := writer.Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		expected := http.StatusNoContent
+		if code := request(userJWT, http.MethodPost, incidentURL+"/acknowledge", nil).Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("PrivateTeam", func(t *testing.T) {
+		writer := request(jwtString, http.MethodDelete, fmt.Sprintf("/teams/%s/members/%s", teamUUID, TestUserUUID), nil)
+		if code := writer.Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		writer = request(jwtString, http.MethodPut, fmt.Sprintf("/teams/%s", teamUUID), map[string]any{
+			"name":    "Test Incident Owners",
+			"private": true,
+		})
+		if code := writer.Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		if code := request(userJWT, http.MethodGet, incidentURL, nil).Code; code != http.StatusNotFound {
+			t.Fatalf("status code %d != %d", code, http.StatusNotFound)
+		}
+
+		writer = request(userJWT, http.MethodGet, "/incidents", nil)
+		if code := writer.Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+		if strings.Contains(writer.Body.String(), "Owned Incident") {
+			t.Fatal("an incident of a private team was listed to a non-member")
+		}
+		// users with the incident:manage permission can see the incidents of every team
+		if code := request(jwtString, http.MethodGet, incidentURL, nil).Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+	})
+
+	t.Run("PrivateTeam Reporter", func(t *testing.T) {
+		writer := request(jwtString, http.MethodPost, "/hosts", map[string]any{
+			"hostname": "owned-incident-host",
+			"ip4":      "192.168.0.21",
+			"os":       "Linux",
+			"teamID":   teamUUID,
+		})
+		if code := writer.Code; code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		location := strings.Split(writer.Result().Header.Get("Location"), "/")
+		hostUUID := location[len(location)-1]
+		writer = request(jwtString, http.MethodPost, "/roles", map[string]any{
+			"name":        "Test Private Reporters",
+			"permissions": []string{database.PermissionIncidentCreate},
+		})
+		if code := writer.Code; code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		location = strings.Split(writer.Result().Header.Get("Location"), "/")
+		roleURL := fmt.Sprintf("/users/%s/roles/%s", TestUserUUID, location[len(location)-1])
+		if code := request(jwtString, http.MethodPut, roleURL, nil).Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		t.Cleanup(func() {
+			request(jwtString, http.MethodDelete, roleURL, nil)
+		})
+		writer = request(userJWT, http.MethodPost, "/me/api-keys", map[string]any{
+			"name":      "Test Private Reporter",
+			"scopes":    []string{database.PermissionIncidentCreate},
+			"expiresAt": time.Now().Add(time.Hour),
+		})
+		if code := writer.Code; code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		key, err := utility.ReadJSONStruct[utility.APIKeyPostResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		report := func(hash string) *httptest.ResponseRecorder {
+			reader, err := getJSONBodyAsReader(map[string]any{
+				"summary":       "Owned Incident",
+				"description":   "Owned Incident Details",
+				"hostsAffected": []string{hostUUID},
+				"hash":          hash,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ := http.NewRequest(http.MethodPut, "/incidents/occurrences", reader)
+			req.Header.Set(middleware.APIKeyHeaderNameString, key.Key)
+			return makeRequest(engine, req)
+		}
+
+		// a reporter keeps reporting occurrences of incidents and on hosts of private teams it cannot see
+		if code := report("owned-incident").Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		if code := report("owned-incident-reported").Code; code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		writer = request(jwtString, http.MethodGet, incidentURL, nil)
+		if code := writer.Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+		incident, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if incident.OccurrenceCount != 2 || len(incident.HostsAffected) != 1 {
+			t.Fatalf("occurrence count %d, hosts %d", incident.OccurrenceCount, len(incident.HostsAffected))
+		}
 	})
 }
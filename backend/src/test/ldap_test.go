@@ -0,0 +1,388 @@
+package test_test
+
+import (
+	"com668-backend/database"
+	"com668-backend/middleware"
+	"com668-backend/utility"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// An entry of the mock directory, which can be bound as if it has a password
+type mockLDAPEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+// A local directory, which answers the binds and searches a login or sync makes. Every search looks through the whole
+// directory, and only the filters those searches use are understood
+type mockLDAPServer struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	entries  map[string]*mockLDAPEntry
+}
+
+func newMockLDAPServer(t *testing.T, entries ...*mockLDAPEntry) *mockLDAPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &mockLDAPServer{listener: listener, entries: make(map[string]*mockLDAPEntry)}
+	for _, entry := range entries {
+		server.entries[entry.dn] = entry
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (server *mockLDAPServer) url() string {
+	return "ldap://" + server.listener.Addr().String()
+}
+
+// Change an entry, or remove it if the change is nil
+func (server *mockLDAPServer) set(dn string, entry *mockLDAPEntry) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if entry == nil {
+		delete(server.entries, dn)
+	} else {
+		server.entries[dn] = entry
+	}
+}
+
+func (server *mockLDAPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	bound := false
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			server.mutex.Lock()
+			entry, ok := server.entries[op.Children[1].Data.String()]
+			bound = ok && entry.password != "" && entry.password == op.Children[2].Data.String()
+			server.mutex.Unlock()
+			code := ldap.LDAPResultSuccess
+			if !bound {
+				code = ldap.LDAPResultInvalidCredentials
+			}
+			server.write(conn, id, mockLDAPResult(ldap.ApplicationBindResponse, code))
+		case ldap.ApplicationSearchRequest:
+			if !bound {
+				server.write(conn, id, mockLDAPResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultInsufficientAccessRights))
+				continue
+			}
+			server.mutex.Lock()
+			for _, entry := range server.entries {
+				if entry.matches(op.Children[6]) {
+					server.write(conn, id, entry.packet())
+				}
+			}
+			server.mutex.Unlock()
+			server.write(conn, id, mockLDAPResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+		default:
+			return
+		}
+	}
+}
+
+func (server *mockLDAPServer) write(conn net.Conn, id int64, op *ber.Packet) {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+	packet.AppendChild(op)
+	conn.Write(packet.Bytes())
+}
+
+func mockLDAPResult(tag ber.Tag, code int) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return op
+}
+
+func (entry *mockLDAPEntry) values(name string) []string {
+	for attribute, values := range entry.attributes {
+		if strings.EqualFold(attribute, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+func (entry *mockLDAPEntry) matches(filter *ber.Packet) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !entry.matches(child) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if entry.matches(child) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !entry.matches(filter.Children[0])
+	case ldap.FilterEqualityMatch:
+		for _, value := range entry.values(filter.Children[0].Data.String()) {
+			if strings.EqualFold(value, filter.Children[1].Data.String()) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		return len(entry.values(filter.Data.String())) > 0
+	default:
+		return false
+	}
+}
+
+func (entry *mockLDAPEntry) packet() *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "DN"))
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for name, values := range entry.attributes {
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+	op.AppendChild(attributes)
+	return op
+}
+
+func newMockLDAPPerson(uid string, groups ...string) *mockLDAPEntry {
+	return &mockLDAPEntry{
+		dn:       fmt.Sprintf("uid=%s,ou=people,dc=example,dc=com", uid),
+		password: uid + "-password",
+		attributes: map[string][]string{
+			"objectClass": {"top", "person"},
+			"uid":         {uid},
+			"mail":        {uid + ".ldap@example.com"},
+			"memberOf":    groups,
+		},
+	}
+}
+
+func TestLDAP(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := func(jwtString string, method string, url string, body map[string]any) *httptest.ResponseRecorder {
+		reader, err := getJSONBodyAsReader(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(method, url, reader)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		return makeRequest(engine, req)
+	}
+	login := func(email string, password string) *httptest.ResponseRecorder {
+		return request("", http.MethodPost, "/users/login", map[string]any{"email": email, "password": password})
+	}
+	getUser := func(userUUID string) *utility.UserGetResponseBodySchema {
+		writer := request(jwtString, http.MethodGet, "/users/"+userUUID, nil)
+		if code := writer.Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+		user, err := utility.ReadJSONStruct[utility.UserGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+
+	t.Run("NotConfigured", func(t *testing.T) {
+		t.Setenv("LDAP_URL", "")
+		if code := request(jwtString, http.MethodPost, "/users/ldap-sync", nil).Code; code != http.StatusNotFound {
+			t.Fatalf("status code %d != %d", code, http.StatusNotFound)
+		}
+	})
+
+	oncall := "cn=aims-oncall,ou=groups,dc=example,dc=com"
+	server := newMockLDAPServer(t,
+		&mockLDAPEntry{
+			dn:         "cn=aims,ou=services,dc=example,dc=com",
+			password:   "service-password",
+			attributes: map[string][]string{"objectClass": {"top", "applicationProcess"}, "cn": {"aims"}},
+		},
+		newMockLDAPPerson("alice", oncall),
+		newMockLDAPPerson("bob"),
+	)
+	t.Setenv("LDAP_URL", server.url())
+	t.Setenv("LDAP_BIND_DN", "cn=aims,ou=services,dc=example,dc=com")
+	t.Setenv("LDAP_BIND_PASSWORD", "service-password")
+	t.Setenv("LDAP_BASE_DN", "dc=example,dc=com")
+
+	teamUUID := createTeam(t, engine, jwtString, "LDAP On Call")
+	// groups are only mapped by their whole DN, so a mapping of the first part of it alone gives nothing
+	writer := request(jwtString, http.MethodPost, "/group-mappings", map[string]any{"group": "aims-oncall", "admin": true})
+	if code := writer.Code; code != http.StatusCreated {
+		t.Fatalf("status code %d != %d", code, http.StatusCreated)
+	}
+	partURL := writer.Result().Header.Get("Location")
+	partURL = partURL[strings.Index(partURL, "/group-mappings/"):]
+	t.Cleanup(func() {
+		request(jwtString, http.MethodDelete, partURL, nil)
+	})
+	writer = request(jwtString, http.MethodPost, "/group-mappings", map[string]any{"group": oncall, "team": teamUUID})
+	if code := writer.Code; code != http.StatusCreated {
+		t.Fatalf("status code %d != %d", code, http.StatusCreated)
+	}
+	mappingURL := writer.Result().Header.Get("Location")
+	mappingURL = mappingURL[strings.Index(mappingURL, "/group-mappings/"):]
+	t.Cleanup(func() {
+		request(jwtString, http.MethodDelete, mappingURL, nil)
+	})
+
+	var aliceUUID, bobUUID, bobJWT string
+	// other tests expect only their own users to be deactivated
+	t.Cleanup(func() {
+		if bobUUID != "" {
+			request(jwtString, http.MethodPost, fmt.Sprintf("/users/%s/reactivate", bobUUID), nil)
+		}
+	})
+	t.Run("Login", func(t *testing.T) {
+		writer := login("alice.ldap@example.com", "alice-password")
+		if code := writer.Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d: %s", code, http.StatusNoContent, writer.Body.String())
+		}
+		writer = request(writer.Result().Header.Get(middleware.AuthHeaderNameString), http.MethodGet, "/me", nil)
+		me, err := utility.ReadJSONStruct[utility.UserGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		aliceUUID = me.UUID
+		if me.Name != "alice" || me.Email != "alice.ldap@example.com" {
+			t.Fatalf("user was not created as expected: %v", me)
+		}
+		if me.Admin != nil && *me.Admin {
+			t.Fatal("user was made an admin by a mapping of the first part of their group's DN")
+		}
+		team, _ := getTeam(t, engine, jwtString, teamUUID)
+		if len(team.Users) != 1 || team.Users[0].UUID != aliceUUID || team.Users[0].Role != database.TeamRoleMember {
+			t.Fatal("user was not added to the team mapped to their group")
+		}
+
+		writer = login("bob.ldap@example.com", "bob-password")
+		if code := writer.Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d: %s", code, http.StatusNoContent, writer.Body.String())
+		}
+		bobJWT = writer.Result().Header.Get(middleware.AuthHeaderNameString)
+		writer = request(bobJWT, http.MethodGet, "/me", nil)
+		me, err = utility.ReadJSONStruct[utility.UserGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		bobUUID = me.UUID
+
+		// users who are not in the directory still log in with their password here
+		if _, err := getJWT(engine, TestUserEmail, TestUserPassword); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Login InvalidCredentials", func(t *testing.T) {
+		tests := []struct {
+			email    string
+			password string
+		}{
+			{"alice.ldap@example.com", "bob-password"},
+			{"carol.ldap@example.com", "carol-password"},
+			{"*", "alice-password"},
+		}
+		for _, test := range tests {
+			if code := login(test.email, test.password).Code; code != http.StatusBadRequest {
+				t.Fatalf("status code %d != %d for %s", code, http.StatusBadRequest, test.email)
+			}
+		}
+	})
+
+	t.Run("Login DirectoryUnavailable", func(t *testing.T) {
+		t.Setenv("LDAP_BIND_PASSWORD", "wrong-password")
+		if code := login("alice.ldap@example.com", "alice-password").Code; code != http.StatusBadGateway {
+			t.Fatalf("status code %d != %d", code, http.StatusBadGateway)
+		}
+	})
+
+	t.Run("ChangePassword", func(t *testing.T) {
+		aliceJWT := login("alice.ldap@example.com", "alice-password").Result().Header.Get(middleware.AuthHeaderNameString)
+		body := map[string]any{"currentPassword": "alice-password", "newPassword": "new-password"}
+		if code := request(aliceJWT, http.MethodPut, "/me/password", body).Code; code != http.StatusForbidden {
+			t.Fatalf("status code %d != %d", code, http.StatusForbidden)
+		}
+	})
+
+	t.Run("Sync", func(t *testing.T) {
+		userJWT, err := getJWT(engine, TestUserEmail, TestUserPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code := request(userJWT, http.MethodPost, "/users/ldap-sync", nil).Code; code != http.StatusForbidden {
+			t.Fatalf("status code %d != %d", code, http.StatusForbidden)
+		}
+
+		// alice leaves the group and bob leaves the organisation
+		server.set(newMockLDAPPerson("alice").dn, newMockLDAPPerson("alice"))
+		server.set(newMockLDAPPerson("bob").dn, nil)
+		if code := request(jwtString, http.MethodPost, "/users/ldap-sync", nil).Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		team, _ := getTeam(t, engine, jwtString, teamUUID)
+		if len(team.Users) != 0 {
+			t.Fatal("user was not removed from the team of a group they left")
+		}
+		if getUser(aliceUUID).DeactivatedAt != nil {
+			t.Fatal("user still in the directory was deactivated")
+		}
+		if getUser(bobUUID).DeactivatedAt == nil {
+			t.Fatal("user removed from the directory was not deactivated")
+		}
+		if code := request(bobJWT, http.MethodGet, "/me", nil).Code; code != http.StatusUnauthorized {
+			t.Fatalf("status code %d != %d", code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("Sync EmptyDirectory", func(t *testing.T) {
+		t.Setenv("LDAP_USER_FILTER", "(objectClass=nobody)")
+		if code := request(jwtString, http.MethodPost, "/users/ldap-sync", nil).Code; code != http.StatusBadGateway {
+			t.Fatalf("status code %d != %d", code, http.StatusBadGateway)
+		}
+		if getUser(aliceUUID).DeactivatedAt != nil {
+			t.Fatal("users were deactivated when the directory looked empty")
+		}
+	})
+}
@@ -0,0 +1,454 @@
+package test_test
+
+import (
+	"com668-backend/database"
+	"com668-backend/middleware"
+	"com668-backend/utility"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	TestOIDCClientID string = "aims-test-client"
+)
+
+// A local identity provider, which gives out an id token for whoever the test says logged in
+type mockOIDCIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	mutex  sync.Mutex
+	// the logins each authorisation code was given for
+	codes map[string]mockOIDCLogin
+}
+
+type mockOIDCLogin struct {
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newMockOIDCIssuer(t *testing.T) *mockOIDCIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &mockOIDCIssuer{key: key, codes: make(map[string]mockOIDCLogin)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                 issuer.server.URL,
+			"authorization_endpoint": issuer.server.URL + "/authorize",
+			"token_endpoint":         issuer.server.URL + "/token",
+			"jwks_uri":               issuer.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		encode := base64.RawURLEncoding.EncodeToString
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]any{{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": "RS256",
+				"kid": "mock-key",
+				"n":   encode(key.N.Bytes()),
+				"e":   encode(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		issuer.mutex.Lock()
+		login, ok := issuer.codes[r.PostForm.Get("code")]
+		delete(issuer.codes, r.PostForm.Get("code"))
+		issuer.mutex.Unlock()
+		// the verifier must hash to the challenge sent with the login, as PKCE requires
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != login.challenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]any{"error": "invalid_grant"})
+			return
+		}
+		claims := jwt.MapClaims{
+			"iss":   issuer.server.URL,
+			"aud":   TestOIDCClientID,
+			"iat":   jwt.NewNumericDate(time.Now()),
+			"exp":   jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			"nonce": login.nonce,
+		}
+		for name, value := range login.claims {
+			claims[name] = value
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "mock-key"
+		idToken, err := token.SignedString(key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "mock-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   60,
+			"id_token":     idToken,
+		})
+	})
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+// Start a login, act as the user logging in to the identity provider with the claims given, and finish the login
+func (issuer *mockOIDCIssuer) login(t *testing.T, engine *gin.Engine, claims jwt.MapClaims) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, "/authorise/oidc", nil)
+	writer := makeRequest(engine, req)
+	if code := writer.Code; code != http.StatusFound {
+		t.Fatalf("status code %d != %d", code, http.StatusFound)
+	}
+	location, err := url.Parse(writer.Result().Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := location.Query()
+	if !strings.HasPrefix(location.String(), issuer.server.URL+"/authorize") || query.Get("code_challenge_method") != "S256" || query.Get("client_id") != TestOIDCClientID {
+		t.Fatalf("login was not sent to the identity provider as expected: %s", location)
+	}
+
+	code := fmt.Sprintf("code-%d", time.Now().UnixNano())
+	issuer.mutex.Lock()
+	issuer.codes[code] = mockOIDCLogin{challenge: query.Get("code_challenge"), nonce: query.Get("nonce"), claims: claims}
+	issuer.mutex.Unlock()
+	return issuer.callback(engine, query.Get("state"), code, writer.Result().Cookies()...)
+}
+
+// Come back from the identity provider with a state and code, in a browser holding the cookies given
+func (issuer *mockOIDCIssuer) callback(engine *gin.Engine, state string, code string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/authorise/oidc/callback?state=%s&code=%s", url.QueryEscape(state), url.QueryEscape(code)), nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	return makeRequest(engine, req)
+}
+
+func TestOIDC(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := func(jwtString string, method string, url string, body map[string]any) *httptest.ResponseRecorder {
+		reader, err := getJSONBodyAsReader(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(method, url, reader)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		return makeRequest(engine, req)
+	}
+	getMe := func(jwtString string) *utility.UserGetResponseBodySchema {
+		writer := request(jwtString, http.MethodGet, "/me", nil)
+		if code := writer.Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+		me, err := utility.ReadJSONStruct[utility.UserGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return me
+	}
+
+	t.Run("NotConfigured", func(t *testing.T) {
+		t.Setenv("OIDC_ISSUER", "")
+		req, _ := http.NewRequest(http.MethodGet, "/authorise/oidc", nil)
+		if code := makeRequest(engine, req).Code; code != http.StatusNotFound {
+			t.Fatalf("status code %d != %d", code, http.StatusNotFound)
+		}
+	})
+
+	issuer := newMockOIDCIssuer(t)
+	t.Setenv("OIDC_ISSUER", issuer.server.URL)
+	t.Setenv("OIDC_CLIENT_ID", TestOIDCClientID)
+	t.Setenv("OIDC_CLIENT_SECRET", "secret")
+	t.Setenv("OIDC_REDIRECT_URL", "https://localhost:5000/authorise/oidc/callback")
+	t.Setenv("OIDC_POST_LOGIN_REDIRECT", "")
+
+	teamUUID := createTeam(t, engine, jwtString, "SSO Responders")
+	writer := request(jwtString, http.MethodPost, "/roles", map[string]any{
+		"name":        "sso-provider-editor",
+		"permissions": []string{database.PermissionProviderWrite},
+	})
+	if code := writer.Code; code != http.StatusCreated {
+		t.Fatalf("status code %d != %d", code, http.StatusCreated)
+	}
+	location := strings.Split(writer.Result().Header.Get("Location"), "/")
+	roleUUID := location[len(location)-1]
+
+	t.Run("CreateGroupMapping", func(t *testing.T) {
+		tests := []struct {
+			body     map[string]any
+			expected int
+		}{
+			{map[string]any{"group": "aims-responders", "team": teamUUID, "teamRole": "lead"}, http.StatusCreated},
+			{map[string]any{"group": "aims-responders", "role": roleUUID}, http.StatusCreated},
+			{map[string]any{"group": "aims-responders"}, http.StatusBadRequest},
+			{map[string]any{"group": "aims-responders", "team": teamUUID, "teamRole": "owner"}, http.StatusBadRequest},
+			{map[string]any{"group": "aims-responders", "role": "1b4e28ba-2fa1-41d2-883f-0016d3cca427"}, http.StatusBadRequest},
+		}
+		for _, test := range tests {
+			if code := request(jwtString, http.MethodPost, "/group-mappings", test.body).Code; code != test.expected {
+				t.Fatalf("status code %d != %d for %v", code, test.expected, test.body)
+			}
+		}
+
+		userJWT, err := getJWT(engine, TestUserEmail, TestUserPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code := request(userJWT, http.MethodPost, "/group-mappings", tests[0].body).Code; code != http.StatusForbidden {
+			t.Fatalf("status code %d != %d", code, http.StatusForbidden)
+		}
+
+		writer := request(jwtString, http.MethodGet, "/group-mappings", nil)
+		if code := writer.Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+		mappings, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.GroupMappingGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		responders := make([]utility.GroupMappingGetResponseBodySchema, 0)
+		for _, mapping := range mappings.Data {
+			if mapping.Group == "aims-responders" {
+				responders = append(responders, mapping)
+			}
+		}
+		if len(responders) != 2 || *responders[0].Team != teamUUID || *responders[0].TeamRole != database.TeamRoleLead || *responders[1].Role != roleUUID {
+			t.Fatalf("group mappings were not created as expected: %v", responders)
+		}
+	})
+
+	var userUUID string
+	t.Run("Login", func(t *testing.T) {
+		writer := issuer.login(t, engine, jwt.MapClaims{
+			"sub":                "sso-user-1",
+			"email":              "sso.user@example.com",
+			"email_verified":     true,
+			"preferred_username": "sso.user",
+			"groups":             []string{"aims-responders", "unmapped"},
+		})
+		if code := writer.Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d: %s", code, http.StatusNoContent, writer.Body.String())
+		}
+		userJWT := writer.Result().Header.Get(middleware.AuthHeaderNameString)
+		if userJWT == "" || writer.Result().Header.Get(middleware.RefreshHeaderNameString) == "" {
+			t.Fatal("tokens were not given")
+		}
+
+		me := getMe(userJWT)
+		userUUID = me.UUID
+		if me.Name != "sso.user" || me.Email != "sso.user@example.com" {
+			t.Fatalf("user was not created as expected: %v", me)
+		}
+		if !slices.Contains(me.Permissions, database.PermissionProviderWrite) {
+			t.Fatal("user was not given the role mapped to their group")
+		}
+		team, _ := getTeam(t, engine, jwtString, teamUUID)
+		if len(team.Users) != 1 || team.Users[0].UUID != userUUID || team.Users[0].Role != database.TeamRoleLead {
+			t.Fatal("user was not made a lead of the team mapped to their group")
+		}
+	})
+
+	t.Run("Login LeftGroup", func(t *testing.T) {
+		writer := issuer.login(t, engine, jwt.MapClaims{
+			"sub":    "sso-user-1",
+			"email":  "sso.user@example.com",
+			"groups": []string{"unmapped"},
+		})
+		if code := writer.Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d: %s", code, http.StatusNoContent, writer.Body.String())
+		}
+		me := getMe(writer.Result().Header.Get(middleware.AuthHeaderNameString))
+		if me.UUID != userUUID {
+			t.Fatal("the same user was not logged in again")
+		}
+		if slices.Contains(me.Permissions, database.PermissionProviderWrite) {
+			t.Fatal("user kept the role of a group they left")
+		}
+		team, _ := getTeam(t, engine, jwtString, teamUUID)
+		if len(team.Users) != 0 {
+			t.Fatal("user was not removed from the team of a group they left")
+		}
+	})
+
+	t.Run("Login LinkExistingUser", func(t *testing.T) {
+		existingUUID := createUser(t, engine, jwtString, "sso-existing", "sso.existing@example.com", "password123")
+		claims := jwt.MapClaims{
+			"sub":   "sso-user-2",
+			"email": "sso.existing@example.com",
+		}
+		// whoever runs the identity provider can give anybody any email, verified or not
+		if code := issuer.login(t, engine, claims).Code; code != http.StatusConflict {
+			t.Fatalf("status code %d != %d", code, http.StatusConflict)
+		}
+		claims["email_verified"] = true
+		if code := issuer.login(t, engine, claims).Code; code != http.StatusConflict {
+			t.Fatalf("status code %d != %d", code, http.StatusConflict)
+		}
+
+		identityURL := fmt.Sprintf("/users/%s/identity", existingUUID)
+		body := map[string]any{"provider": "oidc", "externalID": "sso-user-2"}
+		userJWT, err := getJWT(engine, TestUserEmail, TestUserPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code := request(userJWT, http.MethodPut, identityURL, body).Code; code != http.StatusForbidden {
+			t.Fatalf("status code %d != %d", code, http.StatusForbidden)
+		}
+		if code := request(jwtString, http.MethodPut, identityURL, map[string]any{"provider": "saml", "externalID": "sso-user-2"}).Code; code != http.StatusBadRequest {
+			t.Fatalf("status code %d != %d", code, http.StatusBadRequest)
+		}
+		if code := request(jwtString, http.MethodPut, identityURL, map[string]any{"provider": "oidc", "externalID": "sso-user-1"}).Code; code != http.StatusConflict {
+			t.Fatalf("status code %d != %d", code, http.StatusConflict)
+		}
+		if code := request(jwtString, http.MethodPut, identityURL, body).Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		writer := issuer.login(t, engine, claims)
+		if code := writer.Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d: %s", code, http.StatusNoContent, writer.Body.String())
+		}
+		if me := getMe(writer.Result().Header.Get(middleware.AuthHeaderNameString)); me.UUID != existingUUID {
+			t.Fatal("user was not logged in as the existing user an admin linked them to")
+		}
+	})
+
+	t.Run("Login InvalidState", func(t *testing.T) {
+		if code := issuer.callback(engine, "unknown", "code").Code; code != http.StatusForbidden {
+			t.Fatalf("status code %d != %d", code, http.StatusForbidden)
+		}
+		if code := issuer.callback(engine, "unknown", "code", &http.Cookie{Name: "OIDC-State", Value: "unknown"}).Code; code != http.StatusForbidden {
+			t.Fatalf("status code %d != %d", code, http.StatusForbidden)
+		}
+		if code := issuer.callback(engine, "", "").Code; code != http.StatusBadRequest {
+			t.Fatalf("status code %d != %d", code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Login OtherBrowser", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/authorise/oidc", nil)
+		writer := makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusFound {
+			t.Fatalf("status code %d != %d", code, http.StatusFound)
+		}
+		location, err := url.Parse(writer.Result().Header.Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		state := location.Query().Get("state")
+		cookies := writer.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != "OIDC-State" || cookies[0].Value != state || !cookies[0].HttpOnly {
+			t.Fatalf("the state was not given in a cookie: %v", cookies)
+		}
+
+		// a victim sent back with the state of a login an attacker started is not logged in as the attacker
+		if code := issuer.callback(engine, state, "code", &http.Cookie{Name: "OIDC-State", Value: "another-login"}).Code; code != http.StatusForbidden {
+			t.Fatalf("status code %d != %d", code, http.StatusForbidden)
+		}
+		if code := issuer.callback(engine, state, "code").Code; code != http.StatusForbidden {
+			t.Fatalf("status code %d != %d", code, http.StatusForbidden)
+		}
+	})
+
+	t.Run("Login InvalidNonce", func(t *testing.T) {
+		// the claims given override those the issuer sets, so the token is for another login
+		writer := issuer.login(t, engine, jwt.MapClaims{
+			"sub":   "sso-user-1",
+			"email": "sso.user@example.com",
+			"nonce": "another-login",
+		})
+		if code := writer.Code; code != http.StatusUnauthorized {
+			t.Fatalf("status code %d != %d", code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("Login WrongAudience", func(t *testing.T) {
+		writer := issuer.login(t, engine, jwt.MapClaims{
+			"sub":   "sso-user-1",
+			"email": "sso.user@example.com",
+			"aud":   "another-client",
+		})
+		if code := writer.Code; code != http.StatusUnauthorized {
+			t.Fatalf("status code %d != %d", code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("Login PostLoginRedirect", func(t *testing.T) {
+		t.Setenv("OIDC_POST_LOGIN_REDIRECT", "http://localhost:3000/dashboard")
+		writer := issuer.login(t, engine, jwt.MapClaims{
+			"sub":   "sso-user-1",
+			"email": "sso.user@example.com",
+		})
+		if code := writer.Code; code != http.StatusFound {
+			t.Fatalf("status code %d != %d", code, http.StatusFound)
+		}
+		if writer.Result().Header.Get("Location") != "http://localhost:3000/dashboard" || writer.Result().Header.Get(middleware.AuthHeaderNameString) == "" {
+			t.Fatal("user was not logged in and redirected")
+		}
+	})
+
+	t.Run("DisableLocalPasswords", func(t *testing.T) {
+		t.Setenv("DISABLE_LOCAL_PASSWORDS", "true")
+		body, err := getJSONBodyAsReader(map[string]any{
+			"email":    TestUserEmail,
+			"password": TestUserPassword,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, "/users/login", body)
+		req.Header.Add("Content-Type", "application/json")
+		if code := makeRequest(engine, req).Code; code != http.StatusForbidden {
+			t.Fatalf("status code %d != %d", code, http.StatusForbidden)
+		}
+		// admins can still log in in case the identity provider is down
+		if _, err := getJWT(engine, TestAdminEmail, TestAdminPassword); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("DeleteGroupMapping", func(t *testing.T) {
+		writer := request(jwtString, http.MethodGet, "/group-mappings", nil)
+		mappings, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.GroupMappingGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, mapping := range mappings.Data {
+			if code := request(jwtString, http.MethodDelete, "/group-mappings/"+mapping.UUID, nil).Code; code != http.StatusNoContent {
+				t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+			}
+			if code := request(jwtString, http.MethodGet, "/group-mappings/"+mapping.UUID, nil).Code; code != http.StatusNotFound {
+				t.Fatalf("status code %d != %d", code, http.StatusNotFound)
+			}
+		}
+	})
+}
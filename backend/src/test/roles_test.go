@@ -0,0 +1,279 @@
+package test_test
+
+import (
+	"com668-backend/database"
+	"com668-backend/middleware"
+	"com668-backend/utility"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGetRoles(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userJWT, err := getJWT(engine, TestUserEmail, TestUserPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("GetRoles", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/roles", nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		resp, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.RoleGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		roles := make(map[string]utility.RoleGetResponseBodySchema)
+		for _, role := range resp.Data {
+			roles[role.UUID] = role
+		}
+		if len(roles[database.AdminRoleUUID].Permissions) != len(database.Permissions) {
+			t.Fatal("admin role does not have every permission")
+		}
+		if member := roles[database.MemberRoleUUID]; !member.Default || slices.Contains(member.Permissions, database.PermissionIncidentCreate) {
+			t.Fatal("member role does not match what any user could do")
+		}
+	})
+
+	t.Run("GetRoles Forbidden", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/roles", nil)
+		req.Header.Add(middleware.AuthHeaderNameString, userJWT)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusForbidden
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("GetMe Permissions", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Add(middleware.AuthHeaderNameString, userJWT)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		resp, err := utility.ReadJSONStruct[utility.UserGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Contains(resp.Permissions, database.PermissionIncidentWrite) || slices.Contains(resp.Permissions, database.PermissionHostWrite) {
+			t.Fatal("user does not hold the member role's permissions")
+		}
+	})
+}
+
+func TestRoles(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userJWT, err := getJWT(engine, TestUserEmail, TestUserPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var roleUUID string
+	getProviders := func(t *testing.T, expected int) {
+		req, _ := http.NewRequest(http.MethodGet, "/providers?provider_type=log", nil)
+		req.Header.Add(middleware.AuthHeaderNameString, userJWT)
+		writer := makeRequest(engine, req)
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	}
+	sendRequest := func(t *testing.T, method string, path string, expected int) {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	}
+
+	t.Run("CreateRole", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"name":        "provider-viewer",
+			"description": "See the providers",
+			"permissions": []string{database.PermissionProviderRead},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, "/roles", body)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusCreated
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		location := strings.Split(writer.Result().Header.Get("Location"), "/")
+		roleUUID = location[len(location)-1]
+	})
+
+	t.Run("CreateRole InvalidPermission", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"name":        "invalid",
+			"permissions": []string{"provider:delete"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, "/roles", body)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("AssignUserRole", func(t *testing.T) {
+		getProviders(t, http.StatusForbidden)
+		sendRequest(t, http.MethodPut, fmt.Sprintf("/users/%s/roles/%s", TestUserUUID, roleUUID), http.StatusNoContent)
+		getProviders(t, http.StatusOK)
+		sendRequest(t, http.MethodDelete, fmt.Sprintf("/users/%s/roles/%s", TestUserUUID, roleUUID), http.StatusNoContent)
+		getProviders(t, http.StatusForbidden)
+		sendRequest(t, http.MethodDelete, fmt.Sprintf("/users/%s/roles/%s", TestUserUUID, roleUUID), http.StatusNotFound)
+	})
+
+	t.Run("AssignTeamRole", func(t *testing.T) {
+		// the user is a member of App 1
+		sendRequest(t, http.MethodPut, fmt.Sprintf("/teams/574b5d6a-1fcd-43bf-bb31-7e870ca458d4/roles/%s", roleUUID), http.StatusNoContent)
+		getProviders(t, http.StatusOK)
+		sendRequest(t, http.MethodDelete, fmt.Sprintf("/teams/574b5d6a-1fcd-43bf-bb31-7e870ca458d4/roles/%s", roleUUID), http.StatusNoContent)
+		getProviders(t, http.StatusForbidden)
+	})
+
+	t.Run("AssignUserRole AdminEscalation", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"name":        "user-manager",
+			"permissions": []string{database.PermissionUserWrite, database.PermissionRoleWrite},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, "/roles", body)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		location := strings.Split(writer.Result().Header.Get("Location"), "/")
+		managerUUID := location[len(location)-1]
+		sendRequest(t, http.MethodPut, fmt.Sprintf("/users/%s/roles/%s", TestUserUUID, managerUUID), http.StatusNoContent)
+		defer sendRequest(t, http.MethodDelete, fmt.Sprintf("/roles/%s", managerUUID), http.StatusNoContent)
+
+		// holding user:write and role:write is not enough to make anyone an admin
+		request := func(method string, path string, body string, contentType string) int {
+			req, _ := http.NewRequest(method, path, strings.NewReader(body))
+			req.Header.Add(middleware.AuthHeaderNameString, userJWT)
+			req.Header.Add("Content-Type", contentType)
+			return makeRequest(engine, req).Code
+		}
+		tests := []struct {
+			code     int
+			expected int
+		}{
+			{request(http.MethodPut, fmt.Sprintf("/users/%s/roles/%s", TestUserUUID, database.AdminRoleUUID), "", ""), http.StatusForbidden},
+			{request(http.MethodPut, fmt.Sprintf("/teams/574b5d6a-1fcd-43bf-bb31-7e870ca458d4/roles/%s", database.AdminRoleUUID), "", ""), http.StatusForbidden},
+			{request(http.MethodDelete, fmt.Sprintf("/users/%s/roles/%s", TestAdminUUID, database.AdminRoleUUID), "", ""), http.StatusForbidden},
+			{request(http.MethodPatch, fmt.Sprintf("/users/%s", TestUserUUID), `{"admin": true}`, utility.MergePatchContentType), http.StatusForbidden},
+			{request(http.MethodPost, "/group-mappings", `{"group": "escalators", "admin": true}`, "application/json"), http.StatusForbidden},
+			// changing anything but the admin flag is still allowed
+			{request(http.MethodPatch, fmt.Sprintf("/users/%s", TestUserUUID), `{"name": "Test User"}`, utility.MergePatchContentType), http.StatusNoContent},
+		}
+		for i, test := range tests {
+			if test.code != test.expected {
+				t.Fatalf("status code %d != %d for request %d", test.code, test.expected, i)
+			}
+		}
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%s", TestUserUUID), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		user, err := utility.ReadJSONStruct[utility.UserGetResponseBodySchema](makeRequest(engine, req).Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *user.Admin {
+			t.Fatal("the user was made an admin")
+		}
+	})
+
+	t.Run("UpdateRole", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"name":        "provider-viewer",
+			"default":     true,
+			"permissions": []string{database.PermissionProviderRead},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/roles/%s", roleUUID), body)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusNoContent
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		// a default role is held by every user
+		getProviders(t, http.StatusOK)
+	})
+
+	t.Run("UpdateRole Admin", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"name":        "admin",
+			"permissions": []string{},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/roles/%s", database.AdminRoleUUID), body)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("DeleteRole", func(t *testing.T) {
+		sendRequest(t, http.MethodDelete, fmt.Sprintf("/roles/%s", roleUUID), http.StatusNoContent)
+		getProviders(t, http.StatusForbidden)
+		sendRequest(t, http.MethodGet, fmt.Sprintf("/roles/%s", roleUUID), http.StatusNotFound)
+		sendRequest(t, http.MethodDelete, fmt.Sprintf("/roles/%s", database.AdminRoleUUID), http.StatusBadRequest)
+	})
+}
@@ -391,3 +391,364 @@ func TestDeleteProvider(t *testing.T) {
 		}
 	})
 }
+
+func TestPriorityMatrix(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("GetPriorityMatrix", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/priority-matrix", nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			errorResp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(errorResp.Error)
+			t.Fatalf("Status code %d != %d", code, expected)
+		}
+		resp, err := utility.ReadJSONStruct[utility.PriorityMatrixSchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Entries) != 9 {
+			t.Fatalf("entries length %d != %d", len(resp.Entries), 9)
+		}
+	})
+
+	t.Run("UpdatePriorityMatrix", func(t *testing.T) {
+		// make every combination the lowest priority
+		entries := make([]map[string]any, 0)
+		for impact := 1; impact <= 3; impact++ {
+			for urgency := 1; urgency <= 3; urgency++ {
+				entries = append(entries, map[string]any{"impact": impact, "urgency": urgency, "priority": 5})
+			}
+		}
+		body, err := getJSONBodyAsReader(map[string]any{"entries": entries})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPut, "/priority-matrix", body)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusNoContent
+		if code := writer.Code; code != expected {
+			errorResp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(errorResp.Error)
+			t.Fatalf("Status code %d != %d", code, expected)
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, "/incidents?priority=1,2,3,4", nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusOK
+		if code := writer.Code; code != expected {
+			errorResp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(errorResp.Error)
+			t.Fatalf("Status code %d != %d", code, expected)
+		}
+		resp, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Data) != 0 {
+			t.Fatal("incident priorities were not recalculated")
+		}
+	})
+
+	t.Run("UpdatePriorityMatrix InvalidBody", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"entries": []map[string]any{{"impact": 1, "urgency": 1, "priority": 1}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPut, "/priority-matrix", body)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			t.Fatalf("Status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("UpdatePriorityMatrix Forbidden", func(t *testing.T) {
+		jwtString, err := getJWT(engine, TestUserEmail, TestUserPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, err := getJSONBodyAsReader(map[string]any{"entries": []map[string]any{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPut, "/priority-matrix", body)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusForbidden
+		if code := writer.Code; code != expected {
+			errorResp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(errorResp.Error)
+			t.Fatalf("Status code %d != %d", code, expected)
+		}
+	})
+}
+
+func TestFingerprintRules(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reportHashedOccurrence := func(t *testing.T, summary string, hash string, stackTrace string) (int, string) {
+		fields := map[string]any{
+			"summary":         summary,
+			"description":     "Test Fingerprint Details",
+			"resolutionTeams": []string{},
+			"hostsAffected":   []string{},
+			"stackTrace":      stackTrace,
+		}
+		if hash != "" {
+			fields["hash"] = hash
+		}
+		body, err := getJSONBodyAsReader(fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPut, "/incidents/occurrences", body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusCreated && code != http.StatusNoContent {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d is not a success", code)
+		}
+		return writer.Code, writer.Result().Header.Get("Location")
+	}
+	reportOccurrence := func(t *testing.T, summary string, stackTrace string) (int, string) {
+		return reportHashedOccurrence(t, summary, "", stackTrace)
+	}
+
+	t.Run("Fingerprint Normalisation", func(t *testing.T) {
+		code, location := reportOccurrence(t, "Test Fingerprint", strings.Join([]string{
+			"Error: order 4f0c7a4e-8f0e-4a57-a0b8-0c3f6e1d2b9a not found",
+			"    at loadOrder (/app/src/orders.js:10:5)",
+			"    at Layer.handle (/app/node_modules/express/lib/router/layer.js:95:5)",
+		}, "\n"))
+		if code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+
+		// a redeploy moved the line and the order id differs, but it is the same bug
+		code, sameLocation := reportOccurrence(t, "Test Fingerprint", strings.Join([]string{
+			"Error: order 0a1b2c3d-8f0e-4a57-a0b8-0c3f6e1d2b9a not found",
+			"    at loadOrder (/app/src/orders.js:14:9)",
+			"    at Layer.handle (/app/node_modules/express/lib/router/layer.js:97:5)",
+		}, "\n"))
+		if code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		if sameLocation != location {
+			t.Fatalf("location %s != %s", sameLocation, location)
+		}
+
+		parts := strings.Split(location, "/")
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", parts[len(parts)-1]), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		incident, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if incident.FingerprintVersion != utility.FingerprintVersion {
+			t.Fatalf("fingerprint version %d != %d", incident.FingerprintVersion, utility.FingerprintVersion)
+		}
+		if len(incident.Hash) != 64 {
+			t.Fatalf("hash length %d != %d", len(incident.Hash), 64)
+		}
+	})
+
+	t.Run("Fingerprint GroupRule", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"name":     "Database timeouts",
+			"type":     "group",
+			"pattern":  "ConnectionTimeoutError",
+			"groupKey": "database-timeouts",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, "/fingerprint-rules", body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusCreated
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		ruleLocation := strings.Split(writer.Result().Header.Get("Location"), "/")
+
+		// different call sites which both time out are grouped into one incident
+		_, location := reportOccurrence(t, "Test Fingerprint Timeout", "ConnectionTimeoutError: timed out\n    at query (/app/src/db.js:10:5)")
+		code, sameLocation := reportOccurrence(t, "Test Fingerprint Timeout", "ConnectionTimeoutError: timed out\n    at report (/app/src/reports.js:42:1)")
+		if code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		if sameLocation != location {
+			t.Fatalf("location %s != %s", sameLocation, location)
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, "/fingerprint-rules", nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		res, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.FingerprintRuleGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Data) != 1 || res.Data[0].GroupKey == nil || *res.Data[0].GroupKey != "database-timeouts" {
+			t.Fatal("fingerprint rules mismatch")
+		}
+
+		req, _ = http.NewRequest(http.MethodDelete, fmt.Sprintf("/fingerprint-rules/%s", ruleLocation[len(ruleLocation)-1]), nil)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected = http.StatusNoContent
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+
+		// the rule no longer applies once it is deleted
+		code, otherLocation := reportOccurrence(t, "Test Fingerprint Timeout", "ConnectionTimeoutError: timed out\n    at query (/app/src/db.js:10:5)")
+		if code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		if otherLocation == location {
+			t.Fatal("deleted rule was still applied")
+		}
+	})
+
+	t.Run("Fingerprint ClientHash", func(t *testing.T) {
+		// an incident reported before the backend fingerprinted stack traces keeps counting under the reporter's hash
+		code, location := reportHashedOccurrence(t, "Test Fingerprint Client Hash", "test-fingerprint-client-hash", "")
+		if code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		code, sameLocation := reportHashedOccurrence(t, "Test Fingerprint Client Hash", "test-fingerprint-client-hash", "Error: failed\n    at sync (/app/src/sync.js:3:7)")
+		if code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		if sameLocation != location {
+			t.Fatalf("location %s != %s", sameLocation, location)
+		}
+
+		// a new incident is fingerprinted by the backend, and the reporter's hash still finds it
+		code, location = reportHashedOccurrence(t, "Test Fingerprint New Client Hash", "test-fingerprint-new-client-hash", "Error: failed\n    at send (/app/src/mail.js:8:2)")
+		if code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		code, sameLocation = reportHashedOccurrence(t, "Test Fingerprint New Client Hash", "test-fingerprint-new-client-hash", "")
+		if code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		if sameLocation != location {
+			t.Fatalf("location %s != %s", sameLocation, location)
+		}
+	})
+
+	t.Run("Fingerprint InvalidRule", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"name":     "Invalid",
+			"type":     "group",
+			"pattern":  "(",
+			"groupKey": "invalid",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, "/fingerprint-rules", body)
+		req.Header.Set(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("Fingerprint Forbidden", func(t *testing.T) {
+		userJWT, err := getJWT(engine, TestUserEmail, TestUserPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := getJSONBodyAsReader(map[string]any{
+			"name":    "Forbidden",
+			"type":    "strip",
+			"pattern": "request-id=\\w+",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, "/fingerprint-rules", body)
+		req.Header.Set(middleware.AuthHeaderNameString, userJWT)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusForbidden
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+}
@@ -1,12 +1,17 @@
 package test_test
 
 import (
+	"com668-backend/database"
 	"com668-backend/middleware"
 	"com668-backend/utility"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
 )
 
 func TestGetTeams(t *testing.T) {
@@ -99,3 +104,611 @@ func TestGetTeams(t *testing.T) {
 		}
 	})
 }
+
+func TestGetTeamsSorted(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("GetTeams SortAndCursor", func(t *testing.T) {
+		names := make([]string, 0)
+		prevs := make([]*string, 0)
+		path := "/teams?sort=-name&pageSize=1"
+		for {
+			req, _ := http.NewRequest(http.MethodGet, path, nil)
+			req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+			writer := makeRequest(engine, req)
+
+			expected := http.StatusOK
+			if code := writer.Code; code != expected {
+				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Log(resp.Error)
+				t.Fatalf("status code %d != %d", code, expected)
+			}
+			resp, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.TeamGetResponseBodySchema]](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Data) != 1 {
+				t.Fatalf("data length %d != %d", len(resp.Data), 1)
+			}
+			names = append(names, resp.Data[0].Name)
+			prevs = append(prevs, resp.Meta.Prev)
+			if resp.Meta.Next == nil {
+				if int64(len(names)) != resp.Meta.TotalItems {
+					t.Fatalf("paged through %d teams, expected %d", len(names), resp.Meta.TotalItems)
+				}
+				break
+			}
+			path = fmt.Sprintf("/teams?sort=-name&pageSize=1&cursor=%s", *resp.Meta.Next)
+		}
+		for i := 1; i < len(names); i++ {
+			if names[i-1] < names[i] {
+				t.Fatalf("teams are not sorted by descending name: %v", names)
+			}
+		}
+		if prevs[0] != nil {
+			t.Fatal("first page has a previous cursor")
+		}
+
+		// the previous page of the last page is the second to last page
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/teams?sort=-name&pageSize=1&cursor=%s", *prevs[len(prevs)-1]), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		resp, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.TeamGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Data) != 1 || resp.Data[0].Name != names[len(names)-2] {
+			t.Fatal("previous page mismatch")
+		}
+	})
+
+	t.Run("GetTeams InvalidSortAndCursor", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/teams?sort=name&pageSize=1", nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+		resp, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.TeamGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Meta.Next == nil {
+			t.Fatal("no next cursor")
+		}
+
+		paths := []string{
+			"/teams?sort=uuid",
+			"/teams?sort=name,-name",
+			"/teams?cursor=invalid",
+			// a cursor only applies to the sort it was made for
+			fmt.Sprintf("/teams?sort=-name&cursor=%s", *resp.Meta.Next),
+		}
+		for _, path := range paths {
+			req, _ := http.NewRequest(http.MethodGet, path, nil)
+			req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+			writer := makeRequest(engine, req)
+
+			expected := http.StatusBadRequest
+			if code := writer.Code; code != expected {
+				t.Fatalf("status code %d != %d for %s", code, expected, path)
+			}
+		}
+	})
+}
+
+const (
+	TestAdminUUID string = "39ab8bf8-fd8c-43c2-b691-3acb4f5a3fab"
+	TestUserUUID  string = "417cd42a-ddff-42dc-b358-801807522dbd"
+)
+
+// Create a team as an admin and return its UUID
+func createTeam(t *testing.T, engine *gin.Engine, jwtString string, name string) string {
+	body, err := getJSONBodyAsReader(map[string]any{"name": name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, "/teams", body)
+	req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+	writer := makeRequest(engine, req)
+	if code := writer.Code; code != http.StatusCreated {
+		t.Fatalf("status code %d != %d", code, http.StatusCreated)
+	}
+	location := strings.Split(writer.Result().Header.Get("Location"), "/")
+	return location[len(location)-1]
+}
+
+func getTeam(t *testing.T, engine *gin.Engine, jwtString string, teamUUID string) (*utility.TeamGetResponseBodySchema, string) {
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/teams/%s", teamUUID), nil)
+	req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+	writer := makeRequest(engine, req)
+	if code := writer.Code; code != http.StatusOK {
+		t.Fatalf("status code %d != %d", code, http.StatusOK)
+	}
+	team, err := utility.ReadJSONStruct[utility.TeamGetResponseBodySchema](writer.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return team, writer.Header().Get("ETag")
+}
+
+func TestCreateTeam(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("CreateTeam", func(t *testing.T) {
+		teamUUID := createTeam(t, engine, jwtString, "Test Create Team")
+		team, _ := getTeam(t, engine, jwtString, teamUUID)
+		if team.Name != "Test Create Team" || len(team.Users) != 0 {
+			t.Fatal("team was not created as expected")
+		}
+	})
+
+	t.Run("CreateTeam InvalidBody", func(t *testing.T) {
+		for _, name := range []string{"", strings.Repeat("a", 31), "Test Create Team"} {
+			body, err := getJSONBodyAsReader(map[string]any{"name": name})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ := http.NewRequest(http.MethodPost, "/teams", body)
+			req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+			writer := makeRequest(engine, req)
+
+			expected := http.StatusBadRequest
+			if code := writer.Code; code != expected {
+				t.Fatalf("status code %d != %d for '%s'", code, expected, name)
+			}
+		}
+	})
+
+	t.Run("CreateTeam Forbidden", func(t *testing.T) {
+		userJWT, err := getJWT(engine, TestUserEmail, TestUserPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := getJSONBodyAsReader(map[string]any{"name": "Test Forbidden Team"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, "/teams", body)
+		req.Header.Add(middleware.AuthHeaderNameString, userJWT)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusForbidden
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+}
+
+func TestUpdateTeam(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	teamUUID := createTeam(t, engine, jwtString, "Test Update Team")
+	renameTeam := func(name string, ifMatch string) int {
+		body, err := getJSONBodyAsReader(map[string]any{"name": name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/teams/%s", teamUUID), body)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		req.Header.Add("If-Match", ifMatch)
+		return makeRequest(engine, req).Code
+	}
+
+	t.Run("UpdateTeam", func(t *testing.T) {
+		_, etag := getTeam(t, engine, jwtString, teamUUID)
+		expected := http.StatusNoContent
+		if code := renameTeam("Test Renamed Team", etag); code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		if team, _ := getTeam(t, engine, jwtString, teamUUID); team.Name != "Test Renamed Team" {
+			t.Fatal("team was not renamed")
+		}
+
+		// the team was renamed since the ETag was read
+		expected = http.StatusPreconditionFailed
+		if code := renameTeam("Test Stale Team", etag); code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("UpdateTeam HostETag", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"os":       "Linux",
+			"hostname": "test-renamed-team-host",
+			"ip4":      "10.0.0.18",
+			"teamID":   teamUUID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, "/hosts", body)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		hostLocation := writer.Result().Header.Get("Location")
+		hostPath := fmt.Sprintf("/hosts/%s", hostLocation[strings.LastIndex(hostLocation, "/")+1:])
+		getHostETag := func() string {
+			req, _ := http.NewRequest(http.MethodGet, hostPath, nil)
+			req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+			return makeRequest(engine, req).Header().Get("ETag")
+		}
+		etag := getHostETag()
+
+		_, teamETag := getTeam(t, engine, jwtString, teamUUID)
+		if code := renameTeam("Test Host Owner Team", teamETag); code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		// the host embeds the name of its team, so a copy with the old name is stale
+		if getHostETag() == etag {
+			t.Fatal("host ETag did not change when its team was renamed")
+		}
+		req, _ = http.NewRequest(http.MethodPatch, hostPath, strings.NewReader(`{"os": "Windows"}`))
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		req.Header.Add("Content-Type", utility.MergePatchContentType)
+		req.Header.Add("If-Match", etag)
+		expected := http.StatusPreconditionFailed
+		if code := makeRequest(engine, req).Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("UpdateTeam InvalidBody", func(t *testing.T) {
+		expected := http.StatusBadRequest
+		if code := renameTeam("", ""); code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+}
+
+func TestTeamMembers(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userJWT, err := getJWT(engine, TestUserEmail, TestUserPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	teamUUID := createTeam(t, engine, jwtString, "Test Members Team")
+	setMember := func(jwtString string, teamUUID string, userUUID string, role string) int {
+		body, err := getJSONBodyAsReader(map[string]any{"role": role})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/teams/%s/members/%s", teamUUID, userUUID), body)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		return makeRequest(engine, req).Code
+	}
+	removeMember := func(jwtString string, teamUUID string, userUUID string) int {
+		req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/teams/%s/members/%s", teamUUID, userUUID), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		return makeRequest(engine, req).Code
+	}
+
+	t.Run("SetTeamMember", func(t *testing.T) {
+		expected := http.StatusNoContent
+		if code := setMember(jwtString, teamUUID, TestUserUUID, "lead"); code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		// a lead can manage the team's members
+		if code := setMember(userJWT, teamUUID, TestAdminUUID, ""); code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+
+		team, _ := getTeam(t, engine, jwtString, teamUUID)
+		roles := make(map[string]string)
+		for _, user := range team.Users {
+			roles[user.UUID] = user.Role
+		}
+		if roles[TestUserUUID] != "lead" || roles[TestAdminUUID] != "member" {
+			t.Fatalf("members were not added with their roles: %v", roles)
+		}
+	})
+
+	t.Run("RemoveTeamMember", func(t *testing.T) {
+		expected := http.StatusNoContent
+		if code := removeMember(userJWT, teamUUID, TestAdminUUID); code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		expected = http.StatusNotFound
+		if code := removeMember(userJWT, teamUUID, TestAdminUUID); code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		if team, _ := getTeam(t, engine, jwtString, teamUUID); len(team.Users) != 1 {
+			t.Fatal("member was not removed")
+		}
+	})
+
+	t.Run("RemoveTeamMember Unassigns", func(t *testing.T) {
+		assigningUUID := createTeam(t, engine, jwtString, "Test Leaving Member Team")
+		reassignUUID := createTeam(t, engine, jwtString, "Test Left Member Team")
+		if code := setMember(jwtString, assigningUUID, TestUserUUID, "member"); code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		request := func(method string, url string, body map[string]any) *httptest.ResponseRecorder {
+			reader, err := getJSONBodyAsReader(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ := http.NewRequest(method, url, reader)
+			req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+			return makeRequest(engine, req)
+		}
+		createAssigned := func(summary string) (string, string) {
+			writer := request(http.MethodPost, "/incidents", map[string]any{
+				"summary":         summary,
+				"description":     summary,
+				"resolutionTeams": []string{assigningUUID},
+				"hash":            strings.ReplaceAll(strings.ToLower(summary), " ", "-"),
+			})
+			if code := writer.Code; code != http.StatusCreated {
+				t.Fatalf("status code %d != %d", code, http.StatusCreated)
+			}
+			location := writer.Result().Header.Get("Location")
+			path := fmt.Sprintf("/incidents/%s", location[strings.LastIndex(location, "/")+1:])
+			if code := request(http.MethodPost, path+"/assign", map[string]any{"user": TestUserUUID}).Code; code != http.StatusNoContent {
+				t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+			}
+			return path, request(http.MethodGet, path, nil).Header().Get("ETag")
+		}
+		checkUnassigned := func(path string, etag string) {
+			writer := request(http.MethodGet, path, nil)
+			incident, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if incident.Assignee != nil {
+				t.Fatalf("%s is still assigned to a user outside its resolution teams", path)
+			}
+			last := incident.AssignmentHistory[len(incident.AssignmentHistory)-1]
+			if last.Assigned || last.User.UUID != TestUserUUID {
+				t.Fatalf("unassignment of %s was not recorded", path)
+			}
+			if writer.Header().Get("ETag") == etag {
+				t.Fatalf("ETag of %s did not change", path)
+			}
+		}
+
+		removedPath, removedETag := createAssigned("Test Removed Member Incident")
+		if code := removeMember(jwtString, assigningUUID, TestUserUUID); code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		checkUnassigned(removedPath, removedETag)
+
+		if code := setMember(jwtString, assigningUUID, TestUserUUID, "member"); code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		deletedPath, deletedETag := createAssigned("Test Deleted Team Incident")
+		writer := request(http.MethodDelete, fmt.Sprintf("/teams/%s?reassignTo=%s", assigningUUID, reassignUUID), nil)
+		if code := writer.Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		checkUnassigned(deletedPath, deletedETag)
+	})
+
+	t.Run("TeamMembers RoleEscalation", func(t *testing.T) {
+		request := func(jwtString string, method string, url string, body map[string]any) *httptest.ResponseRecorder {
+			reader, err := getJSONBodyAsReader(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ := http.NewRequest(method, url, reader)
+			req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+			return makeRequest(engine, req)
+		}
+		createRole := func(name string, permissions ...string) string {
+			writer := request(jwtString, http.MethodPost, "/roles", map[string]any{"name": name, "permissions": permissions})
+			if code := writer.Code; code != http.StatusCreated {
+				t.Fatalf("status code %d != %d", code, http.StatusCreated)
+			}
+			location := strings.Split(writer.Result().Header.Get("Location"), "/")
+			return location[len(location)-1]
+		}
+		assign := func(method string, path string) {
+			if code := request(jwtString, method, path, nil).Code; code != http.StatusNoContent {
+				t.Fatalf("status code %d != %d for %s %s", code, http.StatusNoContent, method, path)
+			}
+		}
+
+		// holding team:write is not enough to add anyone to a team holding the admin role
+		managerUUID := createRole("team-manager", database.PermissionTeamWrite)
+		assign(http.MethodPut, fmt.Sprintf("/users/%s/roles/%s", TestUserUUID, managerUUID))
+		defer assign(http.MethodDelete, fmt.Sprintf("/roles/%s", managerUUID))
+		adminTeamUUID := createTeam(t, engine, jwtString, "Test Admin Members Team")
+		assign(http.MethodPut, fmt.Sprintf("/teams/%s/roles/%s", adminTeamUUID, database.AdminRoleUUID))
+		if code := setMember(userJWT, adminTeamUUID, TestAdminUUID, "member"); code != http.StatusForbidden {
+			t.Fatalf("status code %d != %d", code, http.StatusForbidden)
+		}
+		if code := setMember(jwtString, adminTeamUUID, TestAdminUUID, "member"); code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		assign(http.MethodDelete, fmt.Sprintf("/users/%s/roles/%s", TestUserUUID, managerUUID))
+
+		// and a lead acting through a key without the permissions of the team's roles cannot change its members
+		leadTeamUUID := createTeam(t, engine, jwtString, "Test Lead Roles Team")
+		readerUUID := createRole("provider-reader", database.PermissionProviderRead)
+		defer assign(http.MethodDelete, fmt.Sprintf("/roles/%s", readerUUID))
+		assign(http.MethodPut, fmt.Sprintf("/teams/%s/roles/%s", leadTeamUUID, readerUUID))
+		if code := setMember(jwtString, leadTeamUUID, TestUserUUID, "lead"); code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		writer := request(userJWT, http.MethodPost, "/me/api-keys", map[string]any{
+			"name":      "Test Lead Key",
+			"scopes":    []string{database.PermissionIncidentRead},
+			"expiresAt": time.Now().Add(time.Hour),
+		})
+		if code := writer.Code; code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		key, err := utility.ReadJSONStruct[utility.APIKeyPostResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		withKey := func(method string, userUUID string, body map[string]any) int {
+			reader, err := getJSONBodyAsReader(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ := http.NewRequest(method, fmt.Sprintf("/teams/%s/members/%s", leadTeamUUID, userUUID), reader)
+			req.Header.Add(middleware.APIKeyHeaderNameString, key.Key)
+			return makeRequest(engine, req).Code
+		}
+		if code := withKey(http.MethodPut, TestAdminUUID, map[string]any{"role": "member"}); code != http.StatusForbidden {
+			t.Fatalf("status code %d != %d", code, http.StatusForbidden)
+		}
+		if code := withKey(http.MethodDelete, TestUserUUID, nil); code != http.StatusForbidden {
+			t.Fatalf("status code %d != %d", code, http.StatusForbidden)
+		}
+		// the user can still change the members themselves, as they hold the permissions through the team
+		if code := setMember(userJWT, leadTeamUUID, TestAdminUUID, "member"); code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		if team, _ := getTeam(t, engine, jwtString, leadTeamUUID); len(team.Users) != 2 {
+			t.Fatal("the lead could not add a member")
+		}
+	})
+
+	t.Run("TeamMembers Invalid", func(t *testing.T) {
+		missing, err := utility.GenerateRandomUUID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			code     int
+			expected int
+		}{
+			{setMember(jwtString, teamUUID, TestAdminUUID, "owner"), http.StatusBadRequest},
+			{setMember(jwtString, teamUUID, "invalid", "member"), http.StatusBadRequest},
+			{setMember(jwtString, teamUUID, missing, "member"), http.StatusNotFound},
+			{setMember(jwtString, missing, TestAdminUUID, "member"), http.StatusNotFound},
+			// the user is only a member of App 1, not a lead of it
+			{setMember(userJWT, "574b5d6a-1fcd-43bf-bb31-7e870ca458d4", TestAdminUUID, "member"), http.StatusForbidden},
+			{removeMember(userJWT, "574b5d6a-1fcd-43bf-bb31-7e870ca458d4", TestUserUUID), http.StatusForbidden},
+		}
+		for i, test := range tests {
+			if test.code != test.expected {
+				t.Fatalf("status code %d != %d for request %d", test.code, test.expected, i)
+			}
+		}
+	})
+}
+
+func TestDeleteTeam(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleteTeam := func(teamUUID string, query string) int {
+		req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/teams/%s%s", teamUUID, query), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		return makeRequest(engine, req).Code
+	}
+
+	t.Run("DeleteTeam", func(t *testing.T) {
+		teamUUID := createTeam(t, engine, jwtString, "Test Empty Team")
+		expected := http.StatusNoContent
+		if code := deleteTeam(teamUUID, ""); code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		expected = http.StatusNotFound
+		if code := deleteTeam(teamUUID, ""); code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("DeleteTeam Reassign", func(t *testing.T) {
+		teamUUID := createTeam(t, engine, jwtString, "Test Reassigned Team")
+		reassignUUID := createTeam(t, engine, jwtString, "Test Reassignee Team")
+
+		body, err := getJSONBodyAsReader(map[string]any{
+			"os":       "Linux",
+			"hostname": "test-reassigned-host",
+			"ip4":      "10.0.0.17",
+			"teamID":   teamUUID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, "/hosts", body)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		hostLocation := writer.Result().Header.Get("Location")
+		hostUUID := hostLocation[strings.LastIndex(hostLocation, "/")+1:]
+
+		body, err = getJSONBodyAsReader(map[string]any{
+			"summary":         "Test Reassigned Incident",
+			"description":     "Test Reassigned Details",
+			"resolutionTeams": []string{teamUUID},
+			"hostsAffected":   []string{hostUUID},
+			"hash":            "test-reassigned-incident",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodPut, "/incidents/occurrences", body)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusCreated {
+			t.Fatalf("status code %d != %d", code, http.StatusCreated)
+		}
+		incidentLocation := writer.Result().Header.Get("Location")
+		incidentUUID := incidentLocation[strings.LastIndex(incidentLocation, "/")+1:]
+
+		// the team still owns a host and an incident
+		expected := http.StatusConflict
+		if code := deleteTeam(teamUUID, ""); code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		expected = http.StatusBadRequest
+		if code := deleteTeam(teamUUID, fmt.Sprintf("?reassignTo=%s", teamUUID)); code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		expected = http.StatusNoContent
+		if code := deleteTeam(teamUUID, fmt.Sprintf("?reassignTo=%s", reassignUUID)); code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/hosts/%s", hostUUID), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		host, err := utility.ReadJSONStruct[utility.HostMachineGetResponseBodySchema](makeRequest(engine, req).Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if host.Team.UUID != reassignUUID {
+			t.Fatal("host was not reassigned")
+		}
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", incidentUUID), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		incident, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](makeRequest(engine, req).Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(incident.ResolutionTeams) != 1 || incident.ResolutionTeams[0].UUID != reassignUUID {
+			t.Fatal("incident was not reassigned")
+		}
+	})
+}
@@ -1,15 +1,22 @@
 package test_test
 
 import (
+	"com668-backend/database"
 	"com668-backend/middleware"
 	"com668-backend/utility"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
 	"encoding/base64"
 	"fmt"
+	"math/big"
 	"net/http"
-	"os"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -40,14 +47,13 @@ func TestGetMe(t *testing.T) {
 			t.Fatal(err)
 		}
 
+		// the token can be verified offline with the published signing keys
 		token, err := jwt.Parse(
 			strings.Split(jwtString, " ")[1],
 			func(t *jwt.Token) (any, error) {
-				return []byte(os.Getenv("JWT_SIGNING_KEY")), nil
+				return getJWKSKey(t, engine)
 			},
-			jwt.WithValidMethods([]string{
-				middleware.JWTSigningMethod.Name,
-			}),
+			jwt.WithValidMethods(database.SigningAlgorithms),
 		)
 		if err != nil {
 			t.Fatal(err)
@@ -213,3 +219,860 @@ func TestUserLogin(t *testing.T) {
 		}
 	})
 }
+
+func TestPatchUser(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userJWT, err := getJWT(engine, TestUserEmail, TestUserPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Add(middleware.AuthHeaderNameString, userJWT)
+	writer := makeRequest(engine, req)
+	user, err := utility.ReadJSONStruct[utility.UserGetResponseBodySchema](writer.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("PatchUser", func(t *testing.T) {
+		for _, name := range []string{"Patched User", user.Name} {
+			req, _ := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/%s", user.UUID), strings.NewReader(fmt.Sprintf(`{"name": "%s"}`, name)))
+			req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+			req.Header.Add("Content-Type", utility.MergePatchContentType)
+			writer := makeRequest(engine, req)
+
+			expected := http.StatusNoContent
+			if code := writer.Code; code != expected {
+				resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Log(resp.Error)
+				t.Fatalf("status code %d != %d", code, expected)
+			}
+
+			// the password must still work after the user is saved
+			userJWT, err := getJWT(engine, TestUserEmail, TestUserPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ = http.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Add(middleware.AuthHeaderNameString, userJWT)
+			writer = makeRequest(engine, req)
+			resp, err := utility.ReadJSONStruct[utility.UserGetResponseBodySchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Name != name || len(resp.Teams) != len(user.Teams) {
+				t.Fatalf("user was not patched as expected: %s", name)
+			}
+		}
+	})
+
+	t.Run("PatchUser InvalidBody", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/%s", user.UUID), strings.NewReader(`[{"op": "replace", "path": "/email", "value": "not-an-email"}]`))
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		req.Header.Add("Content-Type", utility.JSONPatchContentType)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("PatchUser LastAdmin", func(t *testing.T) {
+		// leave the test admin as the only admin, then put the others back afterwards
+		req, _ := http.NewRequest(http.MethodGet, "/users?pageSize=100", nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+		users, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.UserGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		setAdmin := func(uuid string, admin bool) int {
+			req, _ := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/%s", uuid), strings.NewReader(fmt.Sprintf(`{"admin": %t}`, admin)))
+			req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+			req.Header.Add("Content-Type", utility.MergePatchContentType)
+			return makeRequest(engine, req).Code
+		}
+		for _, other := range users.Data {
+			if other.UUID != TestAdminUUID && other.Admin != nil && *other.Admin && other.DeactivatedAt == nil {
+				if code := setAdmin(other.UUID, false); code != http.StatusNoContent {
+					t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+				}
+				defer setAdmin(other.UUID, true)
+			}
+		}
+
+		expected := http.StatusConflict
+		if code := setAdmin(TestAdminUUID, false); code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("PatchUser Forbidden", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/%s", user.UUID), strings.NewReader(`{"admin": true}`))
+		req.Header.Add(middleware.AuthHeaderNameString, userJWT)
+		req.Header.Add("Content-Type", utility.MergePatchContentType)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusForbidden
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+}
+
+func createUser(t *testing.T, engine *gin.Engine, jwtString string, name string, email string, password string) string {
+	body, err := getJSONBodyAsReader(map[string]any{
+		"name":     name,
+		"email":    email,
+		"password": password,
+		"teams":    []string{"574b5d6a-1fcd-43bf-bb31-7e870ca458d4"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, "/users", body)
+	req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+	writer := makeRequest(engine, req)
+	if code := writer.Code; code != http.StatusCreated {
+		t.Fatalf("status code %d != %d", code, http.StatusCreated)
+	}
+	location := strings.Split(writer.Result().Header.Get("Location"), "/")
+	return location[len(location)-1]
+}
+
+func TestGetUsers(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userJWT, err := getJWT(engine, TestUserEmail, TestUserPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("GetUsers", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/users?sort=email", nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		resp, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.UserGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Data) < 2 || int(resp.Meta.TotalItems) < 2 {
+			t.Fatal("missing users")
+		}
+		for i := 1; i < len(resp.Data); i++ {
+			if resp.Data[i-1].Email > resp.Data[i].Email {
+				t.Fatal("users are not sorted by email")
+			}
+		}
+	})
+
+	t.Run("GetUsers Search", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/users?q=USER1@", nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusOK
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		resp, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.UserGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Data) != 1 || resp.Data[0].UUID != TestUserUUID {
+			t.Fatal("search did not find only the user")
+		}
+	})
+
+	t.Run("GetUsers Forbidden", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/users", nil)
+		req.Header.Add(middleware.AuthHeaderNameString, userJWT)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusForbidden
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+}
+
+func TestUpdateUser(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userUUID := createUser(t, engine, jwtString, "Update User", "update@example.com", "password")
+
+	t.Run("UpdateUser", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"name":  "Updated User",
+			"email": "updated@example.com",
+			"admin": true,
+			"teams": []string{},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/users/%s", userUUID), body)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusNoContent
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%s", userUUID), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+		resp, err := utility.ReadJSONStruct[utility.UserGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Name != "Updated User" || resp.Email != "updated@example.com" || !*resp.Admin || len(resp.Teams) != 0 {
+			t.Fatal("user was not updated")
+		}
+		if _, err := getJWT(engine, "updated@example.com", "password"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("UpdateUser NotFound", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"name":  "Missing User",
+			"email": "missing@example.com",
+			"admin": false,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPut, "/users/00000000-0000-0000-0000-000000000000", body)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusNotFound
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+}
+
+func TestDeactivateUser(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userUUID := createUser(t, engine, jwtString, "Deactivate User", "deactivate@example.com", "password")
+	userJWT, err := getJWT(engine, "deactivate@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("DeactivateUser", func(t *testing.T) {
+		// the user is responding to an incident, which they are taken off when deactivated
+		req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+		incidents, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(incidents.Data) == 0 {
+			t.Fatal("no incidents")
+		}
+		incidentUUID := incidents.Data[0].UUID
+		body, err := getJSONBodyAsReader(map[string]any{"user": userUUID, "role": "responder"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/assign", incidentUUID), body)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		if code := makeRequest(engine, req).Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+
+		req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/deactivate", userUUID), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+
+		expected := http.StatusNoContent
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+
+		// the token given out before the user was deactivated no longer works, and nor does logging in again
+		req, _ = http.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Add(middleware.AuthHeaderNameString, userJWT)
+		writer = makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusUnauthorized {
+			t.Fatalf("status code %d != %d", code, http.StatusUnauthorized)
+		}
+		if _, err := getJWT(engine, "deactivate@example.com", "password"); err == nil {
+			t.Fatal("deactivated user could log in")
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, "/users?active=false", nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+		resp, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.UserGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Data) != 1 || resp.Data[0].UUID != userUUID || resp.Data[0].DeactivatedAt == nil {
+			t.Fatal("deactivated user was not listed")
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", incidentUUID), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+		incident, err := utility.ReadJSONStruct[utility.IncidentGetResponseBodySchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, responder := range incident.Responders {
+			if responder.UUID == userUUID {
+				t.Fatal("deactivated user is still responding")
+			}
+		}
+		if last := incident.AssignmentHistory[len(incident.AssignmentHistory)-1]; last.User.UUID != userUUID || last.Assigned {
+			t.Fatal("assignment history mismatch")
+		}
+	})
+
+	t.Run("DeactivateUser Conflict", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/deactivate", userUUID), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusConflict
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("DeactivateUser Self", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/deactivate", TestAdminUUID), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("ReactivateUser", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/reactivate", userUUID), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusNoContent
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		if _, err := getJWT(engine, "deactivate@example.com", "password"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestDeleteUser(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userUUID := createUser(t, engine, jwtString, "Delete User", "delete@example.com", "password")
+	userJWT, err := getJWT(engine, "delete@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// give the user some history by commenting on an incident
+	req, _ := http.NewRequest(http.MethodGet, "/incidents", nil)
+	req.Header.Add(middleware.AuthHeaderNameString, userJWT)
+	writer := makeRequest(engine, req)
+	incidents, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentGetResponseBodySchema]](writer.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(incidents.Data) == 0 {
+		t.Fatal("no incidents")
+	}
+	incidentUUID := incidents.Data[0].UUID
+	body, err := getJSONBodyAsReader(map[string]any{"comment": "Comment from a user who is deleted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/incidents/%s/comments", incidentUUID), body)
+	req.Header.Add(middleware.AuthHeaderNameString, userJWT)
+	writer = makeRequest(engine, req)
+	if code := writer.Code; code != http.StatusCreated {
+		t.Fatalf("status code %d != %d", code, http.StatusCreated)
+	}
+
+	t.Run("DeleteUser Conflict", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%s", userUUID), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusConflict
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("DeleteUser Self", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%s", TestAdminUUID), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusBadRequest
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("DeleteUser", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%s?reassignTo=%s", userUUID, TestAdminUUID), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusNoContent
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%s", userUUID), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusNotFound {
+			t.Fatalf("status code %d != %d", code, http.StatusNotFound)
+		}
+
+		// the comment is kept, and now belongs to the user it was reassigned to
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s", incidentUUID), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+		if !strings.Contains(writer.Body.String(), "Comment from a user who is deleted") {
+			t.Fatal("comment was not kept")
+		}
+
+		// the timeline is left alone, and keeps the name of the deleted user
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/incidents/%s/timeline?pageSize=100", incidentUUID), nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		writer = makeRequest(engine, req)
+		timeline, err := utility.ReadJSONStruct[utility.GetManyResponseSchema[utility.IncidentEventGetResponseBodySchema]](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, event := range timeline.Data {
+			if event.Type == "comment_added" && event.Actor != nil && event.Actor.Name == "Delete User" && event.Actor.UUID == "" {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatal("deleted user's event was not kept")
+		}
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createUser(t, engine, jwtString, "Password User", "password@example.com", "password")
+	userJWT, err := getJWT(engine, "password@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("ChangePassword WrongPassword", func(t *testing.T) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"currentPassword": "wrongpassword",
+			"newPassword":     "newpassword",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPut, "/me/password", body)
+		req.Header.Add(middleware.AuthHeaderNameString, userJWT)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusForbidden
+		if code := writer.Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+	})
+
+	t.Run("ChangePassword", func(t *testing.T) {
+		otherJWT, err := getJWT(engine, "password@example.com", "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := getJSONBodyAsReader(map[string]any{
+			"currentPassword": "password",
+			"newPassword":     "newpassword",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPut, "/me/password", body)
+		req.Header.Add(middleware.AuthHeaderNameString, userJWT)
+		writer := makeRequest(engine, req)
+
+		expected := http.StatusNoContent
+		if code := writer.Code; code != expected {
+			resp, err := utility.ReadJSONStruct[utility.ErrorResponseSchema](writer.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(resp.Error)
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+
+		if _, err := getJWT(engine, "password@example.com", "password"); err == nil {
+			t.Fatal("old password still works")
+		}
+		if _, err := getJWT(engine, "password@example.com", "newpassword"); err != nil {
+			t.Fatal(err)
+		}
+		// the user's other sessions are revoked, but not the one they changed it in
+		for accessToken, expected := range map[string]int{otherJWT: http.StatusUnauthorized, userJWT: http.StatusOK} {
+			req, _ := http.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Add(middleware.AuthHeaderNameString, accessToken)
+			if code := makeRequest(engine, req).Code; code != expected {
+				t.Fatalf("status code %d != %d", code, expected)
+			}
+		}
+
+		// a password which looks like a bcrypt hash is still hashed before it is stored
+		hashLike := "$2a$10$" + strings.Repeat("a", 53)
+		body, err = getJSONBodyAsReader(map[string]any{
+			"currentPassword": "newpassword",
+			"newPassword":     hashLike,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodPut, "/me/password", body)
+		req.Header.Add(middleware.AuthHeaderNameString, userJWT)
+		if code := makeRequest(engine, req).Code; code != expected {
+			t.Fatalf("status code %d != %d", code, expected)
+		}
+		if _, err := getJWT(engine, "password@example.com", hashLike); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestSessions(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	login := func() (string, string) {
+		body, err := getJSONBodyAsReader(map[string]any{
+			"email":    TestUserEmail,
+			"password": TestUserPassword,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, "/users/login", body)
+		writer := makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		return writer.Header().Get(middleware.AuthHeaderNameString), writer.Header().Get(middleware.RefreshHeaderNameString)
+	}
+	refresh := func(refreshToken string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/users/refresh", nil)
+		req.Header.Add(middleware.RefreshHeaderNameString, refreshToken)
+		return makeRequest(engine, req)
+	}
+	sendRequest := func(jwtString string, method string, url string) int {
+		req, _ := http.NewRequest(method, url, nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		return makeRequest(engine, req).Code
+	}
+
+	t.Run("RefreshUserToken", func(t *testing.T) {
+		_, refreshToken := login()
+		if refreshToken == "" {
+			t.Fatal("no refresh token was given out")
+		}
+		writer := refresh(refreshToken)
+		if code := writer.Code; code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		accessToken, rotated := writer.Header().Get(middleware.AuthHeaderNameString), writer.Header().Get(middleware.RefreshHeaderNameString)
+		if rotated == "" || rotated == refreshToken {
+			t.Fatal("the refresh token was not rotated")
+		}
+		if code := sendRequest(accessToken, http.MethodGet, "/me"); code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+
+		// using a refresh token again means it was stolen, so the session is revoked
+		if code := refresh(refreshToken).Code; code != http.StatusUnauthorized {
+			t.Fatalf("status code %d != %d", code, http.StatusUnauthorized)
+		}
+		if code := refresh(rotated).Code; code != http.StatusUnauthorized {
+			t.Fatalf("status code %d != %d", code, http.StatusUnauthorized)
+		}
+		if code := sendRequest(accessToken, http.MethodGet, "/me"); code != http.StatusUnauthorized {
+			t.Fatalf("status code %d != %d", code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("LogoutUser", func(t *testing.T) {
+		accessToken, refreshToken := login()
+		other, _ := login()
+		if code := sendRequest(accessToken, http.MethodPost, "/users/logout"); code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		if code := sendRequest(accessToken, http.MethodGet, "/me"); code != http.StatusUnauthorized {
+			t.Fatalf("status code %d != %d", code, http.StatusUnauthorized)
+		}
+		if code := refresh(refreshToken).Code; code != http.StatusUnauthorized {
+			t.Fatalf("status code %d != %d", code, http.StatusUnauthorized)
+		}
+		// only the session logged out of is revoked
+		if code := sendRequest(other, http.MethodGet, "/me"); code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+	})
+
+	t.Run("RevokeUserSessions", func(t *testing.T) {
+		first, _ := login()
+		second, refreshToken := login()
+		url := fmt.Sprintf("/users/%s/sessions", TestUserUUID)
+		if code := sendRequest(first, http.MethodDelete, url); code != http.StatusForbidden {
+			t.Fatalf("status code %d != %d", code, http.StatusForbidden)
+		}
+		if code := sendRequest(jwtString, http.MethodDelete, url); code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		for _, accessToken := range []string{first, second} {
+			if code := sendRequest(accessToken, http.MethodGet, "/me"); code != http.StatusUnauthorized {
+				t.Fatalf("status code %d != %d", code, http.StatusUnauthorized)
+			}
+		}
+		if code := refresh(refreshToken).Code; code != http.StatusUnauthorized {
+			t.Fatalf("status code %d != %d", code, http.StatusUnauthorized)
+		}
+		// the admin's own session is untouched
+		if code := sendRequest(jwtString, http.MethodGet, "/me"); code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+	})
+
+	t.Run("PurgeSessions", func(t *testing.T) {
+		accessToken, refreshToken := login()
+		if code := sendRequest(accessToken, http.MethodPost, "/users/logout"); code != http.StatusNoContent {
+			t.Fatalf("status code %d != %d", code, http.StatusNoContent)
+		}
+		if err := database.PurgeSessions(database.GetDBConn()); err != nil {
+			t.Fatal(err)
+		}
+		var count int64
+		if err := database.GetDBConn().Model(&database.Session{}).Where("revoked_at IS NOT NULL OR expires_at < ?", time.Now()).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Fatalf("%d sessions which can no longer be used were kept", count)
+		}
+		// the tokens of a deleted session are refused like those of a revoked one
+		if code := sendRequest(accessToken, http.MethodGet, "/me"); code != http.StatusUnauthorized {
+			t.Fatalf("status code %d != %d", code, http.StatusUnauthorized)
+		}
+		if code := refresh(refreshToken).Code; code != http.StatusUnauthorized {
+			t.Fatalf("status code %d != %d", code, http.StatusUnauthorized)
+		}
+		if code := sendRequest(jwtString, http.MethodGet, "/me"); code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+	})
+}
+
+// Get the public key a token is signed with from the published signing keys, as another service would
+func getJWKSKey(token *jwt.Token, engine *gin.Engine) (any, error) {
+	req, _ := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	writer := makeRequest(engine, req)
+	if code := writer.Code; code != http.StatusOK {
+		return nil, fmt.Errorf("status code %d != %d", code, http.StatusOK)
+	}
+	jwks, err := utility.ReadJSONStruct[utility.JWKSetResponseSchema](writer.Body.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	decode := func(value string) *big.Int {
+		bytes, _ := base64.RawURLEncoding.DecodeString(value)
+		return new(big.Int).SetBytes(bytes)
+	}
+	for _, key := range jwks.Keys {
+		if key.Kid != token.Header["kid"] {
+			continue
+		}
+		if key.Alg != token.Method.Alg() {
+			return nil, fmt.Errorf("key %s is for %s", key.Kid, key.Alg)
+		}
+		switch key.Kty {
+		case "RSA":
+			return &rsa.PublicKey{N: decode(key.N), E: int(decode(key.E).Int64())}, nil
+		case "EC":
+			return &ecdsa.PublicKey{Curve: elliptic.P256(), X: decode(key.X), Y: decode(key.Y)}, nil
+		}
+	}
+	return nil, fmt.Errorf("no published key %v", token.Header["kid"])
+}
+
+func TestSigningKeyRotation(t *testing.T) {
+	engine := setup()
+	jwtString, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parse := func(jwtString string) *jwt.Token {
+		token, err := jwt.Parse(
+			strings.Split(jwtString, " ")[1],
+			func(t *jwt.Token) (any, error) {
+				return getJWKSKey(t, engine)
+			},
+			jwt.WithValidMethods(database.SigningAlgorithms),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	getMe := func(jwtString string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Add(middleware.AuthHeaderNameString, jwtString)
+		return makeRequest(engine, req).Code
+	}
+	rotate := func(algorithm string, publish time.Duration) {
+		if err := database.RotateSigningKeys(database.GetDBConn(), algorithm, time.Hour, publish, middleware.AccessTokenLifetime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	getJWKS := func() *utility.JWKSetResponseSchema {
+		req, _ := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+		writer := makeRequest(engine, req)
+		if code := writer.Code; code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+		if cacheControl := writer.Header().Get("Cache-Control"); cacheControl != "public, max-age=3600" {
+			t.Fatalf("cache control %s != public, max-age=3600", cacheControl)
+		}
+		jwks, err := utility.ReadJSONStruct[utility.JWKSetResponseSchema](writer.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return jwks
+	}
+	before := parse(jwtString)
+
+	// changing the algorithm rotates the keys straight away
+	rotate(database.SigningAlgorithmES256, 0)
+	t.Cleanup(func() {
+		rotate(middleware.JWTSigningAlgorithm(), 0)
+	})
+	rotated, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := parse(rotated)
+	if after.Header["kid"] == before.Header["kid"] || after.Method.Alg() != database.SigningAlgorithmES256 {
+		t.Fatalf("token was signed with %v %s rather than a new ES256 key", after.Header["kid"], after.Method.Alg())
+	}
+
+	// tokens signed with the retired key still work until they expire
+	for _, token := range []string{jwtString, rotated} {
+		if code := getMe(token); code != http.StatusOK {
+			t.Fatalf("status code %d != %d", code, http.StatusOK)
+		}
+	}
+	// a key is not rotated again before the interval has passed
+	rotate(database.SigningAlgorithmES256, 0)
+	again, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parse(again).Header["kid"] != after.Header["kid"] {
+		t.Fatal("the signing key was rotated before it was due")
+	}
+
+	// a new key is published for as long as the published keys may be cached before it takes over signing, and is not
+	// replaced while it waits
+	published := len(getJWKS().Keys)
+	rotate(database.SigningAlgorithmES256, time.Hour)
+	rotate(database.SigningAlgorithmES256, time.Hour)
+	if keys := len(getJWKS().Keys); keys != published+1 {
+		t.Fatalf("%d keys published != %d", keys, published+1)
+	}
+	waiting, err := getJWT(engine, TestAdminEmail, TestAdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parse(waiting).Header["kid"] != after.Header["kid"] {
+		t.Fatal("the new signing key signed before it was published for long enough")
+	}
+
+	// the private keys are only stored encrypted
+	var keys []*database.SigningKey
+	if err := database.GetDBConn().Find(&keys).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if strings.Contains(key.PrivateKey, "PRIVATE KEY") {
+			t.Fatalf("signing key %s is stored unencrypted", key.KID)
+		}
+	}
+}
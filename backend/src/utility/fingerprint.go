@@ -0,0 +1,73 @@
+package utility
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The version of the fingerprinting algorithm. Bump this whenever the normalisation changes,
+// since incidents fingerprinted by different versions will no longer share hashes
+const FingerprintVersion uint = 1
+
+var (
+	fingerprintUUIDRegex    = regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+	fingerprintAddressRegex = regexp.MustCompile(`(?i)0x[0-9a-f]+`)
+	fingerprintNumberRegex  = regexp.MustCompile(`\d+`)
+	// python's 'line 10', javascript's ':10:5' and go's ':10 +0x1d'
+	fingerprintLineRegex = regexp.MustCompile(`(?:, line \d+|:\d+(?::\d+)?(?: \+0x[0-9a-fA-F]+)?)`)
+)
+
+// Compute a SHA-256 fingerprint of a stack trace which is stable across deploys.
+// Any text matching the strip patterns is removed first. The trace is then parsed into frames and only the file and function
+// of in-app frames are hashed, so line numbers and vendor frames do not affect the fingerprint.
+// If no frames can be parsed, every line of the trace is hashed instead.
+// UUIDs, memory addresses and numeric literals are normalised in both cases
+func Fingerprint(trace string, strip []*regexp.Regexp) string {
+	for _, pattern := range strip {
+		trace = pattern.ReplaceAllString(trace, "")
+	}
+
+	parts := []string{fmt.Sprintf("v%d", FingerprintVersion)}
+	_, frames := ParseStackTrace(trace)
+	inApp := make([]StackFrame, 0)
+	for _, frame := range frames {
+		if frame.InApp {
+			inApp = append(inApp, frame)
+		}
+	}
+	if len(inApp) == 0 {
+		inApp = frames
+	}
+	if len(inApp) > 0 {
+		for _, frame := range inApp {
+			parts = append(parts, fmt.Sprintf("%s %s", normaliseFingerprintValue(frame.File), normaliseFingerprintValue(frame.Function)))
+		}
+	} else {
+		for _, line := range strings.Split(trace, "\n") {
+			line = normaliseFingerprintValue(fingerprintLineRegex.ReplaceAllString(strings.TrimSpace(line), ""))
+			if line != "" {
+				parts = append(parts, line)
+			}
+		}
+	}
+	return hashFingerprintParts(parts)
+}
+
+// Compute the fingerprint for a custom grouping key, so every trace given the same key is deduplicated together
+func FingerprintFromKey(key string) string {
+	return hashFingerprintParts([]string{fmt.Sprintf("v%d", FingerprintVersion), "key", key})
+}
+
+func normaliseFingerprintValue(value string) string {
+	value = fingerprintUUIDRegex.ReplaceAllString(value, "<uuid>")
+	value = fingerprintAddressRegex.ReplaceAllString(value, "<addr>")
+	return fingerprintNumberRegex.ReplaceAllString(value, "<num>")
+}
+
+func hashFingerprintParts(parts []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,8 +1,10 @@
 package utility
 
 import (
+	"crypto/sha1"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 )
@@ -28,6 +30,15 @@ func Pointer[T any](value T) *T {
 	return &value
 }
 
+// Cut a string down to at most length characters, for columns which cannot hold any more
+func Truncate(value string, length int) string {
+	runes := []rune(value)
+	if len(runes) <= length {
+		return value
+	}
+	return string(runes[:length])
+}
+
 func MapToSlice(params map[string]any) []string {
 	parts := make([]string, 0)
 	for key, value := range params {
@@ -35,3 +46,63 @@ func MapToSlice(params map[string]any) []string {
 	}
 	return parts
 }
+
+// Remove fields from the JSON form of a schema, where a nested field is given by its path such as 'hostsAffected.team'.
+// A path through a list of objects removes the field from each of them
+func OmitFields(data map[string]any, paths []string) map[string]any {
+	for _, path := range paths {
+		omitField(data, strings.Split(path, "."))
+	}
+	return data
+}
+
+func omitField(value any, keys []string) {
+	switch v := value.(type) {
+	case map[string]any:
+		if len(keys) == 1 {
+			delete(v, keys[0])
+			return
+		}
+		omitField(v[keys[0]], keys[1:])
+	case *map[string]any:
+		if v != nil {
+			omitField(*v, keys)
+		}
+	case []map[string]any:
+		for _, item := range v {
+			omitField(item, keys)
+		}
+	}
+}
+
+// The strong ETag of a resource at a version. It is derived from the response as well as the version, as the response
+// embeds other resources such as the names of teams and users, which change without the version of this one changing
+func ResourceETag(version uint, body ResponseSchema) string {
+	encoded, err := json.Marshal(body.JSON())
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("\"%d-%x\"", version, sha1.Sum(encoded))
+}
+
+// Check whether the list of ETags in an If-Match or If-None-Match header contains etag. If-None-Match uses the weak
+// comparison, which ignores the W/ prefix, and If-Match uses the strong comparison, under which a weak ETag never matches
+func ETagMatches(header string, etag string, weak bool) bool {
+	header = strings.TrimSpace(header)
+	if header == "" || etag == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if weak && strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+		if !weak && tag == etag && !strings.HasPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
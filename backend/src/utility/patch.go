@@ -0,0 +1,99 @@
+package utility
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	MergePatchContentType string = "application/merge-patch+json"
+	JSONPatchContentType  string = "application/json-patch+json"
+)
+
+var jsonPatchOperations []string = []string{"add", "remove", "replace", "move", "copy", "test"}
+
+// Apply a PATCH request body to the current state of a resource, given as the body a PUT request would send, and decode the
+// result back into a body. The patch is a JSON Merge Patch (RFC 7386) or a JSON Patch (RFC 6902) depending on contentType.
+// The result is not validated, so the caller must validate it as it would the body of a PUT request
+func ApplyPatch[T BodySchema](contentType string, current T, patch []byte) (*T, int, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !slices.Contains([]string{MergePatchContentType, JSONPatchContentType}, mediaType) {
+		return nil, http.StatusUnsupportedMediaType, fmt.Errorf("Content-Type must be either '%s' or '%s'", MergePatchContentType, JSONPatchContentType)
+	}
+
+	document, err := patchDocument(current)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	var patched []byte
+	if mediaType == MergePatchContentType {
+		if !json.Valid(patch) || !bytes.HasPrefix(bytes.TrimSpace(patch), []byte("{")) {
+			return nil, http.StatusBadRequest, errors.New("merge patch must be a JSON object")
+		}
+		patched, err = jsonpatch.MergePatch(document, patch)
+		if err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("invalid merge patch: %s", err.Error())
+		}
+	} else {
+		operations, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("JSON patch must be a list of operations")
+		}
+		for i, operation := range operations {
+			if !slices.Contains(jsonPatchOperations, operation.Kind()) {
+				return nil, http.StatusBadRequest, fmt.Errorf("operation %d of the JSON patch must have an 'op' of '%s'", i, strings.Join(jsonPatchOperations, "', '"))
+			}
+		}
+		// the operations are well formed, so failing to apply them means they do not fit the current state, such as a failed 'test'
+		patched, err = operations.Apply(document)
+		if err != nil {
+			return nil, http.StatusConflict, fmt.Errorf("JSON patch cannot be applied: %s", err.Error())
+		}
+	}
+
+	var result T
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&result); err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("patched body is invalid: %s", err.Error())
+	}
+	return &result, -1, nil
+}
+
+// Encode a body as JSON without the schema interfaces embedded in it and its nested schemas, which are not part of the body
+func patchDocument(body BodySchema) ([]byte, error) {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	var document any
+	if err := json.Unmarshal(encoded, &document); err != nil {
+		return nil, err
+	}
+	return json.Marshal(removeEmbeddedSchemas(document))
+}
+
+func removeEmbeddedSchemas(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		delete(v, "BodySchema")
+		delete(v, "ResponseSchema")
+		for key, item := range v {
+			v[key] = removeEmbeddedSchemas(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = removeEmbeddedSchemas(item)
+		}
+	}
+	return value
+}
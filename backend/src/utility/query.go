@@ -0,0 +1,337 @@
+package utility
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// The longest query which is parsed, in characters
+const MaxQueryLength int = 2048
+
+const (
+	QueryOperatorMatch        string = ":"
+	QueryOperatorEqual        string = "="
+	QueryOperatorGreater      string = ">"
+	QueryOperatorGreaterEqual string = ">="
+	QueryOperatorLess         string = "<"
+	QueryOperatorLessEqual    string = "<="
+)
+
+// A node of a parsed query. Positions are 1-based character offsets into the query
+type QueryNode interface {
+	Position() int
+}
+
+// Every operand must match
+type QueryAndNode struct {
+	Pos      int
+	Operands []QueryNode
+}
+
+// At least one operand must match
+type QueryOrNode struct {
+	Pos      int
+	Operands []QueryNode
+}
+
+// The operand must not match
+type QueryNotNode struct {
+	Pos     int
+	Operand QueryNode
+}
+
+// A field compared against one or more values, such as 'severity:<=2' or 'status:open,acknowledged'
+type QueryTermNode struct {
+	Pos      int
+	Field    string
+	Operator string
+	Values   []QueryValue
+}
+
+type QueryValue struct {
+	Pos   int
+	Value string
+}
+
+// A word or quoted phrase without a field
+type QueryTextNode struct {
+	Pos  int
+	Text string
+}
+
+func (n QueryAndNode) Position() int  { return n.Pos }
+func (n QueryOrNode) Position() int   { return n.Pos }
+func (n QueryNotNode) Position() int  { return n.Pos }
+func (n QueryTermNode) Position() int { return n.Pos }
+func (n QueryTextNode) Position() int { return n.Pos }
+
+type QueryError struct {
+	Pos     int
+	Message string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Pos)
+}
+
+type queryParser struct {
+	input []rune
+	pos   int
+}
+
+// Parse a query such as 'status:open severity:<=2 (team:DevOps OR host:web-1) -regressed:true'.
+// Terms next to each other must all match, unless separated by OR. Terms can be grouped with parentheses
+// and negated with a '-' prefix or NOT. Values containing spaces must be quoted, and a comma separated list of values
+// matches any of them. Returns a nil node for an empty query, or a *QueryError pointing at the offending character.
+// Queries longer than MaxQueryLength are refused
+func ParseQuery(query string) (QueryNode, error) {
+	p := &queryParser{input: []rune(query)}
+	if len(p.input) > MaxQueryLength {
+		return nil, &QueryError{Pos: MaxQueryLength + 1, Message: fmt.Sprintf("query is longer than %d characters", MaxQueryLength)}
+	}
+	p.skipSpaces()
+	if p.atEnd() {
+		return nil, nil
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if !p.atEnd() {
+		return nil, p.errorf("unexpected '%c'", p.peek())
+	}
+	return node, nil
+}
+
+func (p *queryParser) parseOr() (QueryNode, error) {
+	start := p.position()
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	operands := []QueryNode{node}
+	for p.acceptKeyword("OR") {
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, node)
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return QueryOrNode{Pos: start, Operands: operands}, nil
+}
+
+func (p *queryParser) parseAnd() (QueryNode, error) {
+	start := p.position()
+	node, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	operands := []QueryNode{node}
+	for {
+		p.skipSpaces()
+		if p.atEnd() || p.peek() == ')' || p.peekKeyword("OR") {
+			break
+		}
+		p.acceptKeyword("AND")
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, node)
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return QueryAndNode{Pos: start, Operands: operands}, nil
+}
+
+func (p *queryParser) parseUnary() (QueryNode, error) {
+	p.skipSpaces()
+	start := p.position()
+	if p.acceptKeyword("NOT") {
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return QueryNotNode{Pos: start, Operand: node}, nil
+	}
+	if p.peek() == '-' && p.pos+1 < len(p.input) && !unicode.IsSpace(p.input[p.pos+1]) && p.input[p.pos+1] != ')' {
+		p.pos++
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return QueryNotNode{Pos: start, Operand: node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (QueryNode, error) {
+	p.skipSpaces()
+	start := p.position()
+	if p.atEnd() {
+		return nil, p.errorf("unexpected end of query")
+	}
+	switch p.peek() {
+	case '(':
+		p.pos++
+		p.skipSpaces()
+		if p.peek() == ')' {
+			return nil, p.errorf("expected a term")
+		}
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if p.peek() != ')' {
+			return nil, &QueryError{Pos: start, Message: "unclosed '('"}
+		}
+		p.pos++
+		return node, nil
+	case ')':
+		return nil, p.errorf("unexpected ')'")
+	case '"':
+		text, err := p.parseQuoted()
+		if err != nil {
+			return nil, err
+		}
+		return QueryTextNode{Pos: start, Text: text}, nil
+	}
+
+	field := p.readWhile(func(r rune) bool { return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r) })
+	if field != "" && p.peek() == ':' {
+		p.pos++
+		return p.parseTerm(start, field)
+	}
+	word := field + p.readWhile(func(r rune) bool { return !isQueryDelimiter(r) && r != '"' })
+	if word == "" {
+		return nil, p.errorf("unexpected '%c'", p.peek())
+	}
+	return QueryTextNode{Pos: start, Text: word}, nil
+}
+
+func (p *queryParser) parseTerm(start int, field string) (QueryNode, error) {
+	operator := QueryOperatorMatch
+	for _, op := range []string{QueryOperatorGreaterEqual, QueryOperatorLessEqual, QueryOperatorGreater, QueryOperatorLess, QueryOperatorEqual} {
+		if p.hasPrefix(op) {
+			operator = op
+			p.pos += len(op)
+			break
+		}
+	}
+	values := make([]QueryValue, 0)
+	for {
+		valueStart := p.position()
+		var value string
+		if p.peek() == '"' {
+			quoted, err := p.parseQuoted()
+			if err != nil {
+				return nil, err
+			}
+			value = quoted
+		} else {
+			value = p.readWhile(func(r rune) bool { return !isQueryDelimiter(r) && r != ',' && r != '"' })
+			if value == "" {
+				return nil, p.errorf("expected a value for '%s'", field)
+			}
+		}
+		values = append(values, QueryValue{Pos: valueStart, Value: value})
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+	}
+	if !p.atEnd() && !isQueryDelimiter(p.peek()) {
+		return nil, p.errorf("unexpected '%c'", p.peek())
+	}
+	return QueryTermNode{Pos: start, Field: field, Operator: operator, Values: values}, nil
+}
+
+func (p *queryParser) parseQuoted() (string, error) {
+	start := p.position()
+	p.pos++
+	var builder strings.Builder
+	for !p.atEnd() {
+		r := p.input[p.pos]
+		p.pos++
+		switch {
+		case r == '\\' && !p.atEnd():
+			builder.WriteRune(p.input[p.pos])
+			p.pos++
+		case r == '"':
+			return builder.String(), nil
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	return "", &QueryError{Pos: start, Message: "unclosed '\"'"}
+}
+
+// Consume a keyword if it is the next word, which must be followed by a delimiter so that 'ORDER' is not read as 'OR'
+func (p *queryParser) acceptKeyword(keyword string) bool {
+	p.skipSpaces()
+	if !p.peekKeyword(keyword) {
+		return false
+	}
+	p.pos += len(keyword)
+	return true
+}
+
+func (p *queryParser) peekKeyword(keyword string) bool {
+	end := p.pos + len(keyword)
+	return p.hasPrefix(keyword) && (end == len(p.input) || isQueryDelimiter(p.input[end]))
+}
+
+func (p *queryParser) readWhile(accept func(rune) bool) string {
+	start := p.pos
+	for !p.atEnd() && accept(p.input[p.pos]) {
+		p.pos++
+	}
+	return string(p.input[start:p.pos])
+}
+
+// Whether the rest of the input starts with prefix, compared in place rather than copying the rest of the input
+func (p *queryParser) hasPrefix(prefix string) bool {
+	i := p.pos
+	for _, r := range prefix {
+		if i >= len(p.input) || p.input[i] != r {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+func (p *queryParser) skipSpaces() {
+	p.readWhile(unicode.IsSpace)
+}
+
+func (p *queryParser) peek() rune {
+	if p.atEnd() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *queryParser) atEnd() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *queryParser) position() int {
+	return p.pos + 1
+}
+
+func (p *queryParser) errorf(format string, args ...any) *QueryError {
+	return &QueryError{Pos: p.position(), Message: fmt.Sprintf(format, args...)}
+}
+
+func isQueryDelimiter(r rune) bool {
+	return unicode.IsSpace(r) || slices.Contains([]rune{'(', ')'}, r)
+}
@@ -0,0 +1,116 @@
+package utility_test
+
+import (
+	"com668-backend/utility"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		node  utility.QueryNode
+		err   *utility.QueryError
+	}{
+		{name: "Empty", query: "", node: nil},
+		{name: "Spaces", query: "   ", node: nil},
+		{
+			name:  "Term",
+			query: "status:open",
+			node:  utility.QueryTermNode{Pos: 1, Field: "status", Operator: utility.QueryOperatorMatch, Values: []utility.QueryValue{{Pos: 8, Value: "open"}}},
+		},
+		{
+			name:  "Comparison",
+			query: "severity:<=2",
+			node:  utility.QueryTermNode{Pos: 1, Field: "severity", Operator: utility.QueryOperatorLessEqual, Values: []utility.QueryValue{{Pos: 12, Value: "2"}}},
+		},
+		{
+			name:  "Values",
+			query: "status:open,acknowledged",
+			node:  utility.QueryTermNode{Pos: 1, Field: "status", Operator: utility.QueryOperatorMatch, Values: []utility.QueryValue{{Pos: 8, Value: "open"}, {Pos: 13, Value: "acknowledged"}}},
+		},
+		{
+			name:  "QuotedValue",
+			query: `host:"web 1"`,
+			node:  utility.QueryTermNode{Pos: 1, Field: "host", Operator: utility.QueryOperatorMatch, Values: []utility.QueryValue{{Pos: 6, Value: "web 1"}}},
+		},
+		{
+			name:  "QuotedText",
+			query: `"disk \"full\""`,
+			node:  utility.QueryTextNode{Pos: 1, Text: `disk "full"`},
+		},
+		{
+			name:  "And",
+			query: "a AND b c",
+			node:  utility.QueryAndNode{Pos: 1, Operands: []utility.QueryNode{utility.QueryTextNode{Pos: 1, Text: "a"}, utility.QueryTextNode{Pos: 7, Text: "b"}, utility.QueryTextNode{Pos: 9, Text: "c"}}},
+		},
+		{
+			name:  "Or",
+			query: "a OR b",
+			node:  utility.QueryOrNode{Pos: 1, Operands: []utility.QueryNode{utility.QueryTextNode{Pos: 1, Text: "a"}, utility.QueryTextNode{Pos: 6, Text: "b"}}},
+		},
+		{
+			name:  "KeywordPrefix",
+			query: "ORDER NOTE",
+			node:  utility.QueryAndNode{Pos: 1, Operands: []utility.QueryNode{utility.QueryTextNode{Pos: 1, Text: "ORDER"}, utility.QueryTextNode{Pos: 7, Text: "NOTE"}}},
+		},
+		{
+			name:  "Group",
+			query: "(a OR b) c",
+			node: utility.QueryAndNode{Pos: 1, Operands: []utility.QueryNode{
+				utility.QueryOrNode{Pos: 2, Operands: []utility.QueryNode{utility.QueryTextNode{Pos: 2, Text: "a"}, utility.QueryTextNode{Pos: 7, Text: "b"}}},
+				utility.QueryTextNode{Pos: 10, Text: "c"},
+			}},
+		},
+		{
+			name:  "Minus",
+			query: "-regressed:true",
+			node:  utility.QueryNotNode{Pos: 1, Operand: utility.QueryTermNode{Pos: 2, Field: "regressed", Operator: utility.QueryOperatorMatch, Values: []utility.QueryValue{{Pos: 12, Value: "true"}}}},
+		},
+		{
+			name:  "Not",
+			query: "NOT a",
+			node:  utility.QueryNotNode{Pos: 1, Operand: utility.QueryTextNode{Pos: 5, Text: "a"}},
+		},
+		{
+			name:  "LoneMinus",
+			query: "a -",
+			node:  utility.QueryAndNode{Pos: 1, Operands: []utility.QueryNode{utility.QueryTextNode{Pos: 1, Text: "a"}, utility.QueryTextNode{Pos: 3, Text: "-"}}},
+		},
+		{
+			name:  "LongestQuery",
+			query: strings.Repeat("a", utility.MaxQueryLength),
+			node:  utility.QueryTextNode{Pos: 1, Text: strings.Repeat("a", utility.MaxQueryLength)},
+		},
+		{name: "UnclosedGroup", query: "(a", err: &utility.QueryError{Pos: 1, Message: "unclosed '('"}},
+		{name: "UnexpectedClose", query: "a)", err: &utility.QueryError{Pos: 2, Message: "unexpected ')'"}},
+		{name: "EmptyGroup", query: "()", err: &utility.QueryError{Pos: 2, Message: "expected a term"}},
+		{name: "UnclosedQuote", query: `"abc`, err: &utility.QueryError{Pos: 1, Message: `unclosed '"'`}},
+		{name: "MissingValue", query: "status:", err: &utility.QueryError{Pos: 8, Message: "expected a value for 'status'"}},
+		{name: "TrailingOr", query: "a OR", err: &utility.QueryError{Pos: 5, Message: "unexpected end of query"}},
+		{name: "UnexpectedAfterValue", query: `status:open"`, err: &utility.QueryError{Pos: 12, Message: `unexpected '"'`}},
+		{name: "TooLong", query: strings.Repeat("a", utility.MaxQueryLength+1), err: &utility.QueryError{Pos: utility.MaxQueryLength + 1, Message: "query is longer than 2048 characters"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node, err := utility.ParseQuery(test.query)
+			if test.err != nil {
+				queryErr := (*utility.QueryError)(nil)
+				if !errors.As(err, &queryErr) || *queryErr != *test.err {
+					t.Fatalf("error %v != %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(node, test.node) {
+				t.Fatalf("node %+v != %+v", node, test.node)
+			}
+		})
+	}
+}
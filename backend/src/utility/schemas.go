@@ -32,39 +32,130 @@ func (e ErrorResponseSchema) String() string {
 	return fmt.Sprintf("{'error': '%s'}", e.Error)
 }
 
+// An error in a query parameter, with the 1-based position of the character at fault
+type QueryErrorResponseSchema struct {
+	ResponseSchema `swaggerignore:"true"`
+	Error          string `json:"error"`
+	Position       int    `json:"position"`
+}
+
+func (e QueryErrorResponseSchema) JSON() map[string]any {
+	return map[string]any{"error": e.Error, "position": e.Position}
+}
+func (e QueryErrorResponseSchema) String() string {
+	return fmt.Sprintf("{'error': '%s', 'position': %d}", e.Error, e.Position)
+}
+
 type UserPostRequestBodySchema struct {
 	BodySchema `swaggerignore:"true"`
 	Name       string   `json:"name"`
 	Email      string   `json:"email"`
 	Password   string   `json:"password"`
 	Teams      []string `json:"teams"`
+	// whether the user is used by automation, which authenticates with API keys and so has no password
+	ServiceAccount bool `json:"serviceAccount"`
 }
 
 func (u UserPostRequestBodySchema) Validate() (int, error) {
-	if len(u.Name) == 0 {
+	if status, err := validateUserDetails(u.Name, u.Email); err != nil {
+		return status, err
+	}
+	if u.ServiceAccount {
+		if len(u.Password) > 0 {
+			return 400, errors.New("'password' cannot be given for a service account")
+		}
+		return -1, nil
+	}
+	if len(u.Password) == 0 {
+		return 400, errors.New("'password' is required")
+	}
+	if len(u.Password) > 72 {
+		return 400, errors.New("'password' cannot be greater than 72 characters")
+	}
+	return -1, nil
+}
+
+type UserPutRequestBodySchema struct {
+	BodySchema `swaggerignore:"true"`
+	Name       string   `json:"name"`
+	Email      string   `json:"email"`
+	Admin      *bool    `json:"admin"`
+	Teams      []string `json:"teams"`
+}
+
+func (u UserPutRequestBodySchema) Validate() (int, error) {
+	if status, err := validateUserDetails(u.Name, u.Email); err != nil {
+		return status, err
+	}
+	if u.Admin == nil {
+		return 400, errors.New("'admin' is required")
+	}
+	for _, t := range u.Teams {
+		if _, err := uuid.Parse(t); err != nil {
+			return 400, errors.New("'teams' must be a list of valid UUIDs")
+		}
+	}
+	return -1, nil
+}
+
+func validateUserDetails(name string, email string) (int, error) {
+	if len(name) == 0 {
 		return 400, errors.New("'name' is required")
 	}
-	if len(u.Name) > 30 {
+	if len(name) > 30 {
 		return 400, errors.New("'name' cannot be longer than 30 characters")
 	}
-	if len(u.Email) == 0 {
+	if len(email) == 0 {
 		return 400, errors.New("'email' is required")
 	}
-	if len(u.Email) > 30 {
+	if len(email) > 30 {
 		return 400, errors.New("'email' cannot be longer than 30 characters")
 	}
-	matched, err := regexp.Match("[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+.[A-Za-z]{2,}", []byte(u.Email))
+	matched, err := regexp.Match("[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+.[A-Za-z]{2,}", []byte(email))
 	if err != nil {
 		return 500, err
 	}
 	if !matched {
 		return 400, errors.New("'email' is not valid")
 	}
-	if len(u.Password) == 0 {
-		return 400, errors.New("'password' is required")
+	return -1, nil
+}
+
+type UserIdentityPutRequestBodySchema struct {
+	BodySchema `swaggerignore:"true"`
+	Provider   string `json:"provider"`
+	// the id of the user in the identity provider, which is their LDAP_ID_ATTRIBUTE or DN for ldap and sub for oidc
+	ExternalID string `json:"externalID"`
+}
+
+func (u UserIdentityPutRequestBodySchema) Validate() (int, error) {
+	if u.Provider != "ldap" && u.Provider != "oidc" {
+		return 400, errors.New("'provider' must be one of 'ldap', 'oidc'")
 	}
-	if len(u.Password) > 72 {
-		return 400, errors.New("'password' cannot be greater than 72 characters")
+	if len(u.ExternalID) == 0 {
+		return 400, errors.New("'externalID' is required")
+	}
+	if len(u.ExternalID) > 255 {
+		return 400, errors.New("'externalID' cannot be longer than 255 characters")
+	}
+	return -1, nil
+}
+
+type UserPasswordPutRequestBodySchema struct {
+	BodySchema      `swaggerignore:"true"`
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+func (u UserPasswordPutRequestBodySchema) Validate() (int, error) {
+	if len(u.CurrentPassword) == 0 {
+		return 400, errors.New("'currentPassword' is required")
+	}
+	if len(u.NewPassword) == 0 {
+		return 400, errors.New("'newPassword' is required")
+	}
+	if len(u.NewPassword) > 72 {
+		return 400, errors.New("'newPassword' cannot be greater than 72 characters")
 	}
 	return -1, nil
 }
@@ -151,17 +242,27 @@ func (p ProviderGetResponseSchema) String() string {
 
 type MetaSchema struct {
 	ResponseSchema `swaggerignore:"true"`
-	TotalItems     int64 `json:"total"`
-	Pages          int   `json:"pages"`
-	Page           int   `json:"page"`
-	PageSize       int   `json:"pageSize"`
+	TotalItems     int64   `json:"total"`
+	Pages          int     `json:"pages"`
+	Page           int     `json:"page"`
+	PageSize       int     `json:"pageSize"`
+	Next           *string `json:"next"`
+	Prev           *string `json:"prev"`
 }
 
 func (m MetaSchema) JSON() map[string]any {
-	return map[string]any{"total": m.TotalItems, "pages": m.Pages, "page": m.Page, "pageSize": m.PageSize}
+	return map[string]any{"total": m.TotalItems, "pages": m.Pages, "page": m.Page, "pageSize": m.PageSize, "next": m.Next, "prev": m.Prev}
 }
 func (m MetaSchema) String() string {
-	return fmt.Sprintf("{'total': %d, 'pages': %d, 'page': %d, 'pageSize': %d}", m.TotalItems, m.Pages, m.Page, m.PageSize)
+	next := "nil"
+	if m.Next != nil {
+		next = fmt.Sprintf("'%s'", *m.Next)
+	}
+	prev := "nil"
+	if m.Prev != nil {
+		prev = fmt.Sprintf("'%s'", *m.Prev)
+	}
+	return fmt.Sprintf("{'total': %d, 'pages': %d, 'page': %d, 'pageSize': %d, 'next': %s, 'prev': %s}", m.TotalItems, m.Pages, m.Page, m.PageSize, next, prev)
 }
 
 type GetManyResponseSchema[T ResponseSchema] struct {
@@ -192,6 +293,10 @@ type IncidentPostRequestBodySchema struct {
 	ResolutionTeams []string `json:"resolutionTeams"`
 	HostsAffected   []string `json:"hostsAffected"`
 	Hash            string   `json:"hash"`
+	Severity        *uint    `json:"severity"`
+	Impact          *uint    `json:"impact"`
+	Urgency         *uint    `json:"urgency"`
+	StackTrace      string   `json:"stackTrace"`
 }
 
 func (i IncidentPostRequestBodySchema) Validate() (int, error) {
@@ -217,11 +322,29 @@ func (i IncidentPostRequestBodySchema) Validate() (int, error) {
 			return 400, errors.New("'hostsAffected' must be a list of valid UUIDs")
 		}
 	}
-	if len(i.Hash) == 0 {
-		return 400, errors.New("'hash' is required")
+	// the hash is computed server side when a stack trace is given
+	if len(i.Hash) == 0 && len(i.StackTrace) == 0 {
+		return 400, errors.New("'hash' is required when no 'stackTrace' is given")
+	}
+	if len(i.Hash) > 64 {
+		return 400, errors.New("'hash' cannot be longer than 64 characters")
+	}
+	if len(i.StackTrace) > 65535 {
+		return 400, errors.New("'stackTrace' cannot be longer than 65535 characters")
+	}
+	return validateIncidentClassification(i.Severity, i.Impact, i.Urgency)
+}
+
+// Validate the optional severity (SEV1-SEV5), impact (1-3) and urgency (1-3) of an incident
+func validateIncidentClassification(severity, impact, urgency *uint) (int, error) {
+	if severity != nil && (*severity < 1 || *severity > 5) {
+		return 400, errors.New("'severity' must be between 1 and 5")
+	}
+	if impact != nil && (*impact < 1 || *impact > 3) {
+		return 400, errors.New("'impact' must be between 1 and 3")
 	}
-	if len(i.Hash) > 40 {
-		return 400, errors.New("'hash' cannot be longer than 40 characters")
+	if urgency != nil && (*urgency < 1 || *urgency > 3) {
+		return 400, errors.New("'urgency' must be between 1 and 3")
 	}
 	return -1, nil
 }
@@ -234,6 +357,14 @@ type UserGetResponseBodySchema struct {
 	Teams          []TeamGetResponseBodySchema `json:"teams"`
 	SlackID        string                      `json:"slackID"`
 	Admin          *bool                       `json:"admin"`
+	// the user's role in a team, which is only set when listed as one of the team's users
+	Role string `json:"role,omitempty"`
+	// only set if the user has been deactivated
+	DeactivatedAt *time.Time `json:"deactivatedAt,omitempty"`
+	// only set if the user is a service account
+	ServiceAccount bool `json:"serviceAccount,omitempty"`
+	// everything the user can do, which is only set when getting a single user
+	Permissions []string `json:"permissions,omitempty"`
 }
 
 func (u UserGetResponseBodySchema) JSON() map[string]any {
@@ -241,7 +372,20 @@ func (u UserGetResponseBodySchema) JSON() map[string]any {
 	for _, t := range u.Teams {
 		teams = append(teams, t.JSON())
 	}
-	return map[string]any{"uuid": u.UUID, "name": u.Name, "email": u.Email, "teams": teams, "slackID": u.SlackID, "admin": u.Admin}
+	user := map[string]any{"uuid": u.UUID, "name": u.Name, "email": u.Email, "teams": teams, "slackID": u.SlackID, "admin": u.Admin}
+	if u.Role != "" {
+		user["role"] = u.Role
+	}
+	if u.DeactivatedAt != nil {
+		user["deactivatedAt"] = u.DeactivatedAt
+	}
+	if u.ServiceAccount {
+		user["serviceAccount"] = true
+	}
+	if u.Permissions != nil {
+		user["permissions"] = u.Permissions
+	}
+	return user
 }
 func (u UserGetResponseBodySchema) String() string {
 	teams := make([]string, 0)
@@ -257,9 +401,11 @@ func (u UserGetResponseBodySchema) String() string {
 
 type TeamGetResponseBodySchema struct {
 	ResponseSchema `swaggerignore:"true"`
-	UUID           string                      `json:"uuid"`
-	Name           string                      `json:"name"`
-	Users          []UserGetResponseBodySchema `json:"users"`
+	UUID           string `json:"uuid"`
+	Name           string `json:"name"`
+	// only set when getting teams themselves, rather than as part of another resource
+	Private *bool                       `json:"private,omitempty"`
+	Users   []UserGetResponseBodySchema `json:"users"`
 }
 
 func (t TeamGetResponseBodySchema) JSON() map[string]any {
@@ -267,7 +413,11 @@ func (t TeamGetResponseBodySchema) JSON() map[string]any {
 	for _, u := range t.Users {
 		users = append(users, u.JSON())
 	}
-	return map[string]any{"uuid": t.UUID, "name": t.Name, "users": users}
+	team := map[string]any{"uuid": t.UUID, "name": t.Name, "users": users}
+	if t.Private != nil {
+		team["private"] = *t.Private
+	}
+	return team
 }
 func (t TeamGetResponseBodySchema) String() string {
 	users := make([]string, 0)
@@ -277,33 +427,307 @@ func (t TeamGetResponseBodySchema) String() string {
 	return fmt.Sprintf("{'uuid': '%s', 'name': '%s', 'users': ['%s']}", t.UUID, t.Name, strings.Join(users, " "))
 }
 
+type RoleGetResponseBodySchema struct {
+	ResponseSchema `swaggerignore:"true"`
+	UUID           string   `json:"uuid"`
+	Name           string   `json:"name"`
+	Description    string   `json:"description"`
+	Default        bool     `json:"default"`
+	Permissions    []string `json:"permissions"`
+}
+
+func (r RoleGetResponseBodySchema) JSON() map[string]any {
+	return map[string]any{"uuid": r.UUID, "name": r.Name, "description": r.Description, "default": r.Default, "permissions": r.Permissions}
+}
+func (r RoleGetResponseBodySchema) String() string {
+	return fmt.Sprintf("{'uuid': '%s', 'name': '%s', 'description': '%s', 'default': %t, 'permissions': ['%s']}", r.UUID, r.Name, r.Description, r.Default, strings.Join(r.Permissions, "', '"))
+}
+
+type APIKeyGetResponseBodySchema struct {
+	ResponseSchema `swaggerignore:"true"`
+	UUID           string     `json:"uuid"`
+	Name           string     `json:"name"`
+	Scopes         []string   `json:"scopes"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	ExpiresAt      time.Time  `json:"expiresAt"`
+	LastUsedAt     *time.Time `json:"lastUsedAt"`
+	RevokedAt      *time.Time `json:"revokedAt"`
+}
+
+func (a APIKeyGetResponseBodySchema) JSON() map[string]any {
+	return map[string]any{"uuid": a.UUID, "name": a.Name, "scopes": a.Scopes, "createdAt": a.CreatedAt, "expiresAt": a.ExpiresAt, "lastUsedAt": a.LastUsedAt, "revokedAt": a.RevokedAt}
+}
+func (a APIKeyGetResponseBodySchema) String() string {
+	return fmt.Sprintf("{'uuid': '%s', 'name': '%s', 'scopes': ['%s'], 'createdAt': '%s', 'expiresAt': '%s', 'lastUsedAt': '%v', 'revokedAt': '%v'}", a.UUID, a.Name, strings.Join(a.Scopes, "', '"), a.CreatedAt, a.ExpiresAt, a.LastUsedAt, a.RevokedAt)
+}
+
+type APIKeyPostResponseBodySchema struct {
+	APIKeyGetResponseBodySchema
+	// the key to authenticate with, which is only shown here
+	Key string `json:"key"`
+}
+
+func (a APIKeyPostResponseBodySchema) JSON() map[string]any {
+	key := a.APIKeyGetResponseBodySchema.JSON()
+	key["key"] = a.Key
+	return key
+}
+func (a APIKeyPostResponseBodySchema) String() string {
+	// the key is a secret, so it is never logged
+	return a.APIKeyGetResponseBodySchema.String()
+}
+
+// A public key tokens are verified with, as a JSON Web Key (RFC 7517)
+type JWKResponseSchema struct {
+	ResponseSchema `swaggerignore:"true"`
+	Kty            string `json:"kty"`
+	Use            string `json:"use"`
+	Alg            string `json:"alg"`
+	Kid            string `json:"kid"`
+	// the modulus and exponent of an RSA key
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// the curve and coordinates of an EC key
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+func (j JWKResponseSchema) JSON() map[string]any {
+	key := map[string]any{"kty": j.Kty, "use": j.Use, "alg": j.Alg, "kid": j.Kid}
+	for name, value := range map[string]string{"n": j.N, "e": j.E, "crv": j.Crv, "x": j.X, "y": j.Y} {
+		if value != "" {
+			key[name] = value
+		}
+	}
+	return key
+}
+func (j JWKResponseSchema) String() string {
+	return fmt.Sprintf("{'kty': '%s', 'use': '%s', 'alg': '%s', 'kid': '%s'}", j.Kty, j.Use, j.Alg, j.Kid)
+}
+
+type JWKSetResponseSchema struct {
+	ResponseSchema `swaggerignore:"true"`
+	Keys           []JWKResponseSchema `json:"keys"`
+}
+
+func (j JWKSetResponseSchema) JSON() map[string]any {
+	keys := make([]map[string]any, 0)
+	for _, k := range j.Keys {
+		keys = append(keys, k.JSON())
+	}
+	return map[string]any{"keys": keys}
+}
+func (j JWKSetResponseSchema) String() string {
+	keys := make([]string, 0)
+	for _, k := range j.Keys {
+		keys = append(keys, k.String())
+	}
+	return fmt.Sprintf("{'keys': [%s]}", strings.Join(keys, ", "))
+}
+
 type IncidentCommentGetResponseBodySchema struct {
 	ResponseSchema `swaggerignore:"true"`
-	UUID           string                    `json:"uuid"`
-	Comment        string                    `json:"comment"`
-	CommentedBy    UserGetResponseBodySchema `json:"commentedBy"`
-	CommentedAt    time.Time                 `json:"commentedAt"`
+	UUID           string `json:"uuid"`
+	Comment        string `json:"comment"`
+	// null if the comment was left automatically
+	CommentedBy *UserGetResponseBodySchema `json:"commentedBy"`
+	Automated   bool                       `json:"automated"`
+	CommentedAt time.Time                  `json:"commentedAt"`
 }
 
 func (i IncidentCommentGetResponseBodySchema) JSON() map[string]any {
-	return map[string]any{"uuid": i.UUID, "comment": i.Comment, "commentedBy": i.CommentedBy.JSON(), "commentedAt": i.CommentedAt}
+	var commentedBy *map[string]any = nil
+	if i.CommentedBy != nil {
+		commentedBy = Pointer(i.CommentedBy.JSON())
+	}
+	return map[string]any{"uuid": i.UUID, "comment": i.Comment, "commentedBy": commentedBy, "automated": i.Automated, "commentedAt": i.CommentedAt}
 }
 func (i IncidentCommentGetResponseBodySchema) String() string {
-	return fmt.Sprintf("{'uuid': '%s', 'comment': '%s', 'commentedBy': %s, 'commentedAt': '%s'}", i.UUID, i.Comment, i.CommentedBy.String(), i.CommentedAt)
+	commentedBy := "nil"
+	if i.CommentedBy != nil {
+		commentedBy = i.CommentedBy.String()
+	}
+	return fmt.Sprintf("{'uuid': '%s', 'comment': '%s', 'commentedBy': %s, 'automated': %t, 'commentedAt': '%s'}", i.UUID, i.Comment, commentedBy, i.Automated, i.CommentedAt)
+}
+
+type IncidentStatusChangeGetResponseBodySchema struct {
+	ResponseSchema `swaggerignore:"true"`
+	UUID           string                    `json:"uuid"`
+	FromStatus     string                    `json:"fromStatus"`
+	ToStatus       string                    `json:"toStatus"`
+	ChangedBy      UserGetResponseBodySchema `json:"changedBy"`
+	ChangedAt      time.Time                 `json:"changedAt"`
+}
+
+func (i IncidentStatusChangeGetResponseBodySchema) JSON() map[string]any {
+	return map[string]any{"uuid": i.UUID, "fromStatus": i.FromStatus, "toStatus": i.ToStatus, "changedBy": i.ChangedBy.JSON(), "changedAt": i.ChangedAt}
+}
+func (i IncidentStatusChangeGetResponseBodySchema) String() string {
+	return fmt.Sprintf("{'uuid': '%s', 'fromStatus': '%s', 'toStatus': '%s', 'changedBy': %s, 'changedAt': '%s'}", i.UUID, i.FromStatus, i.ToStatus, i.ChangedBy.String(), i.ChangedAt)
+}
+
+type IncidentAssignmentGetResponseBodySchema struct {
+	ResponseSchema `swaggerignore:"true"`
+	UUID           string                    `json:"uuid"`
+	User           UserGetResponseBodySchema `json:"user"`
+	Role           string                    `json:"role"`
+	Assigned       bool                      `json:"assigned"`
+	// null if the change was made automatically
+	ChangedBy *UserGetResponseBodySchema `json:"changedBy"`
+	ChangedAt time.Time                  `json:"changedAt"`
+}
+
+func (i IncidentAssignmentGetResponseBodySchema) JSON() map[string]any {
+	var changedBy *map[string]any = nil
+	if i.ChangedBy != nil {
+		changedBy = Pointer(i.ChangedBy.JSON())
+	}
+	return map[string]any{"uuid": i.UUID, "user": i.User.JSON(), "role": i.Role, "assigned": i.Assigned, "changedBy": changedBy, "changedAt": i.ChangedAt}
+}
+func (i IncidentAssignmentGetResponseBodySchema) String() string {
+	changedBy := "nil"
+	if i.ChangedBy != nil {
+		changedBy = i.ChangedBy.String()
+	}
+	return fmt.Sprintf("{'uuid': '%s', 'user': %s, 'role': '%s', 'assigned': %t, 'changedBy': %s, 'changedAt': '%s'}", i.UUID, i.User.String(), i.Role, i.Assigned, changedBy, i.ChangedAt)
+}
+
+type IncidentEventGetResponseBodySchema struct {
+	ResponseSchema `swaggerignore:"true"`
+	UUID           string                     `json:"uuid"`
+	Type           string                     `json:"type"`
+	Field          *string                    `json:"field"`
+	OldValue       *string                    `json:"oldValue"`
+	NewValue       *string                    `json:"newValue"`
+	Actor          *UserGetResponseBodySchema `json:"actor"`
+	Automated      bool                       `json:"automated"`
+	CreatedAt      time.Time                  `json:"createdAt"`
+}
+
+func (i IncidentEventGetResponseBodySchema) JSON() map[string]any {
+	var actor *map[string]any = nil
+	if i.Actor != nil {
+		actor = Pointer(i.Actor.JSON())
+	}
+	return map[string]any{"uuid": i.UUID, "type": i.Type, "field": i.Field, "oldValue": i.OldValue, "newValue": i.NewValue, "actor": actor, "automated": i.Automated, "createdAt": i.CreatedAt}
+}
+func (i IncidentEventGetResponseBodySchema) String() string {
+	field := "nil"
+	if i.Field != nil {
+		field = fmt.Sprintf("'%s'", *i.Field)
+	}
+	oldValue := "nil"
+	if i.OldValue != nil {
+		oldValue = fmt.Sprintf("'%s'", *i.OldValue)
+	}
+	newValue := "nil"
+	if i.NewValue != nil {
+		newValue = fmt.Sprintf("'%s'", *i.NewValue)
+	}
+	actor := "nil"
+	if i.Actor != nil {
+		actor = i.Actor.String()
+	}
+	return fmt.Sprintf("{'uuid': '%s', 'type': '%s', 'field': %s, 'oldValue': %s, 'newValue': %s, 'actor': %s, 'automated': %t, 'createdAt': '%s'}", i.UUID, i.Type, field, oldValue, newValue, actor, i.Automated, i.CreatedAt)
+}
+
+type IncidentLinkGetResponseBodySchema struct {
+	ResponseSchema `swaggerignore:"true"`
+	UUID           string                    `json:"uuid"`
+	Type           string                    `json:"type"`
+	Incident       string                    `json:"incident"`
+	Summary        string                    `json:"summary"`
+	CreatedBy      UserGetResponseBodySchema `json:"createdBy"`
+	CreatedAt      time.Time                 `json:"createdAt"`
+}
+
+func (i IncidentLinkGetResponseBodySchema) JSON() map[string]any {
+	return map[string]any{"uuid": i.UUID, "type": i.Type, "incident": i.Incident, "summary": i.Summary, "createdBy": i.CreatedBy.JSON(), "createdAt": i.CreatedAt}
+}
+func (i IncidentLinkGetResponseBodySchema) String() string {
+	return fmt.Sprintf("{'uuid': '%s', 'type': '%s', 'incident': '%s', 'summary': '%s', 'createdBy': %s, 'createdAt': '%s'}", i.UUID, i.Type, i.Incident, i.Summary, i.CreatedBy.String(), i.CreatedAt)
+}
+
+type IncidentStackFrameGetResponseBodySchema struct {
+	ResponseSchema `swaggerignore:"true"`
+	File           string `json:"file"`
+	Function       string `json:"function"`
+	Line           *uint  `json:"line"`
+	Column         *uint  `json:"column"`
+	InApp          bool   `json:"inApp"`
+	Culprit        bool   `json:"culprit"`
+}
+
+func (i IncidentStackFrameGetResponseBodySchema) JSON() map[string]any {
+	return map[string]any{"file": i.File, "function": i.Function, "line": i.Line, "column": i.Column, "inApp": i.InApp, "culprit": i.Culprit}
+}
+func (i IncidentStackFrameGetResponseBodySchema) String() string {
+	line := "nil"
+	if i.Line != nil {
+		line = fmt.Sprint(*i.Line)
+	}
+	column := "nil"
+	if i.Column != nil {
+		column = fmt.Sprint(*i.Column)
+	}
+	return fmt.Sprintf("{'file': '%s', 'function': '%s', 'line': %s, 'column': %s, 'inApp': %t, 'culprit': %t}", i.File, i.Function, line, column, i.InApp, i.Culprit)
+}
+
+type IncidentSearchGetResponseBodySchema struct {
+	ResponseSchema `swaggerignore:"true"`
+	Score          float64             `json:"score"`
+	Highlights     map[string][]string `json:"highlights"`
+}
+
+func (i IncidentSearchGetResponseBodySchema) JSON() map[string]any {
+	return map[string]any{"score": i.Score, "highlights": i.Highlights}
+}
+func (i IncidentSearchGetResponseBodySchema) String() string {
+	highlights := make([]string, 0)
+	for field, fragments := range i.Highlights {
+		highlights = append(highlights, fmt.Sprintf("'%s': ['%s']", field, strings.Join(fragments, "', '")))
+	}
+	return fmt.Sprintf("{'score': %f, 'highlights': {%s}}", i.Score, strings.Join(highlights, ", "))
 }
 
 type IncidentGetResponseBodySchema struct {
-	ResponseSchema  `swaggerignore:"true"`
-	UUID            string                                 `json:"uuid"`
-	Comments        []IncidentCommentGetResponseBodySchema `json:"comments"`
-	HostsAffected   []HostMachineGetResponseBodySchema     `json:"hostsAffected"`
-	Summary         string                                 `json:"summary"`
-	Description     string                                 `json:"description"`
-	CreatedAt       time.Time                              `json:"createdAt"`
-	ResolvedAt      *time.Time                             `json:"resolvedAt"`
-	ResolvedBy      *UserGetResponseBodySchema             `json:"resolvedBy"`
-	ResolutionTeams []TeamGetResponseBodySchema            `json:"resolutionTeams"`
-	Hash            string                                 `json:"hash"`
+	ResponseSchema     `swaggerignore:"true"`
+	UUID               string                                      `json:"uuid"`
+	Comments           []IncidentCommentGetResponseBodySchema      `json:"comments"`
+	HostsAffected      []HostMachineGetResponseBodySchema          `json:"hostsAffected"`
+	Summary            string                                      `json:"summary"`
+	Description        string                                      `json:"description"`
+	CreatedAt          time.Time                                   `json:"createdAt"`
+	ResolvedAt         *time.Time                                  `json:"resolvedAt"`
+	ResolvedBy         *UserGetResponseBodySchema                  `json:"resolvedBy"`
+	ResolutionTeams    []TeamGetResponseBodySchema                 `json:"resolutionTeams"`
+	Hash               string                                      `json:"hash"`
+	FirstSeenAt        time.Time                                   `json:"firstSeenAt"`
+	LastSeenAt         time.Time                                   `json:"lastSeenAt"`
+	OccurrenceCount    uint                                        `json:"occurrenceCount"`
+	Regressed          bool                                        `json:"regressed"`
+	RegressionCount    uint                                        `json:"regressionCount"`
+	Status             string                                      `json:"status"`
+	StatusHistory      []IncidentStatusChangeGetResponseBodySchema `json:"statusHistory"`
+	Severity           uint                                        `json:"severity"`
+	Impact             uint                                        `json:"impact"`
+	Urgency            uint                                        `json:"urgency"`
+	Priority           uint                                        `json:"priority"`
+	Assignee           *UserGetResponseBodySchema                  `json:"assignee"`
+	Responders         []UserGetResponseBodySchema                 `json:"responders"`
+	AssignmentHistory  []IncidentAssignmentGetResponseBodySchema   `json:"assignmentHistory"`
+	HashAliases        []string                                    `json:"hashAliases"`
+	Links              []IncidentLinkGetResponseBodySchema         `json:"links"`
+	LinkedBy           []IncidentLinkGetResponseBodySchema         `json:"linkedBy"`
+	StackTrace         string                                      `json:"stackTrace"`
+	StackLanguage      string                                      `json:"stackLanguage"`
+	StackFrames        []IncidentStackFrameGetResponseBodySchema   `json:"stackFrames"`
+	FingerprintVersion uint                                        `json:"fingerprintVersion"`
+	MergedInto         *string                                     `json:"mergedInto"`
+	Search             *IncidentSearchGetResponseBodySchema        `json:"search"`
+	// the paths of fields to leave out, such as relations which were not expanded
+	Omit []string `json:"-" swaggerignore:"true"`
 }
 
 func (i IncidentGetResponseBodySchema) JSON() map[string]any {
@@ -319,11 +743,43 @@ func (i IncidentGetResponseBodySchema) JSON() map[string]any {
 	for _, r := range i.ResolutionTeams {
 		resolutionTeams = append(resolutionTeams, r.JSON())
 	}
+	statusHistory := make([]map[string]any, 0)
+	for _, c := range i.StatusHistory {
+		statusHistory = append(statusHistory, c.JSON())
+	}
 	var resolvedBy *map[string]any = nil
 	if i.ResolvedBy != nil {
 		resolvedBy = Pointer(i.ResolvedBy.JSON())
 	}
-	return map[string]any{"uuid": i.UUID, "comments": comments, "hostsAffected": hosts, "summary": i.Summary, "description": i.Description, "createdAt": i.CreatedAt, "resolvedAt": i.ResolvedAt, "resolvedBy": resolvedBy, "resolutionTeams": resolutionTeams, "hash": i.Hash}
+	var assignee *map[string]any = nil
+	if i.Assignee != nil {
+		assignee = Pointer(i.Assignee.JSON())
+	}
+	responders := make([]map[string]any, 0)
+	for _, r := range i.Responders {
+		responders = append(responders, r.JSON())
+	}
+	assignmentHistory := make([]map[string]any, 0)
+	for _, a := range i.AssignmentHistory {
+		assignmentHistory = append(assignmentHistory, a.JSON())
+	}
+	links := make([]map[string]any, 0)
+	for _, l := range i.Links {
+		links = append(links, l.JSON())
+	}
+	linkedBy := make([]map[string]any, 0)
+	for _, l := range i.LinkedBy {
+		linkedBy = append(linkedBy, l.JSON())
+	}
+	stackFrames := make([]map[string]any, 0)
+	for _, f := range i.StackFrames {
+		stackFrames = append(stackFrames, f.JSON())
+	}
+	var search *map[string]any = nil
+	if i.Search != nil {
+		search = Pointer(i.Search.JSON())
+	}
+	return OmitFields(map[string]any{"uuid": i.UUID, "comments": comments, "hostsAffected": hosts, "summary": i.Summary, "description": i.Description, "createdAt": i.CreatedAt, "resolvedAt": i.ResolvedAt, "resolvedBy": resolvedBy, "resolutionTeams": resolutionTeams, "hash": i.Hash, "firstSeenAt": i.FirstSeenAt, "lastSeenAt": i.LastSeenAt, "occurrenceCount": i.OccurrenceCount, "regressed": i.Regressed, "regressionCount": i.RegressionCount, "status": i.Status, "statusHistory": statusHistory, "severity": i.Severity, "impact": i.Impact, "urgency": i.Urgency, "priority": i.Priority, "assignee": assignee, "responders": responders, "assignmentHistory": assignmentHistory, "hashAliases": i.HashAliases, "links": links, "linkedBy": linkedBy, "stackTrace": i.StackTrace, "stackLanguage": i.StackLanguage, "stackFrames": stackFrames, "fingerprintVersion": i.FingerprintVersion, "mergedInto": i.MergedInto, "search": search}, i.Omit)
 }
 func (i IncidentGetResponseBodySchema) String() string {
 	comments := make([]string, 0)
@@ -338,6 +794,10 @@ func (i IncidentGetResponseBodySchema) String() string {
 	for _, r := range i.ResolutionTeams {
 		resolutionTeams = append(resolutionTeams, r.String())
 	}
+	statusHistory := make([]string, 0)
+	for _, c := range i.StatusHistory {
+		statusHistory = append(statusHistory, c.String())
+	}
 	resolvedAt := "nil"
 	if i.ResolvedAt != nil {
 		resolvedAt = fmt.Sprintf("'%s'", *i.ResolvedAt)
@@ -346,7 +806,39 @@ func (i IncidentGetResponseBodySchema) String() string {
 	if i.ResolvedBy != nil {
 		resolvedBy = i.ResolvedBy.String()
 	}
-	return fmt.Sprintf("{'uuid': '%s', 'comments': [%s], 'hostsAffected': [%s], 'summary': '%s', 'description': '%s', 'createdAt': '%s', 'resolvedAt': '%s', 'resolvedBy': %s, 'resolutionTeams': [%s], 'hash': '%s'}", i.UUID, strings.Join(comments, " "), strings.Join(hosts, " "), i.Summary, i.Description, i.CreatedAt, resolvedAt, resolvedBy, strings.Join(resolutionTeams, " "), i.Hash)
+	assignee := "nil"
+	if i.Assignee != nil {
+		assignee = i.Assignee.String()
+	}
+	responders := make([]string, 0)
+	for _, r := range i.Responders {
+		responders = append(responders, r.String())
+	}
+	assignmentHistory := make([]string, 0)
+	for _, a := range i.AssignmentHistory {
+		assignmentHistory = append(assignmentHistory, a.String())
+	}
+	links := make([]string, 0)
+	for _, l := range i.Links {
+		links = append(links, l.String())
+	}
+	linkedBy := make([]string, 0)
+	for _, l := range i.LinkedBy {
+		linkedBy = append(linkedBy, l.String())
+	}
+	stackFrames := make([]string, 0)
+	for _, f := range i.StackFrames {
+		stackFrames = append(stackFrames, f.String())
+	}
+	mergedInto := "nil"
+	if i.MergedInto != nil {
+		mergedInto = fmt.Sprintf("'%s'", *i.MergedInto)
+	}
+	search := "nil"
+	if i.Search != nil {
+		search = i.Search.String()
+	}
+	return fmt.Sprintf("{'uuid': '%s', 'comments': [%s], 'hostsAffected': [%s], 'summary': '%s', 'description': '%s', 'createdAt': '%s', 'resolvedAt': '%s', 'resolvedBy': %s, 'resolutionTeams': [%s], 'hash': '%s', 'firstSeenAt': '%s', 'lastSeenAt': '%s', 'occurrenceCount': %d, 'regressed': %t, 'regressionCount': %d, 'status': '%s', 'statusHistory': [%s], 'severity': %d, 'impact': %d, 'urgency': %d, 'priority': %d, 'assignee': %s, 'responders': [%s], 'assignmentHistory': [%s], 'hashAliases': ['%s'], 'links': [%s], 'linkedBy': [%s], 'stackTrace': '%s', 'stackLanguage': '%s', 'stackFrames': [%s], 'fingerprintVersion': %d, 'mergedInto': %s, 'search': %s}", i.UUID, strings.Join(comments, " "), strings.Join(hosts, " "), i.Summary, i.Description, i.CreatedAt, resolvedAt, resolvedBy, strings.Join(resolutionTeams, " "), i.Hash, i.FirstSeenAt, i.LastSeenAt, i.OccurrenceCount, i.Regressed, i.RegressionCount, i.Status, strings.Join(statusHistory, " "), i.Severity, i.Impact, i.Urgency, i.Priority, assignee, strings.Join(responders, " "), strings.Join(assignmentHistory, " "), strings.Join(i.HashAliases, "', '"), strings.Join(links, " "), strings.Join(linkedBy, " "), i.StackTrace, i.StackLanguage, strings.Join(stackFrames, " "), i.FingerprintVersion, mergedInto, search)
 }
 
 type HostMachineGetResponseBodySchema struct {
@@ -410,6 +902,147 @@ func (p ProviderPutRequestBodySchema) Validate() (int, error) {
 	return -1, nil
 }
 
+type TeamPostPutRequestBodySchema struct {
+	BodySchema `swaggerignore:"true"`
+	Name       string `json:"name"`
+	// whether only the team's members can see the incidents and hosts it owns
+	Private bool `json:"private"`
+}
+
+func (t TeamPostPutRequestBodySchema) Validate() (int, error) {
+	if len(t.Name) == 0 {
+		return 400, errors.New("'name' is required")
+	}
+	if len(t.Name) > 30 {
+		return 400, errors.New("'name' cannot be longer than 30 characters")
+	}
+	return -1, nil
+}
+
+type RolePostPutRequestBodySchema struct {
+	BodySchema  `swaggerignore:"true"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// whether every user holds the role without it being assigned to them
+	Default     bool     `json:"default"`
+	Permissions []string `json:"permissions"`
+}
+
+func (r RolePostPutRequestBodySchema) Validate() (int, error) {
+	if len(r.Name) == 0 {
+		return 400, errors.New("'name' is required")
+	}
+	if len(r.Name) > 30 {
+		return 400, errors.New("'name' cannot be longer than 30 characters")
+	}
+	if len(r.Description) > 255 {
+		return 400, errors.New("'description' cannot be longer than 255 characters")
+	}
+	return -1, nil
+}
+
+type APIKeyPostRequestBodySchema struct {
+	BodySchema `swaggerignore:"true"`
+	Name       string `json:"name"`
+	// the permissions the key can be used for, of those its user holds
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (a APIKeyPostRequestBodySchema) Validate() (int, error) {
+	if len(a.Name) == 0 {
+		return 400, errors.New("'name' is required")
+	}
+	if len(a.Name) > 50 {
+		return 400, errors.New("'name' cannot be longer than 50 characters")
+	}
+	if len(a.Scopes) == 0 {
+		return 400, errors.New("'scopes' must list at least one permission")
+	}
+	if a.ExpiresAt.IsZero() {
+		return 400, errors.New("'expiresAt' is required")
+	}
+	if !a.ExpiresAt.After(time.Now()) {
+		return 400, errors.New("'expiresAt' must be in the future")
+	}
+	return -1, nil
+}
+
+type TeamMemberPutRequestBodySchema struct {
+	BodySchema `swaggerignore:"true"`
+	Role       string `json:"role"`
+}
+
+func (t TeamMemberPutRequestBodySchema) Validate() (int, error) {
+	if t.Role != "" && t.Role != "member" && t.Role != "lead" {
+		return 400, errors.New("'role' must be one of 'member', 'lead'")
+	}
+	return -1, nil
+}
+
+type GroupMappingGetResponseBodySchema struct {
+	ResponseSchema `swaggerignore:"true"`
+	UUID           string `json:"uuid"`
+	Group          string `json:"group"`
+	// the uuid of the team the group's members are added to, with teamRole as their role in it
+	Team     *string `json:"team"`
+	TeamRole *string `json:"teamRole"`
+	// the uuid of the role the group's members are assigned
+	Role  *string `json:"role"`
+	Admin bool    `json:"admin"`
+}
+
+func (g GroupMappingGetResponseBodySchema) JSON() map[string]any {
+	return map[string]any{"uuid": g.UUID, "group": g.Group, "team": g.Team, "teamRole": g.TeamRole, "role": g.Role, "admin": g.Admin}
+}
+func (g GroupMappingGetResponseBodySchema) String() string {
+	optional := func(value *string) string {
+		if value == nil {
+			return "nil"
+		}
+		return fmt.Sprintf("'%s'", *value)
+	}
+	return fmt.Sprintf("{'uuid': '%s', 'group': '%s', 'team': %s, 'teamRole': %s, 'role': %s, 'admin': %t}", g.UUID, g.Group, optional(g.Team), optional(g.TeamRole), optional(g.Role), g.Admin)
+}
+
+type GroupMappingPostRequestBodySchema struct {
+	BodySchema `swaggerignore:"true"`
+	Group      string  `json:"group"`
+	Team       *string `json:"team"`
+	TeamRole   string  `json:"teamRole"`
+	Role       *string `json:"role"`
+	Admin      bool    `json:"admin"`
+}
+
+func (g GroupMappingPostRequestBodySchema) Validate() (int, error) {
+	if len(g.Group) == 0 {
+		return 400, errors.New("'group' is required")
+	}
+	if len(g.Group) > 255 {
+		return 400, errors.New("'group' cannot be longer than 255 characters")
+	}
+	if g.Team == nil && g.Role == nil && !g.Admin {
+		return 400, errors.New("at least one of 'team', 'role' and 'admin' is required")
+	}
+	if g.Team != nil {
+		if _, err := uuid.Parse(*g.Team); err != nil {
+			return 400, errors.New("'team' must be a valid uuid")
+		}
+	}
+	if g.TeamRole != "" && g.Team == nil {
+		return 400, errors.New("'teamRole' can only be given with 'team'")
+	}
+	if g.TeamRole != "" && g.TeamRole != "member" && g.TeamRole != "lead" {
+		return 400, errors.New("'teamRole' must be one of 'member', 'lead'")
+	}
+	if g.Role != nil {
+		if _, err := uuid.Parse(*g.Role); err != nil {
+			return 400, errors.New("'role' must be a valid uuid")
+		}
+	}
+	return -1, nil
+}
+
 type HostMachinePostPutRequestBodySchema struct {
 	BodySchema `swaggerignore:"true"`
 	OS         string  `json:"os"`
@@ -474,13 +1107,159 @@ func (i IncidentCommentPostRequestBodySchema) Validate() (int, error) {
 	return -1, nil
 }
 
+type IncidentAssignmentPostRequestBodySchema struct {
+	BodySchema `swaggerignore:"true"`
+	User       string `json:"user"`
+	Role       string `json:"role"`
+}
+
+func (i IncidentAssignmentPostRequestBodySchema) Validate() (int, error) {
+	if _, err := uuid.Parse(i.User); err != nil {
+		return 400, errors.New("'user' must be a valid UUID")
+	}
+	if i.Role != "" && i.Role != "assignee" && i.Role != "responder" {
+		return 400, errors.New("'role' must be one of 'assignee', 'responder'")
+	}
+	return -1, nil
+}
+
+type IncidentMergePostRequestBodySchema struct {
+	BodySchema `swaggerignore:"true"`
+	Incident   string `json:"incident"`
+}
+
+func (i IncidentMergePostRequestBodySchema) Validate() (int, error) {
+	if _, err := uuid.Parse(i.Incident); err != nil {
+		return 400, errors.New("'incident' must be a valid UUID")
+	}
+	return -1, nil
+}
+
+// The most incidents a bulk action can be applied to at once
+const IncidentBulkLimit int = 100
+
+var IncidentBulkActions []string = []string{"resolve", "reopen", "assignTeam", "comment", "setSeverity", "mergeInto"}
+
+type IncidentBulkPostRequestBodySchema struct {
+	BodySchema `swaggerignore:"true"`
+	// the incidents to act on, given either by their UUIDs or by a query such as 'status:open severity:>=4'
+	Incidents []string `json:"incidents"`
+	Query     *string  `json:"query"`
+	Action    string   `json:"action"`
+	// the team for 'assignTeam', the comment for 'comment', the severity for 'setSeverity' and the incident for 'mergeInto'
+	Team     string `json:"team"`
+	Comment  string `json:"comment"`
+	Severity *uint  `json:"severity"`
+	Target   string `json:"target"`
+	DryRun   bool   `json:"dryRun"`
+}
+
+func (i IncidentBulkPostRequestBodySchema) Validate() (int, error) {
+	if (len(i.Incidents) == 0) == (i.Query == nil) {
+		return 400, errors.New("exactly one of 'incidents' and 'query' is required")
+	}
+	if len(i.Incidents) > IncidentBulkLimit {
+		return 400, fmt.Errorf("'incidents' cannot contain more than %d incidents", IncidentBulkLimit)
+	}
+	for _, incident := range i.Incidents {
+		if _, err := uuid.Parse(incident); err != nil {
+			return 400, errors.New("'incidents' must be a list of valid UUIDs")
+		}
+	}
+	switch i.Action {
+	case "assignTeam":
+		if _, err := uuid.Parse(i.Team); err != nil {
+			return 400, errors.New("'team' must be a valid UUID")
+		}
+	case "comment":
+		return IncidentCommentPostRequestBodySchema{Comment: i.Comment}.Validate()
+	case "setSeverity":
+		if i.Severity == nil {
+			return 400, errors.New("'severity' is required")
+		}
+		return validateIncidentClassification(i.Severity, nil, nil)
+	case "mergeInto":
+		if _, err := uuid.Parse(i.Target); err != nil {
+			return 400, errors.New("'target' must be a valid UUID")
+		}
+	case "resolve", "reopen":
+	default:
+		return 400, fmt.Errorf("'action' must be one of '%s'", strings.Join(IncidentBulkActions, "', '"))
+	}
+	return -1, nil
+}
+
+type IncidentBulkResultSchema struct {
+	ResponseSchema `swaggerignore:"true"`
+	Incident       string `json:"incident"`
+	// the status the action on the incident would have had as a request of its own
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (i IncidentBulkResultSchema) JSON() map[string]any {
+	result := map[string]any{"incident": i.Incident, "status": i.Status}
+	if i.Error != "" {
+		result["error"] = i.Error
+	}
+	return result
+}
+func (i IncidentBulkResultSchema) String() string {
+	return fmt.Sprintf("{'incident': '%s', 'status': %d, 'error': '%s'}", i.Incident, i.Status, i.Error)
+}
+
+type IncidentBulkResponseSchema struct {
+	ResponseSchema `swaggerignore:"true"`
+	Action         string `json:"action"`
+	DryRun         bool   `json:"dryRun"`
+	// whether the changes were kept, which is only when it is not a dry run and the action succeeded on every incident
+	Applied bool                       `json:"applied"`
+	Results []IncidentBulkResultSchema `json:"results"`
+}
+
+func (i IncidentBulkResponseSchema) JSON() map[string]any {
+	results := make([]map[string]any, 0)
+	for _, result := range i.Results {
+		results = append(results, result.JSON())
+	}
+	return map[string]any{"action": i.Action, "dryRun": i.DryRun, "applied": i.Applied, "results": results}
+}
+func (i IncidentBulkResponseSchema) String() string {
+	results := make([]string, 0)
+	for _, result := range i.Results {
+		results = append(results, result.String())
+	}
+	return fmt.Sprintf("{'action': '%s', 'dryRun': %t, 'applied': %t, 'results': [%s]}", i.Action, i.DryRun, i.Applied, strings.Join(results, " "))
+}
+
+type IncidentLinkPostRequestBodySchema struct {
+	BodySchema `swaggerignore:"true"`
+	Incident   string `json:"incident"`
+	Type       string `json:"type"`
+}
+
+func (i IncidentLinkPostRequestBodySchema) Validate() (int, error) {
+	if _, err := uuid.Parse(i.Incident); err != nil {
+		return 400, errors.New("'incident' must be a valid UUID")
+	}
+	if i.Type != "duplicate-of" && i.Type != "caused-by" && i.Type != "related-to" {
+		return 400, errors.New("'type' must be one of 'duplicate-of', 'caused-by', 'related-to'")
+	}
+	return -1, nil
+}
+
 type IncidentPutRequestBodySchema struct {
 	BodySchema      `swaggerignore:"true"`
 	Summary         string   `json:"summary"`
 	Description     string   `json:"description"`
 	HostsAffected   []string `json:"hostsAffected"`
 	ResolutionTeams []string `json:"resolutionTeams"`
-	Resolved        *bool    `json:"resolved"`
+	// deprecated, as incidents are resolved and reopened with POST /incidents/{incident_id}/resolve and /reopen. The
+	// status is left alone if it is not given
+	Resolved *bool `json:"resolved"`
+	Severity *uint `json:"severity"`
+	Impact   *uint `json:"impact"`
+	Urgency  *uint `json:"urgency"`
 }
 
 func (i IncidentPutRequestBodySchema) Validate() (int, error) {
@@ -506,8 +1285,131 @@ func (i IncidentPutRequestBodySchema) Validate() (int, error) {
 			return 400, errors.New("'hostsAffected' must be a list of valid UUIDs")
 		}
 	}
-	if i.Resolved == nil {
-		return 400, errors.New("'resolved' is required")
+	return validateIncidentClassification(i.Severity, i.Impact, i.Urgency)
+}
+
+type PriorityMatrixEntrySchema struct {
+	ResponseSchema `swaggerignore:"true"`
+	BodySchema     `swaggerignore:"true"`
+	Impact         uint `json:"impact"`
+	Urgency        uint `json:"urgency"`
+	Priority       uint `json:"priority"`
+}
+
+func (p PriorityMatrixEntrySchema) JSON() map[string]any {
+	return map[string]any{"impact": p.Impact, "urgency": p.Urgency, "priority": p.Priority}
+}
+func (p PriorityMatrixEntrySchema) String() string {
+	return fmt.Sprintf("{'impact': %d, 'urgency': %d, 'priority': %d}", p.Impact, p.Urgency, p.Priority)
+}
+func (p PriorityMatrixEntrySchema) Validate() (int, error) {
+	if p.Impact < 1 || p.Impact > 3 {
+		return 400, errors.New("'impact' must be between 1 and 3")
+	}
+	if p.Urgency < 1 || p.Urgency > 3 {
+		return 400, errors.New("'urgency' must be between 1 and 3")
+	}
+	if p.Priority < 1 || p.Priority > 5 {
+		return 400, errors.New("'priority' must be between 1 and 5")
+	}
+	return -1, nil
+}
+
+type PriorityMatrixSchema struct {
+	ResponseSchema `swaggerignore:"true"`
+	BodySchema     `swaggerignore:"true"`
+	Entries        []PriorityMatrixEntrySchema `json:"entries"`
+}
+
+func (p PriorityMatrixSchema) JSON() map[string]any {
+	entries := make([]map[string]any, 0)
+	for _, e := range p.Entries {
+		entries = append(entries, e.JSON())
+	}
+	return map[string]any{"entries": entries}
+}
+func (p PriorityMatrixSchema) String() string {
+	entries := make([]string, 0)
+	for _, e := range p.Entries {
+		entries = append(entries, e.String())
+	}
+	return fmt.Sprintf("{'entries': [%s]}", strings.Join(entries, " "))
+}
+func (p PriorityMatrixSchema) Validate() (int, error) {
+	// every impact and urgency combination must be given exactly once
+	seen := make(map[[2]uint]bool, 0)
+	for _, e := range p.Entries {
+		if status, err := e.Validate(); err != nil {
+			return status, err
+		}
+		key := [2]uint{e.Impact, e.Urgency}
+		if seen[key] {
+			return 400, fmt.Errorf("'entries' contains impact %d and urgency %d more than once", e.Impact, e.Urgency)
+		}
+		seen[key] = true
+	}
+	if len(seen) != 9 {
+		return 400, errors.New("'entries' must contain every impact and urgency combination")
+	}
+	return -1, nil
+}
+
+type FingerprintRuleGetResponseBodySchema struct {
+	ResponseSchema `swaggerignore:"true"`
+	UUID           string    `json:"uuid"`
+	Name           string    `json:"name"`
+	Type           string    `json:"type"`
+	Pattern        string    `json:"pattern"`
+	GroupKey       *string   `json:"groupKey"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+func (f FingerprintRuleGetResponseBodySchema) JSON() map[string]any {
+	return map[string]any{"uuid": f.UUID, "name": f.Name, "type": f.Type, "pattern": f.Pattern, "groupKey": f.GroupKey, "createdAt": f.CreatedAt}
+}
+func (f FingerprintRuleGetResponseBodySchema) String() string {
+	groupKey := "nil"
+	if f.GroupKey != nil {
+		groupKey = fmt.Sprintf("'%s'", *f.GroupKey)
+	}
+	return fmt.Sprintf("{'uuid': '%s', 'name': '%s', 'type': '%s', 'pattern': '%s', 'groupKey': %s, 'createdAt': '%s'}", f.UUID, f.Name, f.Type, f.Pattern, groupKey, f.CreatedAt)
+}
+
+type FingerprintRulePostRequestBodySchema struct {
+	BodySchema `swaggerignore:"true"`
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	Pattern    string  `json:"pattern"`
+	GroupKey   *string `json:"groupKey"`
+}
+
+func (f FingerprintRulePostRequestBodySchema) Validate() (int, error) {
+	if len(f.Name) == 0 {
+		return 400, errors.New("'name' is required")
+	}
+	if len(f.Name) > 50 {
+		return 400, errors.New("'name' cannot be longer than 50 characters")
+	}
+	if f.Type != "group" && f.Type != "strip" {
+		return 400, errors.New("'type' must be one of 'group', 'strip'")
+	}
+	if len(f.Pattern) == 0 {
+		return 400, errors.New("'pattern' is required")
+	}
+	if len(f.Pattern) > 500 {
+		return 400, errors.New("'pattern' cannot be longer than 500 characters")
+	}
+	if _, err := regexp.Compile(f.Pattern); err != nil {
+		return 400, errors.New("'pattern' must be a valid regular expression")
+	}
+	if f.Type == "group" && (f.GroupKey == nil || len(strings.TrimSpace(*f.GroupKey)) == 0) {
+		return 400, errors.New("'groupKey' is required for 'group' rules")
+	}
+	if f.Type == "strip" && f.GroupKey != nil {
+		return 400, errors.New("'groupKey' can only be given for 'group' rules")
+	}
+	if f.GroupKey != nil && len(*f.GroupKey) > 100 {
+		return 400, errors.New("'groupKey' cannot be longer than 100 characters")
 	}
 	return -1, nil
 }
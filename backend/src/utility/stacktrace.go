@@ -0,0 +1,207 @@
+package utility
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	StackLanguagePython     string = "python"
+	StackLanguageJavaScript string = "javascript"
+	StackLanguageGo         string = "go"
+	StackLanguageJava       string = "java"
+)
+
+type StackFrame struct {
+	File     string
+	Function string
+	Line     *uint
+	Column   *uint
+	InApp    bool
+	Culprit  bool
+}
+
+var (
+	// File "/app/main.py", line 10, in handler
+	pythonFrameRegex = regexp.MustCompile(`^\s*File "([^"]+)", line (\d+)(?:, in (.+))?$`)
+	// at handler (/app/index.js:10:5), at /app/index.js:10:5 or handler@/app/index.js:10:5
+	javascriptFrameRegex        = regexp.MustCompile(`^\s*at (?:(.+?) \()?(.+?):(\d+):(\d+)\)?$`)
+	javascriptFirefoxFrameRegex = regexp.MustCompile(`^\s*([^@\s]*)@(.+?):(\d+):(\d+)$`)
+	// main.handler(0x1, 0x2) followed by a line of /app/main.go:10 +0x1d
+	goFunctionRegex = regexp.MustCompile(`^(?:created by )?(\S+?)(?:\([^()]*\))?(?: in goroutine \d+)?$`)
+	goFileRegex     = regexp.MustCompile(`^\s+(.+\.go):(\d+)(?: \+0x[0-9a-f]+)?$`)
+	// at com.example.Foo.bar(Foo.java:10)
+	javaFrameRegex = regexp.MustCompile(`^\s*at ([\w$.<>/]+)\.([\w$<>]+)\(([^:)]*)(?::(\d+))?\)$`)
+
+	pythonLibraryPaths     = []string{"site-packages", "dist-packages", "/lib/python", "<frozen", "<string>"}
+	javascriptLibraryPaths = []string{"node_modules", "node:", "internal/", "<anonymous>"}
+	goLibraryPaths         = []string{"/usr/local/go/src/", "/go/pkg/mod/", "/pkg/mod/", "/libexec/src/"}
+	goLibraryFunctions     = []string{"runtime.", "net/http.", "testing."}
+	javaLibraryFunctions   = []string{"java.", "javax.", "jdk.", "sun.", "com.sun.", "kotlin.", "scala.", "org.springframework.", "org.apache.", "org.junit."}
+)
+
+// Parse a raw stack trace from Python, JavaScript, Go or Java into frames, ordered with the most recent call first.
+// The language is detected by whichever format matches the most frames. An empty language is returned if no frames are found.
+// The most recent in-app frame (or the most recent frame, if none are in-app) is marked as the culprit
+func ParseStackTrace(trace string) (string, []StackFrame) {
+	lines := strings.Split(strings.ReplaceAll(trace, "\r\n", "\n"), "\n")
+	language := ""
+	frames := make([]StackFrame, 0)
+	for _, parse := range []func([]string) (string, []StackFrame){parsePythonStackTrace, parseJavaScriptStackTrace, parseGoStackTrace, parseJavaStackTrace} {
+		lang, fs := parse(lines)
+		if len(fs) > len(frames) {
+			language = lang
+			frames = fs
+		}
+	}
+	if len(frames) == 0 {
+		return "", frames
+	}
+
+	culprit := 0
+	for i, frame := range frames {
+		if frame.InApp {
+			culprit = i
+			break
+		}
+	}
+	// java nests the root cause in the last 'Caused by' section, so prefer its frames
+	if language == StackLanguageJava {
+		culprit = javaCulpritFrame(lines, frames, culprit)
+	}
+	frames[culprit].Culprit = true
+	return language, frames
+}
+
+func parsePythonStackTrace(lines []string) (string, []StackFrame) {
+	frames := make([]StackFrame, 0)
+	for _, line := range lines {
+		match := pythonFrameRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		frames = append(frames, StackFrame{
+			File:     match[1],
+			Function: match[3],
+			Line:     parseStackNumber(match[2]),
+			InApp:    !containsAny(match[1], pythonLibraryPaths),
+		})
+	}
+	// python prints the most recent call last
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+	return StackLanguagePython, frames
+}
+
+func parseJavaScriptStackTrace(lines []string) (string, []StackFrame) {
+	frames := make([]StackFrame, 0)
+	for _, line := range lines {
+		match := javascriptFrameRegex.FindStringSubmatch(line)
+		if match == nil {
+			match = javascriptFirefoxFrameRegex.FindStringSubmatch(line)
+		}
+		if match == nil {
+			continue
+		}
+		frames = append(frames, StackFrame{
+			File:     match[2],
+			Function: strings.TrimPrefix(match[1], "async "),
+			Line:     parseStackNumber(match[3]),
+			Column:   parseStackNumber(match[4]),
+			InApp:    !containsAny(match[2], javascriptLibraryPaths),
+		})
+	}
+	return StackLanguageJavaScript, frames
+}
+
+func parseGoStackTrace(lines []string) (string, []StackFrame) {
+	frames := make([]StackFrame, 0)
+	for i := 1; i < len(lines); i++ {
+		fileMatch := goFileRegex.FindStringSubmatch(lines[i])
+		if fileMatch == nil {
+			continue
+		}
+		functionMatch := goFunctionRegex.FindStringSubmatch(strings.TrimSpace(lines[i-1]))
+		if functionMatch == nil {
+			continue
+		}
+		frames = append(frames, StackFrame{
+			File:     fileMatch[1],
+			Function: functionMatch[1],
+			Line:     parseStackNumber(fileMatch[2]),
+			InApp:    !containsAny(fileMatch[1], goLibraryPaths) && !hasAnyPrefix(functionMatch[1], goLibraryFunctions),
+		})
+	}
+	return StackLanguageGo, frames
+}
+
+func parseJavaStackTrace(lines []string) (string, []StackFrame) {
+	frames := make([]StackFrame, 0)
+	for _, line := range lines {
+		match := javaFrameRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		function := match[1] + "." + match[2]
+		frames = append(frames, StackFrame{
+			File:     match[3],
+			Function: function,
+			Line:     parseStackNumber(match[4]),
+			InApp:    match[3] != "Native Method" && !hasAnyPrefix(function, javaLibraryFunctions),
+		})
+	}
+	return StackLanguageJava, frames
+}
+
+// Find the first in-app frame after the last 'Caused by' line, falling back to the given culprit
+func javaCulpritFrame(lines []string, frames []StackFrame, culprit int) int {
+	frame := 0
+	causeStart := -1
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "Caused by:") {
+			causeStart = frame
+		} else if javaFrameRegex.MatchString(line) {
+			frame++
+		}
+	}
+	if causeStart < 0 {
+		return culprit
+	}
+	for i := causeStart; i < len(frames); i++ {
+		if frames[i].InApp {
+			return i
+		}
+	}
+	return culprit
+}
+
+func parseStackNumber(value string) *uint {
+	if value == "" {
+		return nil
+	}
+	number, err := strconv.ParseUint(value, 10, 0)
+	if err != nil {
+		return nil
+	}
+	return Pointer(uint(number))
+}
+
+func containsAny(value string, substrings []string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(value, substring) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(value string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package utility_test
+
+import (
+	"com668-backend/utility"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseStackTrace(t *testing.T) {
+	line := utility.Pointer[uint]
+	tests := []struct {
+		name     string
+		trace    []string
+		language string
+		frames   []utility.StackFrame
+	}{
+		{
+			name: "Python",
+			trace: []string{
+				"Traceback (most recent call last):",
+				`  File "/usr/lib/python3.11/site-packages/flask/app.py", line 1484, in full_dispatch_request`,
+				`  File "/app/main.py", line 10, in handler`,
+				"ValueError: invalid value",
+			},
+			language: utility.StackLanguagePython,
+			frames: []utility.StackFrame{
+				{File: "/app/main.py", Function: "handler", Line: line(10), InApp: true, Culprit: true},
+				{File: "/usr/lib/python3.11/site-packages/flask/app.py", Function: "full_dispatch_request", Line: line(1484)},
+			},
+		},
+		{
+			name: "JavaScript",
+			trace: []string{
+				"TypeError: Cannot read properties of undefined (reading 'id')",
+				"    at handler (/app/index.js:10:5)",
+				"    at Layer.handle [as handle_request] (/app/node_modules/express/lib/router/layer.js:95:5)",
+				"    at /app/server.js:3:1",
+			},
+			language: utility.StackLanguageJavaScript,
+			frames: []utility.StackFrame{
+				{File: "/app/index.js", Function: "handler", Line: line(10), Column: line(5), InApp: true, Culprit: true},
+				{File: "/app/node_modules/express/lib/router/layer.js", Function: "Layer.handle [as handle_request]", Line: line(95), Column: line(5)},
+				{File: "/app/server.js", Line: line(3), Column: line(1), InApp: true},
+			},
+		},
+		{
+			name: "JavaScript Async",
+			trace: []string{
+				"Error: connection refused",
+				"    at process.processTicksAndRejections (node:internal/process/task_queues:95:5)",
+				"    at async main (/app/index.js:4:3)",
+			},
+			language: utility.StackLanguageJavaScript,
+			frames: []utility.StackFrame{
+				{File: "node:internal/process/task_queues", Function: "process.processTicksAndRejections", Line: line(95), Column: line(5)},
+				{File: "/app/index.js", Function: "main", Line: line(4), Column: line(3), InApp: true, Culprit: true},
+			},
+		},
+		{
+			name: "JavaScript Firefox",
+			trace: []string{
+				"handler@https://example.com/app.js:10:5",
+				"@https://example.com/app.js:20:1",
+			},
+			language: utility.StackLanguageJavaScript,
+			frames: []utility.StackFrame{
+				{File: "https://example.com/app.js", Function: "handler", Line: line(10), Column: line(5), InApp: true, Culprit: true},
+				{File: "https://example.com/app.js", Line: line(20), Column: line(1), InApp: true},
+			},
+		},
+		{
+			name: "Go",
+			trace: []string{
+				"panic: runtime error: index out of range [1] with length 1",
+				"",
+				"goroutine 1 [running]:",
+				"net/http.HandlerFunc.ServeHTTP(...)",
+				"\t/usr/local/go/src/net/http/server.go:2136 +0x29",
+				"main.handler(0x1, 0x2)",
+				"\t/app/main.go:10 +0x1d",
+				"created by main.main in goroutine 1",
+				"\t/app/main.go:20 +0x55",
+			},
+			language: utility.StackLanguageGo,
+			frames: []utility.StackFrame{
+				{File: "/usr/local/go/src/net/http/server.go", Function: "net/http.HandlerFunc.ServeHTTP", Line: line(2136)},
+				{File: "/app/main.go", Function: "main.handler", Line: line(10), InApp: true, Culprit: true},
+				{File: "/app/main.go", Function: "main.main", Line: line(20), InApp: true},
+			},
+		},
+		{
+			name: "Java",
+			trace: []string{
+				"java.lang.IllegalStateException: not ready",
+				"\tat sun.reflect.NativeMethodAccessorImpl.invoke0(Native Method)",
+				"\tat com.example.Service.call(Service.java:20)",
+				"\tat java.base/java.lang.Thread.run(Thread.java:833)",
+			},
+			language: utility.StackLanguageJava,
+			frames: []utility.StackFrame{
+				{File: "Native Method", Function: "sun.reflect.NativeMethodAccessorImpl.invoke0"},
+				{File: "Service.java", Function: "com.example.Service.call", Line: line(20), InApp: true, Culprit: true},
+				{File: "Thread.java", Function: "java.base/java.lang.Thread.run", Line: line(833)},
+			},
+		},
+		{
+			name: "Java Caused By",
+			trace: []string{
+				"java.lang.RuntimeException: failed to handle request",
+				"\tat com.example.Controller.handle(Controller.java:12)",
+				"\tat java.base/java.lang.Thread.run(Thread.java:833)",
+				"Caused by: java.lang.IllegalStateException: not ready",
+				"\tat java.base/java.util.Objects.requireNonNull(Objects.java:233)",
+				"\tat com.example.Repository.load(Repository.java:42)",
+				"\t... 1 more",
+			},
+			language: utility.StackLanguageJava,
+			frames: []utility.StackFrame{
+				{File: "Controller.java", Function: "com.example.Controller.handle", Line: line(12), InApp: true},
+				{File: "Thread.java", Function: "java.base/java.lang.Thread.run", Line: line(833)},
+				{File: "Objects.java", Function: "java.base/java.util.Objects.requireNonNull", Line: line(233)},
+				{File: "Repository.java", Function: "com.example.Repository.load", Line: line(42), InApp: true, Culprit: true},
+			},
+		},
+		{
+			name: "Java Caused By Chain",
+			trace: []string{
+				"java.lang.RuntimeException: failed to handle request",
+				"\tat com.example.Controller.handle(Controller.java:12)",
+				"Caused by: java.io.UncheckedIOException: failed to load",
+				"\tat com.example.Repository.load(Repository.java:42)",
+				"\t... 1 more",
+				"Caused by: java.net.ConnectException: Connection refused",
+				"\tat java.base/sun.nio.ch.Net.connect0(Native Method)",
+				"\tat com.example.Client.connect(Client.java:7)",
+				"\t... 2 more",
+			},
+			language: utility.StackLanguageJava,
+			frames: []utility.StackFrame{
+				{File: "Controller.java", Function: "com.example.Controller.handle", Line: line(12), InApp: true},
+				{File: "Repository.java", Function: "com.example.Repository.load", Line: line(42), InApp: true},
+				{File: "Native Method", Function: "java.base/sun.nio.ch.Net.connect0"},
+				{File: "Client.java", Function: "com.example.Client.connect", Line: line(7), InApp: true, Culprit: true},
+			},
+		},
+		{
+			name: "Java Caused By Library Only",
+			trace: []string{
+				"java.lang.RuntimeException: failed to handle request",
+				"\tat com.example.Controller.handle(Controller.java:12)",
+				"Caused by: java.lang.NullPointerException",
+				"\tat java.base/java.util.Objects.requireNonNull(Objects.java:233)",
+			},
+			language: utility.StackLanguageJava,
+			frames: []utility.StackFrame{
+				{File: "Controller.java", Function: "com.example.Controller.handle", Line: line(12), InApp: true, Culprit: true},
+				{File: "Objects.java", Function: "java.base/java.util.Objects.requireNonNull", Line: line(233)},
+			},
+		},
+		{
+			name: "No In-App Frames",
+			trace: []string{
+				"Error: socket hang up",
+				"    at TLSSocket.socketOnEnd (node:_http_client:524:23)",
+				"    at TLSSocket.emit (node:events:525:35)",
+			},
+			language: utility.StackLanguageJavaScript,
+			frames: []utility.StackFrame{
+				{File: "node:_http_client", Function: "TLSSocket.socketOnEnd", Line: line(524), Column: line(23), Culprit: true},
+				{File: "node:events", Function: "TLSSocket.emit", Line: line(525), Column: line(35)},
+			},
+		},
+		{
+			name:   "Unrecognised",
+			trace:  []string{"something went wrong", "and there is no stack trace"},
+			frames: []utility.StackFrame{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, newline := range []string{"\n", "\r\n"} {
+				language, frames := utility.ParseStackTrace(strings.Join(test.trace, newline))
+				if language != test.language {
+					t.Fatalf("language '%s' != '%s'", language, test.language)
+				}
+				if !reflect.DeepEqual(frames, test.frames) {
+					t.Fatalf("frames %+v != %+v", frames, test.frames)
+				}
+			}
+		})
+	}
+}
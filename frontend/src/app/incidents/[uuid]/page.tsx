@@ -148,7 +148,7 @@ export default function IncidentPage({ params }: { params: Promise<{ uuid: strin
         if (postResponse == undefined)
             return;
         setSuccessMessages((prev) => [...prev, "Comment posted successfully"]);
-        setComments((prev) => [{ uuid: postResponse, comment, commentedBy: user as User, commentedAt: new Date().toISOString() } as IncidentComment, ...prev]);
+        setComments((prev) => [{ uuid: postResponse, comment, commentedBy: user as User, automated: false, commentedAt: new Date().toISOString() } as IncidentComment, ...prev]);
         setComment("");
     }
 
@@ -201,10 +201,10 @@ export default function IncidentPage({ params }: { params: Promise<{ uuid: strin
             <Card className="mb-3">
                 <CardHeader>
                     <Row>
-                        <Col className="ms-4">{comment.commentedBy.name}<span style={{color: "gray"}}>{comment.commentedBy.uuid == user.uuid ? " (You)": ""}</span></Col>
+                        <Col className="ms-4">{comment.commentedBy?.name ?? "A.I.M.S"}<span style={{color: "gray"}}>{comment.automated ? " (Automated)" : comment.commentedBy?.uuid == user.uuid ? " (You)": ""}</span></Col>
                         <Col xs={1}>
                             {
-                                (user.admin || comment.commentedBy.uuid == user.uuid) && (
+                                (user.admin || comment.commentedBy?.uuid == user.uuid) && (
                                     <OverlayTrigger overlay={<Tooltip style={{color: pending ? "grey" : "red", cursor: "pointer"}}>Delete Comment</Tooltip>}>
                                         <Trash style={{color: "red", cursor: "pointer"}} onClick={() => pending ? null : deleteComment(index)} />
                                     </OverlayTrigger>
@@ -17,5 +17,7 @@ export interface IncidentComment {
     uuid: string;
     comment: string;
     commentedAt: string;
-    commentedBy: User;
+    // undefined for comments A.I.M.S left automatically
+    commentedBy: User | undefined;
+    automated: boolean;
 }
@@ -8,6 +8,8 @@ env = os.getenv("ENV", "prod").lower()
 assert env in ("dev", "prod"), "Env var `ENV` must be either `dev` or `prod`"
 api_host = os.getenv("API_HOST")
 assert api_host, "Env var `API_HOST` is required"
+api_key = os.getenv("API_KEY")
+assert api_key, "Env var `API_KEY` is required"
 sentry_token = os.getenv("SENTRY_TOKEN")
 assert sentry_token.startswith("sntryu_"), "Invalid env var `SENTRY_TOKEN`"
 
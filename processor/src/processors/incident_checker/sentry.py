@@ -4,7 +4,6 @@ from src.http_clients.backend import backend_client
 from src.http_clients.slack import slack_client
 from typing import Any
 from src.exceptions import ExternalAPIException
-from src.utility import generate_hash
 import re
 
 # Sentry API reqs
@@ -45,29 +44,10 @@ def handle_event(event: dict[str, Any], alert_providers: list[dict[str, Any]]):
 
     # NOTE: future TODO: event["entries"][x]["data"]["type"] == "request" (request values), "breadcrumbs" (logs)
     # for extra context
-    stack_trace = ""
-    for entry in event["entries"]:
-        if entry["type"] == "exception":
-            for value in entry["data"]["values"]:
-                if value["type"] == "Error":
-                    for frame in value["stacktrace"]["frames"]:
-                        if file is not None and frame["filename"] == file or frame["absPath"] == file:
-                            # NOTE: future TODO
-                            # frame["lineNo"] is the line number of the error
-                            # frame["colNo"] is the column number of the error
-                            for ctx in frame["context"]:
-                                # ctx[0] is the line number
-                                stack_trace += ctx[1]
-    incident_hash = generate_hash(stack_trace)
-
-    try:
-        incidents = backend_client.get_incidents({"hash": incident_hash})
-        if not incidents:
-            raise ExternalAPIException("Incident not found")
-        logger.info(f"[SENTRY] Incident already exists for event: {event['id']}")
+    stack_trace = build_stack_trace(event)
+    if not stack_trace:
+        logger.warning(f"[SENTRY] Could not find a stack trace for event: {event['id']}")
         return
-    except ExternalAPIException:
-        pass
 
     # NOTE: Only supporting JavaScript for now
     logger.info(f"[SENTRY] Determining root cause for event: {event['id']}")
@@ -143,16 +123,23 @@ def handle_event(event: dict[str, Any], alert_providers: list[dict[str, Any]]):
         "description": f"{message}\n{endpoint}\n{root_cause}"[:500],
         "hostsAffected": [host["uuid"] for host in hosts],
         "resolutionTeams": resolution_teams,
-        "hash": incident_hash
+        # the backend fingerprints the trace, so the same error is one incident however it is reported
+        "stackTrace": stack_trace[:65535]
     }
 
+    # the backend finds or creates the incident for the hash in one call, so two events with the same hash at once
+    # cannot both create one
     incident_url = None
+    created = False
     try:
-        incident_url = backend_client.create_incident(incident_body)
+        incident_url, created = backend_client.report_occurrence(incident_body)
     except ExternalAPIException as e:
         logger.exception(e)
     if not incident_url:
-        logger.warning(f"[SENTRY] Could not create incident for event: {event['id']}")
+        logger.warning(f"[SENTRY] Could not report incident for event: {event['id']}")
+        return
+    if not created:
+        logger.info(f"[SENTRY] Incident already exists for event: {event['id']}")
         return
 
     users = set()
@@ -175,3 +162,22 @@ def handle_event(event: dict[str, Any], alert_providers: list[dict[str, Any]]):
                     slack_client.send_message(channel["channel"]["id"], f"New incident: {incident_url}")
             except ExternalAPIException as e:
                 logger.exception(e)
+
+
+def build_stack_trace(event: dict[str, Any]) -> str:
+    # the trace as node prints it, with the most recent call first, from the frames Sentry gives with the oldest first
+    lines = []
+    for entry in event["entries"]:
+        if entry["type"] != "exception":
+            continue
+        for value in entry["data"]["values"]:
+            lines.append(f"{value['type']}: {value.get('value') or ''}".rstrip())
+            frames = (value.get("stacktrace") or {}).get("frames") or []
+            for frame in reversed(frames):
+                path = frame.get("absPath") or frame.get("filename")
+                location = f"{path}:{frame.get('lineNo') or 0}:{frame.get('colNo') or 0}"
+                if frame.get("function"):
+                    lines.append(f"    at {frame['function']} ({location})")
+                else:
+                    lines.append(f"    at {location}")
+    return "\n".join(lines)
@@ -27,14 +27,7 @@ def incident_resolver():
                 continue
 
             try:
-                body = {
-                    "resolved": True,
-                    "summary": inc["summary"],
-                    "description": inc["description"],
-                    "hostsAffected": [host["uuid"] for host in inc["hostsAffected"]],
-                    "resolutionTeams": [team["uuid"] for team in inc["resolutionTeams"]],
-                }
-                backend_client.update_incident(inc["uuid"], body)
+                backend_client.resolve_incident(inc["uuid"])
             except ExternalAPIException as e:
                 logger.exception(e)
                 logger.error(f"[A.I.M.S] Failed to resolve incident {inc['uuid']}")
@@ -182,6 +182,8 @@ def mock_api_request(status_code: dict[str, Callable[[dict[str, Any]], int]] = {
             "POST login": lambda **_: 204,
             "POST incidents": lambda **_: 201,
             "PUT incidents": lambda **_: 204,
+            "PUT occurrences": lambda **_: 201,
+            "POST resolve": lambda **_: 204,
             "POST comments": lambda **_: 201,
             "DELETE comments": lambda **_: 204,
         }
@@ -189,6 +191,7 @@ def mock_api_request(status_code: dict[str, Callable[[dict[str, Any]], int]] = {
             "GET events": lambda **_: deepcopy(SENTRY_HEADERS),
             "POST login": lambda **_: {"Authorization": "Bearer test"},
             "POST incidents": lambda **_: {"Location": "http://test.com/incidents/1"},
+            "PUT occurrences": lambda **_: {"Location": "http://test.com/incidents/1"},
             "POST comments": lambda **_: {"Location": "http://test.com/incidents/1/comments/1"},
             "POST providers": lambda **_: {"Location": "http://test.com/providers/1"},
             "POST hosts": lambda **_: {"Location": "http://test.com/hosts/1"}
@@ -214,6 +217,10 @@ def mock_api_request(status_code: dict[str, Callable[[dict[str, Any]], int]] = {
             endpoint = "events"
         elif "comments" in url:
             endpoint = "comments"
+        elif "occurrences" in url:
+            endpoint = "occurrences"
+        elif url.endswith("/resolve"):
+            endpoint = "resolve"
         elif "incidents" in url:
             endpoint = "incidents"
         elif "providers" in url:
@@ -1,5 +1,6 @@
 from unittest.mock import patch, MagicMock
 from src.processors.incident_checker.handler import incident_checker
+from src.processors.incident_checker.sentry import build_stack_trace
 from src.test.fixtures import mock_api_request, SENTRY_HEADERS, SENTRY_EVENTS
 from src.utility import HTTPMethodEnum
 from copy import deepcopy
@@ -20,12 +21,9 @@ class TestIncidentCheckerProcessor:
                 event["errors"][0]["data"]["url"] = "node_modules/.pnpm/test@0.1.0"
             return events
 
-        def mock_incidents(**_):
-            return {"data": []}
-
         events = [lambda **_: deepcopy(SENTRY_EVENTS), mock_events_node_modules, mock_events_node_modules_pnpm]
         for event in events:
-            mock_request.side_effect = mock_api_request(body={"GET events": event, "GET incidents": mock_incidents})
+            mock_request.side_effect = mock_api_request(body={"GET events": event})
 
             incident_checker()
 
@@ -38,8 +36,14 @@ class TestIncidentCheckerProcessor:
                     assert method == HTTPMethodEnum.GET
                 elif "comments" in url:
                     assert method == HTTPMethodEnum.POST
+                elif "occurrences" in url:
+                    assert method == HTTPMethodEnum.PUT
+                    # the backend fingerprints the raw trace, rather than the processor hashing it
+                    assert "hash" not in call.kwargs.get("body")
+                    assert call.kwargs.get("body")["stackTrace"].startswith("Error")
                 elif "incidents" in url:
-                    assert method == HTTPMethodEnum.POST or method == HTTPMethodEnum.GET
+                    # incidents are only reported with the occurrence upsert, never looked up and then created
+                    assert False, f"unexpected incident request: {method} {url}"
                 elif "providers" in url:
                     assert method == HTTPMethodEnum.GET
                 elif "hosts" in url:
@@ -57,6 +61,16 @@ class TestIncidentCheckerProcessor:
                 elif "login" in url:
                     assert method == HTTPMethodEnum.POST
 
+    @patch("src.http_clients.base.APIClient.make_api_request")
+    def test_sentry_handler_existing_incident(self, mock_request: MagicMock):
+        mock_request.side_effect = mock_api_request(status_code={"PUT occurrences": lambda **_: 204})
+
+        incident_checker()
+
+        # another occurrence of an incident which already exists does not alert anybody again
+        assert any(["occurrences" in call.kwargs.get("url") for call in mock_request.call_args_list])
+        assert not any(["conversations.create" in call.kwargs.get("url") for call in mock_request.call_args_list])
+
     @patch("src.http_clients.base.APIClient.make_api_request")
     @patch("src.processors.incident_checker.sentry.handle_event")
     def test_sentry_handler_all_pages(self, mock_handle_event: MagicMock, mock_request: MagicMock):
@@ -74,3 +88,14 @@ class TestIncidentCheckerProcessor:
         incident_checker()
 
         assert mock_handle_event.call_count == 2
+
+    def test_build_stack_trace(self):
+        event = deepcopy(SENTRY_EVENTS[0])
+        event["entries"][0]["data"]["values"][0]["value"] = "boom"
+        event["entries"][0]["data"]["values"][0]["stacktrace"]["frames"] = [
+            {"filename": "index.js", "absPath": "/app/index.js", "function": "main", "lineNo": 3, "colNo": 1},
+            {"filename": "handler.js", "absPath": "/app/handler.js", "function": "handle", "lineNo": 10, "colNo": 5},
+        ]
+
+        # the most recent call comes first, as node prints it
+        assert build_stack_trace(event) == "Error: boom\n    at handle (/app/handler.js:10:5)\n    at main (/app/index.js:3:1)"
@@ -15,8 +15,7 @@ class TestIncidentResolver:
         incident_resolver()
 
         # Assert that it has not tried to resolve the incident
-        assert not any(["incidents" in call.kwargs.get("url") and "comments" not in call.kwargs.get("url")
-                        and call.kwargs.get("method") is HTTPMethodEnum.PUT for call in mock_request.call_args_list])
+        assert not any([call.kwargs.get("url").endswith("/resolve") for call in mock_request.call_args_list])
 
     @patch("src.http_clients.base.APIClient.make_api_request")
     def test_resolve_incident(self, mock_request: MagicMock):
@@ -30,24 +29,23 @@ class TestIncidentResolver:
 
         incident_resolver()
 
-        # Assert that it has tried to update the incident and post a comment
-        assert any(["incidents" in call.kwargs.get("url") and "comments" not in call.kwargs.get("url")
-                    and call.kwargs.get("method") is HTTPMethodEnum.PUT for call in mock_request.call_args_list])
+        # Assert that it has tried to resolve the incident and post a comment
+        assert any([call.kwargs.get("url").endswith("/resolve") and call.kwargs.get("method") is HTTPMethodEnum.POST
+                    for call in mock_request.call_args_list])
         assert any(["comments" in call.kwargs.get("url") and call.kwargs.get("method") is HTTPMethodEnum.POST
                    for call in mock_request.call_args_list])
 
         for call in mock_request.call_args_list:
             url = call.kwargs.get("url")
-            if "incidents" in url and "comments" not in url:
-                if call.kwargs.get("method") == HTTPMethodEnum.PUT:
-                    assert call.kwargs.get("body", {}).get("resolved") is True
-            elif "comments" in url:
+            # the incident is resolved with its transition, never by sending the whole of it back
+            assert call.kwargs.get("method") != HTTPMethodEnum.PUT
+            if "comments" in url:
                 assert call.kwargs.get("method") == HTTPMethodEnum.POST
                 assert call.kwargs.get("body") == {"comment": "Incident automatically resolved due to inactivity"}
 
     @patch("src.http_clients.base.APIClient.make_api_request")
     def test_resolve_incident_delete_comment_on_update_fail(self, mock_request: MagicMock):
-        def mock_put_incident(**_):
+        def mock_resolve_incident(**_):
             raise ExternalAPIException("test")
 
         def mock_incidents(**_):
@@ -58,14 +56,14 @@ class TestIncidentResolver:
             return {"data": incidents}
         mock_request.side_effect = mock_api_request(body={
             "GET incidents": mock_incidents,
-            "PUT incidents": mock_put_incident
+            "POST resolve": mock_resolve_incident
         })
 
         incident_resolver()
 
-        # Assert that it has tried to update the incident and post a comment
-        assert any(["incidents" in call.kwargs.get("url") and "comments" not in call.kwargs.get("url")
-                    and call.kwargs.get("method") is HTTPMethodEnum.PUT for call in mock_request.call_args_list])
+        # Assert that it has tried to resolve the incident and post a comment
+        assert any([call.kwargs.get("url").endswith("/resolve") and call.kwargs.get("method") is HTTPMethodEnum.POST
+                    for call in mock_request.call_args_list])
         assert any(["comments" in call.kwargs.get("url") and call.kwargs.get("method") is HTTPMethodEnum.POST
                     for call in mock_request.call_args_list])
         # Assert that it has tried to delete a comment
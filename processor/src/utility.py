@@ -1,4 +1,3 @@
-import hashlib
 from logging import Formatter, DEBUG, INFO, WARNING, ERROR, CRITICAL
 from typing import Any
 from enum import Enum
@@ -43,7 +42,3 @@ class ThreadLocalStorage:
 
     def get(self, key: str) -> Any:
         return self._storage.get(key)
-
-
-def generate_hash(data: str) -> str:
-    return hashlib.sha1(data.encode()).hexdigest()